@@ -0,0 +1,59 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+// ADCBits restituisce la risoluzione effettiva, in bit, dei campioni prodotti
+// dalla RSP descritta da h con frequenza di campionamento fs, espressa in Hz,
+// prima dell'eventuale decimazione: i modelli successivi alla RSP1 campionano
+// a 14 bit fino a 6.048 MHz, tutti a 12 bit fino a 8.064 MHz, a 10 bit fino a
+// 9.216 MHz ed a 8 bit oltre. Con un modello sconosciuto restituisce 0.
+func (h Hardware) ADCBits(fs float64) int {
+	switch {
+	case h.Model == UnknownModel:
+		return 0
+	case fs > 9.216e6:
+		return 8
+	case fs > 8.064e6:
+		return 10
+	case fs > 6.048e6 || h.Model == RSP1:
+		return 12
+	}
+
+	return 14
+}
+
+// Normalize, se enabled, scala i campioni propagati dalla RSP così che il fondo
+// scala dell'ADC corrisponda a quello dei campioni int16 per ogni modello e
+// frequenza di campionamento: l'API li consegna con la risoluzione
+// dell'ADC, ad esempio entro ±8192 a 14 bit, e senza normalizzazione le
+// conversioni in float del package, come quelle di Spectrum o NewRawWriter,
+// non raggiungono il fondo scala. Con un modello sconosciuto i campioni non
+// vengono scalati.
+func Normalize(enabled bool) Option {
+	return Option{
+		apply: func() {
+			rsp.Normalize = enabled
+		},
+	}
+}
+
+// normalize scala i campioni I e Q, prodotti da un ADC a bits bit, al fondo
+// scala dei campioni int16.
+func normalize(I, Q []int16, bits int) {
+	if bits <= 0 || bits >= 16 {
+		return
+	}
+
+	s := uint(16 - bits)
+	for k := range I {
+		I[k] <<= s
+	}
+	for k := range Q {
+		Q[k] <<= s
+	}
+}
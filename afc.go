@@ -0,0 +1,229 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"math/cmplx"
+	"sync"
+	"time"
+)
+
+// AFC è un Connector che mantiene centrato un trasmettitore la cui frequenza
+// deriva. Stima lo scostamento della portante dalla frequenza sintonizzata,
+// cercandola entro un intervallo di ampiezza span, e lo compensa in uno dei
+// due modi seguenti:
+//   - di default trasla digitalmente il segnale in banda base prima di
+//     propagarlo al connettore out;
+//   - dopo l'invocazione di Retune corregge invece la sintonia del ricevitore
+//     con piccoli passi, propagando ad out il segnale inalterato.
+type AFC struct {
+	mu sync.Mutex
+
+	// fs è la frequenza di campionamento del segnale in ingresso, rate quella
+	// del segnale decimato sul quale avviene la stima, entrambe in Hz.
+	fs, rate float64
+
+	dec *decimator
+
+	// acc è la media dei prodotti tra campioni decimati consecutivi, il cui
+	// argomento è proporzionale allo scostamento; power è la media della loro
+	// potenza e prev l'ultimo campione decimato.
+	acc   complex128
+	power float64
+	prev  complex128
+
+	// average è la costante di tempo della stima, offset lo scostamento
+	// stimato in Hz.
+	average time.Duration
+	offset  float64
+
+	// phase è la fase attuale dell'oscillatore usato per la traslazione
+	// digitale.
+	phase float64
+
+	// tuner, se non nil, abilita la correzione della sintonia: frequency è la
+	// frequenza sintonizzata, deadband lo scostamento minimo che provoca una
+	// correzione, pending indica se una correzione è in corso ed err è
+	// l'errore restituito dall'ultima.
+	tuner     Tuner
+	frequency float64
+	deadband  float64
+	pending   bool
+	err       error
+
+	iq, bb []complex128
+	i, q   []int16
+
+	out Connector
+}
+
+// afcConfidence è il minimo rapporto tra il modulo della correlazione e la
+// potenza del segnale perché la stima sia considerata attendibile: in sua
+// assenza, ad esempio in presenza di solo rumore, lo scostamento non viene
+// aggiornato.
+const afcConfidence = 0.2
+
+// NewAFC restituisce un AFC per un segnale campionato con frequenza fs, che
+// cerca la portante entro span Hz centrati sulla frequenza sintonizzata e
+// propaga il segnale ad out. Di default la stima ha una costante di tempo di
+// 200ms e la compensazione è digitale.
+func NewAFC(fs, span float64, out Connector) *AFC {
+	factor := int(fs / span)
+	if factor < 1 {
+		factor = 1
+	}
+
+	return &AFC{
+		fs:       fs,
+		rate:     fs / float64(factor),
+		dec:      newDecimator(factor, 0.45/float64(factor)),
+		average:  200 * time.Millisecond,
+		deadband: 100,
+		out:      out,
+	}
+}
+
+// Average imposta la costante di tempo della stima dello scostamento.
+func (a *AFC) Average(tau time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.average = tau
+}
+
+// Retune abilita la correzione della sintonia: ogni volta che lo scostamento
+// stimato supera deadband Hz il ricevitore t, attualmente sintonizzato a
+// frequency Hz, viene risintonizzato sulla portante. Con t nil viene ripristinata
+// la compensazione digitale.
+func (a *AFC) Retune(t Tuner, frequency, deadband float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.tuner = t
+	a.frequency = frequency
+	a.deadband = math.Abs(deadband)
+	a.err = nil
+	a.reset()
+}
+
+// Err restituisce l'errore restituito dal ricevitore all'ultima correzione
+// della sintonia, nil se è riuscita. In caso di errore Frequency resta
+// invariata e la correzione viene ritentata con la stima successiva.
+func (a *AFC) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.err
+}
+
+// Offset restituisce lo scostamento stimato della portante, in Hz, rispetto
+// alla frequenza sintonizzata.
+func (a *AFC) Offset() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.offset
+}
+
+// Frequency restituisce la frequenza, espressa in Hz, sulla quale è
+// sintonizzato il ricevitore quando è abilitata la correzione della sintonia.
+func (a *AFC) Frequency() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.frequency
+}
+
+// reset azzera la stima, ad esempio dopo una risintonia.
+func (a *AFC) reset() {
+	a.acc, a.power, a.offset = 0, 0, 0
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (a *AFC) Propagate(I []int16, Q []int16) {
+	a.mu.Lock()
+
+	a.iq = toComplex(I, Q, a.iq[:0])
+	a.estimate()
+
+	var tuner Tuner
+	var frequency float64
+	if a.tuner != nil {
+		if !a.pending && math.Abs(a.offset) > a.deadband {
+			a.pending = true
+			tuner, frequency = a.tuner, a.frequency+a.offset
+		}
+	} else {
+		a.shift()
+		I, Q = a.i, a.q
+	}
+
+	a.mu.Unlock()
+
+	// La risintonia avviene al di fuori del flusso dei campioni, dal quale non
+	// è opportuno invocare le funzioni del ricevitore; la frequenza viene
+	// aggiornata solo se la risintonia riesce.
+	if tuner != nil {
+		go func() {
+			e := tuner.Tune(frequency)
+
+			a.mu.Lock()
+			if e == nil {
+				a.frequency = frequency
+			}
+			a.err = e
+			a.pending = false
+			a.reset()
+			a.mu.Unlock()
+		}()
+	}
+
+	if a.out != nil {
+		a.out.Propagate(I, Q)
+	}
+}
+
+// estimate aggiorna la stima dello scostamento con i campioni in a.iq.
+func (a *AFC) estimate() {
+	a.bb = a.dec.process(a.iq, a.bb[:0])
+	if len(a.bb) == 0 || a.pending {
+		return
+	}
+
+	var acc complex128
+	var power float64
+	for _, x := range a.bb {
+		acc += x * cmplx.Conj(a.prev)
+		power += real(x)*real(x) + imag(x)*imag(x)
+		a.prev = x
+	}
+
+	n := float64(len(a.bb))
+	k := smoothing(a.average, len(a.bb), a.rate)
+	a.acc += complex(k, 0) * (acc/complex(n, 0) - a.acc)
+	a.power += k * (power/n - a.power)
+
+	if a.power > 0 && cmplx.Abs(a.acc) > afcConfidence*a.power {
+		a.offset = cmplx.Phase(a.acc) * a.rate / (2 * math.Pi)
+	}
+}
+
+// shift trasla il segnale in a.iq dello scostamento stimato, scrivendo il
+// risultato in a.i ed a.q.
+func (a *AFC) shift() {
+	a.i, a.q = a.i[:0], a.q[:0]
+
+	step := -2 * math.Pi * a.offset / a.fs
+	for _, x := range a.iq {
+		y := x * cmplx.Rect(fullScale, a.phase)
+		a.i = append(a.i, int16(math.Max(-fullScale, math.Min(fullScale-1, real(y)))))
+		a.q = append(a.q, int16(math.Max(-fullScale, math.Min(fullScale-1, imag(y)))))
+		a.phase = math.Mod(a.phase+step, 2*math.Pi)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/iclac/sdrplay"
+)
+
+// afcTuner è un Tuner che restituisce err ad ogni sintonia.
+type afcTuner struct {
+	err error
+}
+
+// Tune implementa l'interfaccia Tuner.
+func (t afcTuner) Tune(frequency float64) error {
+	return t.err
+}
+
+// TestAFCRetune verifica che AFC aggiorni la frequenza sintonizzata solo se
+// la risintonia riesce, riportando altrimenti l'errore con Err.
+func TestAFCRetune(t *testing.T) {
+	const (
+		fs     = 250e3
+		offset = 1e3
+		tuned  = 100e6
+	)
+
+	I, Q := make([]int16, 2500), make([]int16, 2500)
+
+	failure := errors.New("tune failed")
+	for _, tuner := range []afcTuner{{nil}, {failure}} {
+		a := sdrplay.NewAFC(fs, 10e3, nil)
+		a.Retune(tuner, tuned, 100)
+
+		deadline := time.Now().Add(2 * time.Second)
+		for n := 0; a.Frequency() == tuned && a.Err() == nil && time.Now().Before(deadline); n += len(I) {
+			for k := range I {
+				phase := 2 * math.Pi * offset * float64(n+k) / fs
+				I[k] = int16(10000 * math.Cos(phase))
+				Q[k] = int16(10000 * math.Sin(phase))
+			}
+			a.Propagate(I, Q)
+			time.Sleep(time.Millisecond)
+		}
+
+		switch f := a.Frequency(); {
+		case tuner.err == nil && (a.Err() != nil || math.Abs(f-tuned-offset) > 50):
+			t.Errorf("got %g Hz, %v, want about %g Hz", f, a.Err(), tuned+offset)
+		case tuner.err != nil && (a.Err() != failure || f != tuned):
+			t.Errorf("failing tuner: got %g Hz, %v, want %g Hz, %v", f, a.Err(), float64(tuned), failure)
+		}
+	}
+}
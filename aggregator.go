@@ -0,0 +1,104 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Aggregator è un Connector che raccoglie i frame ricevuti, la cui lunghezza
+// dipende dal numero di campioni per pacchetto dell'API, in blocchi di
+// lunghezza fissa propagati ad out, come richiesto da FFT e da molti decoder.
+// I campioni che non completano un blocco restano in attesa dei frame
+// successivi.
+//
+//	a := sdrplay.NewAggregator(8192, spectrum)
+//	rx, e := sdrplay.RSP(a)
+type Aggregator struct {
+	mu sync.Mutex
+
+	// size è la lunghezza dei blocchi; i e q contengono gli n campioni del
+	// blocco in corso.
+	size int
+	i, q []int16
+	n    int
+
+	out Connector
+}
+
+// NewAggregator restituisce un Aggregator che propaga ad out blocchi di size
+// campioni.
+func NewAggregator(size int, out Connector) *Aggregator {
+	size = maxInt(size, 1)
+
+	return &Aggregator{
+		size: size,
+		i:    make([]int16, size),
+		q:    make([]int16, size),
+		out:  out,
+	}
+}
+
+// NewTimedAggregator restituisce un Aggregator che propaga ad out blocchi di
+// durata d, arrotondata al campione, di un segnale campionato con frequenza fs
+// espressa in Hz.
+func NewTimedAggregator(fs float64, d time.Duration, out Connector) *Aggregator {
+	return NewAggregator(int(math.Round(fs*d.Seconds())), out)
+}
+
+// Size restituisce la lunghezza dei blocchi.
+func (a *Aggregator) Size() int {
+	return a.size
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (a *Aggregator) Propagate(I []int16, Q []int16) {
+	var blocks [][2][]int16
+
+	a.mu.Lock()
+	for len(I) > 0 {
+		k := copy(a.i[a.n:], I)
+		copy(a.q[a.n:], Q[:k])
+		I, Q = I[k:], Q[k:]
+		a.n += k
+
+		if a.n == a.size {
+			blocks = append(blocks, [2][]int16{a.i, a.q})
+			a.i, a.q, a.n = make([]int16, a.size), make([]int16, a.size), 0
+		}
+	}
+	a.mu.Unlock()
+
+	for _, b := range blocks {
+		a.out.Propagate(b[0], b[1])
+	}
+}
+
+// Flush propaga, se presenti, i campioni in attesa come un blocco più corto.
+func (a *Aggregator) Flush() {
+	a.mu.Lock()
+	i, q := a.i[:a.n], a.q[:a.n]
+	if a.n > 0 {
+		a.i, a.q, a.n = make([]int16, a.size), make([]int16, a.size), 0
+	}
+	a.mu.Unlock()
+
+	if len(i) > 0 {
+		a.out.Propagate(i, q)
+	}
+}
+
+// Reset scarta i campioni in attesa, ad esempio dopo un cambio di frequenza.
+func (a *Aggregator) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.n = 0
+}
@@ -0,0 +1,269 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sync"
+)
+
+type (
+	// AISMessage è un messaggio AIS ricevuto da AIS.
+	AISMessage struct {
+		// Channel è il canale di ricezione: 'A' (161.975MHz) o 'B'
+		// (162.025MHz).
+		Channel byte
+
+		// Data contiene i bit del messaggio, esclusi flag e FCS, nell'ordine
+		// definito dalla raccomandazione ITU-R M.1371 (il primo bit è il bit
+		// più significativo del primo byte). Bits è il numero di bit validi.
+		Data []byte
+		Bits int
+
+		// NMEA contiene le sentenze AIVDM che codificano il messaggio, pronte
+		// per essere inviate ad un programma di cartografia o ad un
+		// aggregatore.
+		NMEA []string
+	}
+
+	// AIS è un Connector che riceve contemporaneamente i due canali AIS,
+	// centrati a -25kHz e +25kHz dalla frequenza sintonizzata, come con le
+	// opzioni restituite da AISPreset. Per ciascun canale demodula il segnale
+	// GMSK a 9600 baud, decodifica la codifica NRZI, estrae i frame HDLC
+	// verificandone il CRC e passa i messaggi ottenuti alla funzione report.
+	AIS struct {
+		mu sync.Mutex
+
+		dec *decimator
+		ch  [2]*aisChannel
+
+		// seq è l'identificativo progressivo dei messaggi composti da più
+		// sentenze NMEA.
+		seq int
+
+		iq, bb []complex128
+
+		report func(AISMessage)
+	}
+
+	// aisChannel contiene lo stato della demodulazione di un canale AIS.
+	aisChannel struct {
+		name byte
+
+		// step ed osc rappresentano l'oscillatore che trasla il canale in
+		// banda base.
+		step, osc complex128
+
+		dec *decimator
+
+		// prev è l'ultimo campione, serve al discriminatore.
+		prev complex128
+
+		// clock recupera il clock di bit, frame estrae i frame HDLC.
+		clock bitClock
+		frame hdlc
+
+		sh, bb []complex128
+	}
+)
+
+const (
+	// aisBaud è la velocità di trasmissione AIS in bit al secondo.
+	aisBaud = 9600
+
+	// aisOffset è lo scostamento, espresso in Hz, dei canali AIS dalla
+	// frequenza centrale di 162MHz.
+	aisOffset = 25e3
+
+	// aisRate è la frequenza intermedia, espressa in Hz, alla quale viene
+	// decimato il segnale che contiene entrambi i canali.
+	aisRate = 100e3
+
+	// aisMinBits e aisMaxBits sono le lunghezze minima e massima, in bit e
+	// comprensive di FCS, di un frame AIS.
+	aisMinBits = 72 + 16
+	aisMaxBits = 5*256 + 16
+
+	// aisPayload è il numero massimo di caratteri del payload di una singola
+	// sentenza AIVDM.
+	aisPayload = 60
+)
+
+// AISPreset restituisce le opzioni che configurano la RSP per la ricezione AIS
+// su entrambi i canali: sintonia a 162MHz, campionamento a 2MHz con banda di
+// 200kHz ed IF nulla.
+func AISPreset() []Option {
+	return []Option{
+		InitialRF(162),
+		FS(2.0),
+		Bandwidth(BW200),
+		IF(IFzero),
+		LOmode(LOauto),
+	}
+}
+
+// NewAIS restituisce un AIS per un segnale in banda base campionato con
+// frequenza fs, espressa in Hz, che passa i messaggi ricevuti a report.
+func NewAIS(fs float64, report func(AISMessage)) *AIS {
+	factor := int(fs / aisRate)
+	if factor < 1 {
+		factor = 1
+	}
+	rate := fs / float64(factor)
+
+	return &AIS{
+		dec: newDecimator(factor, math.Min(40e3/fs, 0.45/float64(factor))),
+		ch: [2]*aisChannel{
+			newAISChannel('A', -aisOffset, rate),
+			newAISChannel('B', aisOffset, rate),
+		},
+		report: report,
+	}
+}
+
+// newAISChannel restituisce il demodulatore del canale name, centrato ad offset
+// Hz nel segnale campionato a rate Hz.
+func newAISChannel(name byte, offset, rate float64) *aisChannel {
+	factor := int(rate / (4 * aisBaud))
+	if factor < 1 {
+		factor = 1
+	}
+
+	return &aisChannel{
+		name:  name,
+		step:  cmplx.Rect(1, -2*math.Pi*offset/rate),
+		osc:   1,
+		dec:   newDecimator(factor, 0.45*math.Min(7.5e3/rate, 1/float64(factor))),
+		clock: newBitClock(aisBaud, rate/float64(factor)),
+		frame: hdlc{max: aisMaxBits},
+	}
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (a *AIS) Propagate(I []int16, Q []int16) {
+	a.mu.Lock()
+
+	a.iq = toComplex(I, Q, a.iq[:0])
+	a.bb = a.dec.process(a.iq, a.bb[:0])
+
+	var msgs []AISMessage
+	for _, c := range a.ch {
+		for _, data := range c.process(a.bb) {
+			m := AISMessage{Channel: c.name, Data: data, Bits: 8 * len(data)}
+			m.NMEA = a.nmea(m)
+			msgs = append(msgs, m)
+		}
+	}
+
+	report := a.report
+	a.mu.Unlock()
+
+	if report != nil {
+		for _, m := range msgs {
+			report(m)
+		}
+	}
+}
+
+// process demodula i campioni in e restituisce i byte dei messaggi completati.
+func (c *aisChannel) process(in []complex128) [][]byte {
+	// Traslazione in banda base del canale. L'oscillatore viene normalizzato
+	// ad ogni frame per evitare la deriva della sua ampiezza.
+	c.sh = c.sh[:0]
+	for _, x := range in {
+		c.sh = append(c.sh, x*c.osc)
+		c.osc *= c.step
+	}
+	c.osc /= complex(cmplx.Abs(c.osc), 0)
+
+	c.bb = c.dec.process(c.sh, c.bb[:0])
+
+	var msgs [][]byte
+	for _, x := range c.bb {
+		f := imag(x * cmplx.Conj(c.prev))
+		c.prev = x
+
+		if bit, ok := c.clock.sample(f > 0); ok {
+			if bits := c.frame.push(bit); bits != nil {
+				if m := aisFrame(bits); m != nil {
+					msgs = append(msgs, m)
+				}
+			}
+		}
+	}
+
+	return msgs
+}
+
+// aisFrame verifica la FCS del frame HDLC composto dai bit indicati e ne
+// restituisce i byte esclusa la FCS, oppure nil se il frame non è valido. Come
+// in ogni frame HDLC i byte sono trasmessi a partire dal bit meno
+// significativo, mentre il messaggio AIS che compongono va letto a partire dal
+// bit più significativo del primo byte.
+func aisFrame(bits []byte) []byte {
+	if len(bits) < aisMinBits || len(bits) > aisMaxBits {
+		return nil
+	}
+
+	return hdlcBytes(bits)
+}
+
+// nmea codifica il messaggio m in una o più sentenze AIVDM.
+func (a *AIS) nmea(m AISMessage) []string {
+	// Armatura a 6 bit del payload.
+	var payload []byte
+	for k := 0; k < m.Bits; k += 6 {
+		var v byte
+		for i := 0; i < 6; i++ {
+			v <<= 1
+			if n := k + i; n < m.Bits && m.Data[n/8]&(0x80>>uint(n%8)) != 0 {
+				v |= 1
+			}
+		}
+
+		c := v + 48
+		if c > 87 {
+			c += 8
+		}
+		payload = append(payload, c)
+	}
+	fill := (6 - m.Bits%6) % 6
+
+	total := (len(payload) + aisPayload - 1) / aisPayload
+	seq := ""
+	if total > 1 {
+		seq = fmt.Sprint(a.seq)
+		a.seq = (a.seq + 1) % 10
+	}
+
+	var out []string
+	for n := 0; n < total; n++ {
+		end := (n + 1) * aisPayload
+		if end > len(payload) {
+			end = len(payload)
+		}
+
+		f := 0
+		if n == total-1 {
+			f = fill
+		}
+
+		body := fmt.Sprintf("AIVDM,%d,%d,%s,%c,%s,%d", total, n+1, seq, m.Channel, payload[n*aisPayload:end], f)
+
+		var cs byte
+		for _, c := range []byte(body) {
+			cs ^= c
+		}
+
+		out = append(out, fmt.Sprintf("!%s*%02X", body, cs))
+	}
+
+	return out
+}
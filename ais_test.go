@@ -0,0 +1,132 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/iclac/sdrplay"
+)
+
+// aisDearmor restituisce i byte del payload AIVDM p, armato a 6 bit.
+func aisDearmor(p string) []byte {
+	data := make([]byte, (6*len(p)+7)/8)
+	for k, c := range []byte(p) {
+		v := c - 48
+		if v > 40 {
+			v -= 8
+		}
+		for i := 0; i < 6; i++ {
+			if n := 6*k + i; v&(0x20>>uint(i)) != 0 {
+				data[n/8] |= 0x80 >> uint(n%8)
+			}
+		}
+	}
+
+	return data
+}
+
+// aisModulate restituisce i campioni, campionati con frequenza fs, del frame
+// HDLC che contiene data trasmesso in FSK a 9600 baud con codifica NRZI sul
+// canale centrato ad offset Hz.
+func aisModulate(data []byte, fs, offset float64) ([]int16, []int16) {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for k := 0; k < 8; k++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	crc = ^crc
+	frame := append(append([]byte(nil), data...), byte(crc), byte(crc>>8))
+
+	// Preambolo, flag di apertura, byte del frame a partire dal bit meno
+	// significativo con bit stuffing e flag di chiusura.
+	var bits []bool
+	for k := 0; k < 24; k++ {
+		bits = append(bits, k%2 == 1)
+	}
+	flag := []bool{false, true, true, true, true, true, true, false}
+	bits = append(bits, flag...)
+	ones := 0
+	for _, b := range frame {
+		for i := 0; i < 8; i++ {
+			bit := b&(1<<uint(i)) != 0
+			bits = append(bits, bit)
+			if ones = ones + 1; !bit {
+				ones = 0
+			}
+			if ones == 5 {
+				bits, ones = append(bits, false), 0
+			}
+		}
+	}
+	bits = append(bits, flag...)
+	bits = append(bits, flag...)
+
+	// Codifica NRZI: uno 0 inverte il livello, un 1 lo conserva.
+	sps := fs / 9600
+	var I, Q []int16
+	level, phase := true, 0.0
+	for k, b := range bits {
+		if !b {
+			level = !level
+		}
+
+		f := offset - 2400
+		if level {
+			f = offset + 2400
+		}
+
+		for n := int(float64(k) * sps); n < int(float64(k+1)*sps); n++ {
+			phase += 2 * math.Pi * f / fs
+			I = append(I, int16(10000*math.Cos(phase)))
+			Q = append(Q, int16(10000*math.Sin(phase)))
+		}
+	}
+
+	return I, Q
+}
+
+// TestAISMessage verifica che AIS decodifichi un messaggio modulato sul canale
+// A, restituendone i byte e la sentenza AIVDM originali.
+func TestAISMessage(t *testing.T) {
+	const (
+		fs       = 2e6
+		payload  = "13u?etPv2;0n:dDPwUM1U1Cb069D"
+		sentence = "!AIVDM,1,1,,A," + payload + ",0*24"
+	)
+
+	data := aisDearmor(payload)
+
+	var msgs []sdrplay.AISMessage
+	a := sdrplay.NewAIS(fs, func(m sdrplay.AISMessage) { msgs = append(msgs, m) })
+
+	I, Q := aisModulate(data, fs, -25e3)
+	silence := make([]int16, 20000)
+	a.Propagate(silence, silence)
+	a.Propagate(I, Q)
+	a.Propagate(silence, silence)
+
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+
+	m := msgs[0]
+	if m.Channel != 'A' || m.Bits != 8*len(data) || string(m.Data) != string(data) {
+		t.Errorf("got channel %c, %d bits %X, want A, %d bits %X", m.Channel, m.Bits, m.Data, 8*len(data), data)
+	}
+	if len(m.NMEA) != 1 || m.NMEA[0] != sentence {
+		t.Errorf("got %q, want %q", m.NMEA, sentence)
+	}
+}
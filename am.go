@@ -0,0 +1,153 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"math/cmplx"
+	"sync"
+	"time"
+)
+
+// AM è un Connector che demodula un segnale AM centrato nella frequenza
+// sintonizzata, adatto sia alla radiodiffusione sia alla banda aeronautica, e
+// propaga l'audio ottenuto, a AudioRate campioni al secondo, al connettore
+// audio fornito.
+// Di default la demodulazione è ad inviluppo; abilitando l'aggancio della
+// portante (CarrierTracking) si ottiene invece una demodulazione sincrona, più
+// robusta rispetto al fading selettivo tipico delle onde corte.
+type AM struct {
+	mu sync.Mutex
+
+	// rate è la frequenza intermedia, espressa in Hz, alla quale avviene la
+	// demodulazione.
+	rate float64
+
+	dec, ch *decimator
+	rs      *resampler
+
+	// sync indica se è abilitato l'aggancio della portante, phase e freq sono
+	// lo stato del PLL espressi in radianti e radianti per campione.
+	sync        bool
+	phase, freq float64
+
+	// carrier è l'ampiezza media della portante, usata sia per eliminare la
+	// componente continua sia per normalizzare il livello audio.
+	carrier float64
+	dc      float64
+
+	iq, bb, cb []complex128
+	af, pcm    []float64
+	audio      []float32
+
+	out AudioConnector
+}
+
+const (
+	// amRate è la minima frequenza intermedia, espressa in Hz, alla quale
+	// viene demodulato il segnale AM.
+	amRate = 48e3
+
+	// amLoop è la banda del PLL di aggancio della portante espressa in Hz.
+	amLoop = 30.0
+)
+
+// NewAM restituisce un demodulatore AM per un segnale in banda base campionato
+// con frequenza fs, che seleziona un canale di larghezza bandwidth (entrambe
+// espresse in Hz) e propaga l'audio demodulato al connettore out. Valori tipici
+// di bandwidth sono 9-10kHz per la radiodiffusione e 6-8kHz per la banda
+// aeronautica.
+func NewAM(fs, bandwidth float64, out AudioConnector) *AM {
+	factor := int(fs / math.Max(amRate, 2*bandwidth))
+	if factor < 1 {
+		factor = 1
+	}
+
+	a := &AM{
+		rate: fs / float64(factor),
+		dec:  newDecimator(factor, 0.4/float64(factor)),
+		out:  out,
+	}
+
+	a.Bandwidth(bandwidth)
+
+	return a
+}
+
+// Bandwidth imposta la larghezza, espressa in Hz, del canale demodulato. La
+// banda audio risultante è pari alla metà di bandwidth.
+func (a *AM) Bandwidth(bandwidth float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cutoff := math.Min(bandwidth/2/a.rate, 0.45)
+	a.ch = newFilter(cutoff, 127)
+	a.rs = newResampler(a.rate, AudioRate, bandwidth/2)
+}
+
+// CarrierTracking abilita o meno la demodulazione sincrona, nella quale un PLL
+// aggancia la portante del segnale ricevuto.
+func (a *AM) CarrierTracking(enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.sync = enabled
+	a.phase, a.freq = 0, 0
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (a *AM) Propagate(I []int16, Q []int16) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.iq = toComplex(I, Q, a.iq[:0])
+	a.bb = a.dec.process(a.iq, a.bb[:0])
+	a.cb = a.ch.process(a.bb, a.cb[:0])
+
+	// Coefficienti del PLL del secondo ordine con smorzamento critico.
+	wn := 2 * math.Pi * amLoop / a.rate
+	alpha, beta := 2*0.707*wn, wn*wn
+
+	// La portante viene stimata con una costante di tempo lunga rispetto al
+	// periodo delle frequenze audio più basse.
+	k := smoothing(50*time.Millisecond, 1, a.rate)
+
+	a.af = a.af[:0]
+	for _, x := range a.cb {
+		var env float64
+		if a.sync {
+			y := x * cmplx.Rect(1, -a.phase)
+			e := math.Atan2(imag(y), real(y))
+
+			a.freq += beta * e
+			a.phase = math.Mod(a.phase+a.freq+alpha*e, 2*math.Pi)
+
+			env = real(y)
+		} else {
+			env = cmplx.Abs(x)
+		}
+
+		a.carrier += k * (math.Abs(env) - a.carrier)
+		a.dc += k * (env - a.dc)
+
+		var v float64
+		if a.carrier > floor {
+			v = (env - a.dc) / a.carrier
+		}
+
+		a.af = append(a.af, v)
+	}
+
+	a.pcm = a.rs.process(a.af, a.pcm[:0])
+	if len(a.pcm) == 0 || a.out == nil {
+		return
+	}
+
+	a.audio = toAudio(a.pcm, a.audio[:0])
+	a.out.PropagateAudio(a.audio)
+}
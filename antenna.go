@@ -0,0 +1,141 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"fmt"
+	"io"
+)
+
+type (
+	// AntennaSwitch è l'interfaccia che descrive un commutatore d'antenna
+	// esterno alla RSP, ad esempio un relè pilotato dai GPIO di una SBC oppure
+	// una scheda relè collegata ad una porta seriale.
+	AntennaSwitch interface {
+		// Select collega al ricevitore l'antenna connessa alla porta port del
+		// commutatore.
+		Select(port int) error
+	}
+
+	// AntennaRange associa un intervallo di frequenze, espresse in Hz, alla
+	// porta del commutatore alla quale è collegata l'antenna da usare per tali
+	// frequenze. L'intervallo comprende From ma non To.
+	AntennaRange struct {
+		From, To float64
+		Port     int
+	}
+
+	// antennas contiene il commutatore d'antenna ed il piano di commutazione
+	// impostati con l'opzione Antennas.
+	antennas struct {
+		sw   AntennaSwitch
+		plan []AntennaRange
+	}
+
+	// GPIOSwitch è un AntennaSwitch che codifica in binario la porta da
+	// selezionare sulle linee GPIO esportate tramite l'interfaccia sysfs di
+	// Linux. Il primo pin corrisponde al bit meno significativo.
+	GPIOSwitch struct {
+		pins []int
+	}
+
+	// SerialSwitch è un AntennaSwitch che seleziona la porta scrivendo un
+	// comando testuale verso una scheda relè, tipicamente collegata ad una
+	// porta seriale già aperta e configurata dall'utilizzatore.
+	SerialSwitch struct {
+		w      io.Writer
+		format string
+	}
+)
+
+// Antennas permette di affidare ad un commutatore esterno la scelta
+// dell'antenna: ogni volta che la RSP viene sintonizzata, sia all'avvio sia con
+// Tune o SetUp, viene selezionata la porta associata all'intervallo del plan
+// che contiene la nuova frequenza. Se nessun intervallo la contiene, la porta
+// attuale non viene cambiata.
+func Antennas(sw AntennaSwitch, plan ...AntennaRange) Option {
+	return Option{
+		apply: func() {
+			rsp.Antenna = antennas{sw: sw, plan: plan}
+		},
+	}
+}
+
+// port restituisce la porta associata alla frequenza f espressa in Hz e
+// indica se questa è stata trovata nel piano di commutazione.
+func (a antennas) port(f float64) (int, bool) {
+	if a.sw == nil {
+		return 0, false
+	}
+
+	for _, r := range a.plan {
+		if r.From <= f && f < r.To {
+			return r.Port, true
+		}
+	}
+
+	return 0, false
+}
+
+// switchAntenna seleziona l'antenna adatta alla frequenza f espressa in Hz,
+// comandando il commutatore solo se la porta deve effettivamente cambiare.
+func (r *radio) switchAntenna(f float64) error {
+	p, ok := r.feat.Antenna.port(f)
+	if !ok || p == r.antenna {
+		return nil
+	}
+
+	if e := r.feat.Antenna.sw.Select(p); e != nil {
+		return e
+	}
+
+	r.antenna = p
+
+	return nil
+}
+
+// NewGPIOSwitch esporta, se necessario, i pin GPIO indicati e li configura come
+// uscite. Con n pin si possono indirizzare 2^n porte.
+func NewGPIOSwitch(pins ...int) (*GPIOSwitch, error) {
+	for _, p := range pins {
+		if e := exportGPIO(p); e != nil {
+			return nil, e
+		}
+	}
+
+	return &GPIOSwitch{pins: pins}, nil
+}
+
+// Select implementa l'interfaccia AntennaSwitch.
+func (g *GPIOSwitch) Select(port int) error {
+	if port < 0 || port >= 1<<uint(len(g.pins)) {
+		return fmt.Errorf("antenna port %d out of range", port)
+	}
+
+	for k, p := range g.pins {
+		if e := writeGPIO(p, port&(1<<uint(k)) != 0); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// NewSerialSwitch restituisce un SerialSwitch che per selezionare una porta
+// scrive su w il comando ottenuto formattando il numero di porta con format
+// (secondo le regole di fmt), ad esempio "RELAY %d ON\r\n".
+func NewSerialSwitch(w io.Writer, format string) *SerialSwitch {
+	return &SerialSwitch{w: w, format: format}
+}
+
+// Select implementa l'interfaccia AntennaSwitch.
+func (s *SerialSwitch) Select(port int) error {
+	_, e := fmt.Fprintf(s.w, s.format, port)
+
+	return e
+}
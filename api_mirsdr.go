@@ -0,0 +1,396 @@
+//go:build !nosdr && !mock
+
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+/*
+
+ #cgo CFLAGS: -I/usr/local/include
+
+ #include "mirsdrapi-rsp.h"
+ #include <stdlib.h>
+
+ float api_ver = MIR_SDR_API_VERSION;
+
+ extern void StreamCallback(short *xi, short *xq, unsigned int firstSampleNum, int grChanged, int rfChanged, int fsChanged, unsigned int numSamples, unsigned int reset, unsigned int hwRemoved, void *cbContext);
+
+ extern void AGCCallback(unsigned int grdB, unsigned int lnagrdB, void *cbContext);
+
+ // streamCallback è la funzione che viene invocata dall'API SDRplay quando ci
+ // sono campioni da processare o quando la RSP è stata disconnessa; la sua
+ // firma è quella di mir_sdr_StreamCallback_t dell'API 2.x.
+ static inline void streamCallback(short *xi, short *xq, unsigned int firstSampleNum, int grChanged, int rfChanged, int fsChanged, unsigned int numSamples, unsigned int reset, unsigned int hwRemoved, void *cbContext) {
+	StreamCallback(xi, xq, firstSampleNum, grChanged, rfChanged, fsChanged, numSamples, reset, hwRemoved, cbContext);
+ }
+
+ // agcCallback è la funzione che viene invocata dall'API SDRplay quando ci sono
+ // delle variazioni di guadagno nel loop di retroazione del AGC.
+ static inline void agcCallback(unsigned int grdB, unsigned int lnagrdB, void *cbContext) {
+	AGCCallback(grdB, lnagrdB, cbContext);
+ }
+
+ // streamInit è la funzione che invoca l'API mir_sdr_StreamInit.
+ mir_sdr_ErrT streamInit(int *gRdB, double fsMHz, double rfMHz, mir_sdr_Bw_MHzT bwType, mir_sdr_If_kHzT ifType, int LNAEnable, int *gRdBsystem, int useGrAltMode, int *samplesPerPacket) {
+	return mir_sdr_StreamInit(gRdB, fsMHz, rfMHz, bwType, ifType, LNAEnable, gRdBsystem, useGrAltMode, samplesPerPacket, streamCallback, agcCallback, (void *)NULL);
+ }
+*/
+import "C"
+
+// mirsdr è il driver che invoca la libreria SDRplay attraverso cgo.
+type mirsdr struct{}
+
+// api è il driver usato da radio.
+var api driver = mirsdr{}
+
+// maxDevices è il numero massimo di RSP restituite da Devices.
+const maxDevices = 16
+
+// apiVersion implementa l'interfaccia driver.
+func (mirsdr) apiVersion() (float64, error) {
+	if e := loadLibrary(); e != nil {
+		return 0, e
+	}
+
+	var v C.float
+	if e := toError("mir_sdr_ApiVersion", C.mir_sdr_ApiVersion(&v)); e != nil {
+		return 0, e
+	}
+
+	return float64(v), nil
+}
+
+// expectedVersion implementa l'interfaccia driver restituendo la versione
+// dell'header con cui è stato compilato il package.
+func (mirsdr) expectedVersion() float64 {
+	return float64(C.api_ver)
+}
+
+// devices implementa l'interfaccia driver.
+func (mirsdr) devices() ([]DeviceInfo, error) {
+	if e := loadLibrary(); e != nil {
+		return nil, e
+	}
+
+	var devs [maxDevices]C.mir_sdr_DeviceT
+	var n C.uint
+
+	if e := toError("mir_sdr_GetDevices", C.mir_sdr_GetDevices(&devs[0], &n, maxDevices)); e != nil {
+		return nil, e
+	}
+
+	infos := make([]DeviceInfo, 0, int(n))
+	for _, d := range devs[:int(n)] {
+		hw := int(d.hwVer)
+
+		infos = append(infos, DeviceInfo{
+			Serial:    C.GoString(d.SerNo),
+			Name:      C.GoString(d.DevNm),
+			Model:     model(hw),
+			HwVersion: hw,
+			Available: d.devAvail != 0,
+		})
+	}
+
+	return infos, nil
+}
+
+// streamInit implementa l'interfaccia driver. La gain reduction viene sempre
+// gestita in modalità alternativa, cioè con useGrAltMode pari ad 1.
+func (mirsdr) streamInit(p *streamParams) error {
+	if e := loadLibrary(); e != nil {
+		return e
+	}
+
+	gr, grsys, spp := p.gr.C(), C.int(0), C.int(0)
+
+	// LNA è di tipo enable, ma a differenza di tutti gli altri valori che
+	// permettono di abilitare una particolare caratteristica che sono di tipo
+	// unsigned int, questo è di tipo int. Per questo motivo è necessario il
+	// cast a C.int.
+	e := toError("mir_sdr_StreamInit", C.streamInit(&gr, p.fs.C(), p.rf.C(), p.bw.C(), p.ifm.C(), C.int(p.lna.C()), &grsys, 1, &spp))
+
+	p.gr, p.grsys, p.spp = integer(gr), integer(grsys), integer(spp)
+
+	return e
+}
+
+// reinit implementa l'interfaccia driver.
+func (mirsdr) reinit(p *streamParams, reason reinitReason) error {
+	gr, grsys, spp := p.gr.C(), C.int(0), C.int(0)
+
+	e := toError("mir_sdr_Reinit", C.mir_sdr_Reinit(&gr, p.fs.C(), p.rf.C(), p.bw.C(), p.ifm.C(), p.lo.C(), C.int(p.lna.C()), &grsys, 1, &spp, C.mir_sdr_ReasonForReinitT(reason)))
+
+	p.gr, p.grsys, p.spp = integer(gr), integer(grsys), integer(spp)
+
+	return e
+}
+
+// streamUninit implementa l'interfaccia driver.
+func (mirsdr) streamUninit() error {
+	return toError("mir_sdr_StreamUninit", C.mir_sdr_StreamUninit())
+}
+
+// setRf implementa l'interfaccia driver.
+func (mirsdr) setRf(rf double) error {
+	return toError("mir_sdr_SetRf", C.mir_sdr_SetRf(rf.C(), 1, 0))
+}
+
+// shiftRf implementa l'interfaccia driver.
+func (mirsdr) shiftRf(drf double) error {
+	return toError("mir_sdr_SetRf", C.mir_sdr_SetRf(drf.C(), 0, 0))
+}
+
+// syncRf implementa l'interfaccia driver.
+func (mirsdr) syncRf(rf double, sample uint32) error {
+	if e := toError("mir_sdr_SetSyncUpdateSampleNum", C.mir_sdr_SetSyncUpdateSampleNum(C.uint(sample))); e != nil {
+		return e
+	}
+
+	return toError("mir_sdr_SetRf", C.mir_sdr_SetRf(rf.C(), 1, 1))
+}
+
+// syncGr implementa l'interfaccia driver.
+func (mirsdr) syncGr(gr integer, lna enable, sample uint32) (integer, error) {
+	if e := toError("mir_sdr_SetSyncUpdateSampleNum", C.mir_sdr_SetSyncUpdateSampleNum(C.uint(sample))); e != nil {
+		return 0, e
+	}
+
+	g, grsys := gr.C(), C.int(0)
+
+	e := toError("mir_sdr_SetGrAltMode", C.mir_sdr_SetGrAltMode(&g, C.int(lna.C()), &grsys, 1, 1))
+
+	return integer(grsys), e
+}
+
+// setSyncPeriod implementa l'interfaccia driver.
+func (mirsdr) setSyncPeriod(period uint32) error {
+	return toError("mir_sdr_SetSyncUpdatePeriod", C.mir_sdr_SetSyncUpdatePeriod(C.uint(period)))
+}
+
+// setGr implementa l'interfaccia driver.
+func (mirsdr) setGr(gr integer, lna enable) (integer, error) {
+	g, grsys := gr.C(), C.int(0)
+
+	e := toError("mir_sdr_SetGrAltMode", C.mir_sdr_SetGrAltMode(&g, C.int(lna.C()), &grsys, 1, 0))
+
+	return integer(grsys), e
+}
+
+// selectDevice implementa l'interfaccia driver. L'eventuale RSP selezionata in
+// precedenza viene rilasciata.
+func (mirsdr) selectDevice(idx int) error {
+	C.mir_sdr_ReleaseDeviceIdx()
+
+	return toError("mir_sdr_SetDeviceIdx", C.mir_sdr_SetDeviceIdx(C.uint(idx)))
+}
+
+// releaseDevice implementa l'interfaccia driver.
+func (mirsdr) releaseDevice() {
+	C.mir_sdr_ReleaseDeviceIdx()
+}
+
+// setParam implementa l'interfaccia driver.
+func (mirsdr) setParam(id, value uint32) error {
+	return toError("mir_sdr_SetParam", C.mir_sdr_SetParam(C.uint(id), C.uint(value)))
+}
+
+// setBiasT implementa l'interfaccia driver.
+func (mirsdr) setBiasT(model Model, on enable) error {
+	switch model {
+	case RSP1A:
+		return toError("mir_sdr_rsp1a_BiasT", C.mir_sdr_rsp1a_BiasT(C.int(on.C())))
+	case RSP2:
+		return toError("mir_sdr_RSPII_BiasTControl", C.mir_sdr_RSPII_BiasTControl(on.C()))
+	case RSPduo:
+		return toError("mir_sdr_rspDuo_BiasT", C.mir_sdr_rspDuo_BiasT(C.int(on.C())))
+	}
+
+	return &UnsupportedFeature{Option: "BiasT", Model: model}
+}
+
+// setNotch implementa l'interfaccia driver. La RSP2 ha un solo notch, per MW
+// ed FM.
+func (mirsdr) setNotch(model Model, broadcast, dab enable) error {
+	switch model {
+	case RSP1A:
+		if e := toError("mir_sdr_rsp1a_BroadcastNotch", C.mir_sdr_rsp1a_BroadcastNotch(C.int(broadcast.C()))); e != nil {
+			return e
+		}
+
+		return toError("mir_sdr_rsp1a_DabNotch", C.mir_sdr_rsp1a_DabNotch(C.int(dab.C())))
+	case RSP2:
+		if dab {
+			return &UnsupportedFeature{Option: "DABNotch", Model: model}
+		}
+
+		return toError("mir_sdr_RSPII_RfNotchEnable", C.mir_sdr_RSPII_RfNotchEnable(broadcast.C()))
+	case RSPduo:
+		if e := toError("mir_sdr_rspDuo_BroadcastNotch", C.mir_sdr_rspDuo_BroadcastNotch(C.int(broadcast.C()))); e != nil {
+			return e
+		}
+
+		return toError("mir_sdr_rspDuo_DabNotch", C.mir_sdr_rspDuo_DabNotch(C.int(dab.C())))
+	}
+
+	return &UnsupportedFeature{Option: "BroadcastNotch", Model: model}
+}
+
+// setInput implementa l'interfaccia driver. Sulla RSPduo l'ingresso coincide
+// con il tuner; la RSPdx non è gestita dalla libreria mir_sdr, che non ne
+// controlla quindi gli ingressi.
+func (mirsdr) setInput(model Model, in Input) error {
+	switch {
+	case in == InputA && (model == RSP1 || model == RSP1A):
+		return nil
+	case model == RSP2 && in <= InputB:
+		return toError("mir_sdr_RSPII_AntennaControl", C.mir_sdr_RSPII_AntennaControl(C.mir_sdr_RSPII_AntennaSelectT(int(C.mir_sdr_RSPII_ANTENNA_A)+int(in))))
+	case model == RSPduo && in <= InputB:
+		return toError("mir_sdr_rspDuo_TunerSel", C.mir_sdr_rspDuo_TunerSel(C.mir_sdr_rspDuo_TunerSelT(int(C.mir_sdr_rspDuo_Tuner_1)+int(in))))
+	}
+
+	return &UnsupportedFeature{Option: "AntennaInput", Model: model}
+}
+
+// setAmPort implementa l'interfaccia driver. La RSPduo dispone della porta Hi-Z
+// solo sul tuner 1.
+func (mirsdr) setAmPort(model Model, hiZ enable) error {
+	switch model {
+	case RSP2:
+		return toError("mir_sdr_AmPortSelect", C.mir_sdr_AmPortSelect(C.int(hiZ.C())))
+	case RSPduo:
+		return toError("mir_sdr_rspDuo_Tuner1AmPortSel", C.mir_sdr_rspDuo_Tuner1AmPortSel(C.int(hiZ.C())))
+	}
+
+	return &UnsupportedFeature{Option: "HiZ", Model: model}
+}
+
+// setDuoMode implementa l'interfaccia driver. La libreria mir_sdr controlla la
+// RSPduo solo come ricevitore con un tuner: le modalità Slave e DualTuner
+// richiedono la nuova API sdrplay_api e vengono quindi rifiutate.
+func (mirsdr) setDuoMode(mode DuoMode) error {
+	if mode != SingleTuner {
+		return &UnsupportedFeature{Option: "Duo", Model: RSPduo}
+	}
+
+	return nil
+}
+
+// setTransferMode implementa l'interfaccia driver.
+func (mirsdr) setTransferMode(mode TransferMode) error {
+	if e := loadLibrary(); e != nil {
+		return e
+	}
+
+	return toError("mir_sdr_SetTransferMode", C.mir_sdr_SetTransferMode(mode.C()))
+}
+
+// setDcMode implementa l'interfaccia driver.
+func (mirsdr) setDcMode(mode OffsetMode, trackTime integer) {
+	C.mir_sdr_SetDcMode(mode.C(), 0)
+	C.mir_sdr_SetDcTrackTime(trackTime.C())
+}
+
+// setPpm implementa l'interfaccia driver.
+func (mirsdr) setPpm(ppm double) error {
+	if e := loadLibrary(); e != nil {
+		return e
+	}
+
+	return toError("mir_sdr_SetPpm", C.mir_sdr_SetPpm(ppm.C()))
+}
+
+// agcControl implementa l'interfaccia driver. L'aggiornamento è sempre
+// immediato.
+func (mirsdr) agcControl(mode AGCmode, dBFS integer, lna enable) {
+	C.mir_sdr_AgcControl(mode.C(), dBFS.C(), 0, 0, 0, 0, C.int(lna.C()))
+}
+
+// debugEnable implementa l'interfaccia driver.
+func (mirsdr) debugEnable(on enable) {
+	C.mir_sdr_DebugEnable(on.C())
+}
+
+// dcOffsetIQimbalance implementa l'interfaccia driver.
+func (mirsdr) dcOffsetIQimbalance(dc, iq enable) {
+	C.mir_sdr_DCoffsetIQimbalanceControl(dc.C(), iq.C())
+}
+
+// decimateControl implementa l'interfaccia driver.
+func (mirsdr) decimateControl(on enable, factor Decimation) {
+	C.mir_sdr_DecimateControl(on.C(), factor.C(), 0)
+}
+
+// setLoMode implementa l'interfaccia driver.
+func (mirsdr) setLoMode(mode LOfrequency) {
+	C.mir_sdr_SetLoMode(mode.C())
+}
+
+// toError traduce il codice di errore e restituito dalla funzione fn dell'API
+// in un APIError, nil in caso di successo.
+func toError(fn string, e C.mir_sdr_ErrT) error {
+	if e == C.mir_sdr_Success {
+		return nil
+	}
+
+	return apiError(e).in(fn)
+}
+
+// C traduce il valore di e nel formato compreso dall'API SDRplay.
+func (e enable) C() C.uint {
+	if e {
+		return 1
+	}
+
+	return 0
+}
+
+// C traduce il valore di d nel formato compreso dall'API SDRplay.
+func (d double) C() C.double {
+	return C.double(d)
+}
+
+// C traduce il valore di i nel formato compreso dall'API SDRplay.
+func (i integer) C() C.int {
+	return C.int(i)
+}
+
+// C traduce il valore di bw nel formato compreso dall'API SDRplay.
+func (bw B) C() C.mir_sdr_Bw_MHzT {
+	return C.mir_sdr_Bw_MHzT(bw)
+}
+
+// C traduce il valore di ifm nel formato compreso dall'API SDRplay.
+func (ifm IFmode) C() C.mir_sdr_If_kHzT {
+	return C.mir_sdr_If_kHzT(ifm)
+}
+
+// C traduce il valore di om nel formato compreso dall'API SDRplay.
+func (om OffsetMode) C() C.int {
+	return C.int(om - 1)
+}
+
+// C traduce il valore di olf nel formato compreso dall'API SDRplay.
+func (olf LOfrequency) C() C.mir_sdr_LoModeT {
+	return C.mir_sdr_LoModeT(olf)
+}
+
+// C traduce il valore di tm nel formato compreso dall'API SDRplay.
+func (tm TransferMode) C() C.mir_sdr_TransferModeT {
+	return C.mir_sdr_TransferModeT(tm)
+}
+
+// C traduce il valore di df nel formato compreso dall'API SDRplay.
+func (df Decimation) C() C.uint {
+	return C.uint(df)
+}
+
+// C traduce il valore di agc nel formato compreso dall'API SDRplay.
+func (agc AGCmode) C() C.mir_sdr_AgcControlT {
+	return C.mir_sdr_AgcControlT(agc)
+}
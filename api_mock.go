@@ -0,0 +1,514 @@
+//go:build nosdr || mock
+
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// mock è il driver che simula in memoria una RSP1A, usato compilando con il
+// build tag nosdr o mock così che i test e la CI delle applicazioni possano
+// girare senza la RSP né la libreria SDRplay. Lo stream produce, al ritmo della
+// frequenza di campionamento impostata, un rumore debole ed una portante posta
+// mockTone Hz sopra la frequenza sintonizzata. Il rumore è generato con un seme
+// fisso, così che i campioni prodotti siano riproducibili.
+type mock struct {
+	mu sync.Mutex
+
+	p        streamParams
+	decimate enable
+	factor   Decimation
+	transfer TransferMode
+	params   map[uint32]uint32
+
+	// biasT, broadcast e dab sono le impostazioni di bias-T e notch.
+	biasT, broadcast, dab enable
+
+	// duo è la modalità della RSPduo richiesta.
+	duo DuoMode
+
+	// ppm è l'ultima correzione dell'oscillatore applicata.
+	ppm double
+
+	// gain indica che il guadagno è cambiato e va riportato, come fa l'API,
+	// prima del pacchetto successivo.
+	gain bool
+
+	// unplugged indica che la RSP simulata è stata disconnessa con
+	// MockUnplug e lost che lo stream in corso appartiene ad una RSP
+	// disconnessa, che una volta ricollegata risulta quindi disponibile.
+	unplugged bool
+	lost      bool
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+const (
+	// mockVersion è la versione dell'API dichiarata dal driver simulato.
+	mockVersion = 2.13
+
+	// mockPacket è il numero di campioni per pacchetto.
+	mockPacket = 1008
+
+	// mockTone è lo scarto in Hz della portante simulata dalla frequenza
+	// sintonizzata, mockLevel e mockNoise le ampiezze di portante e rumore.
+	mockTone  = 100e3
+	mockLevel = 1000
+	mockNoise = 30
+
+	// mockLNA è il gain reduction dell'LNA simulato quando è disabilitato.
+	mockLNA = 24
+)
+
+// api è il driver usato da radio.
+var api driver = &mock{}
+
+// apiVersion implementa l'interfaccia driver.
+func (m *mock) apiVersion() (float64, error) {
+	return mockVersion, nil
+}
+
+// expectedVersion implementa l'interfaccia driver.
+func (m *mock) expectedVersion() float64 {
+	return mockVersion
+}
+
+// devices implementa l'interfaccia driver.
+func (m *mock) devices() ([]DeviceInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.unplugged {
+		return nil, nil
+	}
+
+	return []DeviceInfo{{
+		Serial:    "MOCK0001",
+		Name:      "SDRplay Dev0 RSP1A (mock)",
+		Model:     RSP1A,
+		HwVersion: 255,
+		Available: m.stop == nil || m.lost,
+	}}, nil
+}
+
+// streamInit implementa l'interfaccia driver.
+func (m *mock) streamInit(p *streamParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop != nil {
+		return apiAlreadyInitialised.in("mir_sdr_StreamInit")
+	}
+
+	if m.unplugged {
+		return apiHwError.in("mir_sdr_StreamInit")
+	}
+
+	p.grsys, p.spp = p.gr, mockPacket
+	m.p = *p
+	m.gain = true
+
+	m.stop = make(chan struct{})
+	m.stopped = make(chan struct{})
+	go m.run(m.stop, m.stopped)
+
+	return nil
+}
+
+// reinit implementa l'interfaccia driver.
+func (m *mock) reinit(p *streamParams, reason reinitReason) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop == nil {
+		return apiNotInitialised.in("mir_sdr_Reinit")
+	}
+
+	if m.unplugged {
+		return apiHwRemoved.in("mir_sdr_Reinit")
+	}
+
+	if reason&changeGR != 0 {
+		m.p.gr, m.p.lna = p.gr, p.lna
+		m.gain = true
+	}
+	if reason&changeFS != 0 {
+		m.p.fs = p.fs
+	}
+	if reason&changeRF != 0 {
+		m.p.rf = p.rf
+	}
+	if reason&changeBW != 0 {
+		m.p.bw = p.bw
+	}
+	if reason&changeIF != 0 {
+		m.p.ifm = p.ifm
+	}
+	if reason&changeLO != 0 {
+		m.p.lo = p.lo
+	}
+
+	p.grsys, p.spp = m.p.gr, mockPacket
+
+	return nil
+}
+
+// streamUninit implementa l'interfaccia driver ed attende la fine della
+// goroutine che produce i campioni.
+func (m *mock) streamUninit() error {
+	m.mu.Lock()
+	stop, stopped := m.stop, m.stopped
+	m.stop, m.lost = nil, false
+	m.mu.Unlock()
+
+	if stop == nil {
+		return apiNotInitialised.in("mir_sdr_StreamUninit")
+	}
+
+	close(stop)
+	<-stopped
+
+	return nil
+}
+
+// setRf implementa l'interfaccia driver.
+func (m *mock) setRf(rf double) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop == nil {
+		return apiNotInitialised.in("mir_sdr_SetRf")
+	}
+
+	if m.unplugged {
+		return apiHwRemoved.in("mir_sdr_SetRf")
+	}
+
+	m.p.rf = rf / 1e6
+
+	return nil
+}
+
+// shiftRf implementa l'interfaccia driver.
+func (m *mock) shiftRf(drf double) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop == nil {
+		return apiNotInitialised.in("mir_sdr_SetRf")
+	}
+
+	if m.unplugged {
+		return apiHwRemoved.in("mir_sdr_SetRf")
+	}
+
+	m.p.rf += drf / 1e6
+
+	return nil
+}
+
+// syncRf implementa l'interfaccia driver. Il mock non numera i campioni ed
+// applica subito la variazione.
+func (m *mock) syncRf(rf double, sample uint32) error {
+	return m.setRf(rf)
+}
+
+// syncGr implementa l'interfaccia driver come syncRf.
+func (m *mock) syncGr(gr integer, lna enable, sample uint32) (integer, error) {
+	return m.setGr(gr, lna)
+}
+
+// setSyncPeriod implementa l'interfaccia driver.
+func (m *mock) setSyncPeriod(period uint32) error {
+	return nil
+}
+
+// setGr implementa l'interfaccia driver.
+func (m *mock) setGr(gr integer, lna enable) (integer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop == nil {
+		return 0, apiNotInitialised.in("mir_sdr_SetGrAltMode")
+	}
+
+	if m.unplugged {
+		return 0, apiHwRemoved.in("mir_sdr_SetGrAltMode")
+	}
+
+	m.p.gr, m.p.lna = gr, lna
+	m.gain = true
+
+	return gr, nil
+}
+
+// selectDevice implementa l'interfaccia driver. La RSP simulata è l'unica
+// disponibile.
+func (m *mock) selectDevice(idx int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.unplugged || idx != 0 {
+		return apiInvalidParam.in("mir_sdr_SetDeviceIdx")
+	}
+
+	return nil
+}
+
+// releaseDevice implementa l'interfaccia driver.
+func (m *mock) releaseDevice() {}
+
+// setParam implementa l'interfaccia driver memorizzando il valore del
+// parametro.
+func (m *mock) setParam(id, value uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.params == nil {
+		m.params = map[uint32]uint32{}
+	}
+	m.params[id] = value
+
+	return nil
+}
+
+// setTransferMode implementa l'interfaccia driver. Come l'API, accetta la
+// modalità solo a stream fermo.
+func (m *mock) setTransferMode(mode TransferMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop != nil {
+		return apiAlreadyInitialised.in("mir_sdr_SetTransferMode")
+	}
+
+	m.transfer = mode
+
+	return nil
+}
+
+// setBiasT implementa l'interfaccia driver. La RSP1A simulata dispone del
+// bias-T.
+func (m *mock) setBiasT(model Model, on enable) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if model != RSP1A {
+		return &UnsupportedFeature{Option: "BiasT", Model: model}
+	}
+	m.biasT = on
+
+	return nil
+}
+
+// setNotch implementa l'interfaccia driver. La RSP1A simulata dispone dei notch
+// FM e DAB.
+func (m *mock) setNotch(model Model, broadcast, dab enable) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if model != RSP1A {
+		return &UnsupportedFeature{Option: "BroadcastNotch", Model: model}
+	}
+	m.broadcast, m.dab = broadcast, dab
+
+	return nil
+}
+
+// setInput implementa l'interfaccia driver. La RSP1A simulata ha il solo
+// ingresso A.
+func (m *mock) setInput(model Model, in Input) error {
+	if in != InputA {
+		return &UnsupportedFeature{Option: "AntennaInput", Model: model}
+	}
+
+	return nil
+}
+
+// setAmPort implementa l'interfaccia driver. La RSP1A simulata non dispone
+// della porta Hi-Z.
+func (m *mock) setAmPort(model Model, hiZ enable) error {
+	return &UnsupportedFeature{Option: "HiZ", Model: model}
+}
+
+// setDuoMode implementa l'interfaccia driver. La RSP simulata non è una
+// RSPduo: la modalità viene solo memorizzata.
+func (m *mock) setDuoMode(mode DuoMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.duo = mode
+
+	return nil
+}
+
+// setDcMode implementa l'interfaccia driver.
+func (m *mock) setDcMode(mode OffsetMode, trackTime integer) {}
+
+// setPpm implementa l'interfaccia driver. La correzione non ha effetto sul
+// segnale simulato ma viene memorizzata e restituita da MockPPM. Come l'API,
+// prima dello stream si limita a memorizzarla.
+func (m *mock) setPpm(ppm double) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop != nil && m.unplugged {
+		return apiHwRemoved.in("mir_sdr_SetPpm")
+	}
+
+	m.ppm = ppm
+
+	return nil
+}
+
+// agcControl implementa l'interfaccia driver.
+func (m *mock) agcControl(mode AGCmode, dBFS integer, lna enable) {}
+
+// debugEnable implementa l'interfaccia driver.
+func (m *mock) debugEnable(on enable) {}
+
+// dcOffsetIQimbalance implementa l'interfaccia driver.
+func (m *mock) dcOffsetIQimbalance(dc, iq enable) {}
+
+// decimateControl implementa l'interfaccia driver.
+func (m *mock) decimateControl(on enable, factor Decimation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.decimate, m.factor = on, factor
+}
+
+// setLoMode implementa l'interfaccia driver.
+func (m *mock) setLoMode(mode LOfrequency) {}
+
+// MockUnplug simula la disconnessione della RSP: lo stream smette di produrre
+// campioni, le funzioni dell'API restituiscono l'errore HwRemoved e la RSP non
+// compare più tra i dispositivi fino all'invocazione di MockReplug. È
+// disponibile solo compilando con il build tag nosdr o mock, per provare il
+// comportamento delle applicazioni in caso di disconnessione.
+func MockUnplug() {
+	m := api.(*mock)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.unplugged = true
+	m.lost = m.stop != nil
+}
+
+// MockReplug simula la riconnessione della RSP disconnessa con MockUnplug. Lo
+// stream riprende solo dopo una nuova inizializzazione.
+func MockReplug() {
+	m := api.(*mock)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.unplugged = false
+}
+
+// MockPPM restituisce l'ultima correzione dell'oscillatore, espressa in ppm,
+// applicata alla RSP simulata. È disponibile solo compilando con il build tag
+// nosdr o mock.
+func MockPPM() float64 {
+	m := api.(*mock)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return float64(m.ppm)
+}
+
+// rate restituisce la frequenza di campionamento in uscita espressa in Hz,
+// tenendo conto della decimazione.
+func (m *mock) rate() float64 {
+	fs := float64(m.p.fs) * 1e6
+	if m.decimate && m.factor > 1 {
+		fs /= float64(m.factor)
+	}
+
+	return fs
+}
+
+// run produce i pacchetti di campioni fino alla chiusura di stop.
+func (m *mock) run(stop, stopped chan struct{}) {
+	defer close(stopped)
+
+	noise := rand.New(rand.NewSource(1))
+	xi := make([]int16, mockPacket)
+	xq := make([]int16, mockPacket)
+
+	var (
+		phase float64
+		first uint32
+	)
+	next := time.Now()
+
+	wait := time.NewTimer(0)
+	defer wait.Stop()
+
+	for {
+		m.mu.Lock()
+		fs, unplugged := m.rate(), m.unplugged
+		gain, gr, lnagr := m.gain, int(m.p.gr), 0
+		if !m.p.lna {
+			lnagr = mockLNA
+		}
+		m.gain = false
+		m.mu.Unlock()
+
+		// Come la RSP reale, quella disconnessa lo segnala alla callback con
+		// il flag hwRemoved, smette di produrre campioni e lo stream non
+		// riprende fino alla successiva StreamInit.
+		if unplugged {
+			rx.hwRemoved()
+			return
+		}
+
+		if fs <= 0 {
+			fs = 2e6
+		}
+
+		step := 2 * math.Pi * mockTone / fs
+		for k := range xi {
+			s, c := math.Sincos(phase)
+			xi[k] = int16(mockLevel*c + mockNoise*noise.NormFloat64())
+			xq[k] = int16(mockLevel*s + mockNoise*noise.NormFloat64())
+			phase = math.Mod(phase+step, 2*math.Pi)
+		}
+
+		next = next.Add(time.Duration(float64(mockPacket) / fs * float64(time.Second)))
+
+		if !wait.Stop() {
+			select {
+			case <-wait.C:
+			default:
+			}
+		}
+		wait.Reset(time.Until(next))
+
+		select {
+		case <-stop:
+			return
+		case <-wait.C:
+		}
+
+		if gain {
+			rx.gainChanged(gr+lnagr, lnagr)
+		}
+
+		rx.stream(xi, xq, first, false)
+		first += uint32(len(xi))
+	}
+}
@@ -0,0 +1,254 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"sync"
+)
+
+type (
+	// AudioDevice è l'interfaccia che descrive un'uscita audio, ad esempio la
+	// scheda audio di sistema, alla quale AudioSink affida la riproduzione. I
+	// campioni sono normalizzati come quelli di AudioConnector e, nel caso di
+	// più canali, interlacciati.
+	AudioDevice interface {
+		// Write riproduce i campioni forniti, bloccando se necessario fino a
+		// quando l'uscita è in grado di accettarli.
+		Write(samples []float32) error
+
+		// Close termina la riproduzione.
+		Close() error
+	}
+
+	// AudioSink è un AudioConnector ed uno StereoConnector che riproduce
+	// l'audio demodulato su un AudioDevice. La scrittura avviene in una
+	// goroutine dedicata, così che la riproduzione non blocchi la ricezione:
+	// se la coda dei frame in attesa è piena i nuovi frame vengono scartati e
+	// conteggiati. Un ricevitore FM completo si ottiene con:
+	//
+	//	dev, e := sdrplay.NewSystemAudio(sdrplay.AudioRate, 1)
+	//	sink := sdrplay.NewAudioSink(dev)
+	//	rx, e := sdrplay.RSP(sdrplay.NewWBFM(2.048e6, sink), sdrplay.InitialRF(98.5))
+	AudioSink struct {
+		dev    AudioDevice
+		frames chan []float32
+		done   chan struct{}
+
+		mu      sync.Mutex
+		err     error
+		dropped int
+		closed  bool
+	}
+
+	// CommandAudio è un AudioDevice che riproduce l'audio attraverso un
+	// programma esterno, al quale i campioni vengono scritti sullo standard
+	// input come interi a 16 bit con segno in little endian. Il package non
+	// dipende così da librerie audio come ALSA o PortAudio, ma il programma
+	// deve essere installato e presente nel PATH.
+	CommandAudio struct {
+		cmd   *exec.Cmd
+		stdin io.WriteCloser
+		buf   []byte
+	}
+)
+
+// audioQueue è il numero massimo di frame in attesa di essere riprodotti.
+const audioQueue = 32
+
+// NoAudioError indica che il programma di riproduzione audio richiesto, o
+// nessuno di quelli cercati da NewSystemAudio, è presente nel PATH.
+var NoAudioError = errors.New("No Audio Error")
+
+// NewAudioSink restituisce un AudioSink che riproduce l'audio su dev. La
+// riproduzione deve essere conclusa con Close.
+func NewAudioSink(dev AudioDevice) *AudioSink {
+	s := &AudioSink{
+		dev:    dev,
+		frames: make(chan []float32, audioQueue),
+		done:   make(chan struct{}),
+	}
+
+	go s.play()
+
+	return s
+}
+
+// PropagateAudio implementa l'interfaccia AudioConnector.
+func (s *AudioSink) PropagateAudio(samples []float32) {
+	s.queue(append([]float32(nil), samples...))
+}
+
+// PropagateStereo implementa l'interfaccia StereoConnector. L'AudioDevice
+// deve avere due canali.
+func (s *AudioSink) PropagateStereo(L, R []float32) {
+	b := make([]float32, 2*len(L))
+	for k := range L {
+		b[2*k], b[2*k+1] = L[k], R[k]
+	}
+
+	s.queue(b)
+}
+
+// queue accoda il frame b per la riproduzione, scartandolo se la coda è piena.
+func (s *AudioSink) queue(b []float32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.frames <- b:
+	default:
+		s.dropped++
+	}
+}
+
+// Dropped restituisce il numero di frame scartati perché la riproduzione non
+// ha tenuto il passo della ricezione.
+func (s *AudioSink) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+// Err restituisce il primo errore di riproduzione incontrato, dopo il quale i
+// frame successivi vengono ignorati.
+func (s *AudioSink) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Close attende la riproduzione dei frame in coda e chiude l'AudioDevice,
+// restituendo il primo errore incontrato.
+func (s *AudioSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return s.err
+	}
+	s.closed = true
+	close(s.frames)
+	s.mu.Unlock()
+
+	<-s.done
+
+	e := s.dev.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		s.err = e
+	}
+
+	return s.err
+}
+
+// play riproduce i frame in coda fino alla chiusura di AudioSink.
+func (s *AudioSink) play() {
+	defer close(s.done)
+
+	for b := range s.frames {
+		if s.Err() != nil {
+			continue
+		}
+
+		if e := s.dev.Write(b); e != nil {
+			s.mu.Lock()
+			s.err = e
+			s.mu.Unlock()
+		}
+	}
+}
+
+// NewCommandAudio avvia il programma name con gli argomenti args e restituisce
+// il CommandAudio che gli invia l'audio. Gli argomenti devono configurare il
+// programma per leggere dallo standard input campioni a 16 bit in little
+// endian, con la frequenza di campionamento ed il numero di canali dell'audio
+// riprodotto. Se il programma non è presente nel PATH viene restituito l'errore
+// NoAudioError.
+func NewCommandAudio(name string, args ...string) (*CommandAudio, error) {
+	if _, e := exec.LookPath(name); e != nil {
+		return nil, fmt.Errorf("%w: %s not found in PATH", NoAudioError, name)
+	}
+
+	cmd := exec.Command(name, args...)
+
+	stdin, e := cmd.StdinPipe()
+	if e != nil {
+		return nil, e
+	}
+
+	if e := cmd.Start(); e != nil {
+		return nil, e
+	}
+
+	return &CommandAudio{cmd: cmd, stdin: stdin}, nil
+}
+
+// NewSystemAudio restituisce un CommandAudio che riproduce, con frequenza di
+// campionamento rate Hz e channels canali, sulla scheda audio di sistema
+// attraverso il primo programma disponibile tra aplay (ALSA), pacat
+// (PulseAudio o PipeWire) e play (SoX), cercati nel PATH. Se nessuno è
+// installato viene restituito l'errore NoAudioError.
+func NewSystemAudio(rate, channels int) (*CommandAudio, error) {
+	r, c := strconv.Itoa(rate), strconv.Itoa(channels)
+
+	players := [][]string{
+		{"aplay", "-q", "-t", "raw", "-f", "S16_LE", "-r", r, "-c", c},
+		{"pacat", "--playback", "--format=s16le", "--rate=" + r, "--channels=" + c},
+		{"play", "-q", "-t", "raw", "-e", "signed", "-b", "16", "-L", "-r", r, "-c", c, "-"},
+	}
+
+	for _, p := range players {
+		if _, e := exec.LookPath(p[0]); e == nil {
+			return NewCommandAudio(p[0], p[1:]...)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: none of aplay, pacat or play found in PATH", NoAudioError)
+}
+
+// Write implementa l'interfaccia AudioDevice.
+func (a *CommandAudio) Write(samples []float32) error {
+	if cap(a.buf) < 2*len(samples) {
+		a.buf = make([]byte, 2*len(samples))
+	}
+	a.buf = a.buf[:2*len(samples)]
+
+	for k, x := range samples {
+		v := math.Max(-1, math.Min(1, float64(x)))
+		binary.LittleEndian.PutUint16(a.buf[2*k:], uint16(int16(v*(fullScale-1))))
+	}
+
+	_, e := a.stdin.Write(a.buf)
+
+	return e
+}
+
+// Close implementa l'interfaccia AudioDevice, attendendo che il programma
+// abbia riprodotto l'audio ricevuto.
+func (a *CommandAudio) Close() error {
+	if e := a.stdin.Close(); e != nil {
+		return e
+	}
+
+	return a.cmd.Wait()
+}
@@ -0,0 +1,29 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/iclac/sdrplay"
+)
+
+// TestSystemAudioMissing verifica che, senza programmi di riproduzione nel
+// PATH, venga restituito l'errore NoAudioError.
+func TestSystemAudioMissing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	if _, e := sdrplay.NewSystemAudio(sdrplay.AudioRate, 1); !errors.Is(e, sdrplay.NoAudioError) {
+		t.Errorf("NewSystemAudio: got error %v, want %v", e, sdrplay.NoAudioError)
+	}
+
+	if _, e := sdrplay.NewCommandAudio("aplay", "-q"); !errors.Is(e, sdrplay.NoAudioError) {
+		t.Errorf("NewCommandAudio: got error %v, want %v", e, sdrplay.NoAudioError)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// AudioAGC è un AudioConnector che normalizza il volume dell'audio demodulato,
+// così che canali ricevuti con livelli diversi vengano riprodotti con volume
+// simile, e lo propaga al connettore audio fornito. Il livello dell'audio è
+// stimato con un rivelatore di inviluppo che sale con la costante di tempo di
+// attack e scende con quella di decay; il guadagno porta tale livello al valore
+// desiderato senza superare il guadagno massimo, così che il rumore in assenza
+// di segnale non venga amplificato oltre misura.
+type AudioAGC struct {
+	mu sync.Mutex
+
+	// target è il livello desiderato e max il guadagno massimo, entrambi come
+	// rapporti lineari.
+	target, max float64
+
+	// ka e kd sono i coefficienti del rivelatore di inviluppo corrispondenti
+	// alle costanti di tempo di attack e decay, env l'inviluppo attuale.
+	ka, kd, env float64
+
+	audio []float32
+
+	out AudioConnector
+}
+
+// NewAudioAGC restituisce un AudioAGC per audio campionato a AudioRate che
+// propaga l'audio normalizzato ad out. Di default il livello desiderato è di
+// -12dBFS, il guadagno massimo di 30dB e le costanti di tempo sono 5ms in attack
+// e 500ms in decay.
+func NewAudioAGC(out AudioConnector) *AudioAGC {
+	a := &AudioAGC{out: out}
+
+	a.Target(-12)
+	a.MaxGain(30)
+	a.TimeConstants(5*time.Millisecond, 500*time.Millisecond)
+
+	return a
+}
+
+// Target imposta il livello di picco desiderato in dBFS.
+func (a *AudioAGC) Target(dBFS float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.target = math.Pow(10, dBFS/20)
+}
+
+// MaxGain imposta il guadagno massimo in dB.
+func (a *AudioAGC) MaxGain(dB float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.max = math.Pow(10, dB/20)
+}
+
+// TimeConstants imposta le costanti di tempo con cui il livello stimato segue
+// rispettivamente gli aumenti (attack) e le diminuzioni (decay) del segnale.
+func (a *AudioAGC) TimeConstants(attack, decay time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.ka = smoothing(attack, 1, AudioRate)
+	a.kd = smoothing(decay, 1, AudioRate)
+}
+
+// PropagateAudio implementa l'interfaccia AudioConnector.
+func (a *AudioAGC) PropagateAudio(samples []float32) {
+	a.mu.Lock()
+
+	a.audio = a.audio[:0]
+	for _, s := range samples {
+		x := float64(s)
+
+		if v := math.Abs(x); v > a.env {
+			a.env += a.ka * (v - a.env)
+		} else {
+			a.env += a.kd * (v - a.env)
+		}
+
+		gain := a.max
+		if a.env*gain > a.target {
+			gain = a.target / a.env
+		}
+
+		a.audio = append(a.audio, float32(math.Max(-1, math.Min(1, gain*x))))
+	}
+
+	audio := a.audio
+	a.mu.Unlock()
+
+	if a.out != nil {
+		a.out.PropagateAudio(audio)
+	}
+}
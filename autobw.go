@@ -0,0 +1,64 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+// AutoBandwidth, se hz è positiva, configura la RSP per analizzare un canale
+// largo hz Hz, ignorando le opzioni Bandwidth, FS e Decimate: viene scelta la
+// larghezza di banda più stretta che contiene il canale, o BW8000 se nessuna lo
+// contiene, e la frequenza di campionamento più bassa che la ammette; per i
+// canali fino ad 1 MHz la RSP campiona a 2 MHz e decima del fattore più alto,
+// fino a Factor64, che mantiene la frequenza dei campioni propagati non
+// inferiore ad hz, imponendo la IF nulla. La scelta viene ripetuta ad ogni
+// SetUp; AutoBandwidth(0) la disabilita.
+func AutoBandwidth(hz float64) Option {
+	return Option{
+		apply: func() {
+			rsp.Channel = double(hz)
+		},
+	}
+}
+
+// autoBandwidth imposta in f larghezza di banda, frequenza di campionamento e
+// decimazione per il canale scelto con AutoBandwidth.
+func (f *features) autoBandwidth() {
+	ch := float64(f.Channel)
+
+	f.BW = bandwidths[len(bandwidths)-1]
+	for _, bw := range bandwidths {
+		if float64(bw)*1e3 >= ch {
+			f.BW = bw
+			break
+		}
+	}
+
+	fs := float64(f.BW) / 1e3
+	if ch/1e6 > fs {
+		fs = ch / 1e6
+	}
+	switch {
+	case fs < 2:
+		fs = 2
+	case fs > 10:
+		fs = 10
+	}
+	f.FS = double(fs)
+
+	factor := Factor0
+	for d := Factor2; d <= Factor64; d <<= 1 {
+		if fs*1e6/float64(d) < ch {
+			break
+		}
+		factor = d
+	}
+
+	f.Decimate = enable(factor != Factor0)
+	f.Factor = factor
+	if f.Decimate {
+		f.IF = IFzero
+	}
+}
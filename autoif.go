@@ -0,0 +1,37 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+// AutoIF, se enabled, sceglie la IF in base alla frequenza di campionamento ed
+// alla larghezza di banda, ignorando l'opzione IF: viene usata la IF non nulla
+// ammessa dalla combinazione, 450 kHz a 2 MHz con banda fino a 600 kHz, 1620 kHz
+// a 6 MHz con banda fino a 1536 kHz o 2048 kHz ad 8 MHz con banda fino a 5 MHz,
+// che tiene il segnale lontano dalla componente continua, e negli altri casi, o
+// con la decimazione, la IF nulla. La scelta viene ripetuta ad ogni SetUp.
+func AutoIF(enabled bool) Option {
+	return Option{
+		apply: func() {
+			rsp.AutoIF = enabled
+		},
+	}
+}
+
+// autoIF restituisce la IF scelta da AutoIF per la configurazione f.
+func (f features) autoIF() IFmode {
+	if f.Decimate {
+		return IFzero
+	}
+
+	for _, m := range []IFmode{IF450, IF1620, IF2048} {
+		if f.FS == lowIF[m].fs && f.BW <= lowIF[m].bw {
+			return m
+		}
+	}
+
+	return IFzero
+}
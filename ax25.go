@@ -0,0 +1,313 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strings"
+	"sync"
+)
+
+type (
+	// AX25Address è un indirizzo AX.25: nominativo e SSID di una stazione.
+	AX25Address struct {
+		Call string
+		SSID int
+
+		// Repeated indica, per gli indirizzi del percorso, se il frame è già
+		// stato ritrasmesso dal digipeater corrispondente.
+		Repeated bool
+	}
+
+	// AX25Frame è un frame AX.25 ricevuto da AFSK.
+	AX25Frame struct {
+		Dest, Source AX25Address
+
+		// Path contiene gli indirizzi dei digipeater, al massimo otto.
+		Path []AX25Address
+
+		// Control e PID sono i campi di controllo e di identificazione del
+		// protocollo: per i pacchetti APRS valgono 0x03 (frame UI) e 0xF0.
+		Control, PID byte
+
+		// Info è il campo informativo del frame, che per i pacchetti APRS
+		// contiene il messaggio vero e proprio.
+		Info []byte
+	}
+
+	// AFSK è un AudioConnector che demodula l'audio di un ricevitore FM
+	// contenente pacchetti AX.25 con modulazione AFSK Bell 202 a 1200 baud
+	// (1200Hz mark, 2200Hz space), come quelli APRS. I frame con FCS corretta
+	// vengono passati alla funzione report.
+	AFSK struct {
+		mu sync.Mutex
+
+		// mark e space sono le rotazioni per campione dei due oscillatori,
+		// om ed os il loro stato attuale.
+		mark, space complex128
+		om, os      complex128
+
+		// win contiene i prodotti degli ultimi campioni con i due oscillatori,
+		// accumulati in sm ed ss su una finestra lunga un bit.
+		win    [][2]complex128
+		pos    int
+		sm, ss complex128
+
+		clock bitClock
+		frame hdlc
+
+		frames []AX25Frame
+		report func(AX25Frame)
+	}
+
+	// APRS è un Connector che demodula un canale FM a banda stretta centrato
+	// nella frequenza sintonizzata e ne decodifica i pacchetti AX.25 con un
+	// AFSK. La frequenza APRS è 144.800MHz in Europa e 144.390MHz in Nord
+	// America.
+	APRS struct {
+		mu sync.Mutex
+
+		dec  *decimator
+		prev complex128
+
+		// gain converte la differenza di fase tra campioni consecutivi in
+		// audio normalizzato alla deviazione massima.
+		gain float64
+
+		afsk *AFSK
+
+		iq, bb []complex128
+		af     []float64
+	}
+)
+
+const (
+	// afskBaud è la velocità di trasmissione AFSK in bit al secondo.
+	afskBaud = 1200
+
+	// afskMark e afskSpace sono le frequenze dei toni AFSK Bell 202 in Hz.
+	afskMark  = 1200
+	afskSpace = 2200
+
+	// ax25MaxBits è la lunghezza massima, in bit, di un frame AX.25:
+	// indirizzi, controllo, PID, 256 byte di informazione e FCS.
+	ax25MaxBits = (10*7 + 2 + 256 + 2) * 8
+
+	// aprsDeviation è la deviazione massima, espressa in Hz, dei canali FM a
+	// banda stretta usati da APRS.
+	aprsDeviation = 5e3
+)
+
+// NewAFSK restituisce un AFSK per audio campionato a AudioRate che passa i
+// frame ricevuti a report.
+func NewAFSK(report func(AX25Frame)) *AFSK {
+	return newAFSK(AudioRate, report)
+}
+
+// newAFSK restituisce un AFSK per audio campionato con frequenza rate,
+// espressa in Hz.
+func newAFSK(rate float64, report func(AX25Frame)) *AFSK {
+	return &AFSK{
+		mark:   cmplx.Rect(1, -2*math.Pi*afskMark/rate),
+		space:  cmplx.Rect(1, -2*math.Pi*afskSpace/rate),
+		om:     1,
+		os:     1,
+		win:    make([][2]complex128, int(rate/afskBaud+0.5)),
+		clock:  newBitClock(afskBaud, rate),
+		frame:  hdlc{max: ax25MaxBits},
+		report: report,
+	}
+}
+
+// PropagateAudio implementa l'interfaccia AudioConnector.
+func (a *AFSK) PropagateAudio(samples []float32) {
+	a.mu.Lock()
+
+	for _, x := range samples {
+		a.sample(float64(x))
+	}
+
+	frames, report := a.frames, a.report
+	a.frames = nil
+	a.mu.Unlock()
+
+	if report != nil {
+		for _, f := range frames {
+			report(f)
+		}
+	}
+}
+
+// sample elabora un campione audio, aggiungendo ad a.frames l'eventuale frame
+// completato.
+func (a *AFSK) sample(x float64) {
+	// Correlazione con i due toni su una finestra scorrevole lunga un bit.
+	m, s := complex(x, 0)*a.om, complex(x, 0)*a.os
+	a.om *= a.mark
+	a.os *= a.space
+
+	old := a.win[a.pos]
+	a.win[a.pos] = [2]complex128{m, s}
+	a.pos = (a.pos + 1) % len(a.win)
+
+	a.sm += m - old[0]
+	a.ss += s - old[1]
+
+	// L'ampiezza degli oscillatori viene normalizzata ad ogni inizio di
+	// finestra per evitarne la deriva.
+	if a.pos == 0 {
+		a.om /= complex(cmplx.Abs(a.om), 0)
+		a.os /= complex(cmplx.Abs(a.os), 0)
+	}
+
+	bit, ok := a.clock.sample(cmplx.Abs(a.sm) > cmplx.Abs(a.ss))
+	if !ok {
+		return
+	}
+
+	if bits := a.frame.push(bit); bits != nil {
+		if f, ok := parseAX25(hdlcBytes(bits)); ok {
+			a.frames = append(a.frames, f)
+		}
+	}
+}
+
+// parseAX25 interpreta i byte di un frame AX.25, esclusa la FCS.
+func parseAX25(data []byte) (AX25Frame, bool) {
+	var f AX25Frame
+
+	// Gli indirizzi occupano 7 byte ciascuno; il bit meno significativo
+	// dell'ultimo byte indica l'ultimo indirizzo.
+	var addrs []AX25Address
+	k := 0
+	for {
+		if k+7 > len(data) || len(addrs) == 10 {
+			return f, false
+		}
+
+		addrs = append(addrs, parseAX25Address(data[k:k+7]))
+		k += 7
+
+		if data[k-1]&1 != 0 {
+			break
+		}
+	}
+
+	if len(addrs) < 2 || k >= len(data) {
+		return f, false
+	}
+
+	f.Dest, f.Source, f.Path = addrs[0], addrs[1], addrs[2:]
+	f.Control = data[k]
+	k++
+
+	// Solo i frame I e UI hanno il campo PID.
+	if f.Control&1 == 0 || f.Control&^0x10 == 0x03 {
+		if k >= len(data) {
+			return f, false
+		}
+		f.PID = data[k]
+		k++
+	}
+
+	f.Info = data[k:]
+
+	return f, true
+}
+
+// parseAX25Address interpreta i 7 byte di un indirizzo AX.25: i caratteri del
+// nominativo sono traslati di un bit a sinistra ed il settimo byte contiene
+// l'SSID ed il bit H.
+func parseAX25Address(b []byte) AX25Address {
+	call := make([]byte, 6)
+	for k := range call {
+		call[k] = b[k] >> 1
+	}
+
+	return AX25Address{
+		Call:     strings.TrimRight(string(call), " "),
+		SSID:     int(b[6]>>1) & 0x0F,
+		Repeated: b[6]&0x80 != 0,
+	}
+}
+
+// String restituisce l'indirizzo nella forma NOMINATIVO-SSID, omettendo l'SSID
+// se nullo ed aggiungendo un asterisco se il frame è stato ritrasmesso.
+func (a AX25Address) String() string {
+	s := a.Call
+	if a.SSID != 0 {
+		s += fmt.Sprintf("-%d", a.SSID)
+	}
+	if a.Repeated {
+		s += "*"
+	}
+
+	return s
+}
+
+// String restituisce il frame nel formato testuale TNC2 usato da APRS-IS:
+// SORGENTE>DESTINAZIONE,PERCORSO:INFO.
+func (f AX25Frame) String() string {
+	src, dst := f.Source, f.Dest
+	src.Repeated, dst.Repeated = false, false
+
+	s := src.String() + ">" + dst.String()
+	for _, p := range f.Path {
+		s += "," + p.String()
+	}
+
+	return s + ":" + string(f.Info)
+}
+
+// NewAPRS restituisce un APRS per un segnale in banda base campionato con
+// frequenza fs, espressa in Hz, che passa i frame ricevuti a report.
+func NewAPRS(fs float64, report func(AX25Frame)) *APRS {
+	factor := int(fs / amRate)
+	if factor < 1 {
+		factor = 1
+	}
+	rate := fs / float64(factor)
+
+	return &APRS{
+		dec:  newDecimator(factor, math.Min(8e3/fs, 0.45/float64(factor))),
+		gain: rate / (2 * math.Pi * aprsDeviation),
+		afsk: newAFSK(rate, report),
+	}
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (a *APRS) Propagate(I []int16, Q []int16) {
+	a.mu.Lock()
+
+	a.iq = toComplex(I, Q, a.iq[:0])
+	a.bb = a.dec.process(a.iq, a.bb[:0])
+
+	a.af = a.af[:0]
+	for _, x := range a.bb {
+		a.af = append(a.af, a.gain*cmplx.Phase(x*cmplx.Conj(a.prev)))
+		a.prev = x
+	}
+
+	a.afsk.mu.Lock()
+	for _, x := range a.af {
+		a.afsk.sample(x)
+	}
+	frames, report := a.afsk.frames, a.afsk.report
+	a.afsk.frames = nil
+	a.afsk.mu.Unlock()
+
+	a.mu.Unlock()
+
+	if report != nil {
+		for _, f := range frames {
+			report(f)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "math"
+
+// Band descrive una banda di frequenze canalizzata ed il modo di configurare la
+// RSP per riceverla.
+type Band struct {
+	// Name è il nome della banda, ad esempio "2m".
+	Name string
+
+	// Low e High sono le frequenze del primo e dell'ultimo canale, Step la
+	// spaziatura tra i canali, tutte espresse in Hz.
+	Low, High, Step float64
+
+	// SampleRate è la frequenza di campionamento, espressa in Hz, e Bandwidth
+	// la larghezza di banda adatte alla ricezione della banda.
+	SampleRate float64
+	Bandwidth  B
+}
+
+var (
+	// LongWave è la banda della radiodiffusione in onde lunghe.
+	LongWave = Band{"LW", 153e3, 279e3, 9e3, 2e6, BW600}
+
+	// MediumWave è la banda della radiodiffusione in onde medie (Regione 1
+	// ITU).
+	MediumWave = Band{"MW", 531e3, 1602e3, 9e3, 2e6, BW600}
+
+	// FMBroadcast è la banda della radiodiffusione FM.
+	FMBroadcast = Band{"FM", 87.5e6, 108e6, 100e3, 2.048e6, BW1536}
+
+	// Airband è la banda aeronautica civile in AM.
+	Airband = Band{"Airband", 118e6, 136.975e6, 25e3, 2.048e6, BW1536}
+
+	// Band2m è la banda radioamatoriale dei 2 metri (Regione 1 IARU).
+	Band2m = Band{"2m", 144e6, 146e6, 12.5e3, 2.048e6, BW1536}
+
+	// MarineVHF è la banda marittima VHF.
+	MarineVHF = Band{"Marine", 156e6, 162.025e6, 25e3, 2.048e6, BW1536}
+
+	// NOAAWeather è la banda dei bollettini meteorologici NOAA.
+	NOAAWeather = Band{"NOAA", 162.4e6, 162.55e6, 25e3, 2.048e6, BW200}
+
+	// Band70cm è la banda radioamatoriale dei 70 centimetri (Regione 1 IARU).
+	Band70cm = Band{"70cm", 430e6, 440e6, 12.5e3, 2.048e6, BW1536}
+
+	// PMR446 è la banda PMR446 ad uso libero.
+	PMR446 = Band{"PMR446", 446.00625e6, 446.19375e6, 12.5e3, 2.048e6, BW200}
+
+	// Bands contiene tutte le bande predefinite, in ordine di frequenza.
+	Bands = []Band{LongWave, MediumWave, FMBroadcast, Airband, Band2m, MarineVHF, NOAAWeather, Band70cm, PMR446}
+)
+
+// BandAt restituisce la banda predefinita che contiene la frequenza f, espressa
+// in Hz, e se è stata trovata.
+func BandAt(f float64) (Band, bool) {
+	for _, b := range Bands {
+		if b.Contains(f) {
+			return b, true
+		}
+	}
+
+	return Band{}, false
+}
+
+// Contains indica se la frequenza f, espressa in Hz, è compresa tra il primo e
+// l'ultimo canale della banda.
+func (b Band) Contains(f float64) bool {
+	return f >= b.Low && f <= b.High
+}
+
+// Center restituisce la frequenza centrale della banda in Hz.
+func (b Band) Center() float64 {
+	return (b.Low + b.High) / 2
+}
+
+// Channels restituisce le frequenze dei canali della banda in Hz, adatte ad
+// esempio a Scanner.List.
+func (b Band) Channels() []float64 {
+	if b.Step <= 0 {
+		return []float64{b.Low}
+	}
+
+	n := int(math.Floor((b.High-b.Low)/b.Step + 1e-9))
+	freqs := make([]float64, n+1)
+	for k := range freqs {
+		freqs[k] = b.Low + float64(k)*b.Step
+	}
+
+	return freqs
+}
+
+// Channel restituisce la frequenza, in Hz, del canale della banda più vicino
+// alla frequenza f.
+func (b Band) Channel(f float64) float64 {
+	if b.Step <= 0 {
+		return b.Low
+	}
+
+	k := math.Floor((f-b.Low)/b.Step + 0.5)
+	k = math.Max(0, math.Min(k, math.Floor((b.High-b.Low)/b.Step+1e-9)))
+
+	return b.Low + k*b.Step
+}
+
+// Preset restituisce le opzioni che configurano la RSP per la ricezione della
+// banda sintonizzandola al centro.
+func (b Band) Preset() []Option {
+	return b.PresetAt(b.Center())
+}
+
+// PresetAt restituisce le opzioni che configurano la RSP per la ricezione della
+// banda sintonizzandola sulla frequenza f espressa in Hz: frequenza di
+// campionamento e larghezza di banda della banda, IF nulla e frequenza
+// dell'up-converter automatica.
+func (b Band) PresetAt(f float64) []Option {
+	return []Option{
+		InitialRF(f / 1e6),
+		FS(b.SampleRate / 1e6),
+		Bandwidth(b.Bandwidth),
+		IF(IFzero),
+		LOmode(LOauto),
+	}
+}
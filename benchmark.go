@@ -0,0 +1,168 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+type (
+	// BenchmarkResult riporta le prestazioni misurate da Benchmark.
+	BenchmarkResult struct {
+		// Duration è la durata della misura e SampleRate la frequenza di
+		// campionamento impostata, espressa in Hz.
+		Duration   time.Duration
+		SampleRate float64
+
+		// Frames e Samples sono i frame ed i campioni ricevuti, Rate il ritmo
+		// sostenuto in campioni al secondo.
+		Frames  int
+		Samples int
+		Rate    float64
+
+		// Interval è l'intervallo medio tra due invocazioni della callback,
+		// Jitter la sua deviazione standard e MaxInterval il massimo.
+		Interval    time.Duration
+		Jitter      time.Duration
+		MaxInterval time.Duration
+
+		// GCs è il numero di garbage collection avvenute durante la misura,
+		// GCPause la durata complessiva delle relative pause e MaxGCPause la
+		// più lunga.
+		GCs        int
+		GCPause    time.Duration
+		MaxGCPause time.Duration
+
+		// Allocs è il numero medio di allocazioni per frame durante lo stream,
+		// 0 con l'opzione Recycle se il Connector non alloca.
+		Allocs float64
+	}
+
+	// nullSink è il Connector che scarta i campioni ricevuti durante Benchmark,
+	// misurando gli intervalli tra i frame.
+	nullSink struct {
+		mu sync.Mutex
+
+		frames, samples int
+		first, last     time.Time
+
+		// sum e sumsq accumulano gli intervalli, in secondi, ed i loro
+		// quadrati; max è l'intervallo più lungo.
+		sum, sumsq float64
+		max        time.Duration
+	}
+)
+
+// Propagate implementa l'interfaccia Connector.
+func (s *nullSink) Propagate(I []int16, Q []int16) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.frames == 0 {
+		s.first = now
+	} else {
+		// Il primo frame segna l'inizio della misura e non viene conteggiato.
+		d := now.Sub(s.last)
+		s.sum += d.Seconds()
+		s.sumsq += d.Seconds() * d.Seconds()
+		if d > s.max {
+			s.max = d
+		}
+		s.samples += len(I)
+	}
+
+	s.frames++
+	s.last = now
+}
+
+// Benchmark esegue lo stream della RSP per la durata d alla massima frequenza
+// di campionamento, scartando i campioni, e ne riporta il ritmo sostenuto, la
+// regolarità delle invocazioni della callback e le pause del garbage
+// collector, così da verificare l'idoneità del sistema prima dell'uso. Le
+// opzioni opts sono applicate dopo quelle di default e possono quindi
+// modificare la frequenza di campionamento. Come RSP, disattiva l'eventuale
+// ricevitore precedente; al termine lo stream viene fermato.
+func Benchmark(d time.Duration, opts ...Option) (BenchmarkResult, error) {
+	s := &nullSink{}
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	r, e := RSP(s, append([]Option{FS(10), Bandwidth(BW8000)}, opts...)...)
+	if e != nil {
+		return BenchmarkResult{}, e
+	}
+
+	fs := r.State().SampleRate
+
+	var start, stop runtime.MemStats
+	runtime.ReadMemStats(&start)
+	s.mu.Lock()
+	first := s.frames
+	s.mu.Unlock()
+
+	time.Sleep(d)
+
+	runtime.ReadMemStats(&stop)
+	s.mu.Lock()
+	frames := s.frames - first
+	s.mu.Unlock()
+
+	e = r.Close()
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := BenchmarkResult{
+		Duration:    d,
+		SampleRate:  fs,
+		Frames:      s.frames,
+		Samples:     s.samples,
+		MaxInterval: s.max,
+		GCs:         int(after.NumGC - before.NumGC),
+		GCPause:     time.Duration(after.PauseTotalNs - before.PauseTotalNs),
+	}
+
+	if frames > 0 {
+		res.Allocs = float64(stop.Mallocs-start.Mallocs) / float64(frames)
+	}
+
+	if n := float64(s.frames - 1); n > 0 {
+		mean := s.sum / n
+		res.Interval = time.Duration(mean * float64(time.Second))
+		res.Jitter = time.Duration(math.Sqrt(math.Max(0, s.sumsq/n-mean*mean)) * float64(time.Second))
+
+		if elapsed := s.last.Sub(s.first).Seconds(); elapsed > 0 {
+			res.Rate = float64(s.samples) / elapsed
+		}
+	}
+
+	// PauseNs conserva le ultime 256 pause in un buffer circolare.
+	for k := before.NumGC; k < after.NumGC && after.NumGC-k <= uint32(len(after.PauseNs)); k++ {
+		if p := time.Duration(after.PauseNs[k%uint32(len(after.PauseNs))]); p > res.MaxGCPause {
+			res.MaxGCPause = p
+		}
+	}
+
+	return res, e
+}
+
+// String implementa l'interfaccia fmt.Stringer.
+func (r BenchmarkResult) String() string {
+	return fmt.Sprintf("%.3f Msps sustained of %.3f Msps (%d frames in %v), callback interval %v ± %v (max %v), %d GC (total pause %v, max %v), %.2f allocs/frame",
+		r.Rate/1e6, r.SampleRate/1e6, r.Frames, r.Duration, r.Interval, r.Jitter, r.MaxInterval, r.GCs, r.GCPause, r.MaxGCPause, r.Allocs)
+}
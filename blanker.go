@@ -0,0 +1,130 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// NoiseBlanker è un Connector che elimina i disturbi impulsivi, come quelli
+// prodotti da accensioni, scariche e linee elettriche, dal segnale in banda
+// base prima della demodulazione. Un impulso viene rilevato quando l'ampiezza
+// di un campione supera di threshold dB l'ampiezza media del segnale: il
+// campione ed i successivi per la durata width vengono azzerati. Il segnale
+// così ripulito viene propagato al connettore out.
+type NoiseBlanker struct {
+	mu sync.Mutex
+
+	// fs è la frequenza di campionamento del segnale espressa in Hz.
+	fs float64
+
+	// ratio è la soglia espressa come rapporto tra ampiezze, width la durata
+	// dell'azzeramento in campioni.
+	ratio float64
+	width int
+
+	// avg è l'ampiezza media del segnale, k il coefficiente del filtro che la
+	// stima e remaining il numero di campioni ancora da azzerare.
+	avg, k    float64
+	remaining int
+
+	// blanked è il numero totale di campioni azzerati.
+	blanked uint64
+
+	i, q []int16
+
+	out Connector
+}
+
+// NewNoiseBlanker restituisce un NoiseBlanker per un segnale campionato con
+// frequenza fs, espressa in Hz, che propaga il segnale ripulito ad out. Di
+// default la soglia è di 12dB e la durata dell'azzeramento di 20µs.
+func NewNoiseBlanker(fs float64, out Connector) *NoiseBlanker {
+	b := &NoiseBlanker{
+		fs:  fs,
+		k:   smoothing(5*time.Millisecond, 1, fs),
+		out: out,
+	}
+
+	b.Threshold(12)
+	b.Width(20 * time.Microsecond)
+
+	return b
+}
+
+// Threshold imposta di quanti dB l'ampiezza di un campione deve superare quella
+// media del segnale perché venga considerato un impulso. Valori bassi
+// eliminano più disturbi ma rischiano di mutilare i segnali forti.
+func (b *NoiseBlanker) Threshold(dB float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.ratio = math.Pow(10, dB/20)
+}
+
+// Width imposta per quanto tempo il segnale viene azzerato dopo la rilevazione
+// di un impulso; la durata minima è di un campione.
+func (b *NoiseBlanker) Width(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.width = int(d.Seconds()*b.fs + 0.5)
+	if b.width < 1 {
+		b.width = 1
+	}
+}
+
+// Blanked restituisce il numero totale di campioni azzerati.
+func (b *NoiseBlanker) Blanked() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.blanked
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (b *NoiseBlanker) Propagate(I []int16, Q []int16) {
+	b.mu.Lock()
+
+	b.i = append(b.i[:0], I...)
+	b.q = append(b.q[:0], Q...)
+
+	for k := 0; k < len(b.i) && k < len(b.q); k++ {
+		i, q := float64(b.i[k]), float64(b.q[k])
+		a := math.Sqrt(i*i + q*q)
+
+		if b.avg == 0 {
+			b.avg = a
+		}
+
+		if a > b.ratio*b.avg {
+			b.remaining = b.width
+
+			// Gli impulsi contribuiscono alla media solo fino alla soglia,
+			// così che non la alterino ma che essa possa comunque seguire un
+			// aumento improvviso e duraturo del segnale.
+			a = b.ratio * b.avg
+		}
+		b.avg += b.k * (a - b.avg)
+
+		if b.remaining > 0 {
+			b.i[k], b.q[k] = 0, 0
+			b.remaining--
+			b.blanked++
+		}
+	}
+
+	i, q := b.i, b.q
+	b.mu.Unlock()
+
+	if b.out != nil {
+		b.out.Propagate(i, q)
+	}
+}
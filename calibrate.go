@@ -0,0 +1,267 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// Calibrator è un Connector che misura l'errore dell'oscillatore locale della
+// RSP confrontando la frequenza ricevuta di una portante di riferimento, ad
+// esempio quella di un trasmettitore AM in onda media, di un beacon o della
+// portante pilota di un segnale televisivo, con la frequenza nominale nota.
+// La misura avviene con Calibrate, che calcola la correzione in ppm e la
+// imposta sul ricevitore con l'opzione LOppm. Se presente, ogni frame viene
+// propagato inalterato al connettore out.
+//
+//	cal := sdrplay.NewCalibrator(fs, nil)
+//	rx, e := sdrplay.RSP(cal)
+//	ppm, e := cal.Calibrate(rx, 1008e3)
+type Calibrator struct {
+	mu sync.Mutex
+
+	// fs è la frequenza di campionamento espressa in Hz.
+	fs float64
+
+	// offset è lo scostamento, in Hz, della sintonia dalla portante, che la
+	// allontana dalla componente continua; span è l'errore massimo cercato in
+	// ppm; settle è il tempo scartato dopo la sintonia.
+	offset float64
+	span   float64
+	settle time.Duration
+
+	// n è il numero di punti di ogni spettro e blocks il numero di spettri
+	// mediati.
+	n, blocks int
+
+	// skip sono i campioni ancora da scartare, buf quelli acquisiti;
+	// acquiring indica se una misura è in corso e done riceve i campioni
+	// acquisiti.
+	skip      int
+	buf       []complex128
+	acquiring bool
+	done      chan []complex128
+
+	// ppm è l'ultima correzione calcolata.
+	ppm float64
+
+	out Connector
+}
+
+// calibrationResolution è la risoluzione massima, in Hz, degli spettri usati da
+// Calibrator, il cui numero di punti è arrotondato alla potenza di 2
+// successiva.
+const calibrationResolution = 4
+
+// CalibrationError indica che la portante di riferimento non è stata trovata o
+// che non sono stati ricevuti abbastanza campioni per misurarla.
+var CalibrationError = errors.New("Calibration Error")
+
+// NewCalibrator restituisce un Calibrator per un segnale campionato con
+// frequenza fs, espressa in Hz, che propaga i frame ad out se non nil. Di
+// default la portante viene cercata entro 100ppm, sintonizzando la RSP 100kHz
+// sotto di essa ed attendendo 100ms, e vengono mediati 4 spettri.
+func NewCalibrator(fs float64, out Connector) *Calibrator {
+	return &Calibrator{
+		fs:     fs,
+		offset: 100e3,
+		span:   100,
+		settle: 100 * time.Millisecond,
+		n:      pow2(int(fs / calibrationResolution)),
+		blocks: 4,
+		done:   make(chan []complex128, 1),
+		out:    out,
+	}
+}
+
+// Offset imposta lo scostamento in Hz tra la sintonia e la portante di
+// riferimento, che deve essere inferiore a metà della frequenza di
+// campionamento.
+func (c *Calibrator) Offset(hz float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.offset = hz
+}
+
+// Span imposta l'errore massimo dell'oscillatore, in ppm, entro il quale viene
+// cercata la portante.
+func (c *Calibrator) Span(ppm float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.span = ppm
+}
+
+// PPM restituisce l'ultima correzione calcolata da Calibrate.
+func (c *Calibrator) PPM() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ppm
+}
+
+// Calibrate sintonizza rx in prossimità della portante di riferimento, la cui
+// frequenza nominale reference è espressa in Hz, ne misura lo scostamento e
+// restituisce la correzione in ppm, positiva se l'oscillatore è veloce, che
+// imposta su rx con l'opzione LOppm. La correzione tiene conto di quella già
+// applicata da rx e ha effetto dalla sintonia successiva; al termine rx resta
+// sintonizzato vicino al riferimento. Se la portante non viene trovata viene
+// restituito l'errore CalibrationError.
+func (c *Calibrator) Calibrate(rx Receiver, reference float64) (float64, error) {
+	c.mu.Lock()
+	tuned := reference - c.offset
+	span := c.span * reference / 1e6
+	current := c.ppm
+	if d, ok := rx.(DeviceControl); ok {
+		current = d.Config().LOppm
+	}
+	c.mu.Unlock()
+
+	if e := rx.Tune(tuned); e != nil {
+		return 0, e
+	}
+
+	buf, e := c.acquire()
+	if e != nil {
+		return 0, e
+	}
+
+	measured, ok := c.peak(buf, reference-tuned, span)
+	if !ok {
+		return 0, CalibrationError
+	}
+
+	// Una portante ricevuta più in basso del previsto indica un oscillatore
+	// più veloce del nominale.
+	ppm := current + (reference-tuned-measured)/tuned*1e6
+
+	if e := rx.SetUp(LOppm(ppm)); e != nil {
+		return 0, e
+	}
+
+	c.mu.Lock()
+	c.ppm = ppm
+	c.mu.Unlock()
+
+	return ppm, nil
+}
+
+// acquire attende i campioni necessari alla misura. Se non vengono ricevuti
+// entro un tempo pari a dieci volte la durata dell'acquisizione restituisce
+// l'errore CalibrationError.
+func (c *Calibrator) acquire() ([]complex128, error) {
+	c.mu.Lock()
+	select {
+	case <-c.done:
+	default:
+	}
+
+	c.skip = int(c.settle.Seconds() * c.fs)
+	c.buf = make([]complex128, 0, c.n*c.blocks)
+	c.acquiring = true
+
+	timeout := 10 * (c.settle + time.Duration(float64(c.n*c.blocks)/c.fs*float64(time.Second)))
+	c.mu.Unlock()
+
+	select {
+	case buf := <-c.done:
+		return buf, nil
+	case <-time.After(timeout):
+		c.mu.Lock()
+		c.acquiring = false
+		c.mu.Unlock()
+
+		return nil, CalibrationError
+	}
+}
+
+// peak restituisce la frequenza, in Hz rispetto al centro di banda, della
+// portante più forte entro span Hz da expected, stimata interpolando lo spettro
+// medio dei campioni buf, e se è stata trovata una portante che emerge dal
+// rumore.
+func (c *Calibrator) peak(buf []complex128, expected, span float64) (float64, bool) {
+	n := c.n
+	win := hann(n)
+	psd := make([]float64, n)
+
+	block := make([]complex128, n)
+	for b := 0; b+n <= len(buf); b += n {
+		for k := range block {
+			block[k] = buf[b+k] * complex(win[k], 0)
+		}
+
+		fft(block)
+
+		for k, v := range block {
+			psd[k] += real(v)*real(v) + imag(v)*imag(v)
+		}
+	}
+
+	res := c.fs / float64(n)
+	width := int(math.Ceil(span/res)) + 1
+	center := bin(expected, c.fs, n)
+
+	best, sum := center, 0.0
+	for d := -width; d <= width; d++ {
+		k := ((center+d)%n + n) % n
+		if psd[k] > psd[best] {
+			best = k
+		}
+		sum += psd[k]
+	}
+
+	// La portante deve superare di almeno 10dB la potenza media della finestra
+	// di ricerca.
+	if psd[best] < 10*sum/float64(2*width+1) {
+		return 0, false
+	}
+
+	// Interpolazione parabolica sul logaritmo della potenza, che con la
+	// finestra di Hann fornisce una stima precisa ad una frazione di bin.
+	l, m, r := dB(psd[(best-1+n)%n]), dB(psd[best]), dB(psd[(best+1)%n])
+	delta := 0.0
+	if d := l - 2*m + r; d != 0 {
+		delta = 0.5 * (l - r) / d
+	}
+
+	k := float64(best)
+	if best >= n/2 {
+		k -= float64(n)
+	}
+
+	return (k + delta) * res, true
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (c *Calibrator) Propagate(I []int16, Q []int16) {
+	c.mu.Lock()
+	if c.acquiring {
+		for k := 0; k < len(I) && k < len(Q); k++ {
+			if c.skip > 0 {
+				c.skip--
+				continue
+			}
+
+			c.buf = append(c.buf, complex(float64(I[k])/fullScale, float64(Q[k])/fullScale))
+			if len(c.buf) == cap(c.buf) {
+				c.acquiring = false
+				c.done <- c.buf
+				break
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	if c.out != nil {
+		c.out.Propagate(I, Q)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+// sdrplay-fm è un ricevitore FM broadcast a riga di comando: sintonizza la
+// stazione indicata, la demodula e ne riproduce l'audio sulla scheda audio di
+// sistema oppure lo scrive, come interi a 16 bit in little endian a 48kHz, su
+// un file o sullo standard output.
+//
+//	sdrplay-fm -f 98.5
+//	sdrplay-fm -f 98.5 -stereo -o - | sox -t raw -e signed -b 16 -r 48000 -c 2 - fm.wav
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/iclac/sdrplay"
+)
+
+// fileAudio è un AudioDevice che scrive l'audio su un file.
+type fileAudio struct {
+	f   *os.File
+	buf []byte
+}
+
+// Write implementa l'interfaccia sdrplay.AudioDevice.
+func (a *fileAudio) Write(samples []float32) error {
+	a.buf = a.buf[:0]
+	for _, x := range samples {
+		v := math.Max(-1, math.Min(1, float64(x)))
+		a.buf = binary.LittleEndian.AppendUint16(a.buf, uint16(int16(v*32767)))
+	}
+
+	_, e := a.f.Write(a.buf)
+
+	return e
+}
+
+// Close implementa l'interfaccia sdrplay.AudioDevice.
+func (a *fileAudio) Close() error {
+	if a.f == os.Stdout {
+		return nil
+	}
+
+	return a.f.Close()
+}
+
+func main() {
+	frequency := flag.Float64("f", 98.5, "station frequency in MHz")
+	gr := flag.Int("g", 40, "gain reduction in dB")
+	agc := flag.Bool("agc", true, "enable the RF AGC")
+	stereo := flag.Bool("stereo", false, "enable stereo decoding")
+	us := flag.Bool("75us", false, "use the 75µs de-emphasis of the Americas instead of 50µs")
+	out := flag.String("o", "", "write audio to this file (- for stdout) instead of playing it")
+	flag.Parse()
+
+	log.SetFlags(0)
+
+	const fs = 2.048
+
+	channels := 1
+	if *stereo {
+		channels = 2
+	}
+
+	var dev sdrplay.AudioDevice
+	switch *out {
+	case "":
+		d, e := sdrplay.NewSystemAudio(sdrplay.AudioRate, channels)
+		if e != nil {
+			log.Fatalln(e)
+		}
+		dev = d
+	case "-":
+		dev = &fileAudio{f: os.Stdout}
+	default:
+		f, e := os.Create(*out)
+		if e != nil {
+			log.Fatalln(e)
+		}
+		dev = &fileAudio{f: f}
+	}
+
+	sink := sdrplay.NewAudioSink(dev)
+
+	var fm *sdrplay.WBFM
+	if *stereo {
+		fm = sdrplay.NewWBFM(fs*1e6, nil)
+		fm.Stereo(sink)
+	} else {
+		fm = sdrplay.NewWBFM(fs*1e6, sdrplay.NewAudioAGC(sink))
+	}
+
+	if *us {
+		fm.Deemphasis(sdrplay.Emphasis75us)
+	}
+
+	opts := []sdrplay.Option{
+		sdrplay.InitialRF(*frequency),
+		sdrplay.FS(fs),
+		sdrplay.Bandwidth(sdrplay.BW1536),
+		sdrplay.IF(sdrplay.IFzero),
+		sdrplay.LOmode(sdrplay.LOauto),
+		sdrplay.InitialGR(*gr),
+	}
+	if *agc {
+		opts = append(opts, sdrplay.AGC(sdrplay.AGC5Hz, -30))
+	}
+
+	rx, e := sdrplay.RSP(fm, opts...)
+	if e != nil {
+		sink.Close()
+		log.Fatalln(e)
+	}
+
+	fmt.Fprintf(os.Stderr, "listening to %g MHz, Ctrl-C to stop\n", *frequency)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	pilot := false
+	for {
+		select {
+		case <-stop:
+			// Il ricevitore va chiuso per primo, così che il callback non
+			// propaghi più campioni a fm ed alla sink durante la sua chiusura.
+			if e := rx.Close(); e != nil {
+				log.Println(e)
+			}
+			if e := sink.Close(); e != nil {
+				log.Fatalln(e)
+			}
+			return
+		case <-tick.C:
+			// Un errore di riproduzione, ad esempio la terminazione del
+			// programma audio, interrompe la ricezione.
+			if sink.Err() != nil {
+				rx.Close()
+				log.Fatalln(sink.Close())
+			}
+
+			if p := fm.Pilot(); p != pilot {
+				pilot = p
+				fmt.Fprintln(os.Stderr, "stereo pilot:", p)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+// sdrplay-info elenca le RSP collegate al sistema, indicandone modello, numero
+// di serie e disponibilità, insieme alla versione della libreria SDRplay, ai
+// limiti di sintonia e guadagno ed alle caratteristiche supportate. Con -bench
+// esegue inoltre lo stream alla massima frequenza di campionamento per la
+// durata indicata e ne riporta le prestazioni sostenute; con -recycle i frame
+// vengono propagati usando a rotazione il numero di buffer indicato.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/iclac/sdrplay"
+)
+
+func main() {
+	bench := flag.Duration("bench", 0, "run a throughput self-test for the given duration")
+	recycle := flag.Int("recycle", 0, "reuse the given number of preallocated frame buffers during -bench")
+	flag.Parse()
+
+	log.SetFlags(0)
+
+	v, expected, e := sdrplay.Version()
+	if e != nil {
+		log.Fatalln("API version:", e)
+	}
+	fmt.Printf("API version: %.2f (expected %.2f)\n", v, expected)
+
+	devs, e := sdrplay.Devices()
+	if e != nil {
+		log.Fatalln("devices:", e)
+	}
+
+	if len(devs) == 0 {
+		fmt.Println("no RSP found")
+		return
+	}
+
+	d := sdrplay.NewRSPDevice()
+	fr, sr, gr := d.FrequencyRange(), d.SampleRateRange(), d.GainRange()
+
+	var bws []string
+	for _, b := range d.Bandwidths() {
+		bws = append(bws, fmt.Sprintf("%g", b/1e3))
+	}
+
+	for k, dev := range devs {
+		status := "available"
+		if !dev.Available {
+			status = "in use"
+		}
+
+		fmt.Printf("\n#%d %s (hw %d) serial %s [%s]\n", k, dev.Model, dev.HwVersion, dev.Serial, status)
+		fmt.Printf("  frequency:   %g - %g MHz\n", fr.Min/1e6, fr.Max/1e6)
+		fmt.Printf("  sample rate: %g - %g MHz\n", sr.Min/1e6, sr.Max/1e6)
+		fmt.Printf("  bandwidth:   %s kHz\n", strings.Join(bws, ", "))
+		fmt.Printf("  gain:        %g - %g dB (gain reduction %g - %g dB)\n", gr.Min, gr.Max, sdrplay.MaxGainReduction-gr.Max, sdrplay.MaxGainReduction-gr.Min)
+		fmt.Printf("  IF:          0, 450, 1620, 2048 kHz\n")
+		fmt.Printf("  features:    %s\n", strings.Join(features(dev.Hardware()), ", "))
+	}
+
+	if *bench > 0 {
+		r, e := sdrplay.Benchmark(*bench, sdrplay.Recycle(*recycle))
+		if e != nil {
+			log.Fatalln("benchmark:", e)
+		}
+
+		fmt.Printf("\nbenchmark: %s\n", r)
+	}
+}
+
+// features restituisce le caratteristiche della RSP descritta da hw, a partire
+// da quelle comuni a tutti i modelli.
+func features(hw sdrplay.Hardware) []string {
+	f := []string{"AGC", "LNA", "DC/IQ correction"}
+
+	if hw.Tuners > 1 {
+		f = append(f, "dual tuner")
+	}
+	if len(hw.Antennas) > 1 {
+		f = append(f, "antenna "+strings.Join(hw.Antennas, "/"))
+	}
+	if hw.BiasT {
+		f = append(f, "bias-T")
+	}
+	if len(hw.Notches) > 0 {
+		f = append(f, strings.Join(hw.Notches, "/")+" notch")
+	}
+	if hw.ExternalReference {
+		f = append(f, "external reference")
+	}
+	if hw.HDR {
+		f = append(f, "HDR")
+	}
+
+	return f
+}
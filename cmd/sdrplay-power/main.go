@@ -0,0 +1,199 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+// sdrplay-power misura, come rtl_power, la potenza in un intervallo di
+// frequenze più ampio della banda della RSP: l'intervallo viene percorso a
+// passi, sintonizzando la RSP al centro di ogni passo ed integrando gli spettri
+// per il tempo indicato. Per ogni passo viene scritta una riga CSV con data,
+// ora, frequenza minima, frequenza massima, passo in Hz, numero di spettri
+// integrati e potenza di ogni bin in dBFS.
+//
+//	sdrplay-power -start 88 -stop 108 -bin 10000 -i 10s -o fm.csv
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/iclac/sdrplay"
+)
+
+const (
+	// fs è la frequenza di campionamento in MHz.
+	fs = 2.048
+
+	// usable è la frazione della banda di ogni passo usata per la misura,
+	// escludendo i bordi attenuati dal filtro d'ingresso.
+	usable = 0.75
+)
+
+func main() {
+	start := flag.Float64("start", 88, "lower frequency in MHz")
+	stop := flag.Float64("stop", 108, "upper frequency in MHz")
+	step := flag.Float64("bin", 10e3, "bin size in Hz")
+	integration := flag.Duration("dwell", time.Second, "integration time of each hop")
+	settle := flag.Duration("settle", 50*time.Millisecond, "time discarded after each retune")
+	interval := flag.Duration("i", 0, "time between the start of two sweeps (0 for back to back)")
+	once := flag.Bool("1", false, "perform a single sweep and exit")
+	gr := flag.Int("g", 40, "gain reduction in dB")
+	out := flag.String("o", "-", "CSV output file (- for stdout)")
+	flag.Parse()
+
+	log.SetFlags(0)
+
+	w := os.Stdout
+	if *out != "-" {
+		f, e := os.Create(*out)
+		if e != nil {
+			log.Fatalln(e)
+		}
+		defer f.Close()
+		w = f
+	}
+	csv := bufio.NewWriter(w)
+
+	frames := make(chan sdrplay.SpectrumFrame, 64)
+	spec := sdrplay.NewSpectrum(fs*1e6, int(fs*1e6 / *step), nil, func(f sdrplay.SpectrumFrame) {
+		select {
+		case frames <- f:
+		default:
+		}
+	})
+	spec.Average(0)
+	spec.Rate(50)
+
+	// hop è la banda misurata ad ogni passo, arrotondata ad un numero intero
+	// di bin.
+	n := spec.Bins()
+	res := fs * 1e6 / float64(n)
+	used := int(float64(n) * usable)
+	hop := float64(used) * res
+
+	lo := *start * 1e6
+	hops := int(math.Ceil((*stop*1e6 - lo) / hop))
+
+	rx, e := sdrplay.RSP(spec,
+		sdrplay.InitialRF((lo+hop/2)/1e6),
+		sdrplay.FS(fs),
+		sdrplay.Bandwidth(sdrplay.BW1536),
+		sdrplay.InitialGR(*gr),
+		sdrplay.OnRetune(spec),
+	)
+	if e != nil {
+		log.Fatalln(e)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	// sweep percorre l'intervallo fino all'interruzione o, con -1, per una
+	// sola volta; il ricevitore viene chiuso in ogni caso prima di uscire.
+	sweep := func() error {
+		for {
+			began := time.Now()
+
+			for k := 0; k < hops; k++ {
+				center := lo + (float64(k)+0.5)*hop
+				if e := rx.Tune(center); e != nil {
+					return e
+				}
+
+				power, count, ok := integrate(frames, center, *settle, *integration, interrupt)
+				if !ok {
+					return nil
+				}
+
+				// Un passo senza spettri, ad esempio perché lo stream si è
+				// interrotto, non ha misure da scrivere.
+				if count == 0 {
+					log.Printf("no spectrum at %.0f Hz in %v, hop skipped", center, *integration)
+					continue
+				}
+
+				// Si scrivono i soli bin centrali.
+				first := (n - used) / 2
+				now := time.Now()
+				fmt.Fprintf(csv, "%s, %s, %.0f, %.0f, %.2f, %d",
+					now.Format("2006-01-02"), now.Format("15:04:05"),
+					center-hop/2, center+hop/2, res, count)
+				for _, p := range power[first : first+used] {
+					fmt.Fprintf(csv, ", %.2f", p)
+				}
+				fmt.Fprintln(csv)
+			}
+
+			if e := csv.Flush(); e != nil {
+				return e
+			}
+
+			if *once {
+				return nil
+			}
+
+			if wait := *interval - time.Since(began); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-interrupt:
+					return nil
+				}
+			}
+		}
+	}
+
+	e = sweep()
+	if c := rx.Close(); e == nil {
+		e = c
+	}
+	if f := csv.Flush(); e == nil {
+		e = f
+	}
+	if e != nil {
+		log.Fatalln(e)
+	}
+}
+
+// integrate scarta gli spettri ricevuti nel tempo settle dopo la sintonia su
+// center e restituisce la media, in dBFS, di quelli ricevuti nel tempo
+// successivo dwell, insieme al loro numero. Se non ne viene ricevuto nessuno
+// restituisce nil e 0. Restituisce false se viene ricevuta un'interruzione.
+func integrate(frames <-chan sdrplay.SpectrumFrame, center float64, settle, dwell time.Duration, interrupt <-chan os.Signal) ([]float64, int, bool) {
+	var sum []float64
+	var count int
+
+	from := time.Now().Add(settle)
+	deadline := time.After(settle + dwell)
+
+	for {
+		select {
+		case <-interrupt:
+			return nil, 0, false
+		case <-deadline:
+			for k := range sum {
+				sum[k] = 10 * math.Log10(sum[k]/float64(count))
+			}
+			return sum, count, true
+		case f := <-frames:
+			if f.Frequency != center || time.Now().Before(from) {
+				continue
+			}
+
+			if sum == nil {
+				sum = make([]float64, len(f.Power))
+			}
+			for k, p := range f.Power {
+				sum[k] += math.Pow(10, p/10)
+			}
+			count++
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+// sdrplay-rec registra il segnale in banda base ricevuto dalla RSP in formato
+// cs16, WAV o SigMF, per la durata indicata o fino all'interruzione con Ctrl-C.
+//
+//	sdrplay-rec -f 433.92 -s 2 -t 30s -format wav -o capture.wav
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iclac/sdrplay"
+)
+
+// bandwidths sono le larghezze di banda della RSP in ordine decrescente.
+var bandwidths = []sdrplay.B{
+	sdrplay.BW8000, sdrplay.BW7000, sdrplay.BW6000, sdrplay.BW5000,
+	sdrplay.BW1536, sdrplay.BW600, sdrplay.BW300, sdrplay.BW200,
+}
+
+func main() {
+	frequency := flag.Float64("f", 100, "center frequency in MHz")
+	fs := flag.Float64("s", 2.048, "sample rate in MHz")
+	bw := flag.Int("bw", 0, "bandwidth in kHz (0 for the widest within -s)")
+	gr := flag.Int("g", 40, "gain reduction in dB")
+	agc := flag.Bool("agc", false, "enable AGC instead of a fixed gain reduction")
+	duration := flag.Duration("t", 0, "recording duration (0 until Ctrl-C)")
+	format := flag.String("format", "", "format: cs16, wav or sigmf (default from the file extension)")
+	out := flag.String("o", "", "output file")
+	flag.Parse()
+
+	log.SetFlags(0)
+
+	if *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *format == "" {
+		*format = "cs16"
+		switch strings.ToLower(filepath.Ext(*out)) {
+		case ".wav":
+			*format = "wav"
+		case ".sigmf", ".sigmf-data", ".sigmf-meta":
+			*format = "sigmf"
+		}
+	}
+
+	b := sdrplay.B(*bw)
+	if b == 0 {
+		b = sdrplay.BW200
+		for _, c := range bandwidths {
+			if float64(c)*1e3 <= *fs*1e6 {
+				b = c
+				break
+			}
+		}
+	}
+
+	st := sdrplay.NewDisk(filepath.Dir(*out))
+	name := filepath.Base(*out)
+
+	var rec *sdrplay.Recorder
+	var e error
+	switch *format {
+	case "cs16":
+		rec, e = sdrplay.NewRecorder(st, name)
+	case "wav":
+		rec, e = sdrplay.NewWAVRecorder(st, name, *fs*1e6, *frequency*1e6)
+	case "sigmf":
+		name = strings.TrimSuffix(strings.TrimSuffix(name, ".sigmf-data"), ".sigmf-meta")
+		rec, e = sdrplay.NewSigMFRecorder(st, strings.TrimSuffix(name, ".sigmf"), *fs*1e6, *frequency*1e6)
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+	if e != nil {
+		log.Fatalln(e)
+	}
+
+	opts := []sdrplay.Option{
+		sdrplay.InitialRF(*frequency),
+		sdrplay.FS(*fs),
+		sdrplay.Bandwidth(b),
+		sdrplay.InitialGR(*gr),
+	}
+	if *agc {
+		opts = append(opts, sdrplay.AGC(sdrplay.AGC5Hz, -30))
+	}
+
+	rx, e := sdrplay.RSP(rec, opts...)
+	if e != nil {
+		rec.Close()
+		log.Fatalln(e)
+	}
+
+	fmt.Fprintf(os.Stderr, "recording %g MHz at %g MHz (bandwidth %d kHz) to %s\n", *frequency, *fs, b, *out)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+
+	var timeout <-chan time.Time
+	if *duration > 0 {
+		timeout = time.After(*duration)
+	}
+
+	select {
+	case <-stop:
+	case <-timeout:
+	}
+
+	// Il ricevitore va chiuso per primo, così che la registrazione venga
+	// conclusa solo dopo l'ultimo frame propagato.
+	if e := rx.Close(); e != nil {
+		log.Println(e)
+	}
+	if e := rec.Close(); e != nil {
+		log.Fatalln(e)
+	}
+
+	if n := rec.Dropped(); n > 0 {
+		fmt.Fprintf(os.Stderr, "%d frames dropped\n", n)
+	}
+}
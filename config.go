@@ -0,0 +1,329 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// Config contiene tutti i parametri configurabili della RSP, alternativa alle
+// opzioni per i programmi che leggono la configurazione da file o da linea di
+// comando. I campi hanno lo stesso nome, le stesse unità di misura e lo stesso
+// significato delle omonime opzioni; il valore nullo di un campo corrisponde
+// all'opzione non impostata. Le opzioni che non riguardano parametri della
+// RSP, come Logger o Trigger, non fanno parte di Config.
+//
+//	cfg := sdrplay.DefaultConfig()
+//	cfg.InitialRF = 145.5
+//	rx, e := sdrplay.RSP(baseband, cfg.Option())
+type Config struct {
+	// FS è la frequenza di campionamento e InitialRF la frequenza
+	// sintonizzata, entrambe espresse in MHz.
+	FS        float64
+	InitialRF float64
+
+	Bandwidth B
+	IF        IFmode
+	LOmode    LOfrequency
+	LOppm     float64
+
+	IQimbalance bool
+	DCoffset    bool
+	DCmode      OffsetMode
+	DCtrackTime int
+
+	Decimate bool
+	Factor   Decimation
+
+	// LNA abilita l'amplificatore a basso rumore, InitialGR è il gain
+	// reduction in dB, AGC e DBFS il modo ed il livello dell'AGC.
+	LNA       bool
+	InitialGR int
+	AGC       AGCmode
+	DBFS      int
+
+	Debug bool
+
+	// TuningStep e OffsetTuning sono espressi in kHz.
+	TuningStep   float64
+	OffsetTuning float64
+
+	// Transfer è la modalità di trasferimento USB dei campioni.
+	Transfer TransferMode
+
+	// HiZ seleziona la porta d'antenna ad alta impedenza.
+	HiZ bool
+
+	// Input è l'ingresso d'antenna a 50 Ω.
+	Input Input
+
+	// AutoIF sceglie la IF in base a FS e Bandwidth, ignorando IF.
+	AutoIF bool
+
+	// Channel è la larghezza, in Hz, del canale scelto con AutoBandwidth; se
+	// positiva Bandwidth, FS, Decimate e Factor vengono ricalcolati.
+	Channel float64
+
+	// OutputRate è la frequenza, in Hz, dei campioni propagati scelta con
+	// OutputRate; se positiva FS, Decimate, Factor e, salvo che Channel sia
+	// positiva, Bandwidth vengono ricalcolati.
+	OutputRate float64
+
+	// DCChannel è la larghezza, in Hz, del canale scelto con AvoidDC; se
+	// positiva e la IF è nulla OffsetTuning viene ricalcolato.
+	DCChannel float64
+}
+
+// configJSON è la rappresentazione JSON di Config.
+type configJSON struct {
+	Version int `json:"version"`
+
+	FS        float64     `json:"fs"`
+	InitialRF float64     `json:"rf"`
+	Bandwidth B           `json:"bandwidth"`
+	IF        IFmode      `json:"if"`
+	LOmode    LOfrequency `json:"lo_mode"`
+	LOppm     float64     `json:"lo_ppm,omitempty"`
+
+	IQimbalance bool       `json:"iq_imbalance,omitempty"`
+	DCoffset    bool       `json:"dc_offset,omitempty"`
+	DCmode      OffsetMode `json:"dc_mode,omitempty"`
+	DCtrackTime int        `json:"dc_track_time,omitempty"`
+
+	Decimate bool       `json:"decimate,omitempty"`
+	Factor   Decimation `json:"factor,omitempty"`
+
+	LNA       bool    `json:"lna"`
+	InitialGR int     `json:"gain_reduction,omitempty"`
+	AGC       AGCmode `json:"agc"`
+	DBFS      int     `json:"agc_dbfs,omitempty"`
+
+	Debug bool `json:"debug,omitempty"`
+
+	TuningStep   float64 `json:"tuning_step,omitempty"`
+	OffsetTuning float64 `json:"offset_tuning,omitempty"`
+
+	Transfer   TransferMode `json:"transfer,omitempty"`
+	HiZ        bool         `json:"hiz,omitempty"`
+	Input      Input        `json:"input,omitempty"`
+	AutoIF     bool         `json:"auto_if,omitempty"`
+	Channel    float64      `json:"channel,omitempty"`
+	OutputRate float64      `json:"output_rate,omitempty"`
+	DCChannel  float64      `json:"dc_channel,omitempty"`
+}
+
+// configVersion è la versione del formato JSON di Config. Dalla versione 2 le
+// enumerazioni sono riportate con il nome della costante, ad esempio
+// "BW1536"; i numeri della versione 1 vengono comunque accettati, con il
+// fattore di decimazione, che nella versione 1 valeva il doppio, dimezzato.
+const configVersion = 2
+
+// UnsupportedConfigError indica che la configurazione JSON è stata salvata con
+// una versione del formato successiva a quella supportata.
+var UnsupportedConfigError = errors.New("Unsupported Config Error")
+
+// LoadConfig restituisce la configurazione salvata con SaveConfig nel file
+// path.
+func LoadConfig(path string) (Config, error) {
+	b, e := os.ReadFile(path)
+	if e != nil {
+		return Config{}, e
+	}
+
+	var c Config
+	if e := json.Unmarshal(b, &c); e != nil {
+		return Config{}, e
+	}
+
+	return c, nil
+}
+
+// SaveConfig salva la configurazione c nel file path in formato JSON.
+func SaveConfig(path string, c Config) error {
+	b, e := json.MarshalIndent(c, "", "  ")
+	if e != nil {
+		return e
+	}
+
+	return os.WriteFile(path, b, 0644)
+}
+
+// MarshalJSON implementa l'interfaccia json.Marshaler. Il JSON prodotto
+// riporta la versione del formato.
+func (c Config) MarshalJSON() ([]byte, error) {
+	j := c.json()
+	j.Version = configVersion
+
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implementa l'interfaccia json.Unmarshaler. I parametri assenti
+// nel JSON assumono il valore di DefaultConfig.
+func (c *Config) UnmarshalJSON(b []byte) error {
+	j := DefaultConfig().json()
+	if e := json.Unmarshal(b, &j); e != nil {
+		return e
+	}
+
+	if j.Version > configVersion {
+		return UnsupportedConfigError
+	}
+
+	if j.Version < 2 {
+		j.Factor /= 2
+	}
+
+	*c = Config{
+		FS:           j.FS,
+		InitialRF:    j.InitialRF,
+		Bandwidth:    j.Bandwidth,
+		IF:           j.IF,
+		LOmode:       j.LOmode,
+		LOppm:        j.LOppm,
+		IQimbalance:  j.IQimbalance,
+		DCoffset:     j.DCoffset,
+		DCmode:       j.DCmode,
+		DCtrackTime:  j.DCtrackTime,
+		Decimate:     j.Decimate,
+		Factor:       j.Factor,
+		LNA:          j.LNA,
+		InitialGR:    j.InitialGR,
+		AGC:          j.AGC,
+		DBFS:         j.DBFS,
+		Debug:        j.Debug,
+		TuningStep:   j.TuningStep,
+		OffsetTuning: j.OffsetTuning,
+		Transfer:     j.Transfer,
+		HiZ:          j.HiZ,
+		Input:        j.Input,
+		AutoIF:       j.AutoIF,
+		Channel:      j.Channel,
+		OutputRate:   j.OutputRate,
+		DCChannel:    j.DCChannel,
+	}
+
+	return nil
+}
+
+// json restituisce la rappresentazione JSON di c.
+func (c Config) json() configJSON {
+	return configJSON{
+		FS:           c.FS,
+		InitialRF:    c.InitialRF,
+		Bandwidth:    c.Bandwidth,
+		IF:           c.IF,
+		LOmode:       c.LOmode,
+		LOppm:        c.LOppm,
+		IQimbalance:  c.IQimbalance,
+		DCoffset:     c.DCoffset,
+		DCmode:       c.DCmode,
+		DCtrackTime:  c.DCtrackTime,
+		Decimate:     c.Decimate,
+		Factor:       c.Factor,
+		LNA:          c.LNA,
+		InitialGR:    c.InitialGR,
+		AGC:          c.AGC,
+		DBFS:         c.DBFS,
+		Debug:        c.Debug,
+		TuningStep:   c.TuningStep,
+		OffsetTuning: c.OffsetTuning,
+		Transfer:     c.Transfer,
+		HiZ:          c.HiZ,
+		Input:        c.Input,
+		AutoIF:       c.AutoIF,
+		Channel:      c.Channel,
+		OutputRate:   c.OutputRate,
+		DCChannel:    c.DCChannel,
+	}
+}
+
+// DefaultConfig restituisce la configurazione usata da RSP in assenza di
+// opzioni.
+func DefaultConfig() Config {
+	return configured().config()
+}
+
+// Option restituisce l'opzione che imposta tutti i parametri di c.
+func (c Config) Option() Option {
+	return Option{
+		apply: func() {
+			rsp.FS = double(c.FS)
+			rsp.InitialRF = double(c.InitialRF)
+			rsp.BW = c.Bandwidth
+			rsp.IF = c.IF
+			rsp.LOmode = c.LOmode
+			rsp.LOppm = double(c.LOppm)
+			rsp.IQimbalance = enable(c.IQimbalance)
+			rsp.DCoffset = enable(c.DCoffset)
+			rsp.DCmode = c.DCmode
+			rsp.DCTrakTime = integer(c.DCtrackTime)
+			rsp.Decimate = enable(c.Decimate)
+			rsp.Factor = c.Factor
+			rsp.LNA = enable(c.LNA)
+			rsp.InitialGR = integer(c.InitialGR)
+			rsp.AGC = c.AGC
+			rsp.DBFS = integer(c.DBFS)
+			rsp.Debug = enable(c.Debug)
+			rsp.Step = double(c.TuningStep)
+			rsp.TuneOffset = double(c.OffsetTuning)
+			rsp.Transfer = c.Transfer
+			rsp.HiZ = enable(c.HiZ)
+			rsp.Input = c.Input
+			rsp.AutoIF = c.AutoIF
+			rsp.Channel = double(c.Channel)
+			rsp.Output = double(c.OutputRate)
+			rsp.DCChannel = double(c.DCChannel)
+		},
+	}
+}
+
+// config restituisce i parametri di f come Config.
+func (f features) config() Config {
+	return Config{
+		FS:           float64(f.FS),
+		InitialRF:    float64(f.InitialRF),
+		Bandwidth:    f.BW,
+		IF:           f.IF,
+		LOmode:       f.LOmode,
+		LOppm:        float64(f.LOppm),
+		IQimbalance:  bool(f.IQimbalance),
+		DCoffset:     bool(f.DCoffset),
+		DCmode:       f.DCmode,
+		DCtrackTime:  int(f.DCTrakTime),
+		Decimate:     bool(f.Decimate),
+		Factor:       f.Factor,
+		LNA:          bool(f.LNA),
+		InitialGR:    int(f.InitialGR),
+		AGC:          f.AGC,
+		DBFS:         int(f.DBFS),
+		Debug:        bool(f.Debug),
+		TuningStep:   float64(f.Step),
+		OffsetTuning: float64(f.TuneOffset),
+		Transfer:     f.Transfer,
+		HiZ:          bool(f.HiZ),
+		Input:        f.Input,
+		AutoIF:       f.AutoIF,
+		Channel:      float64(f.Channel),
+		OutputRate:   float64(f.Output),
+		DCChannel:    float64(f.DCChannel),
+	}
+}
+
+// Config implementa l'interfaccia DeviceControl. InitialRF riporta la
+// frequenza attualmente sintonizzata.
+func (r *radio) Config() Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.feat.config()
+	c.InitialRF = r.rf / 1e6
+
+	return c
+}
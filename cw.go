@@ -0,0 +1,140 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"math/cmplx"
+	"sync"
+)
+
+// CW è un Connector che demodula un segnale telegrafico (CW) centrato nella
+// frequenza sintonizzata: il canale viene selezionato con un filtro stretto e
+// la portante viene convertita in un tono audio (sidetone) di frequenza pari
+// al pitch impostato. L'audio viene propagato, a AudioRate campioni al secondo,
+// al connettore audio fornito.
+type CW struct {
+	mu sync.Mutex
+
+	// rate è la frequenza, espressa in Hz, alla quale avviene il filtraggio
+	// di canale e la conversione in audio.
+	rate float64
+
+	dec, low, ch *decimator
+	rs           *resampler
+
+	// pitch è la frequenza del tono audio espressa in Hz, phase è la fase
+	// attuale dell'oscillatore di battimento (BFO) in radianti.
+	pitch, phase float64
+
+	// bandwidth è la larghezza del filtro di canale espressa in Hz.
+	bandwidth float64
+
+	iq, bb, nb, cb []complex128
+	af, pcm        []float64
+	audio          []float32
+
+	out AudioConnector
+}
+
+const (
+	// cwRate è la frequenza, espressa in Hz, alla quale viene decimato il
+	// segnale prima del filtraggio di canale.
+	cwRate = 4000.0
+
+	// CWMinBandwidth è la minima larghezza del filtro di canale CW espressa in
+	// Hz.
+	CWMinBandwidth = 100.0
+
+	// cwMaxPitch è la massima frequenza del tono audio espressa in Hz.
+	cwMaxPitch = 1500.0
+)
+
+// NewCW restituisce un demodulatore CW per un segnale in banda base campionato
+// con frequenza fs, espressa in Hz, che propaga l'audio demodulato al
+// connettore out. Di default il tono audio è a 700Hz ed il filtro di canale è
+// largo 500Hz.
+func NewCW(fs float64, out AudioConnector) *CW {
+	f1 := int(fs / amRate)
+	if f1 < 1 {
+		f1 = 1
+	}
+	r1 := fs / float64(f1)
+
+	f2 := int(r1 / cwRate)
+	if f2 < 1 {
+		f2 = 1
+	}
+
+	c := &CW{
+		rate:      r1 / float64(f2),
+		dec:       newDecimator(f1, 0.4/float64(f1)),
+		low:       newDecimator(f2, 0.4/float64(f2)),
+		pitch:     700,
+		bandwidth: 500,
+		out:       out,
+	}
+
+	c.update()
+
+	return c
+}
+
+// Pitch imposta la frequenza, espressa in Hz, del tono audio prodotto dalla
+// portante. Il valore viene limitato all'intervallo 100-1500Hz.
+func (c *CW) Pitch(hz float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pitch = math.Max(100, math.Min(cwMaxPitch, hz))
+	c.update()
+}
+
+// Bandwidth imposta la larghezza, espressa in Hz, del filtro di canale. Il
+// valore minimo ammesso è CWMinBandwidth.
+func (c *CW) Bandwidth(hz float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.bandwidth = math.Max(CWMinBandwidth, hz)
+	c.update()
+}
+
+// update ricalcola il filtro di canale ed il ricampionatore audio sulla base
+// della larghezza di banda e del pitch attuali.
+func (c *CW) update() {
+	c.ch = newFilter(math.Min(c.bandwidth/2/c.rate, 0.45), 511)
+	c.rs = newResampler(c.rate, AudioRate, c.pitch+c.bandwidth)
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (c *CW) Propagate(I []int16, Q []int16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.iq = toComplex(I, Q, c.iq[:0])
+	c.bb = c.dec.process(c.iq, c.bb[:0])
+	c.nb = c.low.process(c.bb, c.nb[:0])
+	c.cb = c.ch.process(c.nb, c.cb[:0])
+
+	step := 2 * math.Pi * c.pitch / c.rate
+
+	c.af = c.af[:0]
+	for _, x := range c.cb {
+		c.af = append(c.af, real(x*cmplx.Rect(1, c.phase)))
+		c.phase = math.Mod(c.phase+step, 2*math.Pi)
+	}
+
+	c.pcm = c.rs.process(c.af, c.pcm[:0])
+	if len(c.pcm) == 0 || c.out == nil {
+		return
+	}
+
+	c.audio = toAudio(c.pcm, c.audio[:0])
+	c.out.PropagateAudio(c.audio)
+}
@@ -0,0 +1,283 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// DABBlock è un blocco DAB della Banda III.
+	DABBlock struct {
+		// Name è il nome del blocco, ad esempio "12C".
+		Name string
+
+		// Frequency è la frequenza centrale del blocco espressa in Hz.
+		Frequency float64
+	}
+
+	// DABEnsemble descrive un ensemble DAB rilevato da DABScanner.
+	DABEnsemble struct {
+		Block DABBlock
+
+		// Power è la potenza del segnale ricevuto nel blocco in dBFS.
+		Power float64
+
+		// Correlation è la correlazione normalizzata tra gli intervalli di
+		// guardia ed i simboli OFDM corrispondenti: vale circa 0.2 per un
+		// segnale DAB pulito e tende a 0 in sua assenza.
+		Correlation float64
+
+		// Null è la profondità, in dB, del simbolo nullo che marca l'inizio di
+		// ogni trama DAB rispetto al livello medio del segnale.
+		Null float64
+	}
+
+	// DABScanner è un Connector che rileva la presenza di ensemble DAB (modo
+	// I) nei blocchi della Banda III. Va inserito nella catena di
+	// elaborazione del ricevitore, configurato ad esempio con DABPreset, e
+	// Scan va invocato dopo la creazione del ricevitore. I frame ricevuti
+	// vengono propagati inalterati al connettore out, se presente.
+	DABScanner struct {
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento del segnale espressa in Hz.
+		fs float64
+
+		// settle è il tempo di attesa dopo ogni sintonia, dwell la durata del
+		// segnale acquisito per ogni blocco.
+		settle, dwell time.Duration
+
+		// buf accumula i campioni richiesti da Scan fino a want campioni, al
+		// raggiungimento dei quali viene chiuso done.
+		buf  []complex128
+		want int
+		done chan struct{}
+
+		out Connector
+	}
+)
+
+const (
+	// dabSymbol è la durata, in secondi, della parte utile dei simboli OFDM
+	// DAB in modo I.
+	dabSymbol = 1e-3
+
+	// dabNull è la durata, in secondi, del simbolo nullo DAB in modo I.
+	dabNull = 1.297e-3
+
+	// dabCorrelation e dabNullDepth sono le soglie oltre le quali un blocco è
+	// considerato occupato da un ensemble.
+	dabCorrelation = 0.08
+	dabNullDepth   = 6
+)
+
+// ScanTimeoutError indica che durante una scansione il ricevitore non ha
+// fornito campioni entro il tempo previsto.
+var ScanTimeoutError = errors.New("Scan Timeout Error")
+
+// DABBlocks contiene i blocchi DAB della Banda III secondo la norma ETSI EN 300
+// 401.
+var DABBlocks = []DABBlock{
+	{"5A", 174.928e6}, {"5B", 176.640e6}, {"5C", 178.352e6}, {"5D", 180.064e6},
+	{"6A", 181.936e6}, {"6B", 183.648e6}, {"6C", 185.360e6}, {"6D", 187.072e6},
+	{"7A", 188.928e6}, {"7B", 190.640e6}, {"7C", 192.352e6}, {"7D", 194.064e6},
+	{"8A", 195.936e6}, {"8B", 197.648e6}, {"8C", 199.360e6}, {"8D", 201.072e6},
+	{"9A", 202.928e6}, {"9B", 204.640e6}, {"9C", 206.352e6}, {"9D", 208.064e6},
+	{"10A", 209.936e6}, {"10N", 210.096e6}, {"10B", 211.648e6}, {"10C", 213.360e6}, {"10D", 215.072e6},
+	{"11A", 216.928e6}, {"11N", 217.088e6}, {"11B", 218.640e6}, {"11C", 220.352e6}, {"11D", 222.064e6},
+	{"12A", 223.936e6}, {"12N", 224.096e6}, {"12B", 225.648e6}, {"12C", 227.360e6}, {"12D", 229.072e6},
+	{"13A", 230.784e6}, {"13B", 232.496e6}, {"13C", 234.208e6}, {"13D", 235.776e6}, {"13E", 237.488e6}, {"13F", 239.200e6},
+}
+
+// DABPreset restituisce le opzioni che configurano la RSP per la ricezione DAB:
+// sintonia sul primo blocco della Banda III, campionamento a 2.048MHz con banda
+// di 1536kHz ed IF nulla.
+func DABPreset() []Option {
+	return []Option{
+		InitialRF(DABBlocks[0].Frequency / 1e6),
+		FS(2.048),
+		Bandwidth(BW1536),
+		IF(IFzero),
+		LOmode(LOauto),
+	}
+}
+
+// NewDABScanner restituisce un DABScanner per un segnale campionato con
+// frequenza fs, espressa in Hz, che propaga i frame ricevuti ad out se non nil.
+// Di default dopo ogni sintonia vengono attesi 50ms e vengono acquisiti 200ms
+// di segnale, pari a due trame DAB.
+func NewDABScanner(fs float64, out Connector) *DABScanner {
+	return &DABScanner{
+		fs:     fs,
+		settle: 50 * time.Millisecond,
+		dwell:  200 * time.Millisecond,
+		out:    out,
+	}
+}
+
+// Timing imposta il tempo di attesa dopo ogni sintonia e la durata del segnale
+// acquisito per ogni blocco. Quest'ultima non può essere inferiore a 100ms,
+// perché deve contenere almeno un simbolo nullo.
+func (s *DABScanner) Timing(settle, dwell time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dwell < 100*time.Millisecond {
+		dwell = 100 * time.Millisecond
+	}
+
+	s.settle, s.dwell = settle, dwell
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (s *DABScanner) Propagate(I []int16, Q []int16) {
+	s.mu.Lock()
+
+	if s.done != nil && len(s.buf) < s.want {
+		for k := 0; k < len(I) && k < len(Q) && len(s.buf) < s.want; k++ {
+			s.buf = append(s.buf, complex(float64(I[k]), float64(Q[k]))/fullScale)
+		}
+
+		if len(s.buf) == s.want {
+			close(s.done)
+			s.done = nil
+		}
+	}
+
+	s.mu.Unlock()
+
+	if s.out != nil {
+		s.out.Propagate(I, Q)
+	}
+}
+
+// Scan sintonizza con t, in sequenza, i blocchi indicati, o tutti i DABBlocks
+// se non ne viene indicato nessuno, e restituisce gli ensemble rilevati
+// ordinati per frequenza. In caso di errore restituisce gli ensemble rilevati
+// fino a quel momento.
+func (s *DABScanner) Scan(t Tuner, blocks ...DABBlock) ([]DABEnsemble, error) {
+	if len(blocks) == 0 {
+		blocks = DABBlocks
+	}
+
+	var found []DABEnsemble
+	for _, b := range blocks {
+		ens, e := s.measure(t, b)
+		if e != nil {
+			return found, e
+		}
+
+		if ens.Correlation > dabCorrelation && ens.Null > dabNullDepth {
+			found = append(found, ens)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Block.Frequency < found[j].Block.Frequency
+	})
+
+	return found, nil
+}
+
+// measure sintonizza il blocco b ed acquisisce e misura il segnale ricevuto.
+func (s *DABScanner) measure(t Tuner, b DABBlock) (DABEnsemble, error) {
+	if e := t.Tune(b.Frequency); e != nil {
+		return DABEnsemble{}, e
+	}
+
+	s.mu.Lock()
+	settle, dwell := s.settle, s.dwell
+	s.mu.Unlock()
+
+	time.Sleep(settle)
+
+	done := make(chan struct{})
+
+	s.mu.Lock()
+	s.buf = s.buf[:0]
+	s.want = int(dwell.Seconds() * s.fs)
+	s.done = done
+	s.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(2*dwell + time.Second):
+		s.mu.Lock()
+		s.done = nil
+		s.mu.Unlock()
+
+		return DABEnsemble{}, ScanTimeoutError
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ens := DABEnsemble{Block: b}
+	ens.Power, ens.Correlation, ens.Null = dabMeasure(s.buf, s.fs)
+
+	return ens, nil
+}
+
+// dabMeasure restituisce la potenza in dBFS, la correlazione degli intervalli
+// di guardia e la profondità del simbolo nullo del segnale x campionato con
+// frequenza fs.
+func dabMeasure(x []complex128, fs float64) (pwr, corr, null float64) {
+	lag := int(dabSymbol*fs + 0.5)
+	if len(x) <= lag {
+		return dB(0), 0, 0
+	}
+
+	// La parte finale di ogni simbolo è ripetuta nel suo intervallo di
+	// guardia: la correlazione con ritardo pari alla parte utile ha fase
+	// costante negli intervalli di guardia e casuale altrove.
+	var acc complex128
+	var energy float64
+	for k := 0; k+lag < len(x); k++ {
+		acc += x[k] * cmplx.Conj(x[k+lag])
+		energy += real(x[k])*real(x[k]) + imag(x[k])*imag(x[k])
+	}
+
+	if energy == 0 {
+		return dB(0), 0, 0
+	}
+
+	pwr = dB(energy / float64(len(x)-lag))
+	corr = cmplx.Abs(acc) / energy
+
+	// Il simbolo nullo viene cercato come la finestra, lunga metà di esso,
+	// di minima energia rispetto a quella media.
+	win := int(dabNull / 2 * fs)
+	if win < 1 {
+		return pwr, corr, 0
+	}
+
+	var sum, total float64
+	min := math.Inf(1)
+	for k, v := range x {
+		sum += real(v)*real(v) + imag(v)*imag(v)
+		total += real(v)*real(v) + imag(v)*imag(v)
+		if k >= win {
+			o := x[k-win]
+			sum -= real(o)*real(o) + imag(o)*imag(o)
+		}
+		if k >= win-1 {
+			min = math.Min(min, sum)
+		}
+	}
+
+	mean := total / float64(len(x)) * float64(win)
+	null = dB(mean) - dB(math.Max(min, 0))
+
+	return pwr, corr, null
+}
@@ -0,0 +1,116 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"sync"
+	"time"
+)
+
+// Emphasis enumera le costanti di tempo della pre-enfasi applicata in
+// trasmissione al segnale FM broadcast, che deve essere compensata in ricezione
+// con una de-enfasi di pari costante.
+type Emphasis int
+
+const (
+	// EmphasisOff disabilita la de-enfasi.
+	EmphasisOff Emphasis = iota
+	// Emphasis50us indica una costante di tempo di 50µs, in uso in Europa e
+	// nella maggior parte del mondo.
+	Emphasis50us
+	// Emphasis75us indica una costante di tempo di 75µs, in uso nelle Americhe
+	// e in Corea del Sud.
+	Emphasis75us
+)
+
+type (
+	// Deemphasis è un AudioConnector che applica la de-enfasi al segnale
+	// audio demodulato e lo propaga al connettore audio fornito. Serve quando
+	// il demodulatore non la applica già, ad esempio per l'audio registrato o
+	// ricevuto da un'altra sorgente.
+	Deemphasis struct {
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento dell'audio espressa in Hz.
+		fs float64
+
+		de    *deemphasis
+		audio []float32
+
+		out AudioConnector
+	}
+
+	// deemphasis è un filtro passa basso del primo ordine che compensa la
+	// pre-enfasi applicata in trasmissione al segnale FM.
+	deemphasis struct {
+		emphasis Emphasis
+		a, y     float64
+	}
+)
+
+// tau restituisce la costante di tempo corrispondente a e, nulla se la
+// de-enfasi è disabilitata.
+func (e Emphasis) tau() time.Duration {
+	switch e {
+	case Emphasis50us:
+		return 50 * time.Microsecond
+	case Emphasis75us:
+		return 75 * time.Microsecond
+	default:
+		return 0
+	}
+}
+
+// NewDeemphasis restituisce un Deemphasis che applica la de-enfasi e all'audio
+// campionato con frequenza fs, espressa in Hz, e lo propaga al connettore out.
+func NewDeemphasis(e Emphasis, fs float64, out AudioConnector) *Deemphasis {
+	return &Deemphasis{
+		fs:  fs,
+		de:  newDeemphasis(e, fs),
+		out: out,
+	}
+}
+
+// Select imposta la de-enfasi applicata, ad esempio al cambio di regione.
+func (d *Deemphasis) Select(e Emphasis) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.de = newDeemphasis(e, d.fs)
+}
+
+// PropagateAudio implementa l'interfaccia AudioConnector.
+func (d *Deemphasis) PropagateAudio(samples []float32) {
+	// Il lock viene trattenuto fino al ritorno di out, perché audio viene
+	// riusato dal frame successivo e de può essere sostituito da Select.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.audio = d.audio[:0]
+	for _, x := range samples {
+		d.audio = append(d.audio, float32(d.de.process(float64(x))))
+	}
+
+	if d.out != nil {
+		d.out.PropagateAudio(d.audio)
+	}
+}
+
+// newDeemphasis restituisce un filtro di de-enfasi e per un segnale campionato
+// con frequenza fs espressa in Hz. Se e è EmphasisOff il filtro lascia
+// inalterato il segnale.
+func newDeemphasis(e Emphasis, fs float64) *deemphasis {
+	return &deemphasis{emphasis: e, a: smoothing(e.tau(), 1, fs)}
+}
+
+// process filtra il campione x e restituisce il campione filtrato.
+func (d *deemphasis) process(x float64) float64 {
+	d.y += d.a * (x - d.y)
+
+	return d.y
+}
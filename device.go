@@ -0,0 +1,389 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"math"
+	"sync"
+)
+
+type (
+	// Device è l'interfaccia generica di una SDR, modellata sulla semantica di
+	// SoapySDR: frequenze, larghezze di banda e frequenze di campionamento sono
+	// espresse in Hz, i guadagni in dB crescenti con l'amplificazione. Le
+	// impostazioni possono essere modificate sia prima sia dopo l'attivazione
+	// del flusso. Le applicazioni scritte per Device non dipendono dalla RSP e
+	// possono usare qualunque SDR per la quale esista un'implementazione.
+	Device interface {
+		// Driver restituisce il nome del driver del dispositivo.
+		Driver() string
+
+		// SampleRate e SetSampleRate leggono ed impostano la frequenza di
+		// campionamento, compresa in SampleRateRange.
+		SampleRate() float64
+		SetSampleRate(hz float64) error
+		SampleRateRange() Range
+
+		// Frequency e SetFrequency leggono ed impostano la frequenza
+		// sintonizzata, compresa in FrequencyRange.
+		Frequency() float64
+		SetFrequency(hz float64) error
+		FrequencyRange() Range
+
+		// Bandwidth e SetBandwidth leggono ed impostano la larghezza di banda
+		// del filtro d'ingresso. SetBandwidth sceglie tra quelle restituite da
+		// Bandwidths la più vicina al valore richiesto.
+		Bandwidth() float64
+		SetBandwidth(hz float64) error
+		Bandwidths() []float64
+
+		// Gain e SetGain leggono ed impostano il guadagno complessivo,
+		// compreso in GainRange.
+		Gain() float64
+		SetGain(dB float64) error
+		GainRange() Range
+
+		// GainMode e SetGainMode leggono ed impostano il controllo automatico
+		// del guadagno.
+		GainMode() bool
+		SetGainMode(automatic bool) error
+
+		// Antenna e SetAntenna leggono e selezionano l'antenna tra quelle
+		// restituite da Antennas.
+		Antennas() []string
+		Antenna() string
+		SetAntenna(name string) error
+
+		// Activate avvia il flusso dei campioni in banda base verso out, che
+		// Deactivate interrompe.
+		Activate(out Connector) error
+		Deactivate() error
+	}
+
+	// Range è un intervallo di valori ammessi, estremi compresi.
+	Range struct {
+		Min, Max float64
+	}
+
+	// RSPDevice è il Device che usa la RSP. Il guadagno è ottenuto dal gain
+	// reduction come MaxGainReduction-reduction, quindi va da 0dB (riduzione
+	// massima) a 39dB (riduzione minima); il controllo automatico usa l'AGC
+	// della RSP con loop a 5Hz ed un livello di -30dBFS. L'unica antenna è
+	// "RX".
+	RSPDevice struct {
+		mu sync.Mutex
+
+		// opts sono le opzioni fornite alla creazione, applicate
+		// all'attivazione prima delle impostazioni correnti.
+		opts []Option
+
+		fs, frequency, bandwidth float64
+		reduction                int
+		automatic                bool
+
+		rx *radio
+	}
+)
+
+const (
+	// MinGainReduction e MaxGainReduction delimitano il gain reduction, in
+	// dB, impostabile con Gain e InitialGR.
+	MinGainReduction = 20
+	MaxGainReduction = 59
+
+	// rspAGCdBFS è il livello mantenuto dall'AGC di RSPDevice.
+	rspAGCdBFS = -30
+
+	// rspAntenna è il nome dell'unica antenna di RSPDevice.
+	rspAntenna = "RX"
+)
+
+// UnsupportedSettingError indica che è stato richiesto un valore non ammesso
+// dal dispositivo.
+var UnsupportedSettingError = errors.New("Unsupported Setting Error")
+
+// NewRSPDevice restituisce il Device della RSP configurato con le opzioni opts,
+// le stesse accettate da RSP. Il flusso viene avviato da Activate.
+func NewRSPDevice(opts ...Option) *RSPDevice {
+	f := configured(opts...)
+
+	d := &RSPDevice{
+		opts:      opts,
+		fs:        float64(f.FS) * 1e6,
+		frequency: float64(f.InitialRF) * 1e6,
+		bandwidth: float64(f.BW) * 1e3,
+		reduction: int(f.InitialGR),
+		automatic: f.AGC != Disable,
+	}
+	if d.reduction == 0 {
+		d.reduction = MaxGainReduction
+	}
+
+	return d
+}
+
+// configured restituisce le caratteristiche che RSP imposterebbe con le
+// opzioni opts, senza modificare quelle correnti.
+func configured(opts ...Option) features {
+	return features{}.with(append(append([]Option(nil), defaults...), opts...)...)
+}
+
+// with restituisce le caratteristiche f modificate dalle opzioni opts.
+func (f features) with(opts ...Option) features {
+	optMu.Lock()
+	defer optMu.Unlock()
+
+	saved := rsp
+	defer func() { rsp = saved }()
+
+	rsp = f
+	configure(opts...)
+	rsp.resolve()
+
+	return rsp
+}
+
+// resolve completa f con i parametri scelti automaticamente dalle opzioni,
+// dopo che sono state tutte applicate così che il risultato non dipenda dal
+// loro ordine.
+func (f *features) resolve() {
+	if f.Channel > 0 {
+		f.autoBandwidth()
+	}
+	if f.Output > 0 {
+		f.outputRate()
+	}
+	if f.AutoIF {
+		f.IF = f.autoIF()
+	}
+	if f.DCChannel > 0 {
+		f.avoidDC()
+	}
+}
+
+// Driver implementa l'interfaccia Device.
+func (d *RSPDevice) Driver() string {
+	return "sdrplay"
+}
+
+// SampleRate implementa l'interfaccia Device.
+func (d *RSPDevice) SampleRate() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.fs
+}
+
+// SetSampleRate implementa l'interfaccia Device.
+func (d *RSPDevice) SetSampleRate(hz float64) error {
+	r := d.SampleRateRange()
+	if hz < r.Min || hz > r.Max {
+		return UnsupportedSettingError
+	}
+
+	return d.apply(func() { d.fs = hz }, FS(hz/1e6))
+}
+
+// SampleRateRange implementa l'interfaccia Device.
+func (d *RSPDevice) SampleRateRange() Range {
+	return Range{Min: 2e6, Max: 10e6}
+}
+
+// Frequency implementa l'interfaccia Device.
+func (d *RSPDevice) Frequency() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.frequency
+}
+
+// SetFrequency implementa l'interfaccia Device.
+func (d *RSPDevice) SetFrequency(hz float64) error {
+	r := d.FrequencyRange()
+	if hz < r.Min || hz > r.Max {
+		return UnsupportedSettingError
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.active() {
+		if e := d.rx.Tune(hz); e != nil {
+			return e
+		}
+	}
+	d.frequency = hz
+
+	return nil
+}
+
+// FrequencyRange implementa l'interfaccia Device.
+func (d *RSPDevice) FrequencyRange() Range {
+	return Range{Min: 100e3, Max: 2e9}
+}
+
+// Bandwidth implementa l'interfaccia Device.
+func (d *RSPDevice) Bandwidth() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.bandwidth
+}
+
+// SetBandwidth implementa l'interfaccia Device.
+func (d *RSPDevice) SetBandwidth(hz float64) error {
+	best := d.Bandwidths()[0]
+	for _, b := range d.Bandwidths() {
+		if math.Abs(b-hz) < math.Abs(best-hz) {
+			best = b
+		}
+	}
+
+	return d.apply(func() { d.bandwidth = best }, Bandwidth(B(best/1e3)))
+}
+
+// Bandwidths implementa l'interfaccia Device.
+func (d *RSPDevice) Bandwidths() []float64 {
+	return []float64{200e3, 300e3, 600e3, 1536e3, 5e6, 6e6, 7e6, 8e6}
+}
+
+// Gain implementa l'interfaccia Device.
+func (d *RSPDevice) Gain() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return float64(MaxGainReduction - d.reduction)
+}
+
+// SetGain implementa l'interfaccia Device.
+func (d *RSPDevice) SetGain(dB float64) error {
+	r := d.GainRange()
+	dB = math.Max(r.Min, math.Min(r.Max, dB))
+	reduction := MaxGainReduction - int(math.Round(dB))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.active() {
+		if e := d.rx.Gain(reduction); e != nil {
+			return e
+		}
+	}
+	d.reduction = reduction
+
+	return nil
+}
+
+// GainRange implementa l'interfaccia Device.
+func (d *RSPDevice) GainRange() Range {
+	return Range{Min: 0, Max: MaxGainReduction - MinGainReduction}
+}
+
+// GainMode implementa l'interfaccia Device.
+func (d *RSPDevice) GainMode() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.automatic
+}
+
+// SetGainMode implementa l'interfaccia Device.
+func (d *RSPDevice) SetGainMode(automatic bool) error {
+	return d.apply(func() { d.automatic = automatic }, d.agc(automatic))
+}
+
+// agc restituisce l'opzione corrispondente al modo di controllo del guadagno.
+func (d *RSPDevice) agc(automatic bool) Option {
+	if automatic {
+		return AGC(AGC5Hz, rspAGCdBFS)
+	}
+
+	return AGC(Disable, 0)
+}
+
+// Antennas implementa l'interfaccia Device.
+func (d *RSPDevice) Antennas() []string {
+	return []string{rspAntenna}
+}
+
+// Antenna implementa l'interfaccia Device.
+func (d *RSPDevice) Antenna() string {
+	return rspAntenna
+}
+
+// SetAntenna implementa l'interfaccia Device.
+func (d *RSPDevice) SetAntenna(name string) error {
+	if name != rspAntenna {
+		return UnsupportedSettingError
+	}
+
+	return nil
+}
+
+// Activate implementa l'interfaccia Device creando il ricevitore con RSP, con
+// le opzioni fornite a NewRSPDevice e le impostazioni correnti.
+func (d *RSPDevice) Activate(out Connector) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	opts := append([]Option(nil), d.opts...)
+	opts = append(opts,
+		FS(d.fs/1e6),
+		InitialRF(d.frequency/1e6),
+		Bandwidth(B(d.bandwidth/1e3)),
+		InitialGR(d.reduction),
+		d.agc(d.automatic),
+	)
+
+	r, e := RSP(out, opts...)
+	if r != nil {
+		d.rx = r.(*radio)
+	}
+
+	return e
+}
+
+// Deactivate implementa l'interfaccia Device fermando il flusso. Se nel
+// frattempo è stato creato un altro ricevitore con RSP, non ha effetto.
+func (d *RSPDevice) Deactivate() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.active() {
+		d.rx = nil
+		return nil
+	}
+
+	e := d.rx.Close()
+	d.rx = nil
+
+	return e
+}
+
+// active indica se il flusso è attivo e la RSP è ancora controllata dal
+// dispositivo.
+func (d *RSPDevice) active() bool {
+	return d.rx != nil && d.rx.active()
+}
+
+// apply memorizza con set la nuova impostazione e, se il flusso è attivo, la
+// applica alla RSP con l'opzione opt.
+func (d *RSPDevice) apply(set func(), opt Option) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.active() {
+		if e := d.rx.SetUp(opt); e != nil {
+			return e
+		}
+	}
+	set()
+
+	return nil
+}
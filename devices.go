@@ -0,0 +1,89 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+type (
+	// Model enumera i modelli di RSP.
+	Model int
+
+	// DeviceInfo descrive una RSP collegata al sistema.
+	DeviceInfo struct {
+		// Serial è il numero di serie e Name il nome del dispositivo USB.
+		Serial, Name string
+
+		// Model è il modello e HwVersion la versione hardware da cui è
+		// dedotto.
+		Model     Model
+		HwVersion int
+
+		// Available indica se la RSP è libera, cioè non usata da un altro
+		// processo.
+		Available bool
+	}
+)
+
+const (
+	// UnknownModel indica un modello non riconosciuto.
+	UnknownModel Model = iota
+	// RSP1 indica la RSP1 originale.
+	RSP1
+	// RSP1A indica la RSP1A.
+	RSP1A
+	// RSP2 indica la RSP2 e la RSP2pro.
+	RSP2
+	// RSPduo indica la RSPduo.
+	RSPduo
+	// RSPdx indica la RSPdx.
+	RSPdx
+)
+
+// String implementa l'interfaccia fmt.Stringer.
+func (m Model) String() string {
+	switch m {
+	case RSP1:
+		return "RSP1"
+	case RSP1A:
+		return "RSP1A"
+	case RSP2:
+		return "RSP2"
+	case RSPduo:
+		return "RSPduo"
+	case RSPdx:
+		return "RSPdx"
+	}
+
+	return "unknown"
+}
+
+// model restituisce il modello corrispondente alla versione hardware hw.
+func model(hw int) Model {
+	switch hw {
+	case 1:
+		return RSP1
+	case 2:
+		return RSP2
+	case 3:
+		return RSPduo
+	case 4:
+		return RSPdx
+	case 255:
+		return RSP1A
+	}
+
+	return UnknownModel
+}
+
+// Devices restituisce le RSP collegate al sistema.
+func Devices() ([]DeviceInfo, error) {
+	return api.devices()
+}
+
+// APIVersion restituisce la versione della libreria SDRplay in uso.
+func APIVersion() (float64, error) {
+	return api.apiVersion()
+}
@@ -8,4 +8,9 @@
 // sdrplay è un package che permette di usare la RSP, la SDR di SDRplay, in un
 // programma Go. Il package maschera però l'API originale, comunque usata
 // attraverso cgo, ma cerca di esporne una più immediata.
+//
+// L'audio demodulato viene riprodotto da AudioSink senza dipendere da librerie
+// audio: NewSystemAudio invia i campioni ad un programma esterno, aplay (ALSA),
+// pacat (PulseAudio o PipeWire) oppure play (SoX), che deve essere installato e
+// presente nel PATH, altrimenti restituisce l'errore NoAudioError.
 package sdrplay
@@ -0,0 +1,315 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// driver è l'interfaccia interna attraverso la quale radio comanda la RSP.
+	// Ogni metodo corrisponde ad una funzione dell'API mir_sdr, ma usa i tipi
+	// Go del package. L'implementazione predefinita invoca la libreria SDRplay
+	// attraverso cgo; compilando con il build tag nosdr, o mock, viene invece
+	// usata una RSP simulata in memoria che non richiede né l'hardware né la
+	// libreria.
+	driver interface {
+		// apiVersion restituisce la versione dell'API e expectedVersion
+		// quella attesa dal driver.
+		apiVersion() (float64, error)
+		expectedVersion() float64
+
+		// devices restituisce le RSP collegate al sistema.
+		devices() ([]DeviceInfo, error)
+
+		// streamInit avvia lo stream con i parametri p, aggiornandone gr,
+		// grsys e spp con i valori restituiti dall'API.
+		streamInit(p *streamParams) error
+
+		// reinit reinizializza lo stream con i parametri di p indicati da
+		// reason.
+		reinit(p *streamParams, reason reinitReason) error
+
+		// streamUninit ferma lo stream.
+		streamUninit() error
+
+		// setRf sintonizza la frequenza rf, espressa in Hz.
+		setRf(rf double) error
+
+		// shiftRf sposta la sintonia di drf, espresso in Hz.
+		shiftRf(drf double) error
+
+		// syncRf e syncGr sintonizzano la frequenza rf, espressa in Hz, ed
+		// impostano la gain reduction gr a partire dal campione sample.
+		// setSyncPeriod imposta il periodo degli aggiornamenti sincroni.
+		syncRf(rf double, sample uint32) error
+		syncGr(gr integer, lna enable, sample uint32) (integer, error)
+		setSyncPeriod(period uint32) error
+
+		// setGr imposta la gain reduction gr, restituendo quella del sistema.
+		setGr(gr integer, lna enable) (integer, error)
+
+		// selectDevice seleziona la RSP di indice idx tra quelle restituite
+		// da devices, da invocare a stream fermo; releaseDevice la rilascia.
+		selectDevice(idx int) error
+		releaseDevice()
+
+		// setParam imposta il parametro id del driver al valore value.
+		setParam(id, value uint32) error
+
+		// setTransferMode imposta la modalità di trasferimento USB, da
+		// invocare a stream fermo.
+		setTransferMode(mode TransferMode) error
+
+		// setBiasT abilita l'alimentazione bias-T e setNotch i filtri notch
+		// della RSP di modello model, usando le funzioni dell'API dedicate a
+		// tale modello.
+		setBiasT(model Model, on enable) error
+		setNotch(model Model, broadcast, dab enable) error
+
+		// setInput seleziona l'ingresso d'antenna in della RSP di modello
+		// model.
+		setInput(model Model, in Input) error
+
+		// setAmPort seleziona, se hiZ, la porta Hi-Z della RSP di modello
+		// model; a stream avviato va seguita da una Reinit con changeAMPort.
+		setAmPort(model Model, hiZ enable) error
+
+		// setDuoMode imposta la modalità della RSPduo, da invocare prima di
+		// streamInit.
+		setDuoMode(mode DuoMode) error
+
+		// setPpm imposta la correzione, in ppm, dell'oscillatore della RSP;
+		// 0 elimina la correzione.
+		setPpm(ppm double) error
+
+		// Le funzioni seguenti, come nell'uso che ne fa radio, non
+		// restituiscono errori.
+		setDcMode(mode OffsetMode, trackTime integer)
+		agcControl(mode AGCmode, dBFS integer, lna enable)
+		debugEnable(on enable)
+		dcOffsetIQimbalance(dc, iq enable)
+		decimateControl(on enable, factor Decimation)
+		setLoMode(mode LOfrequency)
+	}
+
+	// streamParams contiene i parametri di avvio e di reinizializzazione dello
+	// stream, con lo stesso significato di quelli di mir_sdr_StreamInit.
+	streamParams struct {
+		// gr è la gain reduction, grsys quella del sistema restituita
+		// dall'API e spp il numero di campioni per pacchetto.
+		gr, grsys, spp integer
+
+		// fs e rf sono la frequenza di campionamento e la frequenza
+		// sintonizzata, entrambe espresse in MHz.
+		fs, rf double
+
+		bw  B
+		ifm IFmode
+		lo  LOfrequency
+		lna enable
+	}
+
+	// reinitReason indica quali parametri vengono modificati da una
+	// reinizializzazione. I valori coincidono con quelli del tipo
+	// mir_sdr_ReasonForReinitT dell'API.
+	reinitReason int
+
+	// apiError è un codice di errore restituito dall'API. I valori coincidono
+	// con quelli del tipo mir_sdr_ErrT.
+	apiError int
+)
+
+const (
+	changeNone reinitReason = 0
+	changeGR   reinitReason = 1 << (iota - 1)
+	changeFS
+	changeRF
+	changeBW
+	changeIF
+	changeLO
+	changeAMPort
+)
+
+// Le bande in cui è suddiviso lo spettro ricevibile dalla RSP, come definite
+// nel tipo mir_sdr_BandT dell'API.
+const (
+	bandAMLo = iota
+	bandAMMid
+	bandAMHi
+	bandVHF
+	band3
+	bandX
+	band45
+	bandL
+)
+
+const (
+	apiSuccess apiError = iota
+	apiFail
+	apiInvalidParam
+	apiOutOfRange
+	apiGainUpdateError
+	apiRfUpdateError
+	apiFsUpdateError
+	apiHwError
+	apiAliasingError
+	apiAlreadyInitialised
+	apiNotInitialised
+	apiNotEnabled
+	apiHwVerError
+	apiOutOfMemError
+	apiHwRemoved
+)
+
+// MissingLibraryError indica che la libreria SDRplay non è installata. Può
+// essere restituito solo compilando con il build tag dlopen, che carica la
+// libreria all'avvio della prima RSP invece di collegarla in fase di link.
+var MissingLibraryError = errors.New("Missing Library Error")
+
+// APIError descrive un errore restituito da una funzione dell'API SDRplay. Ogni
+// APIError è un caso particolare di una delle categorie FailError,
+// InvalidParamError, OutOfRangeError, GainUpdateError, RfUpdateError,
+// FsUpdateError, HwError, AliasingError, AlreadyInitialisedError,
+// NotInitialisedError e DeviceRemovedError, così che l'errore possa essere riconosciuto con
+// errors.Is:
+//
+//	if errors.Is(e, sdrplay.OutOfRangeError) {
+//		...
+//	}
+type APIError struct {
+	// Func è la funzione dell'API che ha restituito l'errore e Code il codice
+	// di errore, coincidente con il valore del tipo mir_sdr_ErrT.
+	Func string
+	Code int
+}
+
+var (
+	// FailError indica un errore generico dell'API.
+	FailError = errors.New("Fail Error")
+
+	// InvalidParamError indica un parametro non valido.
+	InvalidParamError = errors.New("Invalid Param Error")
+
+	// OutOfRangeError indica un parametro fuori dall'intervallo ammesso.
+	OutOfRangeError = errors.New("Out Of Range Error")
+
+	// GainUpdateError, RfUpdateError e FsUpdateError indicano il fallimento
+	// dell'aggiornamento rispettivamente del guadagno, della frequenza
+	// sintonizzata e della frequenza di campionamento.
+	GainUpdateError = errors.New("Gain Update Error")
+	RfUpdateError   = errors.New("Rf Update Error")
+	FsUpdateError   = errors.New("Fs Update Error")
+
+	// HwError indica un malfunzionamento della RSP o una versione hardware
+	// non supportata.
+	HwError = errors.New("Hw Error")
+
+	// DeviceRemovedError indica la disconnessione della RSP.
+	DeviceRemovedError = errors.New("Device Removed Error")
+
+	// AliasingError indica una configurazione che produrrebbe aliasing.
+	AliasingError = errors.New("Aliasing Error")
+
+	// AlreadyInitialisedError e NotInitialisedError indicano che lo stream è,
+	// rispettivamente, già avviato o non avviato.
+	AlreadyInitialisedError = errors.New("Already Initialised Error")
+	NotInitialisedError     = errors.New("Not Initialised Error")
+)
+
+// errDesc mappa i codice di errore delle API SDRplay con le relative descrizioni.
+var errDesc = [...]string{
+	apiSuccess:            "Success",
+	apiFail:               "Fail",
+	apiInvalidParam:       "Invalid Param",
+	apiOutOfRange:         "Out of Range",
+	apiGainUpdateError:    "Gain Update error",
+	apiRfUpdateError:      "RF Update error",
+	apiFsUpdateError:      "FS Update error",
+	apiHwError:            "HW error",
+	apiAliasingError:      "Aliasing error",
+	apiAlreadyInitialised: "Already Initialised",
+	apiNotInitialised:     "Not Initialised",
+	apiNotEnabled:         "Not Enabled",
+	apiHwVerError:         "HW Version error",
+	apiOutOfMemError:      "Out of Memory error",
+	apiHwRemoved:          "HW Removed",
+}
+
+// errCategory mappa i codici di errore delle API SDRplay con le relative
+// categorie.
+var errCategory = [...]error{
+	apiFail:               FailError,
+	apiInvalidParam:       InvalidParamError,
+	apiOutOfRange:         OutOfRangeError,
+	apiGainUpdateError:    GainUpdateError,
+	apiRfUpdateError:      RfUpdateError,
+	apiFsUpdateError:      FsUpdateError,
+	apiHwError:            HwError,
+	apiAliasingError:      AliasingError,
+	apiAlreadyInitialised: AlreadyInitialisedError,
+	apiNotInitialised:     NotInitialisedError,
+	apiHwVerError:         HwError,
+	apiHwRemoved:          DeviceRemovedError,
+}
+
+// String restituisce la descrizione del codice di errore.
+func (e apiError) String() string {
+	if e < 0 || int(e) >= len(errDesc) {
+		return "Unknown error"
+	}
+
+	return errDesc[e]
+}
+
+// in restituisce l'APIError con codice e restituito dalla funzione fn.
+func (e apiError) in(fn string) error {
+	return &APIError{Func: fn, Code: int(e)}
+}
+
+// Error implementa l'interfaccia error.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %s (%d)", e.Func, apiError(e.Code), e.Code)
+}
+
+// Unwrap restituisce la categoria dell'errore, FailError se il codice non è
+// noto o non ha una categoria propria.
+func (e *APIError) Unwrap() error {
+	if e.Code <= int(apiSuccess) || e.Code >= len(errCategory) || errCategory[e.Code] == nil {
+		return FailError
+	}
+
+	return errCategory[e.Code]
+}
+
+// band restituisce un valore che rappresenta una delle bande, come definite nel
+// tipo mir_sdr_BandT dell'API, in cui ricade la frequenza passata come parametro
+// f.
+func band(f float64) int {
+	switch {
+	case f < 12e6:
+		return bandAMLo
+	case 12e6 <= f && f < 30e6:
+		return bandAMMid
+	case 30e6 <= f && f < 60e6:
+		return bandAMHi
+	case 60e6 <= f && f < 120e6:
+		return bandVHF
+	case 120e6 <= f && f < 250e6:
+		return band3
+	case 250e6 <= f && f < 420e6:
+		return bandX
+	case 420e6 <= f && f < 1000e6:
+		return band45
+	case 1000e6 <= f && f < 2000e6:
+		return bandL
+	default:
+		return -1
+	}
+}
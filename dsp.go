@@ -0,0 +1,82 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"time"
+)
+
+// fullScale è il valore usato per normalizzare i campioni int16 propagati dalla
+// RSP così che un segnale a fondo scala abbia potenza unitaria (0dBFS).
+const fullScale = 32768.0
+
+// floor è la potenza minima considerata, serve ad evitare log10(0) quando un
+// frame è composto da soli zeri.
+const floor = 1e-20
+
+// power restituisce la potenza media, normalizzata al fondo scala, del frame di
+// campioni I e Q.
+func power(I, Q []int16) float64 {
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	var acc float64
+	for k := 0; k < n; k++ {
+		i, q := float64(I[k]), float64(Q[k])
+		acc += i*i + q*q
+	}
+
+	return acc / (float64(n) * fullScale * fullScale)
+}
+
+// dB converte il valore di potenza p in decibel.
+func dB(p float64) float64 {
+	if p < floor {
+		p = floor
+	}
+
+	return 10 * math.Log10(p)
+}
+
+// smoothing restituisce il coefficiente di un filtro passa basso del primo
+// ordine con costante di tempo tau, applicato ad un blocco di n campioni
+// acquisiti con frequenza di campionamento fs espressa in Hz.
+func smoothing(tau time.Duration, n int, fs float64) float64 {
+	if tau <= 0 || fs <= 0 {
+		return 1
+	}
+
+	return 1 - math.Exp(-float64(n)/(fs*tau.Seconds()))
+}
+
+// toComplex converte i campioni I e Q in campioni complessi normalizzati al
+// fondo scala, aggiungendoli ad out.
+func toComplex(I, Q []int16, out []complex128) []complex128 {
+	for k := 0; k < len(I) && k < len(Q); k++ {
+		out = append(out, complex(float64(I[k])/fullScale, float64(Q[k])/fullScale))
+	}
+
+	return out
+}
+
+// toAudio converte i campioni in in campioni audio limitati all'intervallo
+// [-1, 1], aggiungendoli ad out.
+func toAudio(in []float64, out []float32) []float32 {
+	for _, x := range in {
+		out = append(out, float32(math.Max(-1, math.Min(1, x))))
+	}
+
+	return out
+}
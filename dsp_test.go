@@ -0,0 +1,101 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/iclac/sdrplay"
+)
+
+// audioBuffer è un AudioConnector che conserva l'audio propagato.
+type audioBuffer struct {
+	samples []float32
+}
+
+// PropagateAudio implementa l'interfaccia sdrplay.AudioConnector.
+func (a *audioBuffer) PropagateAudio(samples []float32) {
+	a.samples = append(a.samples, samples...)
+}
+
+// toneLevel restituisce l'ampiezza della componente di frequenza f, espressa
+// in Hz, dei campioni x campionati con frequenza fs, stimata con l'algoritmo
+// di Goertzel.
+func toneLevel(x []float32, f, fs float64) float64 {
+	w := 2 * math.Pi * f / fs
+	c := 2 * math.Cos(w)
+
+	var s1, s2 float64
+	for _, v := range x {
+		s1, s2 = float64(v)+c*s1-s2, s1
+	}
+
+	p := s1*s1 + s2*s2 - c*s1*s2
+
+	return 2 * math.Sqrt(math.Max(p, 0)) / float64(len(x))
+}
+
+// TestWBFM verifica che il demodulatore WBFM restituisca, a AudioRate, il tono
+// che modula la portante.
+func TestWBFM(t *testing.T) {
+	const fs = 2e6
+
+	a := &audioBuffer{}
+	g := sdrplay.NewSignalGen(fs, sdrplay.NewWBFM(fs, a), sdrplay.FMTone(0, -10, 50e3, 1000))
+	g.Generate(fs / 2)
+
+	want := sdrplay.AudioRate / 2
+	if n := len(a.samples); math.Abs(float64(n-want)) > float64(want)/50 {
+		t.Fatalf("got %d audio samples, want about %d", n, want)
+	}
+
+	// Si scarta il transitorio iniziale dei filtri.
+	x := a.samples[len(a.samples)/4:]
+
+	tone := toneLevel(x, 1000, sdrplay.AudioRate)
+	if tone < 0.1 {
+		t.Errorf("1 kHz tone level %.3f, want at least 0.1", tone)
+	}
+
+	if other := toneLevel(x, 3000, sdrplay.AudioRate); other > tone/10 {
+		t.Errorf("3 kHz level %.3f not below a tenth of the 1 kHz tone %.3f", other, tone)
+	}
+}
+
+// TestCW verifica che il demodulatore CW converta la portante in un tono di
+// frequenza pari al pitch e attenui quelle fuori dal filtro di canale.
+func TestCW(t *testing.T) {
+	const fs = 2e6
+
+	level := func(offset float64) float64 {
+		a := &audioBuffer{}
+		cw := sdrplay.NewCW(fs, a)
+		cw.Pitch(600)
+
+		g := sdrplay.NewSignalGen(fs, cw, sdrplay.Tone(offset, -20))
+		g.Generate(fs / 2)
+
+		if len(a.samples) == 0 {
+			t.Fatal("no audio")
+		}
+
+		x := a.samples[len(a.samples)/2:]
+
+		return toneLevel(x, 600+offset, sdrplay.AudioRate)
+	}
+
+	in := level(0)
+	if in < 0.01 {
+		t.Fatalf("600 Hz sidetone level %.4f, want at least 0.01", in)
+	}
+
+	if out := level(1500); out > in/100 {
+		t.Errorf("carrier 1500 Hz off the channel: level %.4f, want below %.4f", out, in/100)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "fmt"
+
+type (
+	// DuoMode enumera le modalità con cui può essere aperta la RSPduo.
+	DuoMode int
+
+	// DualConnector è l'interfaccia che deve implementare il connettore
+	// fornito a RSP in modalità DualTuner.
+	DualConnector interface {
+		Connector
+
+		// PropagateDual propaga insieme i frame dei due tuner, allineati
+		// campione per campione: I1 e Q1 provengono dal tuner 1, I2 e Q2 dal
+		// tuner 2 ed hanno tutti la stessa lunghezza.
+		PropagateDual(I1, Q1, I2, Q2 []int16)
+	}
+
+	// SlaveMode descrive una configurazione ammessa in modalità Slave: la
+	// frequenza di campionamento in MHz e la IF, imposte dal master, e la
+	// larghezza di banda massima.
+	SlaveMode struct {
+		FS        float64
+		IF        IFmode
+		Bandwidth B
+	}
+)
+
+const (
+	// SingleTuner usa la RSPduo come un ricevitore con un solo tuner, del
+	// quale l'applicazione ha il pieno controllo.
+	SingleTuner DuoMode = iota
+	// Slave apre la RSPduo mentre un'altra applicazione, ad esempio SDRuno,
+	// la usa come master, ricevendo dal tuner lasciato libero. Frequenza di
+	// campionamento ed IF sono quelle scelte dal master, tra quelle
+	// restituite da SlaveModes.
+	Slave
+	// DualTuner riceve contemporaneamente da entrambi i tuner, sintonizzati
+	// sulla stessa frequenza e campionati dallo stesso clock, per la ricezione
+	// in diversità o la radiogoniometria. Ammette le stesse configurazioni di
+	// Slave ed i campioni vengono propagati con PropagateDual.
+	DualTuner
+)
+
+// Duo imposta la modalità con cui viene aperta la RSPduo. È ammessa solo dalla
+// RSPduo e non può essere modificata con SetUp. In modalità Slave la RSPduo
+// viene usata anche se risulta occupata, perché il master la trattiene, e
+// FS, IF e Bandwidth devono corrispondere ad uno dei SlaveModes; le altre
+// opzioni, come frequenza e gain reduction, restano indipendenti da quelle
+// del master. In modalità DualTuner il Connector fornito a RSP deve essere un
+// DualConnector. La libreria mir_sdr gestisce la RSPduo solo come ricevitore
+// con un tuner: con essa le modalità Slave e DualTuner fanno fallire RSP con un
+// UnsupportedFeature.
+func Duo(mode DuoMode) Option {
+	return Option{
+		apply: func() {
+			rsp.Duo = mode
+		},
+	}
+}
+
+// SlaveModes restituisce le configurazioni ammesse in modalità Slave e
+// DualTuner: il master usa una IF non nulla, 1620 kHz con campionamento a 6 MHz
+// o 2048 kHz con campionamento a 8 MHz, che lo slave deve adottare; in
+// modalità DualTuner valgono gli stessi vincoli per entrambi i tuner.
+func SlaveModes() []SlaveMode {
+	return []SlaveMode{
+		{FS: float64(lowIF[IF1620].fs), IF: IF1620, Bandwidth: lowIF[IF1620].bw},
+		{FS: float64(lowIF[IF2048].fs), IF: IF2048, Bandwidth: lowIF[IF2048].bw},
+	}
+}
+
+// validateDuo verifica che la configurazione f sia ammessa dalla modalità della
+// RSPduo. I vincoli di frequenza di campionamento e larghezza di banda della IF
+// sono verificati da validate.
+func validateDuo(f features) error {
+	switch f.Duo {
+	case SingleTuner:
+	case Slave, DualTuner:
+		if f.IF != IF1620 && f.IF != IF2048 {
+			return &OptionError{Option: "Duo", Conflict: "IF", Reason: fmt.Sprintf("%s mode requires IF 1620 or 2048 kHz, not %d kHz", f.Duo, int(f.IF))}
+		}
+	default:
+		return &OptionError{Option: "Duo", Reason: fmt.Sprintf("unknown RSPduo mode %d", int(f.Duo))}
+	}
+
+	return nil
+}
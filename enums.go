@@ -0,0 +1,446 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// enumName associa il valore di un'enumerazione al nome della relativa
+// costante.
+type enumName struct {
+	value int
+	name  string
+}
+
+// UnknownNameError indica che il nome passato ad una delle funzioni Parse non
+// corrisponde ad alcun valore dell'enumerazione.
+var UnknownNameError = errors.New("Unknown Name Error")
+
+var (
+	bNames = []enumName{
+		{int(BW200), "BW200"},
+		{int(BW300), "BW300"},
+		{int(BW600), "BW600"},
+		{int(BW1536), "BW1536"},
+		{int(BW5000), "BW5000"},
+		{int(BW6000), "BW6000"},
+		{int(BW7000), "BW7000"},
+		{int(BW8000), "BW8000"},
+	}
+
+	ifNames = []enumName{
+		{int(IFzero), "IFzero"},
+		{int(IF450), "IF450"},
+		{int(IF1620), "IF1620"},
+		{int(IF2048), "IF2048"},
+	}
+
+	offsetNames = []enumName{
+		{int(None), "None"},
+		{int(Static), "Static"},
+		{int(Periodic6ms), "Periodic6ms"},
+		{int(Periodic12ms), "Periodic12ms"},
+		{int(Periodic24ms), "Periodic24ms"},
+		{int(OneShot), "OneShot"},
+		{int(Continuous), "Continuous"},
+	}
+
+	loNames = []enumName{
+		{int(LOundefined), "LOundefined"},
+		{int(LOauto), "LOauto"},
+		{int(LO120MHz), "LO120MHz"},
+		{int(LO144MHz), "LO144MHz"},
+		{int(LO168MHz), "LO168MHz"},
+	}
+
+	decimationNames = []enumName{
+		{int(Factor0), "Factor0"},
+		{int(Factor2), "Factor2"},
+		{int(Factor4), "Factor4"},
+		{int(Factor8), "Factor8"},
+		{int(Factor16), "Factor16"},
+		{int(Factor32), "Factor32"},
+		{int(Factor64), "Factor64"},
+	}
+
+	transferNames = []enumName{
+		{int(Isochronous), "Isochronous"},
+		{int(Bulk), "Bulk"},
+	}
+
+	duoNames = []enumName{
+		{int(SingleTuner), "SingleTuner"},
+		{int(Slave), "Slave"},
+		{int(DualTuner), "DualTuner"},
+	}
+
+	agcNames = []enumName{
+		{int(Disable), "Disable"},
+		{int(AGC100Hz), "AGC100Hz"},
+		{int(AGC50Hz), "AGC50Hz"},
+		{int(AGC5Hz), "AGC5Hz"},
+	}
+
+	inputNames = []enumName{
+		{int(InputA), "A"},
+		{int(InputB), "B"},
+		{int(InputC), "C"},
+	}
+)
+
+// enumString restituisce il nome del valore v, oppure typ(v) se v non è un
+// valore dell'enumerazione.
+func enumString(names []enumName, typ string, v int) string {
+	for _, n := range names {
+		if n.value == v {
+			return n.name
+		}
+	}
+
+	return fmt.Sprintf("%s(%d)", typ, v)
+}
+
+// enumParse restituisce il valore il cui nome coincide con s, senza
+// distinguere maiuscole e minuscole.
+func enumParse(names []enumName, typ, s string) (int, error) {
+	for _, n := range names {
+		if strings.EqualFold(n.name, strings.TrimSpace(s)) {
+			return n.value, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q is not a valid %s", UnknownNameError, s, typ)
+}
+
+// enumText restituisce la rappresentazione testuale del valore v: il nome
+// della costante oppure, se v non è un valore dell'enumerazione, il numero.
+func enumText(names []enumName, v int) []byte {
+	for _, n := range names {
+		if n.value == v {
+			return []byte(n.name)
+		}
+	}
+
+	return strconv.AppendInt(nil, int64(v), 10)
+}
+
+// enumUnmarshal imposta v al valore rappresentato da text come in enumText.
+func enumUnmarshal(names []enumName, typ string, text []byte, v *int) error {
+	if n, e := strconv.Atoi(string(text)); e == nil {
+		*v = n
+		return nil
+	}
+
+	n, e := enumParse(names, typ, string(text))
+	if e != nil {
+		return e
+	}
+
+	*v = n
+
+	return nil
+}
+
+// enumJSON imposta v al valore rappresentato dal JSON data: una stringa come
+// in enumUnmarshal oppure, come nei file salvati dalle versioni precedenti,
+// un numero. Il JSON null non modifica v.
+func enumJSON(names []enumName, typ string, data []byte, v *int) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var s string
+	if e := json.Unmarshal(data, &s); e != nil {
+		return json.Unmarshal(data, v)
+	}
+
+	return enumUnmarshal(names, typ, []byte(s), v)
+}
+
+// String implementa l'interfaccia fmt.Stringer restituendo il nome della
+// costante, ad esempio "BW1536".
+func (b B) String() string {
+	return enumString(bNames, "B", int(b))
+}
+
+// ParseB restituisce la larghezza di banda di nome s, ad esempio "BW1536".
+func ParseB(s string) (B, error) {
+	v, e := enumParse(bNames, "B", s)
+
+	return B(v), e
+}
+
+// MarshalText implementa l'interfaccia encoding.TextMarshaler restituendo il
+// nome della costante, come String, o il numero se b non ha nome.
+func (b B) MarshalText() ([]byte, error) {
+	return enumText(bNames, int(b)), nil
+}
+
+// UnmarshalText implementa l'interfaccia encoding.TextUnmarshaler accettando
+// il nome della costante, come ParseB, o il numero.
+func (b *B) UnmarshalText(text []byte) error {
+	return enumUnmarshal(bNames, "B", text, (*int)(b))
+}
+
+// UnmarshalJSON implementa l'interfaccia json.Unmarshaler accettando, oltre
+// alla stringa prodotta da MarshalText, il numero.
+func (b *B) UnmarshalJSON(data []byte) error {
+	return enumJSON(bNames, "B", data, (*int)(b))
+}
+
+// String implementa l'interfaccia fmt.Stringer restituendo il nome della
+// costante, ad esempio "IF450".
+func (m IFmode) String() string {
+	return enumString(ifNames, "IFmode", int(m))
+}
+
+// ParseIFmode restituisce la IF di nome s, ad esempio "IFzero".
+func ParseIFmode(s string) (IFmode, error) {
+	v, e := enumParse(ifNames, "IFmode", s)
+
+	return IFmode(v), e
+}
+
+// MarshalText implementa l'interfaccia encoding.TextMarshaler restituendo il
+// nome della costante, come String, o il numero se m non ha nome.
+func (m IFmode) MarshalText() ([]byte, error) {
+	return enumText(ifNames, int(m)), nil
+}
+
+// UnmarshalText implementa l'interfaccia encoding.TextUnmarshaler accettando
+// il nome della costante, come ParseIFmode, o il numero.
+func (m *IFmode) UnmarshalText(text []byte) error {
+	return enumUnmarshal(ifNames, "IFmode", text, (*int)(m))
+}
+
+// UnmarshalJSON implementa l'interfaccia json.Unmarshaler accettando, oltre
+// alla stringa prodotta da MarshalText, il numero.
+func (m *IFmode) UnmarshalJSON(data []byte) error {
+	return enumJSON(ifNames, "IFmode", data, (*int)(m))
+}
+
+// String implementa l'interfaccia fmt.Stringer restituendo il nome della
+// costante, ad esempio "OneShot".
+func (m OffsetMode) String() string {
+	return enumString(offsetNames, "OffsetMode", int(m))
+}
+
+// ParseOffsetMode restituisce il metodo di correzione dell'offset DC di nome s,
+// ad esempio "Periodic6ms".
+func ParseOffsetMode(s string) (OffsetMode, error) {
+	v, e := enumParse(offsetNames, "OffsetMode", s)
+
+	return OffsetMode(v), e
+}
+
+// MarshalText implementa l'interfaccia encoding.TextMarshaler restituendo il
+// nome della costante, come String, o il numero se m non ha nome.
+func (m OffsetMode) MarshalText() ([]byte, error) {
+	return enumText(offsetNames, int(m)), nil
+}
+
+// UnmarshalText implementa l'interfaccia encoding.TextUnmarshaler accettando
+// il nome della costante, come ParseOffsetMode, o il numero.
+func (m *OffsetMode) UnmarshalText(text []byte) error {
+	return enumUnmarshal(offsetNames, "OffsetMode", text, (*int)(m))
+}
+
+// UnmarshalJSON implementa l'interfaccia json.Unmarshaler accettando, oltre
+// alla stringa prodotta da MarshalText, il numero.
+func (m *OffsetMode) UnmarshalJSON(data []byte) error {
+	return enumJSON(offsetNames, "OffsetMode", data, (*int)(m))
+}
+
+// String implementa l'interfaccia fmt.Stringer restituendo il nome della
+// costante, ad esempio "LOauto".
+func (f LOfrequency) String() string {
+	return enumString(loNames, "LOfrequency", int(f))
+}
+
+// ParseLOfrequency restituisce la frequenza dell'OL di nome s, ad esempio
+// "LO120MHz".
+func ParseLOfrequency(s string) (LOfrequency, error) {
+	v, e := enumParse(loNames, "LOfrequency", s)
+
+	return LOfrequency(v), e
+}
+
+// MarshalText implementa l'interfaccia encoding.TextMarshaler restituendo il
+// nome della costante, come String, o il numero se f non ha nome.
+func (f LOfrequency) MarshalText() ([]byte, error) {
+	return enumText(loNames, int(f)), nil
+}
+
+// UnmarshalText implementa l'interfaccia encoding.TextUnmarshaler accettando
+// il nome della costante, come ParseLOfrequency, o il numero.
+func (f *LOfrequency) UnmarshalText(text []byte) error {
+	return enumUnmarshal(loNames, "LOfrequency", text, (*int)(f))
+}
+
+// UnmarshalJSON implementa l'interfaccia json.Unmarshaler accettando, oltre
+// alla stringa prodotta da MarshalText, il numero.
+func (f *LOfrequency) UnmarshalJSON(data []byte) error {
+	return enumJSON(loNames, "LOfrequency", data, (*int)(f))
+}
+
+// String implementa l'interfaccia fmt.Stringer restituendo il nome della
+// costante, ad esempio "Factor8".
+func (d Decimation) String() string {
+	return enumString(decimationNames, "Decimation", int(d))
+}
+
+// ParseDecimation restituisce il fattore di decimazione di nome s, ad esempio
+// "Factor8".
+func ParseDecimation(s string) (Decimation, error) {
+	v, e := enumParse(decimationNames, "Decimation", s)
+
+	return Decimation(v), e
+}
+
+// MarshalText implementa l'interfaccia encoding.TextMarshaler restituendo il
+// nome della costante, come String, o il numero se d non ha nome.
+func (d Decimation) MarshalText() ([]byte, error) {
+	return enumText(decimationNames, int(d)), nil
+}
+
+// UnmarshalText implementa l'interfaccia encoding.TextUnmarshaler accettando
+// il nome della costante, come ParseDecimation, o il numero.
+func (d *Decimation) UnmarshalText(text []byte) error {
+	return enumUnmarshal(decimationNames, "Decimation", text, (*int)(d))
+}
+
+// UnmarshalJSON implementa l'interfaccia json.Unmarshaler accettando, oltre
+// alla stringa prodotta da MarshalText, il numero.
+func (d *Decimation) UnmarshalJSON(data []byte) error {
+	return enumJSON(decimationNames, "Decimation", data, (*int)(d))
+}
+
+// String implementa l'interfaccia fmt.Stringer restituendo il nome della
+// costante, ad esempio "Bulk".
+func (m TransferMode) String() string {
+	return enumString(transferNames, "TransferMode", int(m))
+}
+
+// ParseTransferMode restituisce la modalità di trasferimento di nome s, ad
+// esempio "Bulk".
+func ParseTransferMode(s string) (TransferMode, error) {
+	v, e := enumParse(transferNames, "TransferMode", s)
+
+	return TransferMode(v), e
+}
+
+// MarshalText implementa l'interfaccia encoding.TextMarshaler restituendo il
+// nome della costante, come String, o il numero se m non ha nome.
+func (m TransferMode) MarshalText() ([]byte, error) {
+	return enumText(transferNames, int(m)), nil
+}
+
+// UnmarshalText implementa l'interfaccia encoding.TextUnmarshaler accettando
+// il nome della costante, come ParseTransferMode, o il numero.
+func (m *TransferMode) UnmarshalText(text []byte) error {
+	return enumUnmarshal(transferNames, "TransferMode", text, (*int)(m))
+}
+
+// UnmarshalJSON implementa l'interfaccia json.Unmarshaler accettando, oltre
+// alla stringa prodotta da MarshalText, il numero.
+func (m *TransferMode) UnmarshalJSON(data []byte) error {
+	return enumJSON(transferNames, "TransferMode", data, (*int)(m))
+}
+
+// String implementa l'interfaccia fmt.Stringer restituendo il nome della
+// costante, ad esempio "Slave".
+func (m DuoMode) String() string {
+	return enumString(duoNames, "DuoMode", int(m))
+}
+
+// ParseDuoMode restituisce la modalità della RSPduo di nome s, ad esempio
+// "Slave".
+func ParseDuoMode(s string) (DuoMode, error) {
+	v, e := enumParse(duoNames, "DuoMode", s)
+
+	return DuoMode(v), e
+}
+
+// MarshalText implementa l'interfaccia encoding.TextMarshaler restituendo il
+// nome della costante, come String, o il numero se m non ha nome.
+func (m DuoMode) MarshalText() ([]byte, error) {
+	return enumText(duoNames, int(m)), nil
+}
+
+// UnmarshalText implementa l'interfaccia encoding.TextUnmarshaler accettando
+// il nome della costante, come ParseDuoMode, o il numero.
+func (m *DuoMode) UnmarshalText(text []byte) error {
+	return enumUnmarshal(duoNames, "DuoMode", text, (*int)(m))
+}
+
+// UnmarshalJSON implementa l'interfaccia json.Unmarshaler accettando, oltre
+// alla stringa prodotta da MarshalText, il numero.
+func (m *DuoMode) UnmarshalJSON(data []byte) error {
+	return enumJSON(duoNames, "DuoMode", data, (*int)(m))
+}
+
+// String implementa l'interfaccia fmt.Stringer restituendo il nome della
+// costante, ad esempio "AGC5Hz".
+func (m AGCmode) String() string {
+	return enumString(agcNames, "AGCmode", int(m))
+}
+
+// ParseAGCmode restituisce il modo dell'AGC di nome s, ad esempio "AGC50Hz".
+func ParseAGCmode(s string) (AGCmode, error) {
+	v, e := enumParse(agcNames, "AGCmode", s)
+
+	return AGCmode(v), e
+}
+
+// MarshalText implementa l'interfaccia encoding.TextMarshaler restituendo il
+// nome della costante, come String, o il numero se m non ha nome.
+func (m AGCmode) MarshalText() ([]byte, error) {
+	return enumText(agcNames, int(m)), nil
+}
+
+// UnmarshalText implementa l'interfaccia encoding.TextUnmarshaler accettando
+// il nome della costante, come ParseAGCmode, o il numero.
+func (m *AGCmode) UnmarshalText(text []byte) error {
+	return enumUnmarshal(agcNames, "AGCmode", text, (*int)(m))
+}
+
+// UnmarshalJSON implementa l'interfaccia json.Unmarshaler accettando, oltre
+// alla stringa prodotta da MarshalText, il numero.
+func (m *AGCmode) UnmarshalJSON(data []byte) error {
+	return enumJSON(agcNames, "AGCmode", data, (*int)(m))
+}
+
+// ParseInput restituisce l'ingresso d'antenna di nome s, ad esempio "B".
+func ParseInput(s string) (Input, error) {
+	v, e := enumParse(inputNames, "Input", s)
+
+	return Input(v), e
+}
+
+// MarshalText implementa l'interfaccia encoding.TextMarshaler restituendo il
+// nome dell'ingresso, come String, o il numero se in non ha nome.
+func (in Input) MarshalText() ([]byte, error) {
+	return enumText(inputNames, int(in)), nil
+}
+
+// UnmarshalText implementa l'interfaccia encoding.TextUnmarshaler accettando
+// il nome dell'ingresso, come ParseInput, o il numero.
+func (in *Input) UnmarshalText(text []byte) error {
+	return enumUnmarshal(inputNames, "Input", text, (*int)(in))
+}
+
+// UnmarshalJSON implementa l'interfaccia json.Unmarshaler accettando, oltre
+// alla stringa prodotta da MarshalText, il numero.
+func (in *Input) UnmarshalJSON(data []byte) error {
+	return enumJSON(inputNames, "Input", data, (*int)(in))
+}
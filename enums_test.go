@@ -0,0 +1,79 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/iclac/sdrplay"
+)
+
+// TestDecimationFactors verifica che il valore delle costanti Decimation sia
+// il fattore di decimazione.
+func TestDecimationFactors(t *testing.T) {
+	factors := map[sdrplay.Decimation]int{
+		sdrplay.Factor0:  0,
+		sdrplay.Factor2:  2,
+		sdrplay.Factor4:  4,
+		sdrplay.Factor8:  8,
+		sdrplay.Factor16: 16,
+		sdrplay.Factor32: 32,
+		sdrplay.Factor64: 64,
+	}
+
+	for d, want := range factors {
+		if int(d) != want {
+			t.Errorf("%v = %d, want %d", d, int(d), want)
+		}
+	}
+}
+
+// TestConfigJSONEnums verifica che Config riporti in JSON le enumerazioni con
+// il nome della costante e accetti i numeri della versione 1 del formato.
+func TestConfigJSONEnums(t *testing.T) {
+	c := sdrplay.DefaultConfig()
+	c.Bandwidth, c.IF, c.AGC = sdrplay.BW600, sdrplay.IF450, sdrplay.AGC50Hz
+	c.Decimate, c.Factor, c.DCmode = true, sdrplay.Factor8, sdrplay.OneShot
+	c.Transfer, c.Input = sdrplay.Bulk, sdrplay.InputB
+
+	b, e := json.Marshal(c)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for _, name := range []string{`"BW600"`, `"IF450"`, `"AGC50Hz"`, `"Factor8"`, `"OneShot"`, `"LOauto"`, `"Bulk"`, `"input":"B"`} {
+		if !strings.Contains(string(b), name) {
+			t.Errorf("%s missing from %s", name, b)
+		}
+	}
+
+	var got sdrplay.Config
+	if e := json.Unmarshal(b, &got); e != nil {
+		t.Fatal(e)
+	}
+
+	if got != c {
+		t.Errorf("round trip: got %+v, want %+v", got, c)
+	}
+
+	v1 := `{"version":1,"fs":2,"rf":100,"bandwidth":600,"if":450,"lo_mode":1,"dc_mode":5,"agc":2,"decimate":true,"factor":16}`
+	if e := json.Unmarshal([]byte(v1), &got); e != nil {
+		t.Fatalf("version 1: %v", e)
+	}
+
+	if got.Bandwidth != sdrplay.BW600 || got.IF != sdrplay.IF450 || got.LOmode != sdrplay.LOauto || got.DCmode != sdrplay.OneShot || got.AGC != sdrplay.AGC50Hz || got.Factor != sdrplay.Factor8 {
+		t.Errorf("version 1: got %v %v %v %v %v %v", got.Bandwidth, got.IF, got.LOmode, got.DCmode, got.AGC, got.Factor)
+	}
+
+	var bw sdrplay.B
+	if e := bw.UnmarshalText([]byte("BW7000x")); e == nil {
+		t.Error("UnmarshalText accepted an unknown name")
+	}
+}
@@ -0,0 +1,209 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+	"sync"
+)
+
+type (
+	// ResponsePoint è un punto di una curva di risposta in frequenza.
+	ResponsePoint struct {
+		// Offset è la distanza dalla frequenza centrale espressa in Hz e Gain
+		// il guadagno, negativo se il segnale è attenuato, espresso in dB.
+		Offset float64 `json:"offset"`
+		Gain   float64 `json:"gain"`
+	}
+
+	// Equalizer corregge gli spettri per l'attenuazione del filtro IF della
+	// RSP ai bordi della banda, così che le misure di potenza vicino ai bordi
+	// siano accurate. Contiene una curva di risposta per ogni larghezza di
+	// banda, tra le quali viene usata quella selezionata con Bandwidth. Le
+	// curve predefinite approssimano il filtro con un Butterworth di ordine
+	// equalizerOrder; curve misurate sulla propria RSP possono essere impostate
+	// con SetCurve o caricate con LoadEqualizer.
+	Equalizer struct {
+		mu sync.Mutex
+
+		curves map[B][]ResponsePoint
+		bw     B
+
+		// limit è la correzione massima applicata, in dB, così che il rumore
+		// oltre i bordi del filtro non venga amplificato indefinitamente.
+		limit float64
+	}
+)
+
+// equalizerOrder è l'ordine del filtro con cui sono calcolate le curve
+// predefinite.
+const equalizerOrder = 6
+
+// bandwidths sono le larghezze di banda per le quali sono definite le curve
+// predefinite.
+var bandwidths = []B{BW200, BW300, BW600, BW1536, BW5000, BW6000, BW7000, BW8000}
+
+// NewEqualizer restituisce un Equalizer con le curve predefinite che corregge
+// gli spettri acquisiti con larghezza di banda bw. Di default la correzione
+// è limitata a 20dB.
+func NewEqualizer(bw B) *Equalizer {
+	eq := &Equalizer{curves: map[B][]ResponsePoint{}, bw: bw, limit: 20}
+
+	for _, b := range bandwidths {
+		eq.curves[b] = rollOff(b)
+	}
+
+	return eq
+}
+
+// LoadEqualizer restituisce un Equalizer che corregge gli spettri acquisiti con
+// larghezza di banda bw, con le curve predefinite sostituite da quelle salvate
+// con Save nel file path.
+func LoadEqualizer(path string, bw B) (*Equalizer, error) {
+	b, e := os.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+
+	var curves map[B][]ResponsePoint
+	if e := json.Unmarshal(b, &curves); e != nil {
+		return nil, e
+	}
+
+	eq := NewEqualizer(bw)
+	for bw, points := range curves {
+		eq.SetCurve(bw, points...)
+	}
+
+	return eq, nil
+}
+
+// Save salva le curve nel file path in formato JSON.
+func (eq *Equalizer) Save(path string) error {
+	eq.mu.Lock()
+	b, e := json.MarshalIndent(eq.curves, "", "  ")
+	eq.mu.Unlock()
+
+	if e != nil {
+		return e
+	}
+
+	return os.WriteFile(path, b, 0644)
+}
+
+// SetCurve imposta la curva di risposta per la larghezza di banda bw. Se tutti
+// i punti hanno Offset non negativo la curva è considerata simmetrica rispetto
+// alla frequenza centrale.
+func (eq *Equalizer) SetCurve(bw B, points ...ResponsePoint) {
+	curve := append([]ResponsePoint(nil), points...)
+	sort.Slice(curve, func(i, j int) bool { return curve[i].Offset < curve[j].Offset })
+
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+
+	eq.curves[bw] = curve
+}
+
+// Bandwidth seleziona la curva corrispondente alla larghezza di banda bw.
+func (eq *Equalizer) Bandwidth(bw B) {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+
+	eq.bw = bw
+}
+
+// Limit imposta la correzione massima applicata, espressa in dB.
+func (eq *Equalizer) Limit(dB float64) {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+
+	eq.limit = math.Max(0, dB)
+}
+
+// Response restituisce il guadagno in dB, interpolato linearmente, alla
+// distanza offset dalla frequenza centrale espressa in Hz. Restituisce 0 se
+// per la larghezza di banda selezionata non è definita alcuna curva.
+func (eq *Equalizer) Response(offset float64) float64 {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+
+	return response(eq.curves[eq.bw], offset)
+}
+
+// Equalize restituisce lo spettro f corretto per la risposta del filtro.
+func (eq *Equalizer) Equalize(f SpectrumFrame) SpectrumFrame {
+	power := append([]float64(nil), f.Power...)
+	eq.correct(power, f.SampleRate/float64(len(power)))
+	f.Power = power
+
+	return f
+}
+
+// correct corregge in place lo spettro power, in dB, i cui bin distano step Hz
+// ed il cui bin centrale corrisponde alla frequenza centrale.
+func (eq *Equalizer) correct(power []float64, step float64) {
+	eq.mu.Lock()
+	curve, limit := eq.curves[eq.bw], eq.limit
+	eq.mu.Unlock()
+
+	if len(curve) == 0 {
+		return
+	}
+
+	n := len(power)
+	for k := range power {
+		power[k] += math.Min(limit, -response(curve, float64(k-n/2)*step))
+	}
+}
+
+// response restituisce il guadagno della curva alla distanza offset dalla
+// frequenza centrale, interpolandone linearmente i punti.
+func response(curve []ResponsePoint, offset float64) float64 {
+	n := len(curve)
+	if n == 0 {
+		return 0
+	}
+
+	if curve[0].Offset >= 0 {
+		offset = math.Abs(offset)
+	}
+
+	k := sort.Search(n, func(i int) bool { return curve[i].Offset >= offset })
+	switch {
+	case k == 0:
+		return curve[0].Gain
+	case k == n:
+		return curve[n-1].Gain
+	}
+
+	a, b := curve[k-1], curve[k]
+	if b.Offset == a.Offset {
+		return b.Gain
+	}
+
+	return a.Gain + (b.Gain-a.Gain)*(offset-a.Offset)/(b.Offset-a.Offset)
+}
+
+// rollOff restituisce la curva predefinita per la larghezza di banda bw: la
+// risposta di un filtro Butterworth di ordine equalizerOrder con banda
+// passante bw, calcolata fino ad una volta e mezza la banda.
+func rollOff(bw B) []ResponsePoint {
+	half := float64(bw) * 1e3 / 2
+
+	curve := make([]ResponsePoint, 0, 61)
+	for k := 0; k <= 60; k++ {
+		f := half * 1.5 * float64(k) / 60
+		g := -10 * math.Log10(1+math.Pow(f/half, 2*equalizerOrder))
+		curve = append(curve, ResponsePoint{Offset: f, Gain: g})
+	}
+
+	return curve
+}
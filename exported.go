@@ -1,3 +1,5 @@
+//go:build !nosdr && !mock
+
 /*
    sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
    Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
@@ -8,33 +10,23 @@
 package sdrplay
 
 import "C"
-import (
-	"log"
-	"unsafe"
-)
+import "unsafe"
 
 // StreamCallback è la funzione che viene invocata dall'API SDRplay quando ci sono
-// campioni da processare.
+// campioni da processare. Se hwRemoved è non nullo la RSP è stata disconnessa e
+// non ci sono campioni.
 
 //export StreamCallback
-func StreamCallback(xi *C.short, xq *C.short, firstSampleNum C.uint, grChanged C.int, rfChanged C.int, fsChanged C.int, numSample C.uint, reset C.uint, cbContext unsafe.Pointer) {
-	if grChanged == 1 || fsChanged == 1 || reset == 1 || rx.baseband == nil {
+func StreamCallback(xi *C.short, xq *C.short, firstSampleNum C.uint, grChanged C.int, rfChanged C.int, fsChanged C.int, numSample C.uint, reset C.uint, hwRemoved C.uint, cbContext unsafe.Pointer) {
+	if hwRemoved != 0 {
+		rx.hwRemoved()
 		return
 	}
 
-	//fs := int(firstSampleNum)
-	//log.Println("fs:", fs)
-
 	is := (*[1 << 30]int16)(unsafe.Pointer(xi))[:numSample:numSample]
-	i := make([]int16, len(is))
-	copy(i, is)
-
 	qs := (*[1 << 30]int16)(unsafe.Pointer(xq))[:numSample:numSample]
-	q := make([]int16, len(qs))
-	copy(q, qs)
 
-	rx.baseband.Propagate(i, q)
-	//rx.baseband.Propagate(i[fs:], q[fs:])
+	rx.stream(is, qs, uint32(firstSampleNum), grChanged == 1 || fsChanged == 1 || reset == 1)
 }
 
 // AGCCallback è la funzione che viene invocata dall'API SDRplay quando ci sono
@@ -42,6 +34,5 @@ func StreamCallback(xi *C.short, xq *C.short, firstSampleNum C.uint, grChanged C
 
 //export AGCCallback
 func AGCCallback(grdB C.uint, lnagrdB C.uint, cbContext unsafe.Pointer) {
-	log.Printf("AGC callback [grdB: %d] [lnagrdB: %d]\n", int(grdB), int(lnagrdB))
-
+	rx.gainChanged(int(grdB), int(lnagrdB))
 }
@@ -0,0 +1,82 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// fft calcola in place la trasformata discreta di Fourier di x con l'algoritmo
+// radix-2 di Cooley-Tukey. La lunghezza di x deve essere una potenza di 2.
+func fft(x []complex128) {
+	n := len(x)
+	if n < 2 {
+		return
+	}
+
+	// Permutazione bit-reversal.
+	shift := uint(bits.UintSize - bits.TrailingZeros(uint(n)))
+	for k := 0; k < n; k++ {
+		j := int(bits.Reverse(uint(k)) >> shift)
+		if j > k {
+			x[k], x[j] = x[j], x[k]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			t := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*t
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				t *= w
+			}
+		}
+	}
+}
+
+// hann restituisce i coefficienti della finestra di Hann di lunghezza n,
+// normalizzati così che la loro somma dei quadrati valga n.
+func hann(n int) []float64 {
+	w := make([]float64, n)
+
+	var e float64
+	for k := range w {
+		w[k] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(k)/float64(n))
+		e += w[k] * w[k]
+	}
+
+	g := math.Sqrt(float64(n) / e)
+	for k := range w {
+		w[k] *= g
+	}
+
+	return w
+}
+
+// pow2 restituisce la più piccola potenza di 2 maggiore o uguale a n.
+func pow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+
+	return p
+}
+
+// bin restituisce l'indice, nello spettro di n punti calcolato da fft su un
+// segnale campionato a fs Hz, della frequenza f espressa in Hz rispetto al
+// centro di banda. Frequenze negative occupano la seconda metà dello spettro.
+func bin(f, fs float64, n int) int {
+	k := int(math.Floor(f*float64(n)/fs + 0.5))
+
+	return ((k % n) + n) % n
+}
@@ -0,0 +1,384 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileFormat enumera i formati dei file letti da FileReceiver.
+type FileFormat int
+
+const (
+	// CS16 indica campioni I e Q interlacciati, interi a 16 bit con segno in
+	// little endian, come quelli scritti da Recorder.
+	CS16 FileFormat = iota
+	// CF32 indica campioni I e Q interlacciati, float a 32 bit in little
+	// endian normalizzati al fondo scala, come quelli di GNU Radio.
+	CF32
+	// WAV indica un file WAV a due canali, a 16 bit o float a 32 bit, come
+	// quelli scritti da NewWAVRecorder, SDR# ed HDSDR.
+	WAV
+)
+
+// UnsupportedFileError indica che il file fornito a FileReceiver non è in un
+// formato supportato.
+var UnsupportedFileError = errors.New("Unsupported File Error")
+
+// FileReceiver è un Receiver che, al posto della RSP, propaga al Connector
+// fornito il segnale registrato in un file, in tempo reale oppure accelerato.
+// Tune, Gain e SetUp non hanno effetto sul segnale.
+type FileReceiver struct {
+	virtual
+
+	mu sync.Mutex
+
+	f      *os.File
+	format FileFormat
+
+	// fs è la frequenza di campionamento del segnale, frequency la frequenza
+	// centrale indicata nel file, se presente, entrambe in Hz.
+	fs, frequency float64
+
+	// data è la posizione del primo campione nel file, size il numero di byte
+	// di campioni, negativo se si estendono fino alla fine del file.
+	data, size int64
+
+	// speed è il fattore di accelerazione, nullo per la massima velocità
+	// possibile, loop indica se ricominciare dall'inizio alla fine del file.
+	speed float64
+	loop  bool
+
+	err  error
+	stop chan struct{}
+	done chan struct{}
+
+	once     sync.Once
+	closeErr error
+
+	baseband Connector
+}
+
+const (
+	// fileFrame è il numero di campioni propagati ad ogni frame da
+	// FileReceiver.
+	fileFrame = 16384
+
+	// fileMaxChunk è la dimensione massima dei chunk letti dall'intestazione
+	// dei file WAV; gli altri chunk vengono saltati senza leggerli.
+	fileMaxChunk = 4096
+)
+
+// NewFileReceiver apre il file name e ne propaga il contenuto a baseband in
+// tempo reale, fino alla fine del file o all'invocazione di Close. Il formato è
+// dedotto dall'estensione: .wav per WAV, .cf32, .fc32 e .cfile per CF32, CS16
+// per tutte le altre. Il parametro fs è la frequenza di campionamento in Hz;
+// per i file WAV viene ignorato e letto dall'intestazione.
+// Il baseband connector deve essere non nil altrimenti viene restituito l'errore
+// UnpluggedConnectorError.
+func NewFileReceiver(name string, fs float64, baseband Connector) (*FileReceiver, error) {
+	if baseband == nil {
+		return nil, UnpluggedConnectorError
+	}
+
+	f, e := os.Open(name)
+	if e != nil {
+		return nil, e
+	}
+
+	r := &FileReceiver{
+		f:        f,
+		fs:       fs,
+		size:     -1,
+		speed:    1,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		baseband: baseband,
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".wav":
+		e = r.parseWAV()
+	case ".cf32", ".fc32", ".cfile":
+		r.format = CF32
+	default:
+		r.format = CS16
+	}
+
+	if e == nil && r.fs <= 0 {
+		e = UnsupportedFileError
+	}
+
+	if e != nil {
+		f.Close()
+		return nil, e
+	}
+
+	go r.run()
+
+	return r, nil
+}
+
+// Speed imposta il fattore di accelerazione della riproduzione: 1 corrisponde
+// al tempo reale, 0 alla massima velocità possibile.
+func (r *FileReceiver) Speed(x float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.speed = math.Max(0, x)
+}
+
+// Loop indica se la riproduzione deve ricominciare dall'inizio una volta
+// raggiunta la fine del file.
+func (r *FileReceiver) Loop(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.loop = enabled
+}
+
+// SampleRate restituisce la frequenza di campionamento del segnale in Hz.
+func (r *FileReceiver) SampleRate() float64 {
+	return r.fs
+}
+
+// Frequency restituisce la frequenza centrale, espressa in Hz, indicata
+// nell'intestazione dei file WAV o, se assente, l'ultima impostata con Tune.
+func (r *FileReceiver) Frequency() float64 {
+	if f := r.tuned(); f != 0 {
+		return f
+	}
+
+	return r.frequency
+}
+
+// Done restituisce un canale che viene chiuso al termine della riproduzione.
+func (r *FileReceiver) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err restituisce l'eventuale errore di lettura che ha interrotto la
+// riproduzione.
+func (r *FileReceiver) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Close interrompe la riproduzione e chiude il file. Le invocazioni successive
+// alla prima non hanno effetto e ne restituiscono lo stesso errore.
+func (r *FileReceiver) Close() error {
+	r.once.Do(func() {
+		close(r.stop)
+		<-r.done
+		r.closeErr = r.f.Close()
+	})
+
+	return r.closeErr
+}
+
+// run legge il file e ne propaga i campioni rispettando la velocità impostata.
+func (r *FileReceiver) run() {
+	defer close(r.done)
+
+	size := 4
+	if r.format == CF32 {
+		size = 8
+	}
+
+	rd := bufio.NewReaderSize(r.samples(), fileFrame*size)
+	buf := make([]byte, fileFrame*size)
+	I, Q := make([]int16, fileFrame), make([]int16, fileFrame)
+
+	// pass è il numero di campioni propagati dall'inizio della riproduzione
+	// corrente del file.
+	start, sent, pass := time.Now(), 0.0, 0
+	for {
+		n, e := io.ReadFull(rd, buf)
+		n /= size
+
+		for k := 0; k < n; k++ {
+			b := buf[k*size:]
+			if r.format == CF32 {
+				I[k] = toInt16(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+				Q[k] = toInt16(math.Float32frombits(binary.LittleEndian.Uint32(b[4:])))
+			} else {
+				I[k] = int16(binary.LittleEndian.Uint16(b))
+				Q[k] = int16(binary.LittleEndian.Uint16(b[2:]))
+			}
+		}
+
+		if n > 0 {
+			r.baseband.Propagate(I[:n], Q[:n])
+		}
+		pass += n
+
+		r.mu.Lock()
+		speed, loop := r.speed, r.loop
+		r.mu.Unlock()
+
+		switch {
+		case e == io.EOF || e == io.ErrUnexpectedEOF:
+			// Un file senza campioni viene riprodotto una sola volta anche
+			// in loop, per non ripetere all'infinito letture vuote.
+			if !loop || pass == 0 {
+				return
+			}
+
+			if _, e := r.f.Seek(r.data, io.SeekStart); e != nil {
+				r.fail(e)
+				return
+			}
+			rd.Reset(r.samples())
+			pass = 0
+		case e != nil:
+			r.fail(e)
+			return
+		}
+
+		// Si attende l'istante in cui, alla velocità impostata, i campioni
+		// propagati sarebbero stati ricevuti.
+		sent += float64(n)
+		var wait time.Duration
+		if speed > 0 {
+			wait = time.Until(start.Add(time.Duration(sent / (r.fs * speed) * float64(time.Second))))
+		}
+
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// samples restituisce il Reader dei campioni del file a partire dalla posizione
+// corrente, limitato alla fine del chunk data per i file WAV così da non
+// riprodurre gli eventuali chunk successivi.
+func (r *FileReceiver) samples() io.Reader {
+	if r.size < 0 {
+		return r.f
+	}
+
+	return io.LimitReader(r.f, r.size)
+}
+
+// fail memorizza l'errore e che ha interrotto la riproduzione.
+func (r *FileReceiver) fail(e error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.err = e
+}
+
+// toInt16 converte un campione normalizzato al fondo scala in un intero a 16
+// bit, limitandolo all'intervallo rappresentabile.
+func toInt16(x float32) int16 {
+	return int16(math.Max(-fullScale, math.Min(fullScale-1, float64(x)*fullScale)))
+}
+
+// parseWAV legge l'intestazione del file WAV, determinandone formato,
+// frequenza di campionamento, frequenza centrale, posizione e dimensione dei
+// campioni. Le dimensioni dei chunk non sono affidabili, quindi vengono letti
+// solo i chunk noti di dimensione limitata.
+func (r *FileReceiver) parseWAV() error {
+	le := binary.LittleEndian
+
+	var riff [12]byte
+	if _, e := io.ReadFull(r.f, riff[:]); e != nil {
+		return UnsupportedFileError
+	}
+
+	if id := string(riff[:4]); (id != "RIFF" && id != "RF64") || string(riff[8:]) != "WAVE" {
+		return UnsupportedFileError
+	}
+
+	// ds64 è la dimensione del chunk data indicata dal chunk ds64 dei file
+	// RF64, negativa se assente.
+	ds64 := int64(-1)
+
+	for {
+		var h [8]byte
+		if _, e := io.ReadFull(r.f, h[:]); e != nil {
+			return UnsupportedFileError
+		}
+
+		id, size := string(h[:4]), int64(le.Uint32(h[4:]))
+		if id == "data" {
+			if r.format != WAV && r.format != CF32 {
+				return UnsupportedFileError
+			}
+
+			pos, e := r.f.Seek(0, io.SeekCurrent)
+			if e != nil {
+				return e
+			}
+
+			r.data = pos
+			switch {
+			case size != wavUnknown:
+				r.size = size
+			case ds64 >= 0:
+				r.size = ds64
+			}
+
+			return nil
+		}
+
+		// I chunk di dimensione dispari sono seguiti da un byte di padding.
+		skip := size + size%2
+
+		var body []byte
+		switch id {
+		case "fmt ", "auxi", "ds64":
+			if size > fileMaxChunk {
+				return UnsupportedFileError
+			}
+
+			body = make([]byte, size)
+			if _, e := io.ReadFull(r.f, body); e != nil {
+				return UnsupportedFileError
+			}
+			skip -= size
+		}
+
+		if _, e := r.f.Seek(skip, io.SeekCurrent); e != nil {
+			return e
+		}
+
+		switch {
+		case id == "fmt " && size >= 16:
+			tag, channels, bits := le.Uint16(body), le.Uint16(body[2:]), le.Uint16(body[14:])
+			switch {
+			case channels != 2:
+				return UnsupportedFileError
+			case tag == 1 && bits == 16:
+				r.format = WAV
+			case tag == 3 && bits == 32:
+				r.format = CF32
+			default:
+				return UnsupportedFileError
+			}
+			r.fs = float64(le.Uint32(body[4:]))
+		case id == "auxi" && size >= 36:
+			r.frequency = float64(le.Uint32(body[32:]))
+		case id == "ds64" && size >= 16:
+			ds64 = int64(le.Uint64(body[8:]) & math.MaxInt64)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iclac/sdrplay"
+	"github.com/iclac/sdrplay/sdrplaytest"
+)
+
+// riffChunk è un chunk di un file WAV di test, con la dimensione dichiarata
+// nell'intestazione che può differire da quella del contenuto.
+type riffChunk struct {
+	id   string
+	size uint32
+	body []byte
+}
+
+// chunk restituisce il chunk id con la dimensione del contenuto body.
+func chunk(id string, body []byte) riffChunk {
+	return riffChunk{id: id, size: uint32(len(body)), body: body}
+}
+
+// wavBytes restituisce il file WAV, o RF64 se riff vale "RF64", composto dai
+// chunk indicati.
+func wavBytes(riff string, chunks ...riffChunk) []byte {
+	b := append([]byte(riff), 0xFF, 0xFF, 0xFF, 0xFF)
+	b = append(b, "WAVE"...)
+	for _, c := range chunks {
+		b = append(b, c.id...)
+		b = binary.LittleEndian.AppendUint32(b, c.size)
+		b = append(b, c.body...)
+	}
+
+	return b
+}
+
+// wavFmt restituisce il contenuto del chunk fmt con i parametri indicati.
+func wavFmt(tag, channels uint16, fs uint32, bits uint16) []byte {
+	le := binary.LittleEndian
+
+	b := le.AppendUint16(nil, tag)
+	b = le.AppendUint16(b, channels)
+	b = le.AppendUint32(b, fs)
+	b = le.AppendUint32(b, fs*uint32(channels*bits/8))
+	b = le.AppendUint16(b, channels*bits/8)
+	return le.AppendUint16(b, bits)
+}
+
+// TestFileReceiverWAV verifica che FileReceiver rifiuti le intestazioni WAV
+// malformate senza allocare la memoria indicata dalle dimensioni dei chunk, e
+// riproduca solo i campioni del chunk data.
+func TestFileReceiverWAV(t *testing.T) {
+	pcm := chunk("fmt ", wavFmt(1, 2, 1e6, 16))
+	samples := make([]byte, 4*100)
+	trailer := chunk("LIST", make([]byte, 40))
+
+	auxi := make([]byte, 68)
+	binary.LittleEndian.PutUint32(auxi[32:], 100e6)
+
+	ds64 := make([]byte, 28)
+	binary.LittleEndian.PutUint64(ds64[8:], 4*10)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		err       error
+		samples   int
+		frequency float64
+	}{
+		{"valid", wavBytes("RIFF", pcm, chunk("auxi", auxi), chunk("data", samples), trailer), nil, 100, 100e6},
+		{"float", wavBytes("RIFF", chunk("fmt ", wavFmt(3, 2, 1e6, 32)), chunk("data", samples)), nil, 50, 0},
+		{"odd chunk", wavBytes("RIFF", chunk("JUNK", []byte{1, 2, 3, 0}), pcm, chunk("data", samples)), nil, 100, 0},
+		{"unknown data size", wavBytes("RIFF", pcm, riffChunk{"data", 0xFFFFFFFF, samples}), nil, 100, 0},
+		{"RF64", wavBytes("RF64", chunk("ds64", ds64), pcm, riffChunk{"data", 0xFFFFFFFF, samples}, trailer), nil, 10, 0},
+		{"empty data", wavBytes("RIFF", pcm, chunk("data", nil), trailer), nil, 0, 0},
+		{"not WAV", []byte("RIFF\x04\x00\x00\x00AVI "), sdrplay.UnsupportedFileError, 0, 0},
+		{"short", []byte("RIFF"), sdrplay.UnsupportedFileError, 0, 0},
+		{"no data", wavBytes("RIFF", pcm), sdrplay.UnsupportedFileError, 0, 0},
+		{"data before fmt", wavBytes("RIFF", chunk("data", samples), pcm), sdrplay.UnsupportedFileError, 0, 0},
+		{"mono", wavBytes("RIFF", chunk("fmt ", wavFmt(1, 1, 1e6, 16)), chunk("data", samples)), sdrplay.UnsupportedFileError, 0, 0},
+		{"8 bit", wavBytes("RIFF", chunk("fmt ", wavFmt(1, 2, 1e6, 8)), chunk("data", samples)), sdrplay.UnsupportedFileError, 0, 0},
+		{"zero rate", wavBytes("RIFF", chunk("fmt ", wavFmt(1, 2, 0, 16)), chunk("data", samples)), sdrplay.UnsupportedFileError, 0, 0},
+		{"truncated fmt", wavBytes("RIFF", riffChunk{"fmt ", 16, []byte{1, 0, 2, 0}}), sdrplay.UnsupportedFileError, 0, 0},
+		{"huge fmt", wavBytes("RIFF", riffChunk{"fmt ", 0xFFFFFFF0, pcm.body}, chunk("data", samples)), sdrplay.UnsupportedFileError, 0, 0},
+		{"huge auxi", wavBytes("RIFF", pcm, riffChunk{"auxi", 0xFFFFFFF0, auxi}, chunk("data", samples)), sdrplay.UnsupportedFileError, 0, 0},
+		{"huge chunk", wavBytes("RIFF", riffChunk{"LIST", 0xFFFFFFF0, nil}, pcm, chunk("data", samples)), sdrplay.UnsupportedFileError, 0, 0},
+	}
+
+	dir := t.TempDir()
+
+	for _, test := range tests {
+		name := filepath.Join(dir, "test.wav")
+		if e := os.WriteFile(name, test.data, 0644); e != nil {
+			t.Fatal(e)
+		}
+
+		b := sdrplaytest.NewBuffer(0)
+		r, e := sdrplay.NewFileReceiver(name, 0, b)
+		if !errors.Is(e, test.err) {
+			t.Errorf("%s: got error %v, want %v", test.name, e, test.err)
+		}
+		if e != nil {
+			continue
+		}
+
+		r.Speed(0)
+
+		select {
+		case <-r.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: playback not done", test.name)
+		}
+
+		if n := b.Len(); n != test.samples {
+			t.Errorf("%s: got %d samples, want %d", test.name, n, test.samples)
+		}
+		if f := r.Frequency(); f != test.frequency {
+			t.Errorf("%s: got frequency %g, want %g", test.name, f, test.frequency)
+		}
+		if e := r.Err(); e != nil {
+			t.Errorf("%s: %v", test.name, e)
+		}
+
+		if e := r.Close(); e != nil {
+			t.Errorf("%s: Close: %v", test.name, e)
+		}
+	}
+}
+
+// TestFileReceiverLoop verifica che la riproduzione in loop di un file vuoto
+// termini e che Close possa essere invocato più volte.
+func TestFileReceiverLoop(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "empty.cs16")
+	if e := os.WriteFile(name, nil, 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	r, e := sdrplay.NewFileReceiver(name, 1e6, sdrplaytest.NewBuffer(0))
+	if e != nil {
+		t.Fatal(e)
+	}
+	r.Loop(true)
+
+	select {
+	case <-r.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("looping playback of an empty file not done")
+	}
+
+	for k := 0; k < 2; k++ {
+		if e := r.Close(); e != nil {
+			t.Errorf("Close %d: %v", k+1, e)
+		}
+	}
+}
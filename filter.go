@@ -0,0 +1,328 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "math"
+
+type (
+	// decimator è un filtro FIR passa basso per segnali complessi che riduce la
+	// frequenza di campionamento di un fattore intero.
+	decimator struct {
+		taps   []float64
+		factor int
+
+		// hist contiene due volte gli ultimi len(taps) campioni in ingresso,
+		// così che hist[pos:pos+len(taps)] li contenga sempre in ordine dal più
+		// recente al meno recente.
+		hist []complex128
+		pos  int
+
+		// phase conta i campioni ricevuti dall'ultimo campione prodotto.
+		phase int
+	}
+
+	// resampler è un filtro polifase per segnali reali che cambia la frequenza
+	// di campionamento del rapporto razionale up/down.
+	resampler struct {
+		up, down int
+		taps     []float64
+
+		// hist contiene due volte gli ultimi campioni in ingresso, così che
+		// hist[pos:pos+len(hist)/2] li contenga sempre in ordine dal più
+		// recente al meno recente.
+		hist []float64
+		pos  int
+
+		// phase è la posizione, nel dominio sovracampionato, del prossimo
+		// campione da produrre rispetto all'ultimo campione ricevuto.
+		phase int
+	}
+
+	// arbitrary è un filtro polifase per segnali complessi che cambia la
+	// frequenza di campionamento di un rapporto qualsiasi, anche irrazionale,
+	// interpolando linearmente tra le uscite dei due rami adiacenti del banco
+	// di arbitraryPhases filtri.
+	arbitrary struct {
+		step float64
+		taps []float64
+
+		// hist contiene due volte gli ultimi campioni in ingresso, così che
+		// hist[pos:pos+len(hist)/2] li contenga sempre in ordine dal più
+		// recente al meno recente.
+		hist []complex128
+		pos  int
+
+		// phase è la posizione, nel dominio sovracampionato, del prossimo
+		// campione da produrre rispetto all'ultimo campione ricevuto.
+		phase float64
+	}
+)
+
+// arbitraryPhases è il numero di rami del banco di filtri di un arbitrary: con
+// l'interpolazione lineare tra i rami l'errore di ricostruzione resta sotto i
+// -70dB.
+const arbitraryPhases = 128
+
+// lowpass restituisce i coefficienti di un filtro FIR passa basso di n punti
+// progettato con il metodo delle finestre (Blackman). La frequenza di taglio
+// cutoff è normalizzata alla frequenza di campionamento (0 < cutoff < 0.5). Il
+// guadagno in continua del filtro è unitario.
+func lowpass(cutoff float64, n int) []float64 {
+	h := make([]float64, n)
+	m := float64(n - 1)
+
+	var sum float64
+	for k := range h {
+		x := float64(k) - m/2
+		if x == 0 {
+			h[k] = 2 * cutoff
+		} else {
+			h[k] = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+
+		if n > 1 {
+			h[k] *= 0.42 - 0.5*math.Cos(2*math.Pi*float64(k)/m) + 0.08*math.Cos(4*math.Pi*float64(k)/m)
+		}
+
+		sum += h[k]
+	}
+
+	for k := range h {
+		h[k] /= sum
+	}
+
+	return h
+}
+
+// newDecimator restituisce un decimator di fattore factor il cui filtro
+// anti-aliasing ha frequenza di taglio cutoff, normalizzata alla frequenza di
+// campionamento in ingresso.
+func newDecimator(factor int, cutoff float64) *decimator {
+	n := 16*factor + 1
+	if factor == 1 {
+		n = 31
+	}
+
+	return &decimator{
+		taps:   lowpass(cutoff, n),
+		factor: factor,
+		hist:   make([]complex128, 2*n),
+	}
+}
+
+// newFilter restituisce un filtro passa basso di n punti, con frequenza di
+// taglio cutoff normalizzata alla frequenza di campionamento, che non esegue
+// alcuna decimazione.
+func newFilter(cutoff float64, n int) *decimator {
+	return &decimator{
+		taps:   lowpass(cutoff, n),
+		factor: 1,
+		hist:   make([]complex128, 2*n),
+	}
+}
+
+// process filtra e decima i campioni in, aggiungendo quelli prodotti ad out.
+func (d *decimator) process(in []complex128, out []complex128) []complex128 {
+	n := len(d.taps)
+	for _, x := range in {
+		d.pos = (d.pos + n - 1) % n
+		d.hist[d.pos], d.hist[d.pos+n] = x, x
+
+		d.phase++
+		if d.phase < d.factor {
+			continue
+		}
+		d.phase = 0
+
+		var re, im float64
+		hist := d.hist[d.pos : d.pos+n]
+		for k, h := range d.taps {
+			re += h * real(hist[k])
+			im += h * imag(hist[k])
+		}
+
+		out = append(out, complex(re, im))
+	}
+
+	return out
+}
+
+// newResampler restituisce un resampler che converte un segnale campionato a
+// in Hz in uno campionato a out Hz. Il filtro anti-aliasing ha frequenza di
+// taglio pari a cutoff Hz, o al 90% della metà della minore tra le due
+// frequenze se cutoff è maggiore o nullo.
+func newResampler(in, out, cutoff float64) *resampler {
+	up, down := ratio(out/in, maxRatio)
+
+	fc := 0.45 * math.Min(in, out)
+	if cutoff > 0 && cutoff < fc {
+		fc = cutoff
+	}
+
+	// Il filtro lavora alla frequenza sovracampionata in*up: la lunghezza è
+	// proporzionale al maggiore dei fattori per mantenere costante la
+	// selettività.
+	n := 16*maxInt(up, down) + 1
+	taps := lowpass(fc/(in*float64(up)), n)
+	for k := range taps {
+		taps[k] *= float64(up)
+	}
+
+	return &resampler{
+		up:   up,
+		down: down,
+		taps: taps,
+		hist: make([]float64, 2*((n+up-1)/up)),
+	}
+}
+
+// process ricampiona i campioni in, aggiungendo quelli prodotti ad out.
+func (r *resampler) process(in []float64, out []float64) []float64 {
+	n := len(r.hist) / 2
+	for _, x := range in {
+		r.pos = (r.pos + n - 1) % n
+		r.hist[r.pos], r.hist[r.pos+n] = x, x
+
+		hist := r.hist[r.pos : r.pos+n]
+		for r.phase < r.up {
+			var acc float64
+			for k, j := 0, r.phase; j < len(r.taps); k, j = k+1, j+r.up {
+				acc += r.taps[j] * hist[k]
+			}
+
+			out = append(out, acc)
+			r.phase += r.down
+		}
+
+		r.phase -= r.up
+	}
+
+	return out
+}
+
+// newArbitrary restituisce un arbitrary che converte un segnale campionato a
+// in Hz in uno campionato a out Hz. Il filtro anti-aliasing ha frequenza di
+// taglio pari al 90% della metà della minore tra le due frequenze.
+func newArbitrary(in, out float64) *arbitrary {
+	fc := 0.45 * math.Min(in, out)
+
+	// Come in newResampler la lunghezza di ogni ramo cresce con il fattore
+	// di decimazione per mantenere costante la selettività.
+	m := 16 * int(math.Ceil(in/out))
+	if m < 16 {
+		m = 16
+	}
+
+	taps := lowpass(fc/(in*arbitraryPhases), arbitraryPhases*m+1)
+	for k := range taps {
+		taps[k] *= arbitraryPhases
+	}
+
+	return &arbitrary{
+		step: arbitraryPhases * in / out,
+		taps: taps,
+		hist: make([]complex128, 2*(m+1)),
+	}
+}
+
+// process ricampiona i campioni in, aggiungendo quelli prodotti ad out.
+func (a *arbitrary) process(in []complex128, out []complex128) []complex128 {
+	n := len(a.hist) / 2
+	for _, x := range in {
+		a.pos = (a.pos + n - 1) % n
+		a.hist[a.pos], a.hist[a.pos+n] = x, x
+
+		hist := a.hist[a.pos : a.pos+n]
+		for a.phase < arbitraryPhases {
+			j := int(a.phase)
+			f := a.phase - float64(j)
+
+			y0, y1 := a.arm(hist, j), a.arm(hist, j+1)
+			out = append(out, y0+complex(f, 0)*(y1-y0))
+			a.phase += a.step
+		}
+
+		a.phase -= arbitraryPhases
+	}
+
+	return out
+}
+
+// arm restituisce l'uscita del ramo j del banco di filtri per i campioni hist,
+// ordinati dal più recente al meno recente.
+func (a *arbitrary) arm(hist []complex128, j int) complex128 {
+	var re, im float64
+	for k := 0; j < len(a.taps) && k < len(hist); k, j = k+1, j+arbitraryPhases {
+		re += a.taps[j] * real(hist[k])
+		im += a.taps[j] * imag(hist[k])
+	}
+
+	return complex(re, im)
+}
+
+// exact indica se il rapporto razionale up/down converte esattamente la
+// frequenza in nella frequenza out.
+func exact(in, out float64, up, down int) bool {
+	return math.Abs(in*float64(up)/float64(down)-out) <= 1e-9*out
+}
+
+// maxRatio è il valore massimo dei fattori di sovracampionamento e decimazione
+// di un resampler. È sufficiente a rappresentare esattamente i rapporti tra le
+// frequenze di campionamento in uso, ad esempio 44100/48000 = 147/160.
+const maxRatio = 1024
+
+// ratio restituisce la migliore approssimazione razionale up/down di x, con
+// up e down non maggiori di max, calcolata con le frazioni continue.
+func ratio(x float64, max int) (up, down int) {
+	// Convergenti h/k della frazione continua di x.
+	h0, h1 := 0, 1
+	k0, k1 := 1, 0
+
+	v := x
+	for {
+		a := int(math.Floor(v))
+		h2, k2 := a*h1+h0, a*k1+k0
+		if h2 > max || k2 > max {
+			break
+		}
+
+		h0, h1 = h1, h2
+		k0, k1 = k1, k2
+
+		f := v - float64(a)
+		if f < 1e-9 || math.Abs(float64(h1)/float64(k1)-x) < 1e-12*x {
+			break
+		}
+
+		v = 1 / f
+	}
+
+	if h1 == 0 || k1 == 0 {
+		return 1, 1
+	}
+
+	return h1, k1
+}
+
+// maxInt restituisce il maggiore tra a e b.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// minInt restituisce il minore tra a e b.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
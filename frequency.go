@@ -0,0 +1,88 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// InvalidFrequencyError è l'errore restituito da ParseFrequency, e quindi da
+// TuneString, per una frequenza non interpretabile.
+var InvalidFrequencyError = errors.New("Invalid Frequency Error")
+
+// frequencyUnits associa i suffissi ammessi da ParseFrequency al relativo
+// moltiplicatore, dal più lungo al più corto così che "MHz" non venga
+// riconosciuto come "Hz".
+var frequencyUnits = []struct {
+	suffix string
+	scale  float64
+}{
+	{"GHz", 1e9},
+	{"MHz", 1e6},
+	{"kHz", 1e3},
+	{"Hz", 1},
+	{"G", 1e9},
+	{"M", 1e6},
+	{"k", 1e3},
+}
+
+// Frequency imposta il valore iniziale della frequenza sintonizzata, espresso
+// in Hz come per Tune. Equivale ad InitialRF, che per compatibilità accetta
+// invece la frequenza in MHz.
+func Frequency(hz float64) Option {
+	return Option{
+		apply: func() {
+			rsp.InitialRF = double(hz / 1e6)
+		},
+	}
+}
+
+// ParseFrequency restituisce la frequenza, espressa in Hz, descritta da s: un
+// numero non negativo seguito, anche dopo uno spazio, da una delle unità Hz,
+// kHz, MHz e GHz o dalle loro abbreviazioni k, M e G, senza distinguere
+// maiuscole e minuscole, ad esempio "99.5M", "1008 kHz" o "1.09GHz". Senza
+// unità il valore è espresso in Hz. Per ogni s non valida restituisce un errore
+// per cui errors.Is(e, InvalidFrequencyError) è vero.
+func ParseFrequency(s string) (float64, error) {
+	v := strings.TrimSpace(s)
+
+	scale := 1.0
+	for _, u := range frequencyUnits {
+		if len(v) >= len(u.suffix) && strings.EqualFold(v[len(v)-len(u.suffix):], u.suffix) {
+			v, scale = strings.TrimSpace(v[:len(v)-len(u.suffix)]), u.scale
+			break
+		}
+	}
+
+	f, e := strconv.ParseFloat(v, 64)
+	switch {
+	case v == "":
+		return 0, fmt.Errorf("%w: %q has no value", InvalidFrequencyError, s)
+	case e != nil:
+		return 0, fmt.Errorf("%w: %q is not a number with unit Hz, kHz, MHz or GHz", InvalidFrequencyError, s)
+	case f < 0 || math.IsInf(f, 0) || math.IsNaN(f):
+		return 0, fmt.Errorf("%w: %q is not a finite non-negative frequency", InvalidFrequencyError, s)
+	}
+
+	return f * scale, nil
+}
+
+// TuneString sintonizza t sulla frequenza descritta da s nel formato accettato
+// da ParseFrequency, ad esempio "99.5M".
+func TuneString(t Tuner, s string) error {
+	f, e := ParseFrequency(s)
+	if e != nil {
+		return e
+	}
+
+	return t.Tune(f)
+}
@@ -0,0 +1,69 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+// BiasT abilita o meno l'alimentazione bias-T sulla porta d'antenna, usata per
+// alimentare un LNA o un convertitore esterno. È ammessa da RSP1A, RSP2 e
+// RSPduo.
+func BiasT(enabled bool) Option {
+	return Option{
+		apply: func() {
+			rsp.BiasT = enable(enabled)
+		},
+	}
+}
+
+// BroadcastNotch abilita o meno il filtro notch hardware delle bande di
+// radiodiffusione: FM sulla RSP1A, MW ed FM sulla RSP2 e sulla RSPduo.
+func BroadcastNotch(enabled bool) Option {
+	return Option{
+		apply: func() {
+			rsp.Broadcast = enable(enabled)
+		},
+	}
+}
+
+// DABNotch abilita o meno il filtro notch hardware della banda DAB. È ammessa
+// da RSP1A e RSPduo.
+func DABNotch(enabled bool) Option {
+	return Option{
+		apply: func() {
+			rsp.DABNotch = enable(enabled)
+		},
+	}
+}
+
+// setFrontEnd imposta ingresso d'antenna, porta Hi-Z, bias-T e notch della RSP
+// quando differiscono da quelli di prev.
+func (r *radio) setFrontEnd(prev features) error {
+	if r.feat.Input != prev.Input {
+		if e := api.setInput(r.hw.Model, r.feat.Input); e != nil {
+			return e
+		}
+	}
+
+	if r.feat.HiZ != prev.HiZ {
+		if e := api.setAmPort(r.hw.Model, r.feat.HiZ); e != nil {
+			return e
+		}
+	}
+
+	if r.feat.BiasT != prev.BiasT {
+		if e := api.setBiasT(r.hw.Model, r.feat.BiasT); e != nil {
+			return e
+		}
+	}
+
+	if r.feat.Broadcast != prev.Broadcast || r.feat.DABNotch != prev.DABNotch {
+		if e := api.setNotch(r.hw.Model, r.feat.Broadcast, r.feat.DABNotch); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "time"
+
+// ramp contiene i parametri della rampa con cui Gain applica le variazioni di
+// guadagno: step è il passo massimo in dB, interval l'attesa tra due passi.
+type ramp struct {
+	step     integer
+	interval time.Duration
+}
+
+// GainRamp fa sì che Gain raggiunga la gain reduction richiesta con una rampa
+// di passi di al più step dB, distanziati di interval, invece che con un unico
+// salto, riducendo il disturbo udibile o visibile quando il guadagno cambia
+// durante la demodulazione. Gain ritorna al termine della rampa e, nel
+// frattempo, gli altri metodi del ricevitore attendono. Un passo non positivo
+// disabilita la rampa.
+func GainRamp(step int, interval time.Duration) Option {
+	return Option{
+		apply: func() {
+			rsp.Ramp = ramp{step: integer(step), interval: interval}
+		},
+	}
+}
+
+// setGain imposta la gain reduction gr, passando per la rampa configurata con
+// GainRamp. In caso di errore r.gr resta l'ultimo valore applicato.
+func (r *radio) setGain(gr integer) error {
+	step := r.feat.Ramp.step
+
+	for {
+		next := gr
+		switch {
+		case step <= 0:
+		case gr > r.gr+step:
+			next = r.gr + step
+		case gr < r.gr-step:
+			next = r.gr - step
+		}
+
+		grsys, e := api.setGr(next, r.feat.LNA)
+		if e := r.failed(e); e != nil {
+			return e
+		}
+
+		r.gr, r.grsys = next, grsys
+		if next == gr {
+			return nil
+		}
+
+		time.Sleep(r.feat.Ramp.interval)
+	}
+}
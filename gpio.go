@@ -0,0 +1,43 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// gpioRoot è la directory dell'interfaccia sysfs dei GPIO.
+const gpioRoot = "/sys/class/gpio"
+
+// exportGPIO esporta, se necessario, il pin GPIO indicato e lo configura come
+// uscita.
+func exportGPIO(pin int) error {
+	dir := filepath.Join(gpioRoot, "gpio"+strconv.Itoa(pin))
+
+	if _, e := os.Stat(dir); os.IsNotExist(e) {
+		e = os.WriteFile(filepath.Join(gpioRoot, "export"), []byte(strconv.Itoa(pin)), 0)
+		if e != nil {
+			return e
+		}
+	}
+
+	return os.WriteFile(filepath.Join(dir, "direction"), []byte("out"), 0)
+}
+
+// writeGPIO imposta il livello logico del pin GPIO indicato, che deve essere
+// già stato esportato con exportGPIO.
+func writeGPIO(pin int, high bool) error {
+	v := []byte("0")
+	if high {
+		v = []byte("1")
+	}
+
+	return os.WriteFile(filepath.Join(gpioRoot, "gpio"+strconv.Itoa(pin), "value"), v, 0)
+}
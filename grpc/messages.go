@@ -0,0 +1,311 @@
+//go:build grpc
+
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package grpc
+
+import (
+	"errors"
+	"math"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type (
+	// message è implementata dai messaggi del servizio, codificati a mano nel
+	// formato protobuf descritto in sdrplay.proto così da non dipendere dal
+	// codice generato da protoc.
+	message interface {
+		marshal() []byte
+		unmarshal(b []byte) error
+	}
+
+	// Empty è il messaggio vuoto restituito dalle chiamate di controllo.
+	Empty struct{}
+
+	// TuneRequest è la richiesta di Tune.
+	TuneRequest struct {
+		// Frequency è la frequenza in Hz.
+		Frequency float64
+	}
+
+	// GainRequest è la richiesta di Gain.
+	GainRequest struct {
+		// Reduction è il gain reduction in dB.
+		Reduction int32
+	}
+
+	// SetUpRequest è la richiesta di SetUp: vengono applicati solo i campi non
+	// nil.
+	SetUpRequest struct {
+		// Frequency e SampleRate sono la frequenza iniziale e quella di
+		// campionamento in Hz.
+		Frequency, SampleRate *float64
+
+		// Bandwidth ed IF sono la larghezza di banda e la IF in kHz.
+		Bandwidth, IF *int32
+
+		// AGCMode ed AGCdBFS sono il modo dell'AGC ed il livello desiderato.
+		AGCMode *int32
+		AGCdBFS int32
+
+		// GainReduction è il gain reduction iniziale in dB.
+		GainReduction *int32
+
+		// PPM è la correzione dell'oscillatore locale.
+		PPM *float64
+	}
+
+	// StreamRequest è la richiesta di Stream.
+	StreamRequest struct{}
+
+	// IQFrame è un frame del segnale in banda base restituito da Stream.
+	IQFrame struct {
+		// Sequence è il numero di sequenza del frame.
+		Sequence uint64
+
+		// Samples contiene i campioni I e Q interlacciati, interi a 16 bit con
+		// segno in little endian.
+		Samples []byte
+	}
+
+	// codec codifica i messaggi del servizio Radio al posto del codec
+	// protobuf predefinito di gRPC, al quale delega gli altri messaggi.
+	codec struct{}
+)
+
+// UnsupportedMessageError indica che il codec non è in grado di codificare un
+// messaggio che non appartiene al servizio Radio, perché il codec protobuf non
+// è registrato.
+var UnsupportedMessageError = errors.New("Unsupported Message Error")
+
+// Marshal implementa l'interfaccia encoding.Codec.
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		if c := encoding.GetCodec("proto"); c != nil {
+			return c.Marshal(v)
+		}
+		return nil, UnsupportedMessageError
+	}
+
+	return m.marshal(), nil
+}
+
+// Unmarshal implementa l'interfaccia encoding.Codec.
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(message)
+	if !ok {
+		if c := encoding.GetCodec("proto"); c != nil {
+			return c.Unmarshal(data, v)
+		}
+		return UnsupportedMessageError
+	}
+
+	return m.unmarshal(data)
+}
+
+// Name implementa l'interfaccia encoding.Codec. Il nome è quello del codec
+// protobuf, con il quale i messaggi sono compatibili.
+func (codec) Name() string {
+	return "proto"
+}
+
+// fields invoca f per ogni campo del messaggio b, saltando quelli per i quali
+// f restituisce false.
+func fields(b []byte, f func(num protowire.Number, typ protowire.Type, b []byte) (int, bool)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, ok := f(num, typ, b)
+		if !ok {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+// double decodifica un campo double.
+func double(typ protowire.Type, b []byte, v *float64) (int, bool) {
+	if typ != protowire.Fixed64Type {
+		return 0, false
+	}
+
+	x, n := protowire.ConsumeFixed64(b)
+	*v = math.Float64frombits(x)
+
+	return n, true
+}
+
+// varint decodifica un campo intero.
+func varint(typ protowire.Type, b []byte, v *uint64) (int, bool) {
+	if typ != protowire.VarintType {
+		return 0, false
+	}
+
+	x, n := protowire.ConsumeVarint(b)
+	*v = x
+
+	return n, true
+}
+
+// int32Field decodifica un campo int32 memorizzandone anche la presenza.
+func int32Field(typ protowire.Type, b []byte, v **int32) (int, bool) {
+	var x uint64
+	n, ok := varint(typ, b, &x)
+	if ok && n >= 0 {
+		i := int32(x)
+		*v = &i
+	}
+
+	return n, ok
+}
+
+// doubleField decodifica un campo double memorizzandone anche la presenza.
+func doubleField(typ protowire.Type, b []byte, v **float64) (int, bool) {
+	var x float64
+	n, ok := double(typ, b, &x)
+	if ok && n >= 0 {
+		*v = &x
+	}
+
+	return n, ok
+}
+
+func (*Empty) marshal() []byte { return nil }
+
+func (*Empty) unmarshal(b []byte) error {
+	return fields(b, func(protowire.Number, protowire.Type, []byte) (int, bool) { return 0, false })
+}
+
+func (*StreamRequest) marshal() []byte { return nil }
+
+func (*StreamRequest) unmarshal(b []byte) error {
+	return fields(b, func(protowire.Number, protowire.Type, []byte) (int, bool) { return 0, false })
+}
+
+func (m *TuneRequest) marshal() []byte {
+	b := protowire.AppendTag(nil, 1, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(m.Frequency))
+}
+
+func (m *TuneRequest) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, bool) {
+		if num == 1 {
+			return double(typ, b, &m.Frequency)
+		}
+		return 0, false
+	})
+}
+
+func (m *GainRequest) marshal() []byte {
+	b := protowire.AppendTag(nil, 1, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(int64(m.Reduction)))
+}
+
+func (m *GainRequest) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, bool) {
+		if num != 1 {
+			return 0, false
+		}
+
+		var x uint64
+		n, ok := varint(typ, b, &x)
+		m.Reduction = int32(x)
+
+		return n, ok
+	})
+}
+
+func (m *SetUpRequest) marshal() []byte {
+	var b []byte
+
+	putDouble := func(num protowire.Number, v *float64) {
+		if v != nil {
+			b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+			b = protowire.AppendFixed64(b, math.Float64bits(*v))
+		}
+	}
+	putInt := func(num protowire.Number, v *int32) {
+		if v != nil {
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(int64(*v)))
+		}
+	}
+
+	putDouble(1, m.Frequency)
+	putDouble(2, m.SampleRate)
+	putInt(3, m.Bandwidth)
+	putInt(4, m.IF)
+	putInt(5, m.AGCMode)
+	if m.AGCdBFS != 0 {
+		putInt(6, &m.AGCdBFS)
+	}
+	putInt(7, m.GainReduction)
+	putDouble(8, m.PPM)
+
+	return b
+}
+
+func (m *SetUpRequest) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, bool) {
+		switch num {
+		case 1:
+			return doubleField(typ, b, &m.Frequency)
+		case 2:
+			return doubleField(typ, b, &m.SampleRate)
+		case 3:
+			return int32Field(typ, b, &m.Bandwidth)
+		case 4:
+			return int32Field(typ, b, &m.IF)
+		case 5:
+			return int32Field(typ, b, &m.AGCMode)
+		case 6:
+			var x uint64
+			n, ok := varint(typ, b, &x)
+			m.AGCdBFS = int32(x)
+			return n, ok
+		case 7:
+			return int32Field(typ, b, &m.GainReduction)
+		case 8:
+			return doubleField(typ, b, &m.PPM)
+		}
+		return 0, false
+	})
+}
+
+func (m *IQFrame) marshal() []byte {
+	b := protowire.AppendTag(nil, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, m.Sequence)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	return protowire.AppendBytes(b, m.Samples)
+}
+
+func (m *IQFrame) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, bool) {
+		switch {
+		case num == 1:
+			return varint(typ, b, &m.Sequence)
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			m.Samples = append([]byte(nil), v...)
+			return n, true
+		}
+		return 0, false
+	})
+}
@@ -0,0 +1,235 @@
+//go:build grpc
+
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+// Package grpc espone un ricevitore sdrplay attraverso il servizio gRPC Radio,
+// descritto in sdrplay.proto, così che possa essere controllato da altri
+// linguaggi ed altre macchine con un'API tipizzata. I client si ottengono
+// compilando sdrplay.proto con protoc per il linguaggio desiderato; il server
+// non richiede invece codice generato. Il package dipende da
+// google.golang.org/grpc e google.golang.org/protobuf, che il package sdrplay
+// non richiede, e viene quindi compilato solo con il tag di build grpc:
+//
+//	go build -tags grpc ./grpc
+//
+//	srv := grpc.NewServer()
+//	rx, e := sdrplay.RSP(srv)
+//	...
+//	g := gogrpc.NewServer(grpc.Codec())
+//	srv.Register(g, rx)
+//	e = g.Serve(l)
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/iclac/sdrplay"
+	gogrpc "google.golang.org/grpc"
+)
+
+// Server è un sdrplay.Connector che implementa il servizio Radio: le chiamate
+// Tune, Gain e SetUp vengono applicate al ricevitore fornito a Register e
+// Stream restituisce ai client il segnale in banda base propagato.
+type Server struct {
+	mu sync.Mutex
+
+	rx      sdrplay.Receiver
+	seq     uint64
+	streams map[chan *IQFrame]struct{}
+}
+
+// streamQueue è il numero massimo di frame in attesa di essere inviati ad un
+// client, oltre il quale i frame vengono scartati.
+const streamQueue = 64
+
+// serviceDesc descrive il servizio Radio al posto del codice generato da
+// protoc.
+var serviceDesc = gogrpc.ServiceDesc{
+	ServiceName: "sdrplay.Radio",
+	HandlerType: (*interface{})(nil),
+	Methods: []gogrpc.MethodDesc{
+		{
+			MethodName: "Tune",
+			Handler: unary("Tune", func() message { return new(TuneRequest) }, func(rx sdrplay.Receiver, m message) error {
+				return rx.Tune(m.(*TuneRequest).Frequency)
+			}),
+		},
+		{
+			MethodName: "Gain",
+			Handler: unary("Gain", func() message { return new(GainRequest) }, func(rx sdrplay.Receiver, m message) error {
+				return rx.Gain(int(m.(*GainRequest).Reduction))
+			}),
+		},
+		{
+			MethodName: "SetUp",
+			Handler: unary("SetUp", func() message { return new(SetUpRequest) }, func(rx sdrplay.Receiver, m message) error {
+				return rx.SetUp(m.(*SetUpRequest).options()...)
+			}),
+		},
+	},
+	Streams: []gogrpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       stream,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sdrplay.proto",
+}
+
+// NewServer restituisce un Server senza client connessi.
+func NewServer() *Server {
+	return &Server{streams: make(map[chan *IQFrame]struct{})}
+}
+
+// Codec restituisce l'opzione da fornire a gogrpc.NewServer perché i messaggi
+// del servizio Radio vengano codificati senza il codice generato da protoc. I
+// messaggi degli altri servizi registrati sullo stesso server vengono
+// codificati dal codec protobuf predefinito.
+func Codec() gogrpc.ServerOption {
+	return gogrpc.ForceServerCodec(codec{})
+}
+
+// Register registra il servizio Radio su g, applicando le chiamate di
+// controllo al ricevitore rx. Il server g va creato con l'opzione Codec.
+func (s *Server) Register(g *gogrpc.Server, rx sdrplay.Receiver) {
+	s.mu.Lock()
+	s.rx = rx
+	s.mu.Unlock()
+
+	g.RegisterService(&serviceDesc, s)
+}
+
+// Propagate implementa l'interfaccia sdrplay.Connector.
+func (s *Server) Propagate(I []int16, Q []int16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seq := s.seq
+	s.seq++
+
+	if len(s.streams) == 0 {
+		return
+	}
+
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	b := make([]byte, 4*n)
+	for k := 0; k < n; k++ {
+		binary.LittleEndian.PutUint16(b[4*k:], uint16(I[k]))
+		binary.LittleEndian.PutUint16(b[4*k+2:], uint16(Q[k]))
+	}
+
+	f := &IQFrame{Sequence: seq, Samples: b}
+	for c := range s.streams {
+		select {
+		case c <- f:
+		default:
+		}
+	}
+}
+
+// unary restituisce il gestore della chiamata name, che decodifica la
+// richiesta creata da in ed invoca call.
+func unary(name string, in func() message, call func(sdrplay.Receiver, message) error) func(interface{}, context.Context, func(interface{}) error, gogrpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor gogrpc.UnaryServerInterceptor) (interface{}, error) {
+		req := in()
+		if e := dec(req); e != nil {
+			return nil, e
+		}
+
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			s := srv.(*Server)
+
+			s.mu.Lock()
+			rx := s.rx
+			s.mu.Unlock()
+
+			if e := call(rx, req.(message)); e != nil {
+				return nil, e
+			}
+
+			return new(Empty), nil
+		}
+
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+
+		info := &gogrpc.UnaryServerInfo{Server: srv, FullMethod: "/sdrplay.Radio/" + name}
+
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+// stream gestisce la chiamata Stream, inviando al client i frame propagati
+// fino alla sua disconnessione.
+func stream(srv interface{}, ss gogrpc.ServerStream) error {
+	if e := ss.RecvMsg(new(StreamRequest)); e != nil {
+		return e
+	}
+
+	s := srv.(*Server)
+	c := make(chan *IQFrame, streamQueue)
+
+	s.mu.Lock()
+	s.streams[c] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.streams, c)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ss.Context().Done():
+			return ss.Context().Err()
+		case f := <-c:
+			if e := ss.SendMsg(f); e != nil {
+				return e
+			}
+		}
+	}
+}
+
+// options restituisce le opzioni corrispondenti ai campi presenti nella
+// richiesta.
+func (m *SetUpRequest) options() []sdrplay.Option {
+	var opts []sdrplay.Option
+
+	if m.Frequency != nil {
+		opts = append(opts, sdrplay.InitialRF(*m.Frequency/1e6))
+	}
+	if m.SampleRate != nil {
+		opts = append(opts, sdrplay.FS(*m.SampleRate/1e6))
+	}
+	if m.Bandwidth != nil {
+		opts = append(opts, sdrplay.Bandwidth(sdrplay.B(*m.Bandwidth)))
+	}
+	if m.IF != nil {
+		opts = append(opts, sdrplay.IF(sdrplay.IFmode(*m.IF)))
+	}
+	if m.AGCMode != nil {
+		opts = append(opts, sdrplay.AGC(sdrplay.AGCmode(*m.AGCMode), int(m.AGCdBFS)))
+	}
+	if m.GainReduction != nil {
+		opts = append(opts, sdrplay.InitialGR(int(*m.GainReduction)))
+	}
+	if m.PPM != nil {
+		opts = append(opts, sdrplay.LOppm(*m.PPM))
+	}
+
+	return opts
+}
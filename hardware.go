@@ -0,0 +1,167 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "fmt"
+
+// Hardware descrive la RSP usata da un Receiver: il modello e la versione
+// hardware riportati dall'API e le caratteristiche del modello, usate per
+// verificare che le opzioni richieste siano ammesse dal dispositivo.
+type Hardware struct {
+	// Model è il modello, HwVersion la versione hardware riportata dall'API e
+	// Serial il numero di serie. Model vale UnknownModel se il dispositivo
+	// non è stato riconosciuto.
+	Model     Model
+	HwVersion int
+	Serial    string
+
+	// Frequency è l'intervallo di frequenze sintonizzabili, in Hz, ed Inputs
+	// quello di ogni ingresso selezionabile con AntennaInput.
+	Frequency Range
+	Inputs    []Range
+
+	// Tuners è il numero di tuner, Antennas i nomi delle porte d'antenna e
+	// Notches i filtri notch disponibili.
+	Tuners   int
+	Antennas []string
+	Notches  []string
+
+	// BiasT, HiZ, ExternalReference e HDR indicano la presenza
+	// dell'alimentazione bias-T, della porta ad alta impedenza, dell'uscita
+	// del riferimento di frequenza e della modalità HDR.
+	BiasT             bool
+	HiZ               bool
+	ExternalReference bool
+	HDR               bool
+}
+
+// capabilities contiene le caratteristiche di ogni modello.
+var capabilities = map[Model]Hardware{
+	RSP1: {
+		Frequency: Range{Min: 10e3, Max: 2e9},
+		Inputs:    []Range{{Min: 10e3, Max: 2e9}},
+		Tuners:    1,
+		Antennas:  []string{"A"},
+	},
+	RSP1A: {
+		Frequency: Range{Min: 1e3, Max: 2e9},
+		Inputs:    []Range{{Min: 1e3, Max: 2e9}},
+		Tuners:    1,
+		Antennas:  []string{"A"},
+		Notches:   []string{"FM", "DAB"},
+		BiasT:     true,
+	},
+	RSP2: {
+		Frequency:         Range{Min: 1e3, Max: 2e9},
+		Inputs:            []Range{{Min: 1e3, Max: 2e9}, {Min: 1e3, Max: 2e9}},
+		Tuners:            1,
+		Antennas:          []string{"A", "B", "Hi-Z"},
+		Notches:           []string{"MW", "FM"},
+		BiasT:             true,
+		HiZ:               true,
+		ExternalReference: true,
+	},
+	RSPduo: {
+		Frequency:         Range{Min: 1e3, Max: 2e9},
+		Inputs:            []Range{{Min: 1e3, Max: 2e9}, {Min: 1e3, Max: 2e9}},
+		Tuners:            2,
+		Antennas:          []string{"Tuner 1 50Ω", "Tuner 2 50Ω", "Tuner 1 Hi-Z"},
+		Notches:           []string{"MW", "FM", "DAB"},
+		BiasT:             true,
+		HiZ:               true,
+		ExternalReference: true,
+	},
+	RSPdx: {
+		Frequency:         Range{Min: 1e3, Max: 2e9},
+		Inputs:            []Range{{Min: 1e3, Max: 2e9}, {Min: 1e3, Max: 2e9}, {Min: 1e3, Max: 200e6}},
+		Tuners:            1,
+		Antennas:          []string{"A", "B", "C"},
+		Notches:           []string{"MW", "FM", "DAB"},
+		BiasT:             true,
+		ExternalReference: true,
+		HDR:               true,
+	},
+}
+
+// Hardware restituisce le caratteristiche della RSP descritta da d.
+func (d DeviceInfo) Hardware() Hardware {
+	h := capabilities[d.Model]
+
+	h.Model, h.HwVersion, h.Serial = d.Model, d.HwVersion, d.Serial
+	h.Inputs = append([]Range(nil), h.Inputs...)
+	h.Antennas = append([]string(nil), h.Antennas...)
+	h.Notches = append([]string(nil), h.Notches...)
+
+	return h
+}
+
+// detect restituisce l'Hardware della RSP che verrà usata dallo stream, cioè
+// la prima disponibile o, in modalità Slave, la prima RSPduo, occupata dal
+// master. Se nessuna RSP è disponibile il modello resta UnknownModel e sarà
+// StreamInit a riportare l'errore.
+func detect(mode DuoMode) (Hardware, error) {
+	devs, e := api.devices()
+	if e != nil {
+		return Hardware{}, e
+	}
+
+	for _, d := range devs {
+		if d.Available || mode == Slave && d.Model == RSPduo {
+			return d.Hardware(), nil
+		}
+	}
+
+	return Hardware{}, nil
+}
+
+// validate verifica che la configurazione f sia ammessa dalla RSP descritta da
+// h. Con un modello sconosciuto non viene eseguita alcuna verifica.
+func (h Hardware) validate(f features) error {
+	if h.Model == UnknownModel {
+		return nil
+	}
+
+	if rf := float64(f.InitialRF) * 1e6; rf < h.Frequency.Min || rf > h.Frequency.Max {
+		return &OptionError{Option: "InitialRF", Reason: fmt.Sprintf("frequency %g MHz outside the %s range %g - %g MHz", float64(f.InitialRF), h.Model, h.Frequency.Min/1e6, h.Frequency.Max/1e6)}
+	}
+
+	if e := h.checkInput(f.Input, float64(f.InitialRF)*1e6); e != nil {
+		return e
+	}
+
+	return nil
+}
+
+// DeviceControl è l'interfaccia di un ricevitore che usa una RSP, per le
+// operazioni che riguardano il dispositivo. Il Receiver restituito da RSP
+// implementa DeviceControl.
+type DeviceControl interface {
+	// Hardware restituisce il modello e le caratteristiche della RSP usata
+	// dal ricevitore.
+	Hardware() Hardware
+
+	// Config restituisce la configurazione attuale della RSP.
+	Config() Config
+
+	// SetPPM imposta la correzione dell'oscillatore, espressa in ppm, come
+	// l'opzione LOppm ma senza reinizializzare lo stream, così da poterla
+	// regolare durante la ricezione.
+	SetPPM(ppm float64) error
+
+	// SetDriverParam imposta il parametro id del driver al valore value,
+	// come mir_sdr_SetParam, per le regolazioni del produttore non ancora
+	// disponibili come Option. Il significato dei parametri, ed il momento
+	// in cui hanno effetto, sono quelli documentati da SDRplay e non vengono
+	// verificati.
+	SetDriverParam(id, value uint32) error
+}
+
+// Hardware implementa l'interfaccia DeviceControl.
+func (r *radio) Hardware() Hardware {
+	return r.hw
+}
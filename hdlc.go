@@ -0,0 +1,161 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+type (
+	// bitClock recupera il clock di un segnale binario con codifica NRZI a
+	// partire dai livelli rilevati ad ogni campione.
+	bitClock struct {
+		// step è la durata di un campione in bit, phase la posizione del
+		// campione attuale all'interno del bit.
+		step, phase float64
+
+		// level è l'ultimo livello rilevato, last quello dell'ultimo bit
+		// campionato.
+		level, last bool
+	}
+
+	// hdlc estrae i frame HDLC da un flusso di bit: riconosce i flag e rimuove
+	// i bit inseriti dal bit stuffing.
+	hdlc struct {
+		// max è la lunghezza massima di un frame in bit.
+		max int
+
+		// ones conta i bit a 1 consecutivi, bits accumula i bit del frame in
+		// corso e inFrame indica se è stato ricevuto il flag di apertura.
+		ones    int
+		bits    []byte
+		inFrame bool
+	}
+)
+
+// newBitClock restituisce un bitClock per un segnale di baud bit al secondo
+// campionato con frequenza rate, espressa in Hz.
+func newBitClock(baud, rate float64) bitClock {
+	return bitClock{step: baud / rate}
+}
+
+// sample elabora il livello rilevato sul campione attuale. Se il campione cade
+// a metà di un bit restituisce il bit decodificato e true.
+func (c *bitClock) sample(level bool) (bit, ok bool) {
+	// Ogni transizione di livello dovrebbe cadere all'inizio di un bit, dove
+	// la fase vale 0.
+	if level != c.level {
+		if c.phase < 0.5 {
+			c.phase -= 0.3 * c.phase
+		} else {
+			c.phase += 0.3 * (1 - c.phase)
+		}
+		c.level = level
+	}
+
+	old := c.phase
+	c.phase += c.step
+	if c.phase >= 1 {
+		c.phase -= 1
+	}
+
+	// Il bit viene campionato a metà del suo periodo.
+	if old < 0.5 && c.phase >= 0.5 || old > c.phase && c.phase >= 0.5 {
+		// Decodifica NRZI: l'assenza di transizione indica un bit a 1.
+		bit = level == c.last
+		c.last = level
+		return bit, true
+	}
+
+	return false, false
+}
+
+// push elabora il bit b. Alla chiusura di un frame ne restituisce i bit,
+// compresa la FCS ed esclusi i flag, nell'ordine di ricezione.
+func (h *hdlc) push(b bool) []byte {
+	if b {
+		h.ones++
+		if h.inFrame {
+			h.bits = append(h.bits, 1)
+		}
+
+		if h.ones > 6 {
+			// Sette o più bit a 1 indicano un abort o l'assenza di segnale.
+			h.inFrame = false
+			h.bits = h.bits[:0]
+		}
+
+		return nil
+	}
+
+	ones := h.ones
+	h.ones = 0
+
+	switch {
+	case ones == 5:
+		// Bit inserito dal bit stuffing.
+		return nil
+	case ones == 6:
+		// Flag 01111110: chiude il frame in corso ed apre il successivo. Gli
+		// ultimi sette bit accumulati appartengono al flag.
+		var frame []byte
+		if h.inFrame && len(h.bits) > 7 {
+			frame = append([]byte(nil), h.bits[:len(h.bits)-7]...)
+		}
+
+		h.inFrame = true
+		h.bits = h.bits[:0]
+
+		return frame
+	}
+
+	if h.inFrame {
+		h.bits = append(h.bits, 0)
+		if len(h.bits) > h.max+8 {
+			h.inFrame = false
+			h.bits = h.bits[:0]
+		}
+	}
+
+	return nil
+}
+
+// hdlcBytes verifica la FCS del frame composto dai bit indicati e ne
+// restituisce i byte, esclusa la FCS, oppure nil se il frame non è valido. I
+// byte HDLC sono trasmessi a partire dal bit meno significativo.
+func hdlcBytes(bits []byte) []byte {
+	if len(bits) < 24 || len(bits)%8 != 0 {
+		return nil
+	}
+
+	data := make([]byte, len(bits)/8)
+	crc := uint16(0xFFFF)
+	for k := range data {
+		for i := 0; i < 8; i++ {
+			data[k] |= bits[8*k+i] << uint(i)
+		}
+		crc = crc16X25(crc, data[k])
+	}
+
+	if crc != 0xF0B8 {
+		return nil
+	}
+
+	return data[:len(data)-2]
+}
+
+// crc16X25 aggiorna con il byte b il CRC-16 CCITT in forma riflessa usato da
+// HDLC.
+func crc16X25(crc uint16, b byte) uint16 {
+	crc ^= uint16(b)
+	for k := 0; k < 8; k++ {
+		if crc&1 != 0 {
+			crc = crc>>1 ^ 0x8408
+		} else {
+			crc >>= 1
+		}
+	}
+
+	return crc
+}
@@ -0,0 +1,201 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// Health riporta lo stato dello stream, notificato ogni secondo alla
+	// funzione impostata con l'opzione OnHealth.
+	Health struct {
+		// Time è l'istante della notifica.
+		Time time.Time
+
+		// Callbacks è il numero di invocazioni della callback nell'ultimo
+		// secondo, Interval l'intervallo medio tra due invocazioni, Jitter la
+		// sua deviazione standard e MaxInterval il massimo.
+		Callbacks   int
+		Interval    time.Duration
+		Jitter      time.Duration
+		MaxInterval time.Duration
+
+		// Samples sono i campioni ricevuti dall'avvio dello stream, o
+		// dall'ultimo cambio della frequenza di campionamento, ed Expected
+		// quelli attesi nello stesso intervallo in base alla frequenza di
+		// campionamento; Drift è il loro scostamento relativo in ppm, negativo
+		// se mancano campioni.
+		Samples  int64
+		Expected int64
+		Drift    float64
+
+		// Fill è il riempimento, tra 0 ed 1, del buffer impostato con
+		// l'opzione Buffering e Overruns il numero di frame scartati perché
+		// pieno; entrambi sono nulli senza Buffering.
+		Fill     float64
+		Overruns uint64
+	}
+
+	// healthMeter accumula le statistiche dello stream per OnHealth.
+	healthMeter struct {
+		mu sync.Mutex
+
+		// callbacks, sum, sumsq e max riguardano gli intervalli, in secondi,
+		// dall'ultima notifica.
+		callbacks  int
+		sum, sumsq float64
+		max        time.Duration
+
+		// first e last sono gli istanti della prima e dell'ultima callback
+		// della misura della deriva, samples i campioni ricevuti dopo la
+		// prima e rate la frequenza di campionamento in Hz.
+		first, last time.Time
+		samples     int64
+		rate        float64
+	}
+)
+
+// healthInterval è l'intervallo con cui viene notificato lo stato dello
+// stream.
+const healthInterval = time.Second
+
+// OnHealth invoca report ogni secondo con lo stato dello stream: la regolarità
+// delle invocazioni della callback, lo scostamento tra i campioni ricevuti e
+// quelli attesi ed il riempimento del buffer, così da rilevare un problema
+// prima che provochi la perdita di campioni. report viene invocata da una
+// goroutine dedicata, che termina con Close.
+func OnHealth(report func(Health)) Option {
+	return Option{
+		apply: func() {
+			rsp.Health = report
+		},
+	}
+}
+
+// startHealth avvia, se richiesta con OnHealth, la notifica dello stato dello
+// stream.
+func (r *radio) startHealth() {
+	if r.feat.Health == nil {
+		return
+	}
+
+	m := &healthMeter{}
+	r.healthStop = make(chan struct{})
+
+	r.smu.Lock()
+	r.health = m
+	r.smu.Unlock()
+
+	go r.reportHealth(r.healthStop, m, r.feat.Health)
+}
+
+// stopHealth termina la notifica dello stato dello stream.
+func (r *radio) stopHealth() {
+	if r.healthStop == nil {
+		return
+	}
+
+	r.smu.Lock()
+	r.health = nil
+	r.smu.Unlock()
+
+	close(r.healthStop)
+	r.healthStop = nil
+}
+
+// add registra una callback di n campioni invocata nell'istante now, a
+// distanza d dalla precedente.
+func (m *healthMeter) add(now time.Time, d time.Duration, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// La prima callback inizia la misura: il suo intervallo dall'avvio dello
+	// stream non è significativo ed i suoi campioni precedono first.
+	if m.first.IsZero() {
+		m.first, m.last = now, now
+		return
+	}
+
+	m.callbacks++
+	m.sum += d.Seconds()
+	m.sumsq += d.Seconds() * d.Seconds()
+	if d > m.max {
+		m.max = d
+	}
+
+	m.last = now
+	m.samples += int64(n)
+}
+
+// reportHealth notifica a report ogni healthInterval lo stato dello stream,
+// fino alla chiusura di stop.
+func (r *radio) reportHealth(stop chan struct{}, m *healthMeter, report func(Health)) {
+	t := time.NewTicker(healthInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+
+		r.smu.Lock()
+		rate := float64(r.feat.FS) * 1e6
+		if r.feat.Decimate && r.feat.Factor > 0 {
+			rate /= float64(r.feat.Factor)
+		}
+		r.smu.Unlock()
+
+		h := m.health(rate)
+
+		if r.ring != nil {
+			h.Fill, h.Overruns = r.ring.Fill(), r.ring.Overruns()
+		}
+
+		select {
+		case <-stop:
+			return
+		default:
+			report(h)
+		}
+	}
+}
+
+// health restituisce lo stato dello stream con frequenza di campionamento rate,
+// espressa in Hz, ed inizia un nuovo intervallo. Se rate è cambiata, la misura
+// della deriva ricomincia.
+func (m *healthMeter) health(rate float64) Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := Health{Time: time.Now(), Callbacks: m.callbacks, MaxInterval: m.max}
+
+	if n := float64(m.callbacks); n > 0 {
+		mean := m.sum / n
+		h.Interval = time.Duration(mean * float64(time.Second))
+		h.Jitter = time.Duration(math.Sqrt(math.Max(0, m.sumsq/n-mean*mean)) * float64(time.Second))
+	}
+
+	if rate != m.rate {
+		m.rate, m.first, m.samples = rate, time.Time{}, 0
+	} else if !m.first.IsZero() {
+		h.Samples = m.samples
+		h.Expected = int64(math.Round(rate * m.last.Sub(m.first).Seconds()))
+		if h.Expected > 0 {
+			h.Drift = float64(h.Samples-h.Expected) / float64(h.Expected) * 1e6
+		}
+	}
+
+	m.callbacks, m.sum, m.sumsq, m.max = 0, 0, 0, 0
+
+	return h
+}
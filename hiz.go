@@ -0,0 +1,56 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+// hiZGainReduction è il gain reduction, in dB, dei preset per la porta Hi-Z: le
+// antenne filari collegate all'ingresso ad alta impedenza ricevono in onde
+// lunghe e medie segnali molto forti, che con meno attenuazione
+// saturerebbero l'ADC.
+const hiZGainReduction = 40
+
+// HiZ seleziona, se enabled, la porta d'antenna ad alta impedenza al posto di
+// quella a 50 Ω: l'ingresso AM Hi-Z della RSP2 o quello del tuner 1 della
+// RSPduo, adatti ad antenne filari non adattate. La porta Hi-Z riceve solo al
+// di sotto dei 60 MHz. Se modificata con SetUp, lo stream viene
+// reinizializzato.
+func HiZ(enabled bool) Option {
+	return Option{
+		apply: func() {
+			rsp.HiZ = enable(enabled)
+		},
+	}
+}
+
+// HiZPreset restituisce le opzioni che configurano la RSP per la ricezione
+// della banda con la porta Hi-Z, sintonizzandola al centro.
+func (b Band) HiZPreset() []Option {
+	return b.HiZPresetAt(b.Center())
+}
+
+// HiZPresetAt restituisce le opzioni che configurano la RSP per la ricezione
+// della banda con la porta Hi-Z, sintonizzandola sulla frequenza f espressa in
+// Hz. Rispetto a PresetAt selezionano la porta Hi-Z e la IF di 450 kHz, che
+// allontana dalla banda il rumore a bassa frequenza del campionamento a IF
+// nulla, con campionamento a 2 MHz e larghezza di banda di al più 600 kHz, un
+// gain reduction elevato e, salvo che f sia nella banda delle onde medie, il
+// notch delle bande di radiodiffusione, che attenua le onde medie.
+func (b Band) HiZPresetAt(f float64) []Option {
+	bw := b.Bandwidth
+	if bw > BW600 {
+		bw = BW600
+	}
+
+	return append(b.PresetAt(f),
+		HiZ(true),
+		FS(2),
+		Bandwidth(bw),
+		IF(IF450),
+		InitialGR(hiZGainReduction),
+		BroadcastNotch(!MediumWave.Contains(f)),
+	)
+}
@@ -0,0 +1,257 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// HopConnector è l'interfaccia che descrive il connettore attraverso il
+	// quale Hopper propaga i frame ricevuti, insieme alla frequenza sulla
+	// quale sono stati ricevuti.
+	HopConnector interface {
+		// PropagateHop propaga un frame di campioni in banda base ricevuto
+		// mentre era sintonizzata la frequenza frequency espressa in Hz.
+		PropagateHop(frequency float64, I []int16, Q []int16)
+	}
+
+	// Hop è un passo della sequenza di Hopper.
+	Hop struct {
+		// Frequency è la frequenza da sintonizzare espressa in Hz e Dwell il
+		// tempo per cui restarvi.
+		Frequency float64
+		Dwell     time.Duration
+	}
+
+	// Hopper è un Connector che sintonizza il ricevitore secondo una sequenza
+	// temporizzata di frequenze, eventualmente ripetuta, e propaga ad out i
+	// frame ricevuti insieme alla frequenza attiva, così che un'unica RSP
+	// possa registrare più canali alternandoli. I tempi sono misurati sui
+	// campioni ricevuti; i campioni ricevuti durante la sintonia e nel tempo
+	// di assestamento successivo vengono scartati.
+	//
+	//	h := sdrplay.NewHopper(fs, nil, logger)
+	//	rx, e := sdrplay.RSP(h)
+	//	h.Plug(rx)
+	//	h.Schedule(true, sdrplay.Hop{145.5e6, time.Second}, sdrplay.Hop{433.5e6, time.Second})
+	//	e = h.Start()
+	Hopper struct {
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento espressa in Hz.
+		fs float64
+
+		tuner    Tuner
+		schedule []Hop
+		loop     bool
+
+		// settle è il tempo scartato dopo ogni sintonia.
+		settle time.Duration
+
+		// active indica se un passo è in corso sulla frequenza frequency;
+		// skip e remain sono i campioni ancora da scartare e da propagare.
+		active    bool
+		frequency float64
+		skip      int
+		remain    int
+		done      chan struct{}
+
+		stop    chan struct{}
+		stopped chan struct{}
+		err     error
+
+		out HopConnector
+	}
+)
+
+// NewHopper restituisce un Hopper per un segnale campionato con frequenza fs,
+// espressa in Hz, che sintonizza t e propaga i frame ad out. Il sintonizzatore
+// può essere fornito anche in seguito con Plug. Di default dopo ogni sintonia
+// vengono scartati 20ms.
+func NewHopper(fs float64, t Tuner, out HopConnector) *Hopper {
+	return &Hopper{
+		fs:     fs,
+		tuner:  t,
+		settle: 20 * time.Millisecond,
+		done:   make(chan struct{}, 1),
+		out:    out,
+	}
+}
+
+// Plug collega all'Hopper il sintonizzatore da comandare, tipicamente il
+// Receiver restituito da RSP.
+func (h *Hopper) Plug(t Tuner) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.tuner = t
+}
+
+// Schedule imposta la sequenza dei passi, ripetuta ciclicamente se loop è
+// true. La nuova sequenza viene usata dall'avvio successivo.
+func (h *Hopper) Schedule(loop bool, hops ...Hop) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.schedule = append([]Hop(nil), hops...)
+	h.loop = loop
+}
+
+// Settle imposta il tempo scartato dopo ogni sintonia per lasciar assestare il
+// sintonizzatore.
+func (h *Hopper) Settle(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.settle = d
+}
+
+// Start avvia la sequenza. Se non è stato fornito alcun passo viene restituito
+// l'errore EmptyScanError, se manca il sintonizzatore l'errore
+// NoReceiverError.
+func (h *Hopper) Start() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch {
+	case len(h.schedule) == 0:
+		return EmptyScanError
+	case h.tuner == nil:
+		return NoReceiverError
+	case h.stop != nil:
+		return nil
+	}
+
+	h.err = nil
+	h.stop = make(chan struct{})
+	h.stopped = make(chan struct{})
+	go h.run(h.stop, h.stopped, h.schedule, h.loop, h.tuner)
+
+	return nil
+}
+
+// Stop interrompe la sequenza ed attende la fine della goroutine di sintonia.
+func (h *Hopper) Stop() {
+	h.mu.Lock()
+	stop, stopped := h.stop, h.stopped
+	h.stop = nil
+	h.active = false
+	h.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-stopped
+}
+
+// Done restituisce un canale che viene chiuso quando la sequenza termina,
+// perché conclusa, interrotta da Stop o da un errore di sintonia. Restituisce
+// nil se la sequenza non è mai stata avviata.
+func (h *Hopper) Done() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.stopped
+}
+
+// Err restituisce l'errore di sintonia che ha interrotto l'ultima sequenza.
+func (h *Hopper) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.err
+}
+
+// run esegue la sequenza schedule fino alla sua conclusione o alla chiusura di
+// stop.
+func (h *Hopper) run(stop, stopped chan struct{}, schedule []Hop, loop bool, tuner Tuner) {
+	defer func() {
+		h.mu.Lock()
+		if h.stop == stop {
+			h.stop = nil
+		}
+		h.active = false
+		h.mu.Unlock()
+
+		close(stopped)
+	}()
+
+	for {
+		for _, hop := range schedule {
+			if e := tuner.Tune(hop.Frequency); e != nil {
+				h.mu.Lock()
+				h.err = e
+				h.mu.Unlock()
+				return
+			}
+
+			h.arm(hop)
+
+			select {
+			case <-h.done:
+			case <-stop:
+				return
+			}
+		}
+
+		if !loop {
+			return
+		}
+	}
+}
+
+// arm avvia il passo hop appena sintonizzato.
+func (h *Hopper) arm(hop Hop) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	select {
+	case <-h.done:
+	default:
+	}
+
+	h.frequency = hop.Frequency
+	h.skip = int(h.settle.Seconds() * h.fs)
+	h.remain = maxInt(1, int(hop.Dwell.Seconds()*h.fs))
+	h.active = true
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (h *Hopper) Propagate(I []int16, Q []int16) {
+	h.mu.Lock()
+	if !h.active {
+		h.mu.Unlock()
+		return
+	}
+
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	first := minInt(h.skip, n)
+	h.skip -= first
+
+	last := first + minInt(h.remain, n-first)
+	h.remain -= last - first
+
+	frequency := h.frequency
+	if h.remain == 0 {
+		h.active = false
+		h.done <- struct{}{}
+	}
+	h.mu.Unlock()
+
+	if last > first && h.out != nil {
+		h.out.PropagateHop(frequency, I[first:last], Q[first:last])
+	}
+}
@@ -0,0 +1,270 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// ImageMeter è un Connector che misura la reiezione della frequenza
+	// immagine della RSP. Non disponendo la RSP di un generatore interno, la
+	// misura usa una portante stabile, ad esempio quella di un generatore di
+	// segnali collegato all'antenna o di un trasmettitore AM in onda media:
+	// Measure sintonizza la RSP così che la portante cada, una dopo l'altra,
+	// agli scostamenti richiesti dal centro di banda e confronta ogni volta la
+	// sua potenza con quella della sua immagine, speculare rispetto al centro.
+	// Ripetendo la misura con IF nulla e con le IF non nulle si può scegliere
+	// la modalità con la reiezione migliore o verificare l'hardware. Se
+	// presente, ogni frame viene propagato inalterato al connettore out.
+	//
+	//	im := sdrplay.NewImageMeter(fs, nil)
+	//	rx, e := sdrplay.RSP(im, sdrplay.IF(sdrplay.IF450), sdrplay.FS(2))
+	//	r, e := im.Measure(rx, 10e6, nil)
+	ImageMeter struct {
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento espressa in Hz.
+		fs float64
+
+		// span è lo scostamento massimo, in Hz, entro cui viene cercata la
+		// portante; settle è il tempo scartato dopo ogni sintonia.
+		span   float64
+		settle time.Duration
+
+		// n è il numero di punti di ogni spettro e blocks il numero di spettri
+		// mediati.
+		n, blocks int
+
+		// skip sono i campioni ancora da scartare, buf quelli acquisiti;
+		// acquiring indica se una misura è in corso e done riceve i campioni
+		// acquisiti.
+		skip      int
+		buf       []complex128
+		acquiring bool
+		done      chan []complex128
+
+		out Connector
+	}
+
+	// ImageRejection è la reiezione misurata da ImageMeter per la portante
+	// ricevuta Offset Hz sopra il centro di banda.
+	ImageRejection struct {
+		Offset float64
+
+		// Rejection è il rapporto, in dB, tra la potenza della portante e
+		// quella della sua immagine. Se Limited è true l'immagine non emerge
+		// dal rumore e Rejection è solo il limite inferiore della reiezione.
+		Rejection float64
+		Limited   bool
+	}
+)
+
+// imageResolution è la risoluzione massima, in Hz, degli spettri usati da
+// ImageMeter, il cui numero di punti è arrotondato alla potenza di 2
+// successiva.
+const imageResolution = 100
+
+// ImageMeasureError indica che la portante non è stata trovata o che non sono
+// stati ricevuti abbastanza campioni per misurarla.
+var ImageMeasureError = errors.New("Image Measure Error")
+
+// NewImageMeter restituisce un ImageMeter per un segnale campionato con
+// frequenza fs, espressa in Hz, che propaga i frame ad out se non nil. Di
+// default la portante viene cercata entro 5 kHz dalla posizione attesa, dopo
+// aver atteso 50ms da ogni sintonia, e vengono mediati 4 spettri.
+func NewImageMeter(fs float64, out Connector) *ImageMeter {
+	return &ImageMeter{
+		fs:     fs,
+		span:   5e3,
+		settle: 50 * time.Millisecond,
+		n:      pow2(int(fs / imageResolution)),
+		blocks: 4,
+		done:   make(chan []complex128, 1),
+		out:    out,
+	}
+}
+
+// ImageOffsets restituisce n scostamenti, espressi in Hz, distribuiti
+// uniformemente tra il 5% ed il 40% della frequenza di campionamento fs, così
+// da coprire la banda utile evitando la componente continua ed i bordi del
+// filtro anti-aliasing.
+func ImageOffsets(fs float64, n int) []float64 {
+	offsets := make([]float64, n)
+	for k := range offsets {
+		offsets[k] = fs * 0.05
+		if n > 1 {
+			offsets[k] += fs * 0.35 * float64(k) / float64(n-1)
+		}
+	}
+
+	return offsets
+}
+
+// Settle imposta il tempo scartato dopo ogni sintonia per lasciar assestare il
+// PLL della RSP.
+func (m *ImageMeter) Settle(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.settle = d
+}
+
+// Measure misura la reiezione dell'immagine di rx con la portante di
+// frequenza carrier, espressa in Hz, sintonizzando rx così che la portante
+// cada ad ognuno degli scostamenti offsets, in Hz sopra il centro di banda,
+// o a quelli restituiti da ImageOffsets(fs, 8) se offsets è nil. Al termine rx
+// resta sintonizzato per l'ultimo scostamento. Se la portante non viene
+// trovata viene restituito l'errore ImageMeasureError.
+func (m *ImageMeter) Measure(rx Receiver, carrier float64, offsets []float64) ([]ImageRejection, error) {
+	if offsets == nil {
+		offsets = ImageOffsets(m.fs, 8)
+	}
+
+	res := make([]ImageRejection, 0, len(offsets))
+	for _, d := range offsets {
+		if e := rx.Tune(carrier - d); e != nil {
+			return res, e
+		}
+
+		buf, e := m.acquire()
+		if e != nil {
+			return res, e
+		}
+
+		r, ok := m.rejection(buf, d)
+		if !ok {
+			return res, ImageMeasureError
+		}
+
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// acquire attende i campioni necessari alla misura. Se non vengono ricevuti
+// entro un tempo pari a dieci volte la durata dell'acquisizione restituisce
+// l'errore ImageMeasureError.
+func (m *ImageMeter) acquire() ([]complex128, error) {
+	m.mu.Lock()
+	select {
+	case <-m.done:
+	default:
+	}
+
+	m.skip = int(m.settle.Seconds() * m.fs)
+	m.buf = make([]complex128, 0, m.n*m.blocks)
+	m.acquiring = true
+
+	timeout := 10 * (m.settle + time.Duration(float64(m.n*m.blocks)/m.fs*float64(time.Second)))
+	m.mu.Unlock()
+
+	select {
+	case buf := <-m.done:
+		return buf, nil
+	case <-time.After(timeout):
+		m.mu.Lock()
+		m.acquiring = false
+		m.mu.Unlock()
+
+		return nil, ImageMeasureError
+	}
+}
+
+// rejection misura, sullo spettro medio dei campioni buf, la reiezione
+// dell'immagine della portante attesa offset Hz sopra il centro di banda e
+// restituisce se la portante emerge dal rumore.
+func (m *ImageMeter) rejection(buf []complex128, offset float64) (ImageRejection, bool) {
+	n := m.n
+	win := hann(n)
+	psd := make([]float64, n)
+
+	block := make([]complex128, n)
+	for b := 0; b+n <= len(buf); b += n {
+		for k := range block {
+			block[k] = buf[b+k] * complex(win[k], 0)
+		}
+
+		fft(block)
+
+		for k, v := range block {
+			psd[k] += real(v)*real(v) + imag(v)*imag(v)
+		}
+	}
+
+	width := int(m.span*float64(n)/m.fs) + 1
+	center := bin(offset, m.fs, n)
+
+	best := center
+	for d := -width; d <= width; d++ {
+		if k := ((center+d)%n + n) % n; psd[k] > psd[best] {
+			best = k
+		}
+	}
+
+	// Con la finestra di Hann la potenza di una portante si distribuisce sul
+	// bin centrale e sui due adiacenti; il rumore è stimato con la mediana
+	// dello spettro, insensibile alle portanti.
+	power := func(k int) float64 {
+		return psd[(k-1+n)%n] + psd[k] + psd[(k+1)%n]
+	}
+
+	sorted := append([]float64(nil), psd...)
+	sort.Float64s(sorted)
+	noise := 3 * sorted[n/2]
+
+	wanted, image := power(best), power((n-best)%n)
+	if wanted < 10*noise {
+		return ImageRejection{}, false
+	}
+
+	limited := image < 2*noise
+	if limited {
+		image = noise
+	}
+
+	k := float64(best)
+	if best >= n/2 {
+		k -= float64(n)
+	}
+
+	return ImageRejection{
+		Offset:    k * m.fs / float64(n),
+		Rejection: dB(wanted) - dB(image),
+		Limited:   limited,
+	}, true
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (m *ImageMeter) Propagate(I []int16, Q []int16) {
+	m.mu.Lock()
+	if m.acquiring {
+		for k := 0; k < len(I) && k < len(Q); k++ {
+			if m.skip > 0 {
+				m.skip--
+				continue
+			}
+
+			m.buf = append(m.buf, complex(float64(I[k])/fullScale, float64(Q[k])/fullScale))
+			if len(m.buf) == cap(m.buf) {
+				m.acquiring = false
+				m.done <- m.buf
+				break
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	if m.out != nil {
+		m.out.Propagate(I, Q)
+	}
+}
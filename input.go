@@ -0,0 +1,63 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "fmt"
+
+// Input enumera gli ingressi d'antenna a 50 Ω della RSP.
+type Input int
+
+const (
+	// InputA è l'ingresso A, l'unico della RSP1 e della RSP1A e quello del
+	// tuner 1 della RSPduo.
+	InputA Input = iota
+	// InputB è l'ingresso B della RSP2 e della RSPdx e quello del tuner 2
+	// della RSPduo.
+	InputB
+	// InputC è l'ingresso BNC della RSPdx, che riceve solo fino a 200 MHz.
+	InputC
+)
+
+// AntennaInput seleziona l'ingresso d'antenna a 50 Ω della RSP. Gli ingressi
+// ammessi, e le frequenze che ognuno riceve, dipendono dal modello e sono
+// riportati in Hardware.Inputs: un ingresso assente è un UnsupportedFeature,
+// una frequenza iniziale fuori dall'intervallo dell'ingresso un OptionError,
+// come lo è per Tune e Memories.Tune una frequenza non ricevibile dall'ingresso
+// selezionato.
+func AntennaInput(in Input) Option {
+	return Option{
+		apply: func() {
+			rsp.Input = in
+		},
+	}
+}
+
+// String implementa l'interfaccia fmt.Stringer restituendo il nome
+// dell'ingresso, ad esempio "C".
+func (in Input) String() string {
+	if in < InputA || in > InputC {
+		return fmt.Sprintf("Input(%d)", int(in))
+	}
+
+	return string(rune('A' + in))
+}
+
+// checkInput verifica che l'ingresso in riceva la frequenza f, espressa in Hz.
+// Con un modello sconosciuto o un ingresso assente non viene eseguita alcuna
+// verifica.
+func (h Hardware) checkInput(in Input, f float64) error {
+	if in < 0 || int(in) >= len(h.Inputs) {
+		return nil
+	}
+
+	if r := h.Inputs[in]; f < r.Min || f > r.Max {
+		return &OptionError{Option: "AntennaInput", Conflict: "Frequency", Reason: fmt.Sprintf("input %s of the %s receives %g - %g MHz, not %g MHz", in, h.Model, r.Min/1e6, r.Max/1e6, f/1e6)}
+	}
+
+	return nil
+}
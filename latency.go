@@ -0,0 +1,211 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "time"
+
+type (
+	// LatencyViolation descrive un frame il cui ritardo di consegna ha superato
+	// il limite impostato con l'opzione LowLatency.
+	LatencyViolation struct {
+		// Delay è il tempo trascorso tra l'invocazione della callback da parte
+		// dell'API SDRplay ed il ritorno di Propagate del baseband connector.
+		Delay time.Duration
+
+		// Bound è il limite impostato.
+		Bound time.Duration
+
+		// Samples è il numero di campioni del frame.
+		Samples int
+	}
+
+	// latency contiene i parametri impostati con l'opzione LowLatency.
+	latency struct {
+		bound  time.Duration
+		report func(LatencyViolation)
+	}
+
+	// framePool è un insieme di buffer preallocati, usati a rotazione per i
+	// frame propagati in modalità a bassa latenza o con l'opzione Recycle.
+	framePool struct {
+		i, q [][]int16
+		next int
+	}
+)
+
+const (
+	// lowLatencyFrames è il numero di buffer usati a rotazione in modalità a
+	// bassa latenza: un frame resta valido finché non ne sono stati propagati
+	// altrettanti.
+	lowLatencyFrames = 8
+
+	// lowLatencyReports è il numero massimo di violazioni in attesa di essere
+	// notificate.
+	lowLatencyReports = 64
+)
+
+// LowLatency configura il ricevitore per ridurre al minimo e mantenere entro il
+// limite bound il ritardo con cui ogni frame viene consegnato al baseband
+// connector. In questa modalità:
+//   - ogni pacchetto prodotto dall'API viene propagato immediatamente, senza
+//     alcuna aggregazione, direttamente dal thread della callback
+//   - i frame vengono copiati in buffer preallocati all'avvio dello stream ed
+//     usati a rotazione, così da evitare allocazioni e pause del garbage
+//     collector. Un frame resta quindi valido solo finché non ne sono stati
+//     propagati altri 8: il connettore che deve conservarlo più a lungo deve
+//     copiarlo
+//   - ogni volta che il tempo impiegato supera bound viene invocata report
+//     (se non nil) con i dettagli della violazione. report viene invocata da
+//     una goroutine dedicata, così da non ritardare la consegna dei frame
+//     successivi.
+//
+// Un valore di bound non positivo disabilita la modalità.
+func LowLatency(bound time.Duration, report func(LatencyViolation)) Option {
+	return Option{
+		apply: func() {
+			rsp.Latency = latency{bound: bound, report: report}
+		},
+	}
+}
+
+// Recycle fa sì che i frame propagati dalla RSP vengano copiati in frames
+// buffer preallocati all'avvio dello stream ed usati a rotazione, invece di
+// essere allocati ad ogni invocazione della callback: il percorso dei campioni
+// dalla callback al baseband connector non esegue quindi allocazioni e non
+// produce lavoro per il garbage collector. Un frame resta valido solo finché
+// non ne sono stati propagati altri frames: il connettore che deve conservarlo
+// più a lungo deve copiarlo. Con l'opzione Buffering anche i frame propagati
+// dal buffer vengono copiati in frames buffer usati a rotazione. Un valore non
+// positivo ripristina l'allocazione di ogni frame, salvo in modalità
+// LowLatency che usa 8 buffer.
+func Recycle(frames int) Option {
+	return Option{
+		apply: func() {
+			rsp.Recycle = frames
+		},
+	}
+}
+
+// Inline fa sì che il Connector riceva direttamente i buffer dell'API SDRplay,
+// senza copie, ottenendo la latenza minima. I frame sono validi solo durante
+// l'invocazione di Propagate, dopo la quale l'API li riutilizza: il Connector
+// che deve conservarli deve copiarli. Con Shift la traslazione viene applicata
+// sul posto ai buffer dell'API. Inline prevale su Recycle e sulla modalità
+// LowLatency, di cui resta attivo il solo controllo dei tempi.
+func Inline(enabled bool) Option {
+	return Option{
+		apply: func() {
+			rsp.Inline = enabled
+		},
+	}
+}
+
+// newFramePool restituisce un framePool di count buffer da n campioni.
+func newFramePool(n, count int) *framePool {
+	p := &framePool{
+		i: make([][]int16, count),
+		q: make([][]int16, count),
+	}
+
+	for k := range p.i {
+		p.i[k] = make([]int16, n)
+		p.q[k] = make([]int16, n)
+	}
+
+	return p
+}
+
+// frame restituisce i buffer per un frame di n campioni, usando quelli
+// preallocati se sufficientemente capienti.
+func (p *framePool) frame(n int) ([]int16, []int16) {
+	if p == nil || n > cap(p.i[p.next]) {
+		return make([]int16, n), make([]int16, n)
+	}
+
+	i, q := p.i[p.next][:n], p.q[p.next][:n]
+	p.next = (p.next + 1) % len(p.i)
+
+	return i, q
+}
+
+// startLatency prealloca, se richiesto dall'opzione Recycle o dalla modalità a
+// bassa latenza, i buffer dei frame sulla base del numero di campioni per
+// pacchetto comunicato dall'API e avvia, se abilitata, la goroutine di
+// notifica delle violazioni.
+func (r *radio) startLatency() {
+	var (
+		pool       *framePool
+		violations chan LatencyViolation
+	)
+
+	if n := r.frames(); n > 0 {
+		pool = newFramePool(int(r.spp), n)
+
+		if r.ring != nil && r.spp > 0 {
+			r.ring.recycle(int(r.spp), n)
+		}
+	}
+
+	if r.feat.Latency.bound > 0 {
+		if r.feat.Latency.report != nil {
+			violations = make(chan LatencyViolation, lowLatencyReports)
+
+			go func(c <-chan LatencyViolation, report func(LatencyViolation)) {
+				for v := range c {
+					report(v)
+				}
+			}(violations, r.feat.Latency.report)
+		}
+	}
+
+	r.smu.Lock()
+	r.pool, r.violations = pool, violations
+	r.smu.Unlock()
+}
+
+// frames restituisce il numero di buffer dei frame da preallocare, 0 se i
+// frame vanno allocati.
+func (r *radio) frames() int {
+	switch {
+	case r.feat.Inline:
+		return 0
+	case r.feat.Recycle > 0:
+		return r.feat.Recycle
+	case r.feat.Latency.bound > 0:
+		return lowLatencyFrames
+	}
+
+	return 0
+}
+
+// stopLatency termina la goroutine di notifica delle violazioni.
+func (r *radio) stopLatency() {
+	r.smu.Lock()
+	violations := r.violations
+	r.pool, r.violations = nil, nil
+	r.smu.Unlock()
+
+	if violations != nil {
+		close(violations)
+	}
+}
+
+// checkLatency verifica che il ritardo d di consegna di un frame di n campioni
+// non abbia superato il limite bound, notificando in caso contrario la
+// violazione sulla coda violations. Se la coda è piena la violazione viene
+// scartata.
+func checkLatency(violations chan<- LatencyViolation, bound, d time.Duration, n int) {
+	if d <= bound || violations == nil {
+		return
+	}
+
+	select {
+	case violations <- LatencyViolation{Delay: d, Bound: bound, Samples: n}:
+	default:
+	}
+}
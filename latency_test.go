@@ -0,0 +1,106 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"testing"
+	"time"
+)
+
+// streamPacket è il numero di campioni per pacchetto dei frame propagati.
+const streamPacket = 1008
+
+// discard è un Connector che scarta i frame ricevuti.
+type discard struct{}
+
+// Propagate implementa l'interfaccia Connector.
+func (discard) Propagate(I []int16, Q []int16) {}
+
+// PropagatePacket implementa l'interfaccia PacketConnector.
+func (discard) PropagatePacket(info PacketInfo, I []int16, Q []int16) {}
+
+// streamRadio restituisce un radio che propaga a baseband i frame passati a
+// stream, senza avviare la RSP, come all'avvio dello stream con Recycle(frames).
+func streamRadio(baseband Connector, frames int) *radio {
+	r := &radio{baseband: baseband, spp: streamPacket}
+	r.feat.Recycle = frames
+	r.stats = streamStats{since: time.Now(), reported: time.Now()}
+
+	if ring, ok := baseband.(*Ring); ok {
+		r.ring = ring
+	}
+
+	r.startLatency()
+
+	return r
+}
+
+// benchmarkStream misura la propagazione dei frame dalla callback a baseband.
+func benchmarkStream(b *testing.B, baseband Connector, frames int) {
+	r := streamRadio(baseband, frames)
+	defer r.stopLatency()
+
+	xi, xq := make([]int16, streamPacket), make([]int16, streamPacket)
+
+	b.ReportAllocs()
+	b.SetBytes(4 * streamPacket)
+	b.ResetTimer()
+
+	for k := 0; k < b.N; k++ {
+		r.stream(xi, xq, uint32(k*streamPacket), false)
+	}
+}
+
+// BenchmarkStream misura la propagazione dei frame allocati ad ogni callback.
+func BenchmarkStream(b *testing.B) {
+	benchmarkStream(b, discard{}, 0)
+}
+
+// BenchmarkStreamRecycle misura la propagazione dei frame con Recycle, che non
+// esegue allocazioni.
+func BenchmarkStreamRecycle(b *testing.B) {
+	benchmarkStream(b, discard{}, 8)
+}
+
+// BenchmarkStreamBufferingRecycle misura la propagazione dei frame attraverso
+// il buffer di Buffering con Recycle, che non esegue allocazioni neanche nella
+// goroutine del buffer.
+func BenchmarkStreamBufferingRecycle(b *testing.B) {
+	ring := NewRing(2e6, time.Second, discard{})
+	defer ring.Close()
+
+	benchmarkStream(b, ring, 8)
+}
+
+// TestRecycleAllocs verifica che con Recycle la propagazione dei frame, anche
+// attraverso il buffer di Buffering, non esegua allocazioni.
+func TestRecycleAllocs(t *testing.T) {
+	xi, xq := make([]int16, streamPacket), make([]int16, streamPacket)
+
+	r := streamRadio(discard{}, 8)
+	defer r.stopLatency()
+
+	if n := testing.AllocsPerRun(100, func() { r.stream(xi, xq, 0, false) }); n != 0 {
+		t.Errorf("%g allocations per frame with Recycle, want 0", n)
+	}
+
+	ring := NewRing(2e6, time.Second, discard{})
+	r = streamRadio(ring, 8)
+	defer r.stopLatency()
+
+	if n := testing.AllocsPerRun(100, func() {
+		r.stream(xi, xq, 0, false)
+		for ring.Fill() > 0 {
+			time.Sleep(0)
+		}
+	}); n != 0 {
+		t.Errorf("%g allocations per frame with Buffering and Recycle, want 0", n)
+	}
+
+	ring.Close()
+}
@@ -0,0 +1,21 @@
+//go:build !nosdr && !mock && !dlopen
+
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+/*
+ #cgo LDFLAGS: -L/usr/local/lib -lmirsdrapi-rsp
+*/
+import "C"
+
+// loadLibrary non ha nulla da fare: la libreria SDRplay è collegata al
+// programma in fase di link.
+func loadLibrary() error {
+	return nil
+}
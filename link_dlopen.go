@@ -0,0 +1,220 @@
+//go:build dlopen && !nosdr && !mock
+
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+/*
+ #cgo CFLAGS: -I/usr/local/include
+ #cgo LDFLAGS: -ldl
+
+ #include "mirsdrapi-rsp.h"
+ #include <dlfcn.h>
+ #include <stddef.h>
+ #include <stdio.h>
+
+ // libraries sono i nomi con cui viene cercata la libreria SDRplay.
+ static const char *libraries[] = {
+	"libmirsdrapi-rsp.so",
+	"libmirsdrapi-rsp.so.2",
+	"/usr/local/lib/libmirsdrapi-rsp.so",
+	"libmirsdrapi-rsp.dylib",
+	"/usr/local/lib/libmirsdrapi-rsp.dylib",
+	NULL,
+ };
+
+ static void *lib;
+
+ // loadError conserva la descrizione dell'errore di caricamento, perché il
+ // buffer restituito da dlerror viene riusato dai tentativi successivi.
+ static char loadError[512];
+
+ // loadLibrary apre la libreria SDRplay, restituendo NULL in caso di successo
+ // o la descrizione dell'errore.
+ static const char *loadLibrary(void) {
+	int k;
+
+	if (lib != NULL) {
+		return NULL;
+	}
+
+	for (k = 0; libraries[k] != NULL; k++) {
+		lib = dlopen(libraries[k], RTLD_NOW | RTLD_GLOBAL);
+		if (lib != NULL) {
+			return NULL;
+		}
+		// Viene riportato l'errore relativo al primo nome, il più
+		// significativo.
+		if (k == 0) {
+			snprintf(loadError, sizeof(loadError), "%s", dlerror());
+		}
+	}
+
+	return loadError;
+ }
+
+ // CALL risolve alla prima invocazione la funzione name della libreria e la
+ // invoca con gli argomenti forniti. Se la libreria o la funzione non sono
+ // disponibili restituisce mir_sdr_Fail.
+ #define CALL(name, ...) \
+	static __typeof__(&name) fn; \
+	if (fn == NULL) { \
+		if (loadLibrary() != NULL) return mir_sdr_Fail; \
+		fn = (__typeof__(&name))dlsym(lib, #name); \
+		if (fn == NULL) return mir_sdr_Fail; \
+	} \
+	return fn(__VA_ARGS__);
+
+ // Le funzioni seguenti sostituiscono quelle della libreria, che non viene
+ // collegata in fase di link, inoltrando le chiamate a quelle caricate.
+
+ mir_sdr_ErrT mir_sdr_ApiVersion(float *version) {
+	CALL(mir_sdr_ApiVersion, version)
+ }
+
+ mir_sdr_ErrT mir_sdr_GetDevices(mir_sdr_DeviceT *devices, unsigned int *numDevs, unsigned int maxDevs) {
+	CALL(mir_sdr_GetDevices, devices, numDevs, maxDevs)
+ }
+
+ mir_sdr_ErrT mir_sdr_StreamInit(int *gRdB, double fsMHz, double rfMHz, mir_sdr_Bw_MHzT bwType, mir_sdr_If_kHzT ifType, int LNAEnable, int *gRdBsystem, int useGrAltMode, int *samplesPerPacket, mir_sdr_StreamCallback_t StreamCbFn, mir_sdr_GainChangeCallback_t GainChangeCbFn, void *cbContext) {
+	CALL(mir_sdr_StreamInit, gRdB, fsMHz, rfMHz, bwType, ifType, LNAEnable, gRdBsystem, useGrAltMode, samplesPerPacket, StreamCbFn, GainChangeCbFn, cbContext)
+ }
+
+ mir_sdr_ErrT mir_sdr_StreamUninit(void) {
+	CALL(mir_sdr_StreamUninit)
+ }
+
+ mir_sdr_ErrT mir_sdr_Reinit(int *gRdB, double fsMHz, double rfMHz, mir_sdr_Bw_MHzT bwType, mir_sdr_If_kHzT ifType, mir_sdr_LoModeT LoMode, int LNAEnable, int *gRdBsystem, int useGrAltMode, int *samplesPerPacket, mir_sdr_ReasonForReinitT reasonForReinit) {
+	CALL(mir_sdr_Reinit, gRdB, fsMHz, rfMHz, bwType, ifType, LoMode, LNAEnable, gRdBsystem, useGrAltMode, samplesPerPacket, reasonForReinit)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetRf(double drfHz, int abs, int syncUpdate) {
+	CALL(mir_sdr_SetRf, drfHz, abs, syncUpdate)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetSyncUpdateSampleNum(unsigned int sampleNum) {
+	CALL(mir_sdr_SetSyncUpdateSampleNum, sampleNum)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetSyncUpdatePeriod(unsigned int period) {
+	CALL(mir_sdr_SetSyncUpdatePeriod, period)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetGrAltMode(int *gRidx, int LNAstate, int *gRdBsystem, int abs, int syncUpdate) {
+	CALL(mir_sdr_SetGrAltMode, gRidx, LNAstate, gRdBsystem, abs, syncUpdate)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetDeviceIdx(unsigned int idx) {
+	CALL(mir_sdr_SetDeviceIdx, idx)
+ }
+
+ mir_sdr_ErrT mir_sdr_ReleaseDeviceIdx(void) {
+	CALL(mir_sdr_ReleaseDeviceIdx)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetParam(unsigned int id, unsigned int value) {
+	CALL(mir_sdr_SetParam, id, value)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetTransferMode(mir_sdr_TransferModeT mode) {
+	CALL(mir_sdr_SetTransferMode, mode)
+ }
+
+ mir_sdr_ErrT mir_sdr_rsp1a_BiasT(int enable) {
+	CALL(mir_sdr_rsp1a_BiasT, enable)
+ }
+
+ mir_sdr_ErrT mir_sdr_rsp1a_BroadcastNotch(int enable) {
+	CALL(mir_sdr_rsp1a_BroadcastNotch, enable)
+ }
+
+ mir_sdr_ErrT mir_sdr_rsp1a_DabNotch(int enable) {
+	CALL(mir_sdr_rsp1a_DabNotch, enable)
+ }
+
+ mir_sdr_ErrT mir_sdr_RSPII_BiasTControl(unsigned int enable) {
+	CALL(mir_sdr_RSPII_BiasTControl, enable)
+ }
+
+ mir_sdr_ErrT mir_sdr_RSPII_RfNotchEnable(unsigned int enable) {
+	CALL(mir_sdr_RSPII_RfNotchEnable, enable)
+ }
+
+ mir_sdr_ErrT mir_sdr_rspDuo_BiasT(int enable) {
+	CALL(mir_sdr_rspDuo_BiasT, enable)
+ }
+
+ mir_sdr_ErrT mir_sdr_rspDuo_BroadcastNotch(int enable) {
+	CALL(mir_sdr_rspDuo_BroadcastNotch, enable)
+ }
+
+ mir_sdr_ErrT mir_sdr_rspDuo_DabNotch(int enable) {
+	CALL(mir_sdr_rspDuo_DabNotch, enable)
+ }
+
+ mir_sdr_ErrT mir_sdr_RSPII_AntennaControl(mir_sdr_RSPII_AntennaSelectT select) {
+	CALL(mir_sdr_RSPII_AntennaControl, select)
+ }
+
+ mir_sdr_ErrT mir_sdr_rspDuo_TunerSel(mir_sdr_rspDuo_TunerSelT sel) {
+	CALL(mir_sdr_rspDuo_TunerSel, sel)
+ }
+
+ mir_sdr_ErrT mir_sdr_AmPortSelect(int port) {
+	CALL(mir_sdr_AmPortSelect, port)
+ }
+
+ mir_sdr_ErrT mir_sdr_rspDuo_Tuner1AmPortSel(int port) {
+	CALL(mir_sdr_rspDuo_Tuner1AmPortSel, port)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetDcMode(int dcCal, int speedUp) {
+	CALL(mir_sdr_SetDcMode, dcCal, speedUp)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetDcTrackTime(int trackTime) {
+	CALL(mir_sdr_SetDcTrackTime, trackTime)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetPpm(double ppm) {
+	CALL(mir_sdr_SetPpm, ppm)
+ }
+
+ mir_sdr_ErrT mir_sdr_AgcControl(mir_sdr_AgcControlT enable, int setPoint_dBfs, int knee_dBfs, unsigned int decay_ms, unsigned int hang_ms, int syncUpdate, int LNAEnable) {
+	CALL(mir_sdr_AgcControl, enable, setPoint_dBfs, knee_dBfs, decay_ms, hang_ms, syncUpdate, LNAEnable)
+ }
+
+ mir_sdr_ErrT mir_sdr_DebugEnable(unsigned int enable) {
+	CALL(mir_sdr_DebugEnable, enable)
+ }
+
+ mir_sdr_ErrT mir_sdr_DCoffsetIQimbalanceControl(unsigned int DCenable, unsigned int IQenable) {
+	CALL(mir_sdr_DCoffsetIQimbalanceControl, DCenable, IQenable)
+ }
+
+ mir_sdr_ErrT mir_sdr_DecimateControl(unsigned int enable, unsigned int decimationFactor, unsigned int wideBandSignal) {
+	CALL(mir_sdr_DecimateControl, enable, decimationFactor, wideBandSignal)
+ }
+
+ mir_sdr_ErrT mir_sdr_SetLoMode(mir_sdr_LoModeT loMode) {
+	CALL(mir_sdr_SetLoMode, loMode)
+ }
+*/
+import "C"
+import "fmt"
+
+// loadLibrary carica, alla prima invocazione, la libreria SDRplay. Se non è
+// installata restituisce l'errore MissingLibraryError con la descrizione
+// fornita dal sistema, così che il programma possa proseguire senza la RSP.
+func loadLibrary() error {
+	if err := C.loadLibrary(); err != nil {
+		return fmt.Errorf("%w: %s", MissingLibraryError, C.GoString(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// streamStats contiene le statistiche dello stream riportate nel log.
+type streamStats struct {
+	// frames e samples sono i frame ed i campioni propagati, discarded i
+	// frame scartati perché successivi ad una variazione o ad un reset.
+	frames, samples, discarded int
+
+	// since è l'avvio dello stream e reported l'ultima volta in cui le
+	// statistiche sono state riportate.
+	since, reported time.Time
+}
+
+// statsInterval è l'intervallo con cui le statistiche dello stream vengono
+// riportate a livello Debug.
+const statsInterval = 10 * time.Second
+
+// Logger imposta il logger strutturato sul quale vengono riportati, con i
+// relativi livelli, l'inizializzazione e le reinizializzazioni della RSP con i
+// loro motivi, le azioni dell'AGC e le statistiche dello stream. Di default
+// viene usato slog.Default().
+func Logger(l *slog.Logger) Option {
+	return Option{
+		apply: func() {
+			rsp.Logger = l
+		},
+	}
+}
+
+// logger restituisce il logger impostato con l'opzione Logger.
+func (f features) logger() *slog.Logger {
+	if f.Logger != nil {
+		return f.Logger
+	}
+
+	return slog.Default()
+}
+
+// logger restituisce il logger impostato con l'opzione Logger.
+func (r *radio) logger() *slog.Logger {
+	r.smu.Lock()
+	defer r.smu.Unlock()
+
+	return r.feat.logger()
+}
+
+// logInit riporta la configurazione con cui è stato avviato lo stream.
+func (r *radio) logInit() {
+	r.logger().Info("rsp init",
+		"rf", r.rf,
+		"fs", float64(r.feat.FS)*1e6,
+		"bw", r.feat.BW,
+		"if", r.feat.IF,
+		"lo", r.feat.LOmode,
+		"lna", bool(r.feat.LNA),
+		"gr", int(r.gr),
+		"grsys", int(r.grsys),
+		"agc", r.feat.AGC,
+		"dbfs", int(r.feat.DBFS),
+		"decimate", bool(r.feat.Decimate),
+		"factor", r.feat.Factor,
+		"dcoffset", bool(r.feat.DCoffset),
+		"iqimbalance", bool(r.feat.IQimbalance),
+		"ppm", float64(r.feat.LOppm),
+		"spp", int(r.spp),
+	)
+}
+
+// count aggiorna le statistiche con un frame di n campioni, scartato se
+// discarded è true, e le riporta se è trascorso statsInterval.
+func (r *radio) count(n int, discarded bool) {
+	s := &r.stats
+	if discarded {
+		s.discarded++
+	} else {
+		s.frames++
+		s.samples += n
+	}
+
+	if now := time.Now(); now.Sub(s.reported) >= statsInterval {
+		s.reported = now
+		r.logStats(slog.LevelDebug, "rsp stream")
+	}
+}
+
+// logStats riporta con livello level le statistiche dello stream.
+func (r *radio) logStats(level slog.Level, msg string) {
+	s := r.stats
+	elapsed := time.Since(s.since)
+
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(s.samples) / elapsed.Seconds()
+	}
+
+	r.logger().Log(context.Background(), level, msg,
+		"frames", s.frames,
+		"samples", s.samples,
+		"discarded", s.discarded,
+		"elapsed", elapsed,
+		"rate", rate,
+	)
+}
+
+// String implementa l'interfaccia fmt.Stringer elencando i parametri
+// modificati.
+func (reason reinitReason) String() string {
+	names := []string{"gr", "fs", "rf", "bw", "if", "lo", "amport"}
+
+	var changed []string
+	for k, name := range names {
+		if reason&(1<<k) != 0 {
+			changed = append(changed, name)
+		}
+	}
+
+	if len(changed) == 0 {
+		return "none"
+	}
+
+	return strings.Join(changed, ",")
+}
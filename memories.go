@@ -0,0 +1,164 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+type (
+	// Memory è un canale memorizzato.
+	Memory struct {
+		// Name è il nome, univoco, del canale.
+		Name string `json:"name"`
+
+		// Frequency è la frequenza del canale espressa in Hz.
+		Frequency float64 `json:"frequency"`
+
+		// Mode è il modo di demodulazione, ad esempio "WFM", "NFM" o "AM". Il
+		// valore è libero ed è interpretato dall'applicazione.
+		Mode string `json:"mode,omitempty"`
+
+		// Bandwidth è la larghezza di banda da impostare sulla RSP, se non
+		// nulla.
+		Bandwidth B `json:"bandwidth,omitempty"`
+
+		// Squelch è la soglia dello squelch in dBFS, nulla se lo squelch non è
+		// usato.
+		Squelch float64 `json:"squelch,omitempty"`
+	}
+
+	// Memories è un archivio di canali memorizzati, conservati nell'ordine di
+	// inserimento e salvabili in formato JSON, sintonizzabili per nome con
+	// Tune.
+	Memories struct {
+		mu   sync.Mutex
+		list []Memory
+	}
+)
+
+// NoMemoryError indica che il canale richiesto non è memorizzato.
+var NoMemoryError = errors.New("No Memory Error")
+
+// NewMemories restituisce un archivio vuoto.
+func NewMemories() *Memories {
+	return &Memories{}
+}
+
+// LoadMemories restituisce l'archivio salvato con Save nel file path.
+func LoadMemories(path string) (*Memories, error) {
+	b, e := os.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+
+	m := NewMemories()
+	if e := json.Unmarshal(b, &m.list); e != nil {
+		return nil, e
+	}
+
+	return m, nil
+}
+
+// Save salva l'archivio nel file path in formato JSON.
+func (m *Memories) Save(path string) error {
+	m.mu.Lock()
+	b, e := json.MarshalIndent(m.list, "", "  ")
+	m.mu.Unlock()
+
+	if e != nil {
+		return e
+	}
+
+	return os.WriteFile(path, b, 0644)
+}
+
+// Add memorizza il canale mem, sostituendo quello con lo stesso nome se
+// presente.
+func (m *Memories) Add(mem Memory) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.list {
+		if m.list[k].Name == mem.Name {
+			m.list[k] = mem
+			return
+		}
+	}
+
+	m.list = append(m.list, mem)
+}
+
+// Remove elimina il canale name.
+func (m *Memories) Remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.list {
+		if m.list[k].Name == name {
+			m.list = append(m.list[:k], m.list[k+1:]...)
+			return
+		}
+	}
+}
+
+// Get restituisce il canale name e se è memorizzato.
+func (m *Memories) Get(name string) (Memory, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, mem := range m.list {
+		if mem.Name == name {
+			return mem, true
+		}
+	}
+
+	return Memory{}, false
+}
+
+// List restituisce i canali memorizzati.
+func (m *Memories) List() []Memory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Memory(nil), m.list...)
+}
+
+// Frequencies restituisce le frequenze dei canali memorizzati, adatte ad
+// esempio a Scanner.List.
+func (m *Memories) Frequencies() []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	freqs := make([]float64, len(m.list))
+	for k, mem := range m.list {
+		freqs[k] = mem.Frequency
+	}
+
+	return freqs
+}
+
+// Tune sintonizza t sul canale name, impostandone la larghezza di banda se
+// indicata e se t è un Receiver.
+func (m *Memories) Tune(t Tuner, name string) error {
+	mem, ok := m.Get(name)
+	if !ok {
+		return NoMemoryError
+	}
+
+	if rx, ok := t.(Receiver); ok && mem.Bandwidth != 0 {
+		if e := rx.SetUp(Bandwidth(mem.Bandwidth)); e != nil {
+			return e
+		}
+	}
+
+	return t.Tune(mem.Frequency)
+}
@@ -0,0 +1,141 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Level rappresenta il livello del segnale misurato da Meter su un frame.
+	Level struct {
+		// RMS è la potenza efficace del frame espressa in dBFS.
+		RMS float64
+
+		// Smoothed è il valore di RMS filtrato secondo le costanti di attack e
+		// decay del Meter, espresso in dBFS. È il valore adatto ad essere
+		// visualizzato come S-meter.
+		Smoothed float64
+
+		// DBm è il valore di Smoothed riportato in dBm. Ha significato solo se
+		// Calibrated è true.
+		DBm float64
+
+		// Calibrated indica se è stata fornita al Meter una calibrazione.
+		Calibrated bool
+	}
+
+	// Meter è un Connector che misura, per ogni frame ricevuto, la potenza del
+	// segnale in banda base e la comunica attraverso la funzione report. Se
+	// presente, il frame viene poi propagato inalterato al connettore out, così
+	// che Meter possa essere inserito in una catena di elaborazione.
+	Meter struct {
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento del segnale espressa in Hz.
+		fs float64
+
+		// attack e decay sono le costanti di tempo usate rispettivamente quando
+		// il livello sale e quando scende.
+		attack, decay time.Duration
+
+		// offset è il valore in dB da sommare al livello in dBFS per ottenere
+		// il livello in dBm.
+		offset float64
+
+		// calibrated indica se offset è stato impostato.
+		calibrated bool
+
+		// level è il livello filtrato attuale in dBFS.
+		level float64
+
+		// primed indica se level è già stato inizializzato dal primo frame.
+		primed bool
+
+		report func(Level)
+		out    Connector
+	}
+)
+
+// NewMeter restituisce un Meter per un segnale campionato con frequenza fs
+// espressa in Hz. Ad ogni frame la misura viene passata a report, mentre il
+// frame stesso viene propagato ad out se non nil.
+// Di default le costanti di tempo sono 10ms in attack e 300ms in decay, valori
+// tipici di uno S-meter.
+func NewMeter(fs float64, out Connector, report func(Level)) *Meter {
+	return &Meter{
+		fs:     fs,
+		attack: 10 * time.Millisecond,
+		decay:  300 * time.Millisecond,
+		report: report,
+		out:    out,
+	}
+}
+
+// Ballistics imposta le costanti di tempo con cui il livello misurato insegue
+// rispettivamente gli aumenti (attack) e le diminuzioni (decay) della potenza
+// del segnale. Un valore nullo rende istantanea la relativa risposta.
+func (m *Meter) Ballistics(attack, decay time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.attack = attack
+	m.decay = decay
+}
+
+// Calibrate imposta l'offset in dB che, sommato al livello in dBFS, fornisce il
+// livello in dBm all'ingresso d'antenna. Tale valore dipende dal guadagno del
+// ricevitore (quindi dal gain reduction impostato) e va misurato con un segnale
+// di riferimento di potenza nota.
+func (m *Meter) Calibrate(offset float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.offset = offset
+	m.calibrated = true
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (m *Meter) Propagate(I []int16, Q []int16) {
+	// Un frame vuoto non porta informazioni sul livello, che resta invariato.
+	if len(I) == 0 || len(Q) == 0 {
+		return
+	}
+
+	rms := dB(power(I, Q))
+
+	m.mu.Lock()
+	if !m.primed {
+		m.level = rms
+		m.primed = true
+	} else {
+		tau := m.decay
+		if rms > m.level {
+			tau = m.attack
+		}
+
+		m.level += smoothing(tau, len(I), m.fs) * (rms - m.level)
+	}
+
+	l := Level{
+		RMS:        rms,
+		Smoothed:   m.level,
+		DBm:        m.level + m.offset,
+		Calibrated: m.calibrated,
+	}
+	m.mu.Unlock()
+
+	if m.report != nil {
+		m.report(l)
+	}
+
+	if m.out != nil {
+		m.out.Propagate(I, Q)
+	}
+}
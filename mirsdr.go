@@ -7,61 +7,47 @@
 
 package sdrplay
 
-/*
-
- #cgo CFLAGS: -I/usr/local/include
- #cgo LDFLAGS: -L/usr/local/lib -lmirsdrapi-rsp
-
- #include "mirsdrapi-rsp.h"
- #include <stdlib.h>
-
- float api_ver = MIR_SDR_API_VERSION;
-
- extern void StreamCallback(short *xi, short *xq, unsigned int firstSampleNum, int grChanged, int rfChanged, int fsChanged, unsigned int numSamples, unsigned int reset, void *cbContext);
-
- extern void AGCCallback(unsigned int grdB, unsigned int lnagrdB, void *cbContext);
-
- // streamCallback è la funzione che viene invocata dall'API SDRplay quando ci
- // sono campioni da processare.
- static inline void streamCallback(short *xi, short *xq, unsigned int firstSampleNum, int grChanged, int rfChanged, int fsChanged, unsigned int numSamples, unsigned int reset, void *cbContext) {
-	StreamCallback(xi, xq, firstSampleNum, grChanged, rfChanged, fsChanged, numSamples, reset, cbContext);
- }
-
- // agcCallback è la funzione che viene invocata dall'API SDRplay quando ci sono
- // delle variazioni di guadagno nel loop di retroazione del AGC.
- static inline void agcCallback(unsigned int grdB, unsigned int lnagrdB, void *cbContext) {
-	AGCCallback(grdB, lnagrdB, cbContext);
- }
-
- // streamInit è la funzione che invoca l'API mir_sdr_StreamInit.
- mir_sdr_ErrT streamInit(int *gRdB, double fsMHz, double rfMHz, mir_sdr_Bw_MHzT bwType, mir_sdr_If_kHzT ifType, int LNAEnable, int *gRdBsystem, int useGrAltMode, int *samplesPerPacket) {
-	return mir_sdr_StreamInit(gRdB, fsMHz, rfMHz, bwType, ifType, LNAEnable, gRdBsystem, useGrAltMode, samplesPerPacket, streamCallback, agcCallback, (void *)NULL);
- }
-*/
-import "C"
-import "log"
-
-// init verifica la versione della libreria, in caso di errore ottenuto dall'API
-// o di non corrispondenza di versione viene sollevato un errore fatale.
-func init() {
-	var vr C.float
-	ev := C.mir_sdr_ApiVersion(&vr)
-	if ev != 0 {
-		log.Fatalf("ApiVersion check Error: %s\n", ev)
-	}
-
-	if C.api_ver != vr {
-		log.Fatalf("API version mismatch! Version is %f\n", vr)
-	}
-}
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 type (
 	// radio mantiene lo stato attuale della RSP.
 	radio struct {
+		// mu serializza le operazioni di controllo della RSP, così che il
+		// ricevitore possa essere usato da più goroutine. smu protegge i campi
+		// letti dalla callback dello stream: baseband, feat, spp, shift,
+		// convert, pool, violations e health vengono modificati solo
+		// possedendo entrambi i lock, e letti possedendone almeno uno. settle
+		// viene invece modificato anche dalla callback, possedendo solo smu.
+		mu  sync.Mutex
+		smu sync.Mutex
+
+		// cmu serializza le invocazioni di Close e inflight conta i frame in
+		// corso di propagazione.
+		cmu      sync.Mutex
+		inflight sync.WaitGroup
+
+		// tmu serializza le attivazioni del Trigger, che avvengono senza
+		// possedere mu.
+		tmu sync.Mutex
+
+		// streaming indica che lo stream è stato avviato con StreamInit e non
+		// ancora fermato.
+		streaming bool
+
 		// baseband è il connettore dal quale viene propagato il segnale in banda
 		// base ricevuto dalla RSP.
 		baseband Connector
 
+		// ring, se non nil, è il buffer impostato con l'opzione Buffering
+		// attraverso il quale i frame raggiungono il Connector fornito a RSP.
+		ring *Ring
+
 		// rf è la frequenza attualmente sintonizzata espressa in Hz.
 		rf float64
 
@@ -70,19 +56,72 @@ type (
 		band int
 
 		// gr è l'attuale valore di gain reduction
-		gr *C.int
+		gr integer
 
-		// grsys è il valore del gain reduction del sistema se useGrAltMode == 1
-		grsys *C.int
+		// grsys è il valore del gain reduction del sistema
+		grsys integer
 
-		// spp è il valore di samples per packet
-		spp *C.int
+		// lnagr è il gain reduction dovuto all'LNA riportato dall'ultima
+		// callback di variazione del guadagno. È aggiornato dalla callback e
+		// letto senza lock.
+		lnagr atomic.Int32
 
-		// useGrAltMode ha lo stesso significato dell'API
-		useGrAltMode C.int
+		// spp è il valore di samples per packet
+		spp integer
 
 		// feat contiene le caratteristiche attualmente impostate nella radio.
 		feat features
+
+		// hw descrive la RSP usata, rilevata da RSP prima dell'avvio dello
+		// stream e non più modificata.
+		hw Hardware
+
+		// last è l'istante, in nanosecondi Unix, dell'ultima invocazione della
+		// callback e hwErrors il numero di HwError consecutivi restituiti
+		// dall'API. removal e removalDone fermano ed attendono la goroutine
+		// che rileva la disconnessione, gone indica se è già stata notificata.
+		last        atomic.Int64
+		hwErrors    int
+		removal     chan struct{}
+		removalDone chan struct{}
+		gone        atomic.Bool
+
+		// watchdog e watchdogDone fermano ed attendono il watchdog dello
+		// stream.
+		watchdog     chan struct{}
+		watchdogDone chan struct{}
+
+		// health accumula le statistiche per OnHealth, protetto da smu, e
+		// healthStop ne ferma la notifica.
+		health     *healthMeter
+		healthStop chan struct{}
+
+		// selected indica che la RSP è stata selezionata esplicitamente,
+		// riaprendola dopo una disconnessione, e va quindi rilasciata.
+		selected bool
+
+		// antenna è la porta attualmente selezionata sul commutatore d'antenna
+		// esterno, -1 se non ancora selezionata.
+		antenna int
+
+		// pool contiene i buffer preallocati dei frame e violations la coda
+		// delle notifiche di ritardo in modalità a bassa latenza.
+		pool       *framePool
+		violations chan LatencyViolation
+
+		// shift, se non nil, trasla il segnale in banda base per compensare
+		// l'offset di sintonia.
+		shift *shifter
+
+		// convert, se non nil, ricampiona il segnale in banda base alla
+		// frequenza richiesta con OutputRate.
+		convert *rateConverter
+
+		// settle, se non nil, è l'assestamento atteso da TuneAndWait.
+		settle *settling
+
+		// stats contiene le statistiche dello stream riportate nel log.
+		stats streamStats
 	}
 
 	// enable è un alias di bool introdotto solo per avere una sintassi più
@@ -114,280 +153,698 @@ type (
 		InitialGR   integer
 		InitialRF   double
 		Debug       enable
+		Antenna     antennas
+		Trigger     Trigger
+		Logger      *slog.Logger
+		Version     versionPolicy
+		Latency     latency
+		Buffering   time.Duration
+		Recycle     int
+		Inline      bool
+		Ramp        ramp
+		Transfer    TransferMode
+		Removed     func(DeviceRemoved)
+		Reconnect   bool
+		Reconnected func(Reconnect)
+		Watchdog    watchdog
+		Health      func(Health)
+		BiasT       enable
+		Broadcast   enable
+		DABNotch    enable
+		Unsupported FeatureCheck
+		Duo         DuoMode
+		HiZ         enable
+		Input       Input
+		Normalize   bool
+		AutoIF      bool
+		Channel     double
+		Output      double
+		DCChannel   double
+		SyncPeriod  uint32
+		Step        double
+		TuneOffset  double
 	}
 )
 
 var (
-	// fm102MHz è una configurazione di default che serve nel caso venga invocata
-	// la funzione RSP senza alcun parametro di opzione. In particolare la RSP
-	// viene impostata per:
-	//   * Sintonizzarsi sulla frequenza 102.0 MHz
-	//   * Campionare il segnale IF con una FS pari a 2.048 MHz
-	//   * Usare una larghezza di banda pari a 1536 kHz
-	//   * Usare una IF di 0
-	//   * Impostare il modo automatico di gestione della frequenza del up-converter
-	fm102MHz = []Option{
-		InitialRF(102),
-		FS(2.048),
-		Bandwidth(BW1536),
-		IF(IFzero),
-		LOmode(LOauto),
-	}
+	// defaults è la configurazione di default che serve nel caso venga
+	// invocata la funzione RSP senza alcun parametro di opzione: la RSP viene
+	// impostata con il preset della banda FMBroadcast e sintonizzata sulla
+	// frequenza 102.0 MHz.
+	defaults = FMBroadcast.PresetAt(102e6)
 )
 
 var (
-	// rsp contiene i valori attuali dei parametri configurati sulla RSP.
+	// rsp è la configurazione sulla quale agiscono le opzioni. Viene usata
+	// solo da configured e features.with, sotto optMu; la configurazione
+	// attuale della RSP è in rx.feat.
 	rsp features
 
+	// optMu serializza l'applicazione delle opzioni.
+	optMu sync.Mutex
+
+	// rxMu serializza la creazione e la disattivazione di rx.
+	rxMu sync.Mutex
+
 	// rx è l'oggetto che rappresenta sempre lo stato attuale della RSP. rx è
 	// globale perchè rappresenta un'unica unità RSP.
 	rx *radio
 )
 
-// newRadio inizializza i puntatori di rx e delle variabili puntatore di radio.
+// newRadio inizializza rx.
 func newRadio() {
 	rx = new(radio)
 
-	rx.gr = new(C.int)
-	rx.grsys = new(C.int)
-	rx.spp = new(C.int)
+	rx.antenna = -1
 }
 
 // Tune implementa l'interfaccia Tuner.
 func (r *radio) Tune(frequency float64) error {
+	r.mu.Lock()
+
+	return r.retuned(r.tune(frequency), frequency)
+}
+
+// tune sintonizza la RSP sulla frequenza espressa in Hz, senza attivare il
+// Trigger. Va invocata possedendo r.mu.
+func (r *radio) tune(frequency float64) error {
 	if r.baseband == nil {
 		return DeactivatedReceiverError
 	}
 
-	nb := band(frequency)
+	if e := r.hw.checkInput(r.feat.Input, frequency); e != nil {
+		return e
+	}
+
+	if e := r.switchAntenna(frequency); e != nil {
+		return e
+	}
+
+	rfMHz := r.hardware(frequency)
+
+	nb := band(float64(rfMHz) * 1e6)
 	if nb == r.band {
-		return toError(C.mir_sdr_SetRf(double(frequency).C(), 1, 0))
+		if e := r.failed(api.setRf(rfMHz * 1e6)); e != nil {
+			return e
+		}
+
+		r.rf = frequency
+
+		return nil
 	}
 
 	r.band = nb
 
-	var reason C.mir_sdr_ReasonForReinitT = C.mir_sdr_CHANGE_RF_FREQ
-	var rfMHz = double(frequency / 1.0e6)
+	r.logger().Debug("rsp reinit", "reason", changeRF, "rf", frequency, "band", nb)
 
-	return toError(C.mir_sdr_Reinit(nil, 0, rfMHz.C(), 0, 0, 0, 0, nil, 0, nil, reason))
+	if e := r.failed(api.reinit(&streamParams{rf: rfMHz}, changeRF)); e != nil {
+		r.logger().Error("rsp reinit", "reason", changeRF, "err", e)
+		return e
+	}
+
+	r.rf = frequency
+
+	return nil
 }
 
 // Gain implementa l'intarfaccia Amplifier.
 func (r *radio) Gain(reduction int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if r.baseband == nil {
 		return DeactivatedReceiverError
 	}
 
-	*r.gr = integer(reduction).C()
+	return r.setGain(integer(reduction))
+}
+
+// SetDriverParam implementa l'interfaccia DeviceControl.
+func (r *radio) SetDriverParam(id, value uint32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.baseband == nil {
+		return DeactivatedReceiverError
+	}
 
-	return toError(C.mir_sdr_SetGrAltMode(r.gr, C.int(r.feat.LNA.C()), r.grsys, 1, 0))
+	r.logger().Debug("rsp driver param", "id", id, "value", value)
+
+	return r.failed(api.setParam(id, value))
+}
+
+// SetPPM implementa l'interfaccia DeviceControl.
+func (r *radio) SetPPM(ppm float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.baseband == nil {
+		return DeactivatedReceiverError
+	}
+
+	r.logger().Debug("rsp ppm", "ppm", ppm)
+
+	if e := r.failed(api.setPpm(double(ppm))); e != nil {
+		return e
+	}
+
+	r.smu.Lock()
+	r.feat.LOppm = double(ppm)
+	r.smu.Unlock()
+
+	return nil
 }
 
 // SetUp implementa l'ultimo metodo dell'interfaccia Receiver così rende radio
 // un Receiver.
 func (r *radio) SetUp(opts ...Option) error {
+	r.mu.Lock()
+
+	retuned, e := r.setUp(opts...)
+	if !retuned {
+		r.mu.Unlock()
+		return e
+	}
+
+	return r.retuned(nil, r.rf)
+}
+
+// setUp applica le opzioni opts alla RSP ed indica se è stata sintonizzata
+// una nuova frequenza. Va invocata possedendo r.mu.
+func (r *radio) setUp(opts ...Option) (bool, error) {
 	if r.baseband == nil {
-		return DeactivatedReceiverError
+		return false, DeactivatedReceiverError
+	}
+
+	// Le opzioni vengono verificate prima di modificare la RSP: in caso di
+	// errore la configurazione resta invariata.
+	f := r.feat.with(opts...)
+
+	if e := validate(f); e != nil {
+		return false, e
+	}
+
+	if e := r.hw.validate(f); e != nil {
+		return false, e
+	}
+
+	f, e := r.hw.support(f)
+	if e != nil {
+		return false, e
 	}
 
-	configure(opts...)
+	if f.Duo != r.feat.Duo {
+		return false, &OptionError{Option: "Duo", Reason: "the RSPduo mode is chosen when opening the receiver"}
+	}
+
+	if f.DCmode != r.feat.DCmode && f.DCmode != None {
+		api.setDcMode(f.DCmode, f.DCTrakTime)
+	}
 
-	if rsp.DCmode != r.feat.DCmode && rsp.DCmode != None {
-		C.mir_sdr_SetDcMode(rsp.DCmode.C(), 0)
-		C.mir_sdr_SetDcTrackTime(rsp.DCTrakTime.C())
+	// Una correzione nulla va comunque impostata, per eliminare quella
+	// precedente.
+	if f.LOppm != r.feat.LOppm {
+		if e := r.failed(api.setPpm(f.LOppm)); e != nil {
+			return false, e
+		}
 	}
 
-	if rsp.LOppm != r.feat.LOppm && rsp.LOppm != 0.0 {
-		C.mir_sdr_SetPpm(rsp.LOppm.C())
+	// L'AGC viene aggiornato immediatamente, senza reinizializzare la RSP.
+	if f.AGC != r.feat.AGC || f.DBFS != r.feat.DBFS {
+		api.agcControl(f.AGC, f.DBFS, f.LNA)
+		r.logger().Info("rsp agc", "mode", f.AGC, "dbfs", int(f.DBFS))
 	}
 
-	var reason C.mir_sdr_ReasonForReinitT = C.mir_sdr_CHANGE_NONE
+	// Decimazione, correzioni di DC offset e IQ imbalance e debug vengono
+	// aggiornati durante lo stream, senza reinizializzare la RSP.
+	if f.Decimate != r.feat.Decimate || f.Factor != r.feat.Factor {
+		api.decimateControl(f.Decimate, f.Factor)
+	}
 
-	if rsp.InitialGR != r.feat.InitialGR || rsp.LNA != r.feat.LNA {
-		reason |= C.mir_sdr_CHANGE_GR
+	if f.DCoffset != r.feat.DCoffset || f.IQimbalance != r.feat.IQimbalance {
+		api.dcOffsetIQimbalance(f.DCoffset, f.IQimbalance)
 	}
 
-	if rsp.FS != r.feat.FS {
-		reason |= C.mir_sdr_CHANGE_FS_FREQ
+	if f.Debug != r.feat.Debug {
+		api.debugEnable(f.Debug)
 	}
 
-	if rsp.InitialRF != r.feat.InitialRF {
-		reason |= C.mir_sdr_CHANGE_RF_FREQ
+	reason := changeNone
+
+	if f.InitialGR != r.feat.InitialGR || f.LNA != r.feat.LNA {
+		reason |= changeGR
 	}
 
-	if rsp.BW != r.feat.BW {
-		reason |= C.mir_sdr_CHANGE_BW_TYPE
+	if f.FS != r.feat.FS {
+		reason |= changeFS
 	}
 
-	if rsp.IF != r.feat.IF {
-		reason |= C.mir_sdr_CHANGE_IF_TYPE
+	if f.InitialRF != r.feat.InitialRF {
+		reason |= changeRF
+		r.rf = float64(f.InitialRF) * 1e6
 	}
 
-	if rsp.LOmode != r.feat.LOmode {
-		reason |= C.mir_sdr_CHANGE_LO_MODE
+	if f.TuneOffset != r.feat.TuneOffset {
+		reason |= changeRF
 	}
 
-	r.feat = rsp
+	if f.BW != r.feat.BW {
+		reason |= changeBW
+	}
 
-	if reason != C.mir_sdr_CHANGE_NONE {
-		*r.gr = r.feat.InitialGR.C()
-		*r.grsys = 0
-		*r.spp = 0
-		r.useGrAltMode = 1
+	if f.IF != r.feat.IF {
+		reason |= changeIF
+	}
 
-		return toError(C.mir_sdr_Reinit(r.gr, r.feat.FS.C(), r.feat.InitialRF.C(), r.feat.BW.C(), r.feat.IF.C(), r.feat.LOmode.C(), C.int(r.feat.LNA.C()), r.grsys, r.useGrAltMode, r.spp, reason))
+	if f.LOmode != r.feat.LOmode {
+		reason |= changeLO
 	}
 
+	if f.HiZ != r.feat.HiZ {
+		reason |= changeAMPort
+	}
+
+	transfer := f.Transfer != r.feat.Transfer
+	prev := r.feat
+
+	r.smu.Lock()
+	r.feat = f
+	r.smu.Unlock()
+
+	r.setShift()
+	r.setConverter()
+
+	if e := r.failed(r.setFrontEnd(prev)); e != nil {
+		return false, e
+	}
+
+	if f.SyncPeriod != prev.SyncPeriod {
+		if e := r.syncPeriod(); e != nil {
+			return false, e
+		}
+	}
+
+	if reason&changeRF != 0 {
+		if e := r.switchAntenna(r.rf); e != nil {
+			return false, e
+		}
+	}
+
+	if transfer {
+		// La modalità di trasferimento si imposta solo a stream fermo: il
+		// riavvio applica anche le altre variazioni.
+		p := r.params()
+		r.band = band(float64(p.rf) * 1e6)
+
+		r.logger().Info("rsp transfer mode", "mode", f.Transfer)
+
+		if e := r.restart(p); e != nil {
+			return false, e
+		}
+
+		r.update(p)
+	} else if e := r.reconfigure(reason); e != nil {
+		return false, e
+	}
+
+	return reason&changeRF != 0, nil
+}
+
+// reconfigure applica alla RSP in streaming le variazioni indicate da reason,
+// scegliendo l'operazione meno invasiva: la frequenza entro la stessa banda ed
+// il gain reduction vengono impostati direttamente, le altre variazioni con
+// una sola Reinit che riporta i soli motivi necessari. Solo se la Reinit
+// fallisce lo stream viene riavviato; durante le altre operazioni il
+// Connector continua a ricevere i campioni.
+func (r *radio) reconfigure(reason reinitReason) error {
+	p := r.params()
+
+	// Un cambio di frequenza entro la stessa banda che non accompagna altre
+	// variazioni da applicare con la Reinit non richiede la Reinit.
+	if reason&changeRF != 0 {
+		nb := band(float64(p.rf) * 1e6)
+		if nb == r.band && reason&^(changeRF|changeGR) == 0 {
+			if e := r.failed(api.setRf(p.rf * 1e6)); e != nil {
+				return e
+			}
+			reason &^= changeRF
+		}
+		r.band = nb
+	}
+
+	if reason == changeGR {
+		grsys, e := api.setGr(p.gr, p.lna)
+		if e := r.failed(e); e != nil {
+			return e
+		}
+
+		r.gr, r.grsys = p.gr, grsys
+
+		return nil
+	}
+
+	if reason == changeNone {
+		return nil
+	}
+
+	r.logger().Info("rsp reinit",
+		"reason", reason,
+		"rf", r.rf,
+		"fs", float64(p.fs)*1e6,
+		"bw", p.bw,
+		"if", p.ifm,
+		"lo", p.lo,
+		"lna", bool(p.lna),
+		"gr", int(p.gr),
+	)
+
+	if e := r.failed(api.reinit(p, reason)); e != nil {
+		r.logger().Warn("rsp reinit failed, restarting stream", "reason", reason, "err", e)
+
+		if e := r.restart(p); e != nil {
+			return e
+		}
+	}
+
+	r.update(p)
+
 	return nil
 }
 
-// init inizializza RSP e abilita lo Stream dei campioni in banda base.
-func (r *radio) init() error {
-	*r.gr = r.feat.InitialGR.C()
-	*r.grsys = 0
-	*r.spp = 0
-	r.useGrAltMode = 1
+// restart riavvia lo stream con i parametri p, quando non è possibile
+// applicarli con una Reinit, impostando a stream fermo la modalità di
+// trasferimento.
+func (r *radio) restart(p *streamParams) error {
+	api.streamUninit()
+	r.streaming = false
+
+	if e := api.setTransferMode(r.feat.Transfer); e != nil {
+		r.logger().Error("rsp transfer mode", "err", e)
+		return e
+	}
+
+	if e := r.failed(api.streamInit(p)); e != nil {
+		r.logger().Error("rsp restart", "err", e)
+		return e
+	}
+	r.streaming = true
+
+	return nil
+}
 
+// init inizializza RSP e abilita lo Stream dei campioni in banda base. Il
+// Trigger per la frequenza iniziale va attivato dal chiamante, con started.
+func (r *radio) init() error {
 	// Si abilita o meno il debugging. Non esegue controllo di errore.
-	C.mir_sdr_DebugEnable(r.feat.Debug.C())
+	api.debugEnable(r.feat.Debug)
 
 	// Si abilitano o meno DC offset e IQ imbalance. Non esegue controllo di
 	// errore.
-	C.mir_sdr_DCoffsetIQimbalanceControl(r.feat.DCoffset.C(), r.feat.IQimbalance.C())
+	api.dcOffsetIQimbalance(r.feat.DCoffset, r.feat.IQimbalance)
 
 	// Imposta il fattore di decimazione se presente. Non esegue controllo di
 	// errore.
-	C.mir_sdr_DecimateControl(r.feat.Decimate.C(), r.feat.Factor.C(), 0)
+	api.decimateControl(r.feat.Decimate, r.feat.Factor)
 
 	// Imposta l'AGC: attualmente impone aggiornamento immediato. Non esegue
 	// controllo di errore.
-	C.mir_sdr_AgcControl(r.feat.AGC.C(), r.feat.DBFS.C(), 0, 0, 0, 0, C.int(r.feat.LNA.C()))
+	api.agcControl(r.feat.AGC, r.feat.DBFS, r.feat.LNA)
 
 	// Imposta il DC offset mode ed il relativo track time se è stato impostato
 	// un DC mode. Non è chiaro dalla documentazione SDRplay se questo valore
 	// venga ingnorato nel caso DC offset non sia abilitato, ma penso proprio che
 	// sia così.
 	if r.feat.DCmode != None {
-		C.mir_sdr_SetDcMode(rsp.DCmode.C(), 0)
-		C.mir_sdr_SetDcTrackTime(rsp.DCTrakTime.C())
+		api.setDcMode(r.feat.DCmode, r.feat.DCTrakTime)
 	}
 
 	// Imposta il valore, in parti per milione, del fattore di correzione della
-	// frequenza dell'OL della RSP.
-	if r.feat.LOppm != 0.0 {
-		C.mir_sdr_SetPpm(r.feat.LOppm.C())
+	// frequenza dell'OL della RSP, anche se nullo, così da eliminare quello
+	// impostato da un ricevitore precedente.
+	if e := api.setPpm(r.feat.LOppm); e != nil {
+		r.logger().Error("rsp ppm", "err", e)
+		return e
 	}
 
 	// Imposta il modo di funzionamento del up-converter.
 	if r.feat.LOmode != LOundefined {
-		C.mir_sdr_SetLoMode(r.feat.LOmode.C())
+		api.setLoMode(r.feat.LOmode)
 	}
 
-	dump()
+	// Imposta bias-T e notch, se abilitati.
+	if e := r.setFrontEnd(features{}); e != nil {
+		r.logger().Error("rsp front end", "err", e)
+		return e
+	}
 
-	// LNA è di tipo enable, ma a differenza di tutti gli altri valori che permettono
-	// di abilitare una particolare caratteristica che sono di tipo unsigned int,
-	// questo è di tipo int. Per questo motivo è necessario il cast a C.int.
-	return toError(C.streamInit(r.gr, r.feat.FS.C(), r.feat.InitialRF.C(), r.feat.BW.C(), r.feat.IF.C(), C.int(r.feat.LNA.C()), r.grsys, r.useGrAltMode, r.spp))
-}
+	// Seleziona l'antenna esterna adatta alla frequenza iniziale.
+	if e := r.switchAntenna(float64(r.feat.InitialRF) * 1e6); e != nil {
+		return e
+	}
 
-// uninit ferma lo Stream ed esegue un reset dell'API.
-func (r *radio) uninit() error {
-	return C.mir_sdr_StreamUninit()
-}
+	r.rf = float64(r.feat.InitialRF) * 1e6
+	r.setShift()
+	r.setConverter()
 
-// dump mostra su stdout lo stato interno.
-func dump() {
-	msg := `
---------------------------------------------------------------------------------
+	p := r.params()
+	r.band = band(float64(p.rf) * 1e6)
 
-	Radio
-		%+v
+	// La modalità della RSPduo va impostata prima di StreamInit.
+	if e := api.setDuoMode(r.feat.Duo); e != nil {
+		r.logger().Error("rsp duo mode", "err", e)
+		return e
+	}
 
+	// La modalità di trasferimento va impostata prima di StreamInit, anche
+	// quando è quella di default, perché l'API conserva quella di uno stream
+	// precedente.
+	if e := api.setTransferMode(r.feat.Transfer); e != nil {
+		r.logger().Error("rsp transfer mode", "err", e)
+		return e
+	}
 
-	Features
-		%+v
+	now := time.Now()
+	r.stats = streamStats{since: now, reported: now}
 
---------------------------------------------------------------------------------
-	`
+	if e := api.streamInit(p); e != nil {
+		r.logger().Error("rsp init", "err", e)
+		return e
+	}
+	r.streaming = true
 
-	log.Printf(msg, *rx, rsp)
-}
+	r.update(p)
 
-// errDesc mappa i codice di errore delle API SDRplay con le relative descrizioni.
-var errDesc = [...]string{
-	C.mir_sdr_Success:            "Success",
-	C.mir_sdr_Fail:               "Fail",
-	C.mir_sdr_InvalidParam:       "Invalid Param",
-	C.mir_sdr_OutOfRange:         "Out of Range",
-	C.mir_sdr_GainUpdateError:    "Gain Update error",
-	C.mir_sdr_RfUpdateError:      "RF Update error",
-	C.mir_sdr_FsUpdateError:      "FS Update error",
-	C.mir_sdr_HwError:            "HW error",
-	C.mir_sdr_AliasingError:      "Aliasing error",
-	C.mir_sdr_AlreadyInitialised: "Already Initialised",
-	C.mir_sdr_NotInitialised:     "Not Initialised",
-}
+	if e := r.syncPeriod(); e != nil {
+		return e
+	}
 
-func (e C.mir_sdr_ErrT) Error() string {
-	return errDesc[e]
+	r.logInit()
+
+	r.startLatency()
+	r.startRemoval()
+	r.startWatchdog()
+	r.startHealth()
+
+	return nil
 }
 
-func toError(e C.mir_sdr_ErrT) error {
-	if e == C.mir_sdr_Success {
-		return nil
+// uninit ferma lo Stream ed esegue un reset dell'API.
+func (r *radio) uninit() error {
+	// Lo stream va fermato prima di terminare la notifica delle violazioni,
+	// perché la callback potrebbe ancora produrne. Se lo stream non è mai
+	// stato avviato, o un riavvio non è riuscito, l'API riporta che non è
+	// inizializzato: non è un errore.
+	e := api.streamUninit()
+	if !r.streaming && errors.Is(e, NotInitialisedError) {
+		e = nil
 	}
+	r.streaming = false
+
+	r.stopLatency()
+	r.stopRemoval()
+	r.stopWatchdog()
+	r.stopHealth()
+
+	if r.selected {
+		api.releaseDevice()
+		r.selected = false
+	}
+	r.logStats(slog.LevelInfo, "rsp stream stopped")
 
 	return e
 }
 
-// C traduce il valore di e nel formato compreso dall'API SDRplay.
-func (e enable) C() C.uint {
-	if e {
-		return 1
+// Close implementa l'interfaccia Receiver fermando la RSP con la sequenza:
+// arresto della propagazione, attesa dei frame in corso di consegna,
+// StreamUninit, rilascio delle risorse dello stream e, con l'opzione
+// Buffering, propagazione dei campioni rimasti nel buffer. Al ritorno di Close il
+// Connector non riceverà altri frame e i metodi del ricevitore restituiranno
+// DeactivatedReceiverError. Viene restituito l'eventuale errore di
+// StreamUninit, salvo NotInitialisedError se lo stream non era avviato; le
+// invocazioni successive non hanno effetto e restituiscono nil. Close non va
+// invocato dal Connector durante la propagazione.
+func (r *radio) Close() error {
+	r.cmu.Lock()
+	defer r.cmu.Unlock()
+
+	if !r.active() {
+		return nil
 	}
 
-	return 0
-}
+	// Da questo momento la callback scarta i frame e i metodi restituiscono
+	// DeactivatedReceiverError.
+	r.mu.Lock()
+	r.smu.Lock()
+	r.baseband = nil
+	r.settled(DeactivatedReceiverError)
+	r.smu.Unlock()
+	r.mu.Unlock()
+
+	// mu non viene trattenuto durante l'attesa, perché il Connector potrebbe
+	// invocare i metodi del ricevitore.
+	r.inflight.Wait()
+
+	r.mu.Lock()
+	e := r.uninit()
+	r.mu.Unlock()
+
+	// Come per i frame in corso, mu non viene trattenuto mentre il buffer
+	// propaga i campioni rimasti.
+	if r.ring != nil {
+		r.ring.Close()
+	}
 
-// C traduce il valore di d nel formato compreso dall'API SDRplay.
-func (d double) C() C.double {
-	return C.double(d)
+	return e
 }
 
-// C traduce il valore di i nel formato compreso dall'API SDRplay.
-func (i integer) C() C.int {
-	return C.int(i)
-}
+// active indica se il ricevitore è ancora attivo.
+func (r *radio) active() bool {
+	r.smu.Lock()
+	defer r.smu.Unlock()
 
-// C traduce il valore di bw nel formato compreso dall'API SDRplay.
-func (bw B) C() C.mir_sdr_Bw_MHzT {
-	return C.mir_sdr_Bw_MHzT(bw)
+	return r.baseband != nil
 }
 
-// C traduce il valore di ifm nel formato compreso dall'API SDRplay.
-func (ifm IFmode) C() C.mir_sdr_If_kHzT {
-	return C.mir_sdr_If_kHzT(ifm)
-}
+// update memorizza i valori di gain reduction e di campioni per pacchetto
+// restituiti dall'API all'avvio o alla reinizializzazione dello stream.
+func (r *radio) update(p *streamParams) {
+	r.gr, r.grsys = p.gr, p.grsys
+
+	r.smu.Lock()
+	defer r.smu.Unlock()
+
+	// Se i pacchetti sono cresciuti, i buffer preallocati vengono sostituiti
+	// per non tornare ad allocare ogni frame.
+	if r.pool != nil && p.spp > r.spp {
+		r.pool = newFramePool(int(p.spp), len(r.pool.i))
+	}
 
-// C traduce il valore di om nel formato compreso dall'API SDRplay.
-func (om OffsetMode) C() C.int {
-	return C.int(om - 1)
+	r.spp = p.spp
 }
 
-// C traduce il valore di olf nel formato compreso dall'API SDRplay.
-func (olf LOfrequency) C() C.mir_sdr_LoModeT {
-	return C.mir_sdr_LoModeT(olf)
+// params restituisce i parametri dello stream corrispondenti alla
+// configurazione attuale.
+func (r *radio) params() *streamParams {
+	return &streamParams{
+		gr:  r.feat.InitialGR,
+		fs:  r.feat.FS,
+		rf:  r.hardware(r.rf),
+		bw:  r.feat.BW,
+		ifm: r.feat.IF,
+		lo:  r.feat.LOmode,
+		lna: r.feat.LNA,
+	}
 }
 
-// C traduce il valore di df nel formato compreso dall'API SDRplay.
-func (df Decimation) C() C.uint {
-	return C.uint(df)
+// stream propaga il frame di campioni xi e xq ricevuto dal driver. I campioni
+// vengono copiati, perché i buffer appartengono al driver. changed indica che
+// il frame segue una variazione di guadagno o di frequenza di campionamento o
+// un reset, nel qual caso viene scartato.
+func (r *radio) stream(xi, xq []int16, first uint32, changed bool) {
+	now := time.Now()
+	prev := r.last.Swap(now.UnixNano())
+
+	// I campi condivisi vengono copiati senza trattenere smu durante la
+	// propagazione, così che il Connector possa invocare i metodi del
+	// ricevitore.
+	r.smu.Lock()
+	baseband, shift, convert, pool := r.baseband, r.shift, r.convert, r.pool
+	bound, violations := r.feat.Latency.bound, r.violations
+	inline := r.feat.Inline
+	health := r.health
+	var bits int
+	if r.feat.Normalize {
+		bits = r.hw.ADCBits(float64(r.feat.FS) * 1e6)
+	}
+	spp := int(r.spp)
+	var skip int
+	if baseband != nil {
+		r.inflight.Add(1)
+		if !changed {
+			skip = r.discard(len(xi))
+		}
+	}
+	r.smu.Unlock()
+
+	if baseband == nil {
+		return
+	}
+	defer r.inflight.Done()
+
+	if health != nil {
+		health.add(now, time.Duration(now.UnixNano()-prev), len(xi))
+	}
+
+	r.count(len(xi), changed)
+	if changed || skip == len(xi) {
+		return
+	}
+	xi, xq = xi[skip:], xq[skip:]
+	first += uint32(skip)
+
+	var start time.Time
+	if bound > 0 {
+		start = time.Now()
+	}
+
+	n := len(xi)
+	i, q := xi, xq
+	if !inline {
+		i, q = pool.frame(n)
+		copy(i, xi)
+		copy(q, xq)
+	}
+
+	normalize(i, q, bits)
+
+	if shift != nil {
+		shift.apply(i, q)
+	}
+
+	if convert != nil {
+		if i, q = convert.apply(i, q); len(i) == 0 {
+			return
+		}
+	}
+
+	if pc, ok := baseband.(PacketConnector); ok {
+		pc.PropagatePacket(PacketInfo{SamplesPerPacket: spp, FirstSample: first, LNAGainReduction: int(r.lnagr.Load()), Time: time.Now()}, i, q)
+	} else {
+		baseband.Propagate(i, q)
+	}
+
+	if bound > 0 {
+		checkLatency(violations, bound, time.Since(start), n)
+	}
 }
 
-// C traduce il valore di agc nel formato compreso dall'API SDRplay.
-func (agc AGCmode) C() C.mir_sdr_AgcControlT {
-	return C.mir_sdr_AgcControlT(agc)
+// gainChanged registra la variazione del guadagno della RSP riportata
+// dall'API, dovuta all'AGC o ad un'impostazione del gain reduction: gr è il
+// gain reduction complessivo e lnagr quello dovuto all'LNA, in dB.
+func (r *radio) gainChanged(gr, lnagr int) {
+	r.lnagr.Store(int32(lnagr))
+
+	r.logger().Debug("rsp agc callback", "grdB", gr, "lnagrdB", lnagr)
 }
 
 // configure permette di configurare la RSP.
@@ -398,29 +855,3 @@ func configure(opts ...Option) {
 		}
 	}
 }
-
-// band restituisce un valore che rappresenta una delle bande, come definite nel
-// tipo mir_sdr_BandT dell'API, in cui ricade la frequenza passata come parametro
-// f.
-func band(f float64) int {
-	switch {
-	case f < 12e6:
-		return C.mir_sdr_BAND_AM_LO
-	case 12e6 <= f && f < 30e6:
-		return C.mir_sdr_BAND_AM_MID
-	case 30e6 <= f && f < 60e6:
-		return C.mir_sdr_BAND_AM_HI
-	case 60e6 <= f && f < 120e6:
-		return C.mir_sdr_BAND_VHF
-	case 120e6 <= f && f < 250e6:
-		return C.mir_sdr_BAND_3
-	case 250e6 <= f && f < 420e6:
-		return C.mir_sdr_BAND_X
-	case 420e6 <= f && f < 1000e6:
-		return C.mir_sdr_BAND_4_5
-	case 1000e6 <= f && f < 2000e6:
-		return C.mir_sdr_BAND_L
-	default:
-		return -1
-	}
-}
@@ -0,0 +1,312 @@
+//go:build nosdr || mock
+
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iclac/sdrplay"
+	"github.com/iclac/sdrplay/sdrplaytest"
+)
+
+// TestRSPInitFailure verifica che un ricevitore il cui stream non parte venga
+// chiuso, così che le invocazioni successive di RSP non falliscano.
+func TestRSPInitFailure(t *testing.T) {
+	rx, e := sdrplay.RSP(sdrplaytest.NewCounter())
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	sdrplay.MockUnplug()
+
+	failed, e := sdrplay.RSP(sdrplaytest.NewCounter())
+	sdrplay.MockReplug()
+	if !errors.Is(e, sdrplay.HwError) {
+		t.Fatalf("RSP with the RSP unplugged: got %v, want HwError", e)
+	}
+
+	if e := failed.Tune(100e6); e != sdrplay.DeactivatedReceiverError {
+		t.Errorf("Tune on the failed receiver: got %v, want DeactivatedReceiverError", e)
+	}
+
+	if e := failed.Close(); e != nil {
+		t.Errorf("Close on the failed receiver: %v", e)
+	}
+
+	if e := rx.Tune(100e6); e != sdrplay.DeactivatedReceiverError {
+		t.Errorf("Tune on the replaced receiver: got %v, want DeactivatedReceiverError", e)
+	}
+
+	rx, e = sdrplay.RSP(sdrplaytest.NewCounter())
+	if e != nil {
+		t.Fatalf("RSP after the failed init: %v", e)
+	}
+
+	if e := rx.Close(); e != nil {
+		t.Errorf("Close: %v", e)
+	}
+}
+
+// TestRSPStream verifica che il ricevitore simulato propaghi i campioni al
+// ritmo della frequenza di campionamento decimata e smetta di farlo con Close.
+func TestRSPStream(t *testing.T) {
+	c := sdrplaytest.NewCounter()
+
+	rx, e := sdrplay.RSP(c, sdrplay.FS(2), sdrplay.Bandwidth(sdrplay.BW200), sdrplay.Decimate(true, sdrplay.Factor4))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	fs := rx.State().OutputRate
+	if fs >= 2e6 {
+		t.Errorf("State().OutputRate = %g, want the decimated rate", fs)
+	}
+
+	sdrplaytest.AssertSamples(t, c, int(0.2*fs))
+	sdrplaytest.AssertRate(t, c, fs, 0.1)
+
+	if e := rx.Close(); e != nil {
+		t.Fatalf("Close: %v", e)
+	}
+
+	n := c.Samples()
+	time.Sleep(50 * time.Millisecond)
+	if c.Samples() != n {
+		t.Errorf("received %d samples after Close", c.Samples()-n)
+	}
+
+	if e := rx.Close(); e != nil {
+		t.Errorf("second Close: %v", e)
+	}
+
+	if e := rx.Gain(30); e != sdrplay.DeactivatedReceiverError {
+		t.Errorf("Gain after Close: got %v, want DeactivatedReceiverError", e)
+	}
+}
+
+// TestRSPReconfigure verifica che sintonia e opzioni modificate durante lo
+// stream, con o senza Reinit, siano riportate da State, attivino il Trigger
+// e non interrompano i campioni.
+func TestRSPReconfigure(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		fired []float64
+	)
+
+	c := sdrplaytest.NewCounter()
+
+	var rx sdrplay.Receiver
+	trigger := sdrplay.TriggerFunc(func(f float64) error {
+		// Il Trigger viene attivato senza bloccare il ricevitore.
+		if rx != nil {
+			rx.State()
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		fired = append(fired, f)
+
+		return nil
+	})
+
+	rx, e := sdrplay.RSP(c, sdrplay.FMBroadcast.PresetAt(100e6)...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer rx.Close()
+
+	if e := rx.SetUp(sdrplay.OnRetune(trigger)); e != nil {
+		t.Fatal(e)
+	}
+
+	// Nella stessa banda e, con Reinit, in un'altra.
+	for _, f := range []float64{101e6, 145e6} {
+		if e := rx.Tune(f); e != nil {
+			t.Fatalf("Tune(%g): %v", f, e)
+		}
+
+		if got := rx.State().Frequency; got != f {
+			t.Errorf("State().Frequency = %g after Tune(%g)", got, f)
+		}
+	}
+
+	if e := rx.SetUp(sdrplay.Bandwidth(sdrplay.BW600), sdrplay.InitialGR(40)); e != nil {
+		t.Fatal(e)
+	}
+
+	s := rx.State()
+	if s.Bandwidth != sdrplay.BW600 || s.GainReduction != 40 {
+		t.Errorf("State() = %v/%d dB, want BW600/40 dB", s.Bandwidth, s.GainReduction)
+	}
+
+	n := c.Samples()
+	time.Sleep(100 * time.Millisecond)
+	if c.Samples() == n {
+		t.Error("no samples after the reconfiguration")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(fired) != 2 || fired[0] != 101e6 || fired[1] != 145e6 {
+		t.Errorf("Trigger fired for %v, want [1.01e+08 1.45e+08]", fired)
+	}
+}
+
+// TestRSPApplyProfile verifica che Profiles.Apply sostituisca le scelte
+// automatiche, come OutputRate, con la configurazione del profilo e che
+// Config le riporti così che ApplyProfile le ripristini.
+func TestRSPApplyProfile(t *testing.T) {
+	r, e := sdrplay.RSP(sdrplaytest.NewCounter(), sdrplay.OutputRate(250e3))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer r.Close()
+
+	rx := r.(sdrplay.DeviceControl)
+
+	saved := rx.Config()
+	if saved.OutputRate != 250e3 || !saved.Decimate || saved.Factor != sdrplay.Factor8 {
+		t.Errorf("OutputRate(250e3): got rate %g, decimate %v %v", saved.OutputRate, saved.Decimate, saved.Factor)
+	}
+
+	if e := sdrplay.DefaultProfiles().Apply(r, "airband"); e != nil {
+		t.Fatal(e)
+	}
+
+	c := rx.Config()
+	if c.OutputRate != 0 || c.Bandwidth != sdrplay.BW1536 || c.FS != 2.048 || c.Decimate {
+		t.Errorf("airband: got rate %g, %v, FS %g, decimate %v", c.OutputRate, c.Bandwidth, c.FS, c.Decimate)
+	}
+
+	if s := r.State(); s.OutputRate != 2.048e6 {
+		t.Errorf("airband: got output rate %g, want 2.048e6", s.OutputRate)
+	}
+
+	if e := sdrplay.ApplyProfile(r, saved); e != nil {
+		t.Fatal(e)
+	}
+
+	if c := rx.Config(); c != saved {
+		t.Errorf("Apply: got %+v, want %+v", c, saved)
+	}
+}
+
+// TestRSPDeviceRemoved verifica che la disconnessione della RSP segnalata
+// dall'API alla callback dello stream venga riportata subito, senza attendere
+// l'assenza dei campioni, con l'errore DeviceRemovedError.
+func TestRSPDeviceRemoved(t *testing.T) {
+	removed := make(chan sdrplay.DeviceRemoved, 1)
+
+	rx, e := sdrplay.RSP(sdrplaytest.NewCounter(), sdrplay.OnDeviceRemoved(func(d sdrplay.DeviceRemoved) { removed <- d }))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer rx.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	sdrplay.MockUnplug()
+	defer sdrplay.MockReplug()
+
+	select {
+	case d := <-removed:
+		if !errors.Is(d.Err, sdrplay.DeviceRemovedError) {
+			t.Errorf("got %v, want DeviceRemovedError", d.Err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("removal not reported")
+	}
+}
+
+// TestRSPPPM verifica che una correzione nulla, impostata con SetUp o
+// all'apertura del ricevitore, elimini quella precedente e che SetPPM
+// restituisca l'errore dell'API.
+func TestRSPPPM(t *testing.T) {
+	rx, e := sdrplay.RSP(sdrplaytest.NewCounter(), sdrplay.LOppm(5))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if e := rx.SetUp(sdrplay.LOppm(0)); e != nil {
+		t.Fatal(e)
+	}
+	if p := sdrplay.MockPPM(); p != 0 {
+		t.Errorf("SetUp(LOppm(0)): got %g ppm, want 0", p)
+	}
+
+	if e := rx.(sdrplay.DeviceControl).SetPPM(10); e != nil {
+		t.Fatal(e)
+	}
+	if e := rx.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	rx, e = sdrplay.RSP(sdrplaytest.NewCounter())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer rx.Close()
+
+	if p := sdrplay.MockPPM(); p != 0 {
+		t.Errorf("RSP without LOppm: got %g ppm, want 0", p)
+	}
+
+	sdrplay.MockUnplug()
+	defer sdrplay.MockReplug()
+
+	if e := rx.(sdrplay.DeviceControl).SetPPM(1); !errors.Is(e, sdrplay.DeviceRemovedError) {
+		t.Errorf("SetPPM with the RSP unplugged: got %v, want DeviceRemovedError", e)
+	}
+}
+
+// TestRSPInterfaces verifica che il ricevitore restituito da RSP implementi le
+// interfacce facoltative e che Memories.Tune ne imposti frequenza e larghezza
+// di banda.
+func TestRSPInterfaces(t *testing.T) {
+	rx, e := sdrplay.RSP(sdrplaytest.NewCounter())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer rx.Close()
+
+	var (
+		_, step     = rx.(sdrplay.StepTuner)
+		_, relative = rx.(sdrplay.RelativeTuner)
+		_, settling = rx.(sdrplay.SettlingTuner)
+		_, synced   = rx.(sdrplay.SyncUpdater)
+		_, device   = rx.(sdrplay.DeviceControl)
+		_, packet   = rx.(sdrplay.PacketSizer)
+	)
+	if !step || !relative || !settling || !synced || !device || !packet {
+		t.Errorf("optional interfaces: StepTuner %v, RelativeTuner %v, SettlingTuner %v, SyncUpdater %v, DeviceControl %v, PacketSizer %v",
+			step, relative, settling, synced, device, packet)
+	}
+
+	m := sdrplay.NewMemories()
+	m.Add(sdrplay.Memory{Name: "tower", Frequency: 118.1e6, Mode: "AM", Bandwidth: sdrplay.BW200})
+
+	if e := m.Tune(rx, "tower"); e != nil {
+		t.Fatal(e)
+	}
+	if s := rx.State(); s.Frequency != 118.1e6 || s.Bandwidth != sdrplay.BW200 {
+		t.Errorf("Memories.Tune: got %g Hz, %v, want 1.181e+08 Hz, BW200", s.Frequency, s.Bandwidth)
+	}
+
+	if e := m.Tune(rx, "missing"); e != sdrplay.NoMemoryError {
+		t.Errorf("Memories.Tune of a missing channel: got %v, want NoMemoryError", e)
+	}
+}
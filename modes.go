@@ -0,0 +1,282 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"sync"
+)
+
+type (
+	// ModeSFrame è un frame Mode S (ADS-B compreso) ricevuto da ModeS.
+	ModeSFrame struct {
+		// Data contiene i bit del frame: 7 byte per i frame corti (56 bit) e
+		// 14 byte per quelli lunghi (112 bit).
+		Data []byte
+
+		// Parity è il residuo del controllo CRC: vale 0 per i frame DF17 e DF18
+		// corretti, l'identificativo dell'interrogatore per i DF11 e
+		// l'indirizzo ICAO del velivolo per i frame con indirizzo sovrapposto
+		// alla parità (DF0, DF4, DF5, DF16, DF20, DF21, DF24).
+		Parity uint32
+
+		// Signal è la potenza media degli impulsi del frame in dBFS.
+		Signal float64
+
+		// Sample è la posizione, nel flusso di campioni ricevuti da ModeS, del
+		// primo campione del preambolo. Serve a datare i frame con la
+		// risoluzione del campionamento.
+		Sample uint64
+	}
+
+	// ModeS è un Connector che rivela i frame Mode S trasmessi dai transponder
+	// a 1090MHz: riconosce il preambolo, decodifica i bit con modulazione PPM
+	// e passa i frame corti e lunghi ottenuti alla funzione report. Il segnale
+	// deve essere campionato ad almeno 2MHz, come con le opzioni restituite da
+	// ModeSPreset.
+	ModeS struct {
+		mu sync.Mutex
+
+		// sps è il numero di campioni per microsecondo.
+		sps int
+
+		// mag contiene il modulo dei campioni non ancora esaminati, preceduti
+		// da quelli del frame precedente che potrebbero contenere l'inizio di
+		// un messaggio.
+		mag []float64
+
+		// base è la posizione nel flusso del primo campione di mag.
+		base uint64
+
+		// all indica se vanno riportati anche i frame il cui CRC non può
+		// essere verificato senza conoscere l'indirizzo del velivolo.
+		all bool
+
+		report func(ModeSFrame)
+	}
+)
+
+const (
+	// modeSPreamble è la durata del preambolo in microsecondi.
+	modeSPreamble = 8
+
+	// modeSLong è il numero di bit di un frame lungo.
+	modeSLong = 112
+
+	// modeSPoly è il polinomio generatore del CRC Mode S.
+	modeSPoly = 0x1FFF409
+)
+
+// ModeSPreset restituisce le opzioni che configurano la RSP per la ricezione
+// Mode S / ADS-B: sintonia a 1090MHz, campionamento a 2MHz con banda di
+// 1536kHz, IF nulla, AGC disabilitato e guadagno fisso.
+func ModeSPreset() []Option {
+	return []Option{
+		InitialRF(1090),
+		FS(2.0),
+		Bandwidth(BW1536),
+		IF(IFzero),
+		LOmode(LOauto),
+		AGC(Disable, 0),
+		InitialGR(30),
+	}
+}
+
+// NewModeS restituisce un ModeS per un segnale campionato con frequenza fs,
+// espressa in Hz, che passa i frame ricevuti a report. Se fs è inferiore a 2MHz
+// viene considerata pari a 2MHz.
+func NewModeS(fs float64, report func(ModeSFrame)) *ModeS {
+	sps := int(math.Floor(fs/1e6 + 0.5))
+	if sps < 2 {
+		sps = 2
+	}
+
+	return &ModeS{sps: sps, report: report}
+}
+
+// All permette di riportare, oltre ai frame DF11, DF17 e DF18 dei quali viene
+// verificato il CRC, anche tutti i frame con indirizzo sovrapposto alla
+// parità. Questi ultimi vanno validati dall'utilizzatore confrontando Parity con
+// gli indirizzi dei velivoli noti, perché il rumore produce frequentemente
+// frame apparentemente validi.
+func (m *ModeS) All(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.all = enabled
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (m *ModeS) Propagate(I []int16, Q []int16) {
+	m.mu.Lock()
+
+	for k := 0; k < len(I) && k < len(Q); k++ {
+		i, q := float64(I[k]), float64(Q[k])
+		m.mag = append(m.mag, math.Sqrt(i*i+q*q)/fullScale)
+	}
+
+	span := (modeSPreamble + modeSLong) * m.sps
+
+	var frames []ModeSFrame
+	j := 0
+	for ; j+span <= len(m.mag); j++ {
+		if f, n, ok := m.decode(j); ok {
+			f.Sample = m.base + uint64(j)
+			frames = append(frames, f)
+			j += n - 1
+		}
+	}
+
+	// Si conservano i campioni che potrebbero contenere l'inizio di un frame
+	// non ancora completo.
+	m.base += uint64(j)
+	m.mag = append(m.mag[:0], m.mag[j:]...)
+
+	report := m.report
+	m.mu.Unlock()
+
+	if report != nil {
+		for _, f := range frames {
+			report(f)
+		}
+	}
+}
+
+// at restituisce il modulo del campione a t microsecondi dall'inizio del
+// preambolo che inizia in posizione j.
+func (m *ModeS) at(j int, t float64) float64 {
+	return m.mag[j+int(t*float64(m.sps))]
+}
+
+// decode tenta di decodificare un frame il cui preambolo inizia in posizione
+// j. Restituisce il frame, il numero di campioni che occupa e se è valido.
+func (m *ModeS) decode(j int) (ModeSFrame, int, bool) {
+	// Il preambolo è composto da quattro impulsi di 0.5µs che iniziano a 0,
+	// 1, 3.5 e 4.5µs, seguiti da una pausa fino a 8µs.
+	high := [...]float64{m.at(j, 0), m.at(j, 1), m.at(j, 3.5), m.at(j, 4.5)}
+	low := [...]float64{m.at(j, 0.5), m.at(j, 1.5), m.at(j, 2), m.at(j, 2.5), m.at(j, 3), m.at(j, 4), m.at(j, 5.5), m.at(j, 6.5)}
+
+	minHigh, sumHigh := high[0], 0.0
+	for _, h := range high {
+		minHigh = math.Min(minHigh, h)
+		sumHigh += h
+	}
+
+	var maxLow float64
+	for _, l := range low {
+		maxLow = math.Max(maxLow, l)
+	}
+
+	if minHigh < 2*maxLow || minHigh == 0 {
+		return ModeSFrame{}, 0, false
+	}
+
+	// Ogni bit dura 1µs: vale 1 se l'impulso occupa la prima metà, 0 se
+	// occupa la seconda.
+	data := make([]byte, modeSLong/8)
+	half := m.sps / 2
+	var energy float64
+	bits := modeSLong
+	for b := 0; b < bits; b++ {
+		start := j + (modeSPreamble+b)*m.sps
+
+		var first, second float64
+		for k := 0; k < half; k++ {
+			first += m.mag[start+k]
+			second += m.mag[start+half+k]
+		}
+
+		if first > second {
+			data[b/8] |= 0x80 >> uint(b%8)
+		}
+		energy += math.Max(first, second) / float64(half)
+
+		// Dai primi 5 bit (downlink format) si ricava la lunghezza del frame.
+		if b == 4 && data[0]>>3 < 16 {
+			bits = modeSLong / 2
+		}
+	}
+
+	f := ModeSFrame{
+		Data:   data[:bits/8],
+		Signal: 20 * math.Log10(energy/float64(bits)),
+	}
+	f.Parity = modeSCRC(f.Data)
+
+	switch f.DF() {
+	case 17, 18:
+		if f.Parity != 0 {
+			return f, 0, false
+		}
+	case 11:
+		if f.Parity&^0x7F != 0 {
+			return f, 0, false
+		}
+	case 0, 4, 5, 16, 20, 21, 24:
+		if !m.all {
+			return f, 0, false
+		}
+	default:
+		return f, 0, false
+	}
+
+	return f, (modeSPreamble + bits) * m.sps, true
+}
+
+// DF restituisce il downlink format del frame.
+func (f ModeSFrame) DF() int {
+	if len(f.Data) == 0 {
+		return -1
+	}
+
+	df := int(f.Data[0] >> 3)
+	if df >= 24 {
+		return 24
+	}
+
+	return df
+}
+
+// ICAO restituisce l'indirizzo ICAO del velivolo che ha trasmesso il frame: è
+// contenuto in chiaro nei frame DF11, DF17 e DF18 mentre negli altri coincide
+// con il residuo del CRC.
+func (f ModeSFrame) ICAO() uint32 {
+	switch f.DF() {
+	case 11, 17, 18:
+		return uint32(f.Data[1])<<16 | uint32(f.Data[2])<<8 | uint32(f.Data[3])
+	default:
+		return f.Parity
+	}
+}
+
+// modeSCRC restituisce il residuo di parità del frame data: il CRC Mode S dei
+// bit del messaggio, esclusi gli ultimi 24 di parità, sommato (XOR) a questi
+// ultimi. Vale 0 per un frame integro con parità semplice, altrimenti l'indirizzo
+// o l'identificativo sovrapposto alla parità.
+func modeSCRC(data []byte) uint32 {
+	if len(data) < 3 {
+		return 0
+	}
+
+	n := len(data) - 3
+
+	var crc uint32
+	for _, b := range data[:n] {
+		crc ^= uint32(b) << 16
+		for k := 0; k < 8; k++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= modeSPoly
+			}
+		}
+	}
+
+	parity := uint32(data[n])<<16 | uint32(data[n+1])<<8 | uint32(data[n+2])
+
+	return (crc ^ parity) & 0xFFFFFF
+}
@@ -0,0 +1,88 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/iclac/sdrplay"
+)
+
+// modeSModulate restituisce i campioni I, campionati a 2MHz, del frame Mode S
+// data modulato in PPM e preceduto dal preambolo, con del silenzio intorno.
+func modeSModulate(data []byte) []int16 {
+	const level = 10000
+
+	// Ogni microsecondo è composto da due campioni.
+	I := make([]int16, 2*(8+8*len(data))+400)
+	p := I[200:]
+
+	for _, t := range []int{0, 2, 7, 9} {
+		p[t] = level
+	}
+
+	for b := 0; b < 8*len(data); b++ {
+		k := 2 * (8 + b)
+		if data[b/8]&(0x80>>uint(b%8)) != 0 {
+			p[k] = level
+		} else {
+			p[k+1] = level
+		}
+	}
+
+	return I
+}
+
+// TestModeSParity verifica che ModeS ricavi dal residuo di parità
+// l'identificativo dell'interrogatore dei frame DF11 e l'indirizzo ICAO dei
+// frame con indirizzo sovrapposto alla parità.
+func TestModeSParity(t *testing.T) {
+	tests := []struct {
+		name   string
+		frame  string
+		df     int
+		parity uint32
+		icao   uint32
+	}{
+		{"DF17", "8D4840D6202CC371C32CE0576098", 17, 0, 0x4840D6},
+		{"DF11 IID 0", "5D4840D6F8740F", 11, 0, 0x4840D6},
+		{"DF11 IID 5", "5D4840D6F8740A", 11, 5, 0x4840D6},
+		{"DF4", "20001718024EBD", 4, 0x4840D6, 0x4840D6},
+	}
+
+	for _, test := range tests {
+		data, _ := hex.DecodeString(test.frame)
+
+		var frames []sdrplay.ModeSFrame
+		m := sdrplay.NewModeS(2e6, func(f sdrplay.ModeSFrame) { frames = append(frames, f) })
+		m.All(true)
+
+		I := modeSModulate(data)
+		m.Propagate(I, make([]int16, len(I)))
+
+		if len(frames) != 1 {
+			t.Errorf("%s: got %d frames, want 1", test.name, len(frames))
+			continue
+		}
+
+		f := frames[0]
+		if hex.EncodeToString(f.Data) != hex.EncodeToString(data) {
+			t.Errorf("%s: got frame %X", test.name, f.Data)
+		}
+		if f.DF() != test.df {
+			t.Errorf("%s: got DF%d, want DF%d", test.name, f.DF(), test.df)
+		}
+		if f.Parity != test.parity {
+			t.Errorf("%s: got parity %06X, want %06X", test.name, f.Parity, test.parity)
+		}
+		if f.ICAO() != test.icao {
+			t.Errorf("%s: got ICAO %06X, want %06X", test.name, f.ICAO(), test.icao)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"sync"
+)
+
+type (
+	// IQServer è un Connector che trasmette il segnale in banda base, via TCP,
+	// ad uno o più IQClient. Ogni frame è preceduto da un'intestazione con la
+	// frequenza centrale, la frequenza di campionamento ed un numero di
+	// sequenza, che permette ai client di rilevare i frame persi. I comandi di
+	// sintonia e guadagno inviati dai client vengono applicati al ricevitore
+	// fornito a Serve; il protocollo non prevede risposte, per cui gli errori
+	// vengono riportati con livello Warn sul logger impostato con Logger.
+	// Perché la frequenza trasmessa segua anche le risintonie eseguite
+	// localmente, IQServer va registrato come Trigger:
+	//
+	//   srv := sdrplay.NewIQServer(fs)
+	//   rx, e := sdrplay.RSP(srv, sdrplay.OnRetune(srv))
+	//   ...
+	//   e = srv.ListenAndServe(":5555", rx)
+	IQServer struct {
+		mu sync.Mutex
+
+		fs, frequency float64
+		seq           uint64
+
+		rx       Receiver
+		log      *slog.Logger
+		clients  map[*iqPeer]struct{}
+		listener net.Listener
+		closed   bool
+	}
+
+	// iqPeer è un client connesso ad IQServer.
+	iqPeer struct {
+		conn   net.Conn
+		frames chan []byte
+	}
+
+	// IQClient è un Receiver che riceve il segnale in banda base da un
+	// IQServer remoto e lo propaga al Connector fornito. Tune e Gain vengono
+	// inoltrati al server senza attenderne l'esito, che il server riporta nel
+	// proprio log; SetUp non ha effetto, perché le opzioni riguardano la RSP
+	// locale.
+	IQClient struct {
+		virtual
+
+		mu sync.Mutex
+
+		conn net.Conn
+
+		// fs e frequency sono i valori indicati nell'ultimo frame ricevuto,
+		// next il numero di sequenza atteso e lost il numero di frame persi.
+		fs, frequency float64
+		next, lost    uint64
+
+		err  error
+		done chan struct{}
+
+		baseband Connector
+	}
+)
+
+const (
+	// iqMagic identifica l'intestazione di un frame IQServer.
+	iqMagic = 0x51524453 // "SDRQ"
+
+	// iqHeader è la dimensione in byte dell'intestazione di un frame.
+	iqHeader = 32
+
+	// iqQueue è il numero massimo di frame in attesa di essere inviati ad un
+	// client, oltre il quale i frame vengono scartati.
+	iqQueue = 64
+
+	// iqMaxFrame è il numero massimo di campioni di un frame accettato da
+	// IQClient.
+	iqMaxFrame = 1 << 20
+)
+
+// Comandi inviati da IQClient ad IQServer.
+const (
+	iqTune = 0x01
+	iqGain = 0x02
+)
+
+// InvalidStreamError indica che IQClient ha ricevuto dati che non rispettano
+// il protocollo di IQServer.
+var InvalidStreamError = errors.New("Invalid Stream Error")
+
+// NewIQServer restituisce un IQServer per un segnale campionato con frequenza
+// fs, espressa in Hz.
+func NewIQServer(fs float64) *IQServer {
+	return &IQServer{fs: fs, log: slog.Default(), clients: make(map[*iqPeer]struct{})}
+}
+
+// Logger imposta il logger sul quale vengono riportati i comandi dei client
+// non riusciti. Di default viene usato slog.Default().
+func (s *IQServer) Logger(l *slog.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.log = l
+}
+
+// Fire implementa l'interfaccia Trigger, aggiornando la frequenza centrale
+// trasmessa ai client.
+func (s *IQServer) Fire(frequency float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frequency = frequency
+
+	return nil
+}
+
+// ListenAndServe accetta connessioni TCP all'indirizzo addr ed invoca Serve.
+func (s *IQServer) ListenAndServe(addr string, rx Receiver) error {
+	l, e := net.Listen("tcp", addr)
+	if e != nil {
+		return e
+	}
+
+	return s.Serve(l, rx)
+}
+
+// Serve accetta le connessioni dei client su l ed applica i loro comandi al
+// ricevitore rx, fino all'invocazione di Close o ad un errore di l.
+func (s *IQServer) Serve(l net.Listener, rx Receiver) error {
+	s.mu.Lock()
+	s.rx, s.listener = rx, l
+	s.mu.Unlock()
+
+	for {
+		conn, e := l.Accept()
+		if e != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+
+			if closed {
+				return nil
+			}
+			return e
+		}
+
+		go s.serve(conn)
+	}
+}
+
+// Close chiude il listener e tutte le connessioni dei client.
+func (s *IQServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for c := range s.clients {
+		c.conn.Close()
+	}
+
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+
+	return nil
+}
+
+// serve gestisce la connessione di un client: avvia l'invio dei frame e ne
+// interpreta i comandi fino alla disconnessione.
+func (s *IQServer) serve(conn net.Conn) {
+	c := &iqPeer{conn: conn, frames: make(chan []byte, iqQueue)}
+
+	// Una connessione accettata mentre Close era in corso non viene
+	// registrata, perché nessuno la chiuderebbe più.
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	go c.send()
+
+	cmd := make([]byte, 9)
+	for {
+		if _, e := io.ReadFull(conn, cmd); e != nil {
+			break
+		}
+
+		s.command(cmd[0], binary.LittleEndian.Uint64(cmd[1:]))
+	}
+
+	s.mu.Lock()
+	delete(s.clients, c)
+	close(c.frames)
+	s.mu.Unlock()
+
+	conn.Close()
+}
+
+// send invia al client i frame in coda fino alla sua disconnessione.
+func (c *iqPeer) send() {
+	for b := range c.frames {
+		if _, e := c.conn.Write(b); e != nil {
+			c.conn.Close()
+		}
+	}
+}
+
+// command applica al ricevitore il comando cmd con parametro p, riportando sul
+// logger l'eventuale errore.
+func (s *IQServer) command(cmd byte, p uint64) {
+	s.mu.Lock()
+	rx, log := s.rx, s.log
+	s.mu.Unlock()
+
+	if rx == nil {
+		return
+	}
+
+	var e error
+	switch cmd {
+	case iqTune:
+		f := math.Float64frombits(p)
+		if e = rx.Tune(f); e == nil {
+			s.Fire(f)
+		}
+	case iqGain:
+		e = rx.Gain(int(int64(p)))
+	}
+
+	if e == nil {
+		return
+	}
+
+	log.Warn("iq command failed", "cmd", cmd, "param", p, "err", e)
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (s *IQServer) Propagate(I []int16, Q []int16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	seq := s.seq
+	s.seq++
+
+	if len(s.clients) == 0 {
+		return
+	}
+
+	le := binary.LittleEndian
+	b := make([]byte, iqHeader+4*n)
+	le.PutUint32(b[0:], iqMagic)
+	le.PutUint32(b[4:], uint32(n))
+	le.PutUint64(b[8:], seq)
+	le.PutUint64(b[16:], math.Float64bits(s.frequency))
+	le.PutUint64(b[24:], math.Float64bits(s.fs))
+
+	for k := 0; k < n; k++ {
+		le.PutUint16(b[iqHeader+4*k:], uint16(I[k]))
+		le.PutUint16(b[iqHeader+4*k+2:], uint16(Q[k]))
+	}
+
+	for c := range s.clients {
+		select {
+		case c.frames <- b:
+		default:
+		}
+	}
+}
+
+// DialIQ si connette all'IQServer all'indirizzo addr e restituisce un IQClient
+// che ne propaga il segnale a baseband fino all'invocazione di Close.
+// Il baseband connector deve essere non nil altrimenti viene restituito l'errore
+// UnpluggedConnectorError.
+func DialIQ(addr string, baseband Connector) (*IQClient, error) {
+	if baseband == nil {
+		return nil, UnpluggedConnectorError
+	}
+
+	conn, e := net.Dial("tcp", addr)
+	if e != nil {
+		return nil, e
+	}
+
+	c := &IQClient{
+		conn:     conn,
+		done:     make(chan struct{}),
+		baseband: baseband,
+	}
+
+	go c.receive()
+
+	return c, nil
+}
+
+// Tune implementa l'interfaccia Tuner inoltrando la richiesta al server.
+// Restituisce solo l'errore di invio: l'esito della sintonia non viene
+// comunicato dal server.
+func (c *IQClient) Tune(frequency float64) error {
+	c.virtual.Tune(frequency)
+
+	return c.command(iqTune, math.Float64bits(frequency))
+}
+
+// Gain implementa l'interfaccia Amplifier inoltrando la richiesta al server.
+// Come per Tune, viene restituito solo l'errore di invio.
+func (c *IQClient) Gain(reduction int) error {
+	c.virtual.Gain(reduction)
+
+	return c.command(iqGain, uint64(int64(reduction)))
+}
+
+// command invia al server il comando cmd con parametro p.
+func (c *IQClient) command(cmd byte, p uint64) error {
+	b := make([]byte, 9)
+	b[0] = cmd
+	binary.LittleEndian.PutUint64(b[1:], p)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, e := c.conn.Write(b)
+
+	return e
+}
+
+// Frequency restituisce la frequenza centrale, espressa in Hz, indicata
+// nell'ultimo frame ricevuto.
+func (c *IQClient) Frequency() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.frequency
+}
+
+// SampleRate restituisce la frequenza di campionamento, espressa in Hz,
+// indicata nell'ultimo frame ricevuto.
+func (c *IQClient) SampleRate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.fs
+}
+
+// Lost restituisce il numero di frame persi, perché scartati dal server o
+// perché la connessione non ha tenuto il passo della ricezione.
+func (c *IQClient) Lost() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lost
+}
+
+// Done restituisce un canale che viene chiuso alla chiusura della
+// connessione.
+func (c *IQClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err restituisce l'eventuale errore che ha interrotto la ricezione.
+func (c *IQClient) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// Close chiude la connessione con il server.
+func (c *IQClient) Close() error {
+	e := c.conn.Close()
+	<-c.done
+
+	return e
+}
+
+// receive legge i frame inviati dal server e li propaga fino alla chiusura
+// della connessione.
+func (c *IQClient) receive() {
+	defer close(c.done)
+
+	le := binary.LittleEndian
+	h := make([]byte, iqHeader)
+	var buf []byte
+	var I, Q []int16
+
+	for {
+		if _, e := io.ReadFull(c.conn, h); e != nil {
+			c.fail(e)
+			return
+		}
+
+		n := int(le.Uint32(h[4:]))
+		if le.Uint32(h) != iqMagic || n > iqMaxFrame {
+			c.fail(InvalidStreamError)
+			c.conn.Close()
+			return
+		}
+
+		if cap(buf) < 4*n {
+			buf = make([]byte, 4*n)
+			I, Q = make([]int16, n), make([]int16, n)
+		}
+		buf = buf[:4*n]
+
+		if _, e := io.ReadFull(c.conn, buf); e != nil {
+			c.fail(e)
+			return
+		}
+
+		for k := 0; k < n; k++ {
+			I[k] = int16(le.Uint16(buf[4*k:]))
+			Q[k] = int16(le.Uint16(buf[4*k+2:]))
+		}
+
+		seq := le.Uint64(h[8:])
+
+		c.mu.Lock()
+		if c.next != 0 && seq > c.next {
+			c.lost += seq - c.next
+		}
+		c.next = seq + 1
+		c.frequency = math.Float64frombits(le.Uint64(h[16:]))
+		c.fs = math.Float64frombits(le.Uint64(h[24:]))
+		c.mu.Unlock()
+
+		c.baseband.Propagate(I[:n], Q[:n])
+	}
+}
+
+// fail memorizza l'errore e che ha interrotto la ricezione, ignorando quello
+// dovuto alla chiusura della connessione.
+func (c *IQClient) fail(e error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil && e != io.EOF && !errors.Is(e, net.ErrClosed) {
+		c.err = e
+	}
+}
@@ -0,0 +1,81 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iclac/sdrplay"
+	"github.com/iclac/sdrplay/sdrplaytest"
+)
+
+// iqReceiver è un Receiver la cui sintonia fallisce con l'errore err.
+type iqReceiver struct {
+	sdrplay.Receiver
+	err error
+}
+
+// Tune implementa l'interfaccia Tuner.
+func (r *iqReceiver) Tune(frequency float64) error {
+	return r.err
+}
+
+// logLines è un io.Writer che inoltra al canale ogni riga scritta dal logger.
+type logLines chan string
+
+// Write implementa l'interfaccia io.Writer.
+func (l logLines) Write(b []byte) (int, error) {
+	l <- string(b)
+
+	return len(b), nil
+}
+
+// TestIQServerCommandError verifica che IQServer riporti sul logger gli errori
+// dei comandi inviati dai client.
+func TestIQServerCommandError(t *testing.T) {
+	lines := make(logLines, 1)
+
+	s := sdrplay.NewIQServer(2e6)
+	s.Logger(slog.New(slog.NewTextHandler(lines, nil)))
+
+	l, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(l, &iqReceiver{err: errors.New("tune failed")}) }()
+	defer func() {
+		s.Close()
+		<-served
+	}()
+
+	c, e := sdrplay.DialIQ(l.Addr().String(), sdrplaytest.NewCounter())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+
+	if e := c.Tune(100e6); e != nil {
+		t.Fatal(e)
+	}
+
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, "level=WARN") || !strings.Contains(line, "tune failed") {
+			t.Errorf("got log %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("command error not logged")
+	}
+}
@@ -0,0 +1,204 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"math/cmplx"
+	"sync"
+)
+
+type (
+	// Notch è una serie di filtri notch IIR che eliminano portanti indesiderate
+	// (birdie ed eterodine) non coperte dai notch hardware della RSP. Creato
+	// con NewNotch è un Connector che filtra il segnale in banda base, nel
+	// quale le frequenze sono scostamenti, anche negativi, dalla frequenza
+	// sintonizzata; creato con NewAudioNotch è un AudioConnector che filtra
+	// l'audio demodulato.
+	Notch struct {
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento del segnale espressa in Hz.
+		fs float64
+
+		// filters contiene i notch attivi, next è l'identificativo che verrà
+		// assegnato al prossimo.
+		filters []*notchFilter
+		next    int
+
+		iq    []complex128
+		i, q  []int16
+		audio []float32
+
+		out  Connector
+		aout AudioConnector
+	}
+
+	// notchFilter è un singolo notch: una coppia zero-polo complessa per il
+	// segnale in banda base, un biquad per l'audio.
+	notchFilter struct {
+		id int
+
+		// zero e pole sono lo zero ed il polo del notch complesso, gain il
+		// guadagno che rende unitaria la risposta lontano dal notch, x ed y gli
+		// ultimi campioni in ingresso ed in uscita.
+		zero, pole complex128
+		gain       float64
+		x, y       complex128
+
+		// b ed a sono i coefficienti del biquad normalizzati ad a0, x1, x2, y1
+		// ed y2 il suo stato.
+		b0, b1, b2, a1, a2 float64
+		x1, x2, y1, y2     float64
+	}
+)
+
+// NewNotch restituisce un Notch, inizialmente senza filtri, per un segnale in
+// banda base campionato con frequenza fs, espressa in Hz, che propaga il segnale
+// filtrato ad out.
+func NewNotch(fs float64, out Connector) *Notch {
+	return &Notch{fs: fs, out: out}
+}
+
+// NewAudioNotch restituisce un Notch, inizialmente senza filtri, per audio
+// campionato a AudioRate che propaga l'audio filtrato ad out.
+func NewAudioNotch(out AudioConnector) *Notch {
+	return &Notch{fs: AudioRate, aout: out}
+}
+
+// Add aggiunge un notch alla frequenza frequency, espressa in Hz, con fattore di
+// merito q, e ne restituisce l'identificativo da usare con Remove. La larghezza
+// del notch è pari a |frequency|/q, con un minimo di 1Hz per i notch in banda
+// base prossimi alla frequenza sintonizzata.
+func (n *Notch) Add(frequency, q float64) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.next++
+	n.filters = append(n.filters, newNotchFilter(n.next, frequency, q, n.fs))
+
+	return n.next
+}
+
+// Remove elimina il notch con identificativo id.
+func (n *Notch) Remove(id int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for k, f := range n.filters {
+		if f.id == id {
+			n.filters = append(n.filters[:k], n.filters[k+1:]...)
+			return
+		}
+	}
+}
+
+// Clear elimina tutti i notch.
+func (n *Notch) Clear() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.filters = nil
+}
+
+// newNotchFilter restituisce il notch id alla frequenza f, con fattore di merito
+// q, per un segnale campionato con frequenza fs.
+func newNotchFilter(id int, f, q, fs float64) *notchFilter {
+	if q <= 0 {
+		q = 1
+	}
+
+	w := 2 * math.Pi * f / fs
+	bw := math.Max(math.Abs(f)/q, 1)
+	r := math.Max(0, 1-math.Pi*bw/fs)
+
+	// Biquad notch secondo le formule di R. Bristow-Johnson.
+	alpha := math.Sin(w) / (2 * q)
+	a0 := 1 + alpha
+
+	return &notchFilter{
+		id:   id,
+		zero: cmplx.Rect(1, w),
+		pole: cmplx.Rect(r, w),
+		gain: (1 + r) / 2,
+		b0:   1 / a0,
+		b1:   -2 * math.Cos(w) / a0,
+		b2:   1 / a0,
+		a1:   -2 * math.Cos(w) / a0,
+		a2:   (1 - alpha) / a0,
+	}
+}
+
+// processIQ filtra il campione x del segnale in banda base.
+func (f *notchFilter) processIQ(x complex128) complex128 {
+	y := x - f.zero*f.x + f.pole*f.y
+	f.x, f.y = x, y
+
+	return y * complex(f.gain, 0)
+}
+
+// processAudio filtra il campione audio x.
+func (f *notchFilter) processAudio(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+
+	return y
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (n *Notch) Propagate(I []int16, Q []int16) {
+	n.mu.Lock()
+
+	if len(n.filters) > 0 {
+		n.iq = toComplex(I, Q, n.iq[:0])
+		n.i, n.q = n.i[:0], n.q[:0]
+
+		for _, x := range n.iq {
+			for _, f := range n.filters {
+				x = f.processIQ(x)
+			}
+
+			x *= fullScale
+			n.i = append(n.i, int16(math.Max(-fullScale, math.Min(fullScale-1, real(x)))))
+			n.q = append(n.q, int16(math.Max(-fullScale, math.Min(fullScale-1, imag(x)))))
+		}
+
+		I, Q = n.i, n.q
+	}
+
+	n.mu.Unlock()
+
+	if n.out != nil {
+		n.out.Propagate(I, Q)
+	}
+}
+
+// PropagateAudio implementa l'interfaccia AudioConnector.
+func (n *Notch) PropagateAudio(samples []float32) {
+	n.mu.Lock()
+
+	if len(n.filters) > 0 {
+		n.audio = n.audio[:0]
+		for _, s := range samples {
+			x := float64(s)
+			for _, f := range n.filters {
+				x = f.processAudio(x)
+			}
+			n.audio = append(n.audio, float32(x))
+		}
+
+		samples = n.audio
+	}
+
+	n.mu.Unlock()
+
+	if n.aout != nil {
+		n.aout.PropagateAudio(samples)
+	}
+}
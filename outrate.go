@@ -0,0 +1,163 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "fmt"
+
+// rateConverter ricampiona il segnale in banda base: con un resampler per
+// ciascuna componente se il rapporto tra le frequenze è rappresentabile
+// esattamente, altrimenti con un arbitrary.
+type rateConverter struct {
+	i, q    *resampler
+	in, out [2][]float64
+
+	arb       *arbitrary
+	cin, cout []complex128
+}
+
+// minOutputRate è la frequenza minima, in Hz, ammessa da OutputRate.
+const minOutputRate = 1e3
+
+// OutputRate configura la RSP per propagare i campioni alla frequenza hz,
+// espressa in Hz, tra 1 kHz e 10 MHz, ignorando le opzioni FS e Decimate ed
+// impostando, salvo che sia abilitata AutoBandwidth, la larghezza di banda più
+// ampia non superiore ad hz. Viene scelta la frequenza di campionamento più
+// bassa che, con la decimazione hardware, raggiunge esattamente hz; al di sotto
+// di 31250 Hz, oltre la decimazione massima, i campioni decimati vengono
+// ricampionati via software. La scelta viene ripetuta ad ogni SetUp;
+// OutputRate(0) la disabilita.
+func OutputRate(hz float64) Option {
+	return Option{
+		apply: func() {
+			rsp.Output = double(hz)
+		},
+	}
+}
+
+// rate restituisce la frequenza, in Hz, dei campioni prodotti dalla RSP dopo
+// l'eventuale decimazione hardware.
+func (f features) rate() float64 {
+	fs := float64(f.FS) * 1e6
+	if f.Decimate && f.Factor > 0 {
+		fs /= float64(f.Factor)
+	}
+
+	return fs
+}
+
+// outputRate imposta in f frequenza di campionamento, decimazione e, se non
+// scelta da AutoBandwidth, larghezza di banda per la frequenza scelta con
+// OutputRate.
+func (f *features) outputRate() {
+	hz := float64(f.Output)
+	if hz < minOutputRate || hz > 10e6 {
+		return
+	}
+
+	f.FS, f.Factor = 2, Factor64
+	for _, d := range []Decimation{Factor0, Factor2, Factor4, Factor8, Factor16, Factor32, Factor64} {
+		fs := hz
+		if d != Factor0 {
+			fs *= float64(d)
+		}
+
+		if fs >= 2e6 && fs <= 10e6 {
+			f.FS, f.Factor = double(fs/1e6), d
+			break
+		}
+	}
+
+	f.Decimate = enable(f.Factor != Factor0)
+	if f.Decimate {
+		f.IF = IFzero
+	}
+
+	if f.Channel > 0 {
+		return
+	}
+
+	f.BW = bandwidths[0]
+	for _, bw := range bandwidths {
+		if float64(bw)*1e3 <= hz {
+			f.BW = bw
+		}
+	}
+}
+
+// validateOutputRate verifica la frequenza scelta con OutputRate.
+func validateOutputRate(f features) error {
+	if f.Output != 0 && (f.Output < minOutputRate || f.Output > 10e6) {
+		return &OptionError{Option: "OutputRate", Reason: fmt.Sprintf("rate %g Hz outside 1 kHz - 10 MHz", float64(f.Output))}
+	}
+
+	return nil
+}
+
+// newRateConverter restituisce un rateConverter che converte un segnale
+// campionato ad in Hz in uno campionato ad out Hz.
+func newRateConverter(in, out float64) *rateConverter {
+	if up, down := ratio(out/in, maxRatio); !exact(in, out, up, down) {
+		return &rateConverter{arb: newArbitrary(in, out)}
+	}
+
+	return &rateConverter{
+		i: newResampler(in, out, 0),
+		q: newResampler(in, out, 0),
+	}
+}
+
+// setConverter aggiorna il ricampionamento software secondo la configurazione
+// attuale.
+func (r *radio) setConverter() {
+	var c *rateConverter
+
+	if in, out := r.feat.rate(), float64(r.feat.Output); out != 0 && in != out {
+		c = newRateConverter(in, out)
+	}
+
+	r.smu.Lock()
+	r.convert = c
+	r.smu.Unlock()
+}
+
+// apply ricampiona i campioni I e Q, restituendo nuovi buffer con quelli
+// prodotti, eventualmente vuoti.
+func (c *rateConverter) apply(I, Q []int16) ([]int16, []int16) {
+	if c.arb != nil {
+		c.cin = c.cin[:0]
+		for k := 0; k < len(I) && k < len(Q); k++ {
+			c.cin = append(c.cin, complex(float64(I[k]), float64(Q[k])))
+		}
+
+		c.cout = c.arb.process(c.cin, c.cout[:0])
+
+		i, q := make([]int16, len(c.cout)), make([]int16, len(c.cout))
+		for k, x := range c.cout {
+			i[k], q[k] = clamp16(real(x)), clamp16(imag(x))
+		}
+
+		return i, q
+	}
+
+	c.in[0], c.in[1] = c.in[0][:0], c.in[1][:0]
+	for k := 0; k < len(I) && k < len(Q); k++ {
+		c.in[0] = append(c.in[0], float64(I[k]))
+		c.in[1] = append(c.in[1], float64(Q[k]))
+	}
+
+	c.out[0] = c.i.process(c.in[0], c.out[0][:0])
+	c.out[1] = c.q.process(c.in[1], c.out[1][:0])
+
+	n := minInt(len(c.out[0]), len(c.out[1]))
+	i, q := make([]int16, n), make([]int16, n)
+	for k := range i {
+		i[k], q[k] = clamp16(c.out[0][k]), clamp16(c.out[1][k])
+	}
+
+	return i, q
+}
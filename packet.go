@@ -0,0 +1,75 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "time"
+
+type (
+	// PacketInfo descrive un frame propagato dalla RSP.
+	PacketInfo struct {
+		// SamplesPerPacket è il numero di campioni per pacchetto comunicato
+		// dall'API all'avvio o all'ultima reinizializzazione dello stream: ogni
+		// invocazione della callback consegna un pacchetto, la cui lunghezza
+		// coincide di norma con SamplesPerPacket.
+		SamplesPerPacket int
+
+		// FirstSample è il numero, assegnato dall'API, del primo campione
+		// del frame, il riferimento per ScheduleTune e ScheduleGain. Con
+		// OutputRate numera i campioni prodotti dalla RSP, prima del
+		// ricampionamento software.
+		FirstSample uint32
+
+		// LNAGainReduction è il gain reduction dovuto all'LNA, espresso in
+		// dB, in vigore alla ricezione del frame secondo l'ultima variazione
+		// del guadagno riportata dall'API. Va sommato al gain reduction
+		// impostato per ricavare la potenza del segnale all'antenna.
+		LNAGainReduction int
+
+		// Time è l'istante in cui il frame è stato ricevuto dall'API.
+		Time time.Time
+	}
+
+	// PacketConnector è l'interfaccia che può implementare, in aggiunta a
+	// Connector, il connettore fornito a RSP per ricevere insieme ad ogni
+	// frame le relative informazioni. Se implementata, la RSP invoca
+	// PropagatePacket al posto di Propagate.
+	PacketConnector interface {
+		// PropagatePacket propaga un frame di campioni in banda base insieme
+		// alle relative informazioni.
+		PropagatePacket(info PacketInfo, I []int16, Q []int16)
+	}
+
+	// PacketSizer è l'interfaccia di una sorgente che propaga i campioni in
+	// frame di lunghezza nota. Il Receiver restituito da RSP, FileReceiver e
+	// SignalGen implementano PacketSizer.
+	PacketSizer interface {
+		// SamplesPerPacket restituisce il numero di campioni per pacchetto
+		// consegnati dalla sorgente, 0 se non è noto.
+		SamplesPerPacket() int
+	}
+)
+
+// SamplesPerPacket implementa l'interfaccia PacketSizer.
+func (r *radio) SamplesPerPacket() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return int(r.spp)
+}
+
+// SamplesPerPacket implementa l'interfaccia PacketSizer restituendo il numero
+// di campioni dei frame propagati, escluso l'ultimo che può essere più corto.
+func (r *FileReceiver) SamplesPerPacket() int {
+	return fileFrame
+}
+
+// SamplesPerPacket implementa l'interfaccia PacketSizer restituendo il numero
+// di campioni dei frame propagati da Start.
+func (g *SignalGen) SamplesPerPacket() int {
+	return sigFrame
+}
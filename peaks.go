@@ -0,0 +1,149 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"sort"
+	"sync"
+)
+
+type (
+	// Peak è un segnale rilevato da PeakDetector in uno spettro.
+	Peak struct {
+		// Frequency è la frequenza del bin più forte e Bandwidth l'ampiezza
+		// dell'intervallo di bin contigui sopra la soglia, entrambe espresse
+		// in Hz.
+		Frequency float64
+		Bandwidth float64
+
+		// Power è la potenza del bin più forte, nell'unità dello spettro, e
+		// SNR il suo rapporto in dB con il rumore di fondo locale.
+		Power float64
+		SNR   float64
+	}
+
+	// PeakDetector rileva negli spettri i segnali che superano di una soglia il
+	// rumore di fondo, stimato per ogni bin come mediana dei bin vicini così da
+	// seguirne le variazioni lungo lo spettro. I segnali rilevati possono ad
+	// esempio alimentare uno Scanner attraverso PeakFrequencies.
+	PeakDetector struct {
+		mu sync.Mutex
+
+		// threshold è lo scarto in dB dal rumore di fondo oltre il quale un
+		// bin appartiene ad un segnale, window il numero di bin su cui è
+		// stimato il rumore di fondo.
+		threshold float64
+		window    int
+	}
+)
+
+// NewPeakDetector restituisce un PeakDetector che rileva i segnali che superano
+// di threshold dB il rumore di fondo. Di default il rumore di fondo è stimato
+// su 64 bin.
+func NewPeakDetector(threshold float64) *PeakDetector {
+	return &PeakDetector{threshold: threshold, window: 64}
+}
+
+// Threshold imposta lo scarto in dB dal rumore di fondo oltre il quale viene
+// rilevato un segnale.
+func (d *PeakDetector) Threshold(dB float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.threshold = dB
+}
+
+// Window imposta il numero di bin su cui viene stimato il rumore di fondo, che
+// deve essere ampio rispetto ai segnali da rilevare.
+func (d *PeakDetector) Window(bins int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.window = maxInt(3, bins)
+}
+
+// Detect restituisce i segnali rilevati nello spettro f, in ordine di
+// frequenza.
+func (d *PeakDetector) Detect(f SpectrumFrame) []Peak {
+	step := f.SampleRate / float64(len(f.Power))
+
+	return d.detect(f.Frequency-f.SampleRate/2, step, f.Power)
+}
+
+// DetectSweep restituisce i segnali rilevati nello spettro a banda larga f, in
+// ordine di frequenza.
+func (d *PeakDetector) DetectSweep(f SweepFrame) []Peak {
+	return d.detect(f.Start, f.Step, f.Power)
+}
+
+// detect restituisce i segnali rilevati nello spettro power, il cui primo bin
+// ha frequenza start ed i successivi distano step Hz.
+func (d *PeakDetector) detect(start, step float64, power []float64) []Peak {
+	d.mu.Lock()
+	threshold, window := d.threshold, d.window
+	d.mu.Unlock()
+
+	floor := noiseFloor(power, window)
+
+	var peaks []Peak
+	for k := 0; k < len(power); k++ {
+		if power[k]-floor[k] < threshold {
+			continue
+		}
+
+		// Il segnale si estende sui bin contigui sopra la soglia.
+		best, j := k, k
+		for ; j < len(power) && power[j]-floor[j] >= threshold; j++ {
+			if power[j] > power[best] {
+				best = j
+			}
+		}
+
+		peaks = append(peaks, Peak{
+			Frequency: start + float64(best)*step,
+			Bandwidth: float64(j-k) * step,
+			Power:     power[best],
+			SNR:       power[best] - floor[best],
+		})
+
+		k = j
+	}
+
+	return peaks
+}
+
+// noiseFloor restituisce per ogni bin di power la mediana dei window bin
+// centrati su di esso.
+func noiseFloor(power []float64, window int) []float64 {
+	n := len(power)
+	floor := make([]float64, n)
+	buf := make([]float64, 0, window)
+
+	for k := range power {
+		lo := maxInt(0, k-window/2)
+		hi := minInt(n, lo+window)
+		lo = maxInt(0, hi-window)
+
+		buf = append(buf[:0], power[lo:hi]...)
+		sort.Float64s(buf)
+		floor[k] = buf[len(buf)/2]
+	}
+
+	return floor
+}
+
+// PeakFrequencies restituisce le frequenze dei segnali peaks, adatte ad esempio
+// a Scanner.List.
+func PeakFrequencies(peaks []Peak) []float64 {
+	freqs := make([]float64, len(peaks))
+	for k, p := range peaks {
+		freqs[k] = p.Frequency
+	}
+
+	return freqs
+}
@@ -0,0 +1,162 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Profiles è un archivio di configurazioni complete della RSP identificate da
+// un nome, salvabili in formato JSON, applicabili ad un Receiver con Apply.
+type Profiles struct {
+	mu       sync.Mutex
+	profiles map[string]Config
+}
+
+// NoProfileError indica che il profilo richiesto non è presente nell'archivio.
+var NoProfileError = errors.New("No Profile Error")
+
+// NewProfiles restituisce un archivio vuoto.
+func NewProfiles() *Profiles {
+	return &Profiles{profiles: map[string]Config{}}
+}
+
+// DefaultProfiles restituisce un archivio con alcuni profili d'uso comune:
+// "airband" per la banda aeronautica, "fm-dx" per la ricezione a lunga distanza
+// della banda FM e "hf-ssb" per le bande HF con LNA disabilitato.
+func DefaultProfiles() *Profiles {
+	p := NewProfiles()
+
+	airband := configured(Airband.Preset()...).config()
+	p.Add("airband", airband)
+
+	fmdx := configured(FMBroadcast.Preset()...).config()
+	fmdx.Bandwidth = BW300
+	fmdx.LNA = true
+	fmdx.IQimbalance = true
+	p.Add("fm-dx", fmdx)
+
+	hf := configured(InitialRF(14.2), FS(2), Bandwidth(BW200), IF(IFzero), LOmode(LOauto)).config()
+	hf.TuningStep = 1
+	p.Add("hf-ssb", hf)
+
+	return p
+}
+
+// LoadProfiles restituisce l'archivio salvato con Save nel file path.
+func LoadProfiles(path string) (*Profiles, error) {
+	b, e := os.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+
+	p := NewProfiles()
+	if e := json.Unmarshal(b, &p.profiles); e != nil {
+		return nil, e
+	}
+
+	return p, nil
+}
+
+// Save salva l'archivio nel file path in formato JSON.
+func (p *Profiles) Save(path string) error {
+	p.mu.Lock()
+	b, e := json.MarshalIndent(p.profiles, "", "  ")
+	p.mu.Unlock()
+
+	if e != nil {
+		return e
+	}
+
+	return os.WriteFile(path, b, 0644)
+}
+
+// Add memorizza la configurazione c con il nome name, sostituendo il profilo
+// con lo stesso nome se presente.
+func (p *Profiles) Add(name string, c Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.profiles[name] = c
+}
+
+// Remove elimina il profilo name.
+func (p *Profiles) Remove(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.profiles, name)
+}
+
+// Get restituisce il profilo name e se è presente.
+func (p *Profiles) Get(name string) (Config, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.profiles[name]
+
+	return c, ok
+}
+
+// Names restituisce i nomi dei profili in ordine alfabetico.
+func (p *Profiles) Names() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, 0, len(p.profiles))
+	for name := range p.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Export salva il profilo name nel file path, nel formato di SaveConfig, così
+// che possa essere condiviso.
+func (p *Profiles) Export(name, path string) error {
+	c, ok := p.Get(name)
+	if !ok {
+		return NoProfileError
+	}
+
+	return SaveConfig(path, c)
+}
+
+// Import aggiunge con il nome name la configurazione salvata nel file path con
+// SaveConfig o Export.
+func (p *Profiles) Import(name, path string) error {
+	c, e := LoadConfig(path)
+	if e != nil {
+		return e
+	}
+
+	p.Add(name, c)
+
+	return nil
+}
+
+// Apply imposta su rx la configurazione del profilo name, come ApplyProfile.
+func (p *Profiles) Apply(rx Receiver, name string) error {
+	c, ok := p.Get(name)
+	if !ok {
+		return NoProfileError
+	}
+
+	return ApplyProfile(rx, c)
+}
+
+// ApplyProfile imposta su rx la configurazione p, ad esempio un profilo
+// restituito da Profiles.Get, come SetUp con le opzioni corrispondenti.
+func ApplyProfile(rx Receiver, p Config) error {
+	return rx.SetUp(p.Option())
+}
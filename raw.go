@@ -0,0 +1,84 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+)
+
+// rawWriter adatta un io.Writer all'io.WriteCloser richiesto da Recorder,
+// senza chiuderlo.
+type rawWriter struct {
+	io.Writer
+}
+
+// Close implementa l'interfaccia io.Closer.
+func (rawWriter) Close() error {
+	return nil
+}
+
+// NewRawWriter restituisce un Recorder che scrive su w il segnale ricevuto come
+// campioni I e Q interlacciati grezzi, senza intestazione, nel formato CS16 o
+// CF32 e con l'ordine dei byte order, ad esempio per alimentare una pipeline di
+// csdr o un file descriptor di GNU Radio. Con order nil viene usato il little
+// endian. Close attende la scrittura dei frame in coda ma non chiude w.
+// Con formati diversi da CS16 e CF32 viene restituito l'errore
+// UnsupportedFileError.
+func NewRawWriter(w io.Writer, format FileFormat, order binary.ByteOrder) (*Recorder, error) {
+	if order == nil {
+		order = binary.LittleEndian
+	}
+
+	r := newRecorder(rawWriter{w})
+
+	switch format {
+	case CS16:
+		r.encode = func(I, Q []int16) []byte {
+			return encodeRaw(I, Q, 2, func(b []byte, x int16) {
+				order.PutUint16(b, uint16(x))
+			})
+		}
+	case CF32:
+		r.encode = func(I, Q []int16) []byte {
+			return encodeRaw(I, Q, 4, func(b []byte, x int16) {
+				order.PutUint32(b, math.Float32bits(float32(x)/fullScale))
+			})
+		}
+	default:
+		r.Close()
+		return nil, UnsupportedFileError
+	}
+
+	return r, nil
+}
+
+// NewStdout restituisce il Recorder creato da NewRawWriter che scrive sullo
+// standard output.
+func NewStdout(format FileFormat, order binary.ByteOrder) (*Recorder, error) {
+	return NewRawWriter(os.Stdout, format, order)
+}
+
+// encodeRaw restituisce i campioni I e Q interlacciati, ciascuno di size byte
+// codificato con put.
+func encodeRaw(I, Q []int16, size int, put func(b []byte, x int16)) []byte {
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	b := make([]byte, 2*size*n)
+	for k := 0; k < n; k++ {
+		put(b[2*size*k:], I[k])
+		put(b[2*size*k+size:], Q[k])
+	}
+
+	return b
+}
@@ -0,0 +1,131 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "time"
+
+// Reconnect descrive l'inizio o la fine di un'interruzione dello stream dovuta
+// alla disconnessione della RSP, notificate con l'opzione AutoReconnect.
+type Reconnect struct {
+	// Hardware descrive la RSP e Time l'istante dell'evento.
+	Hardware Hardware
+	Time     time.Time
+
+	// Restored è false all'inizio dell'interruzione e true alla ripresa
+	// dello stream, quando Outage ne riporta la durata.
+	Restored bool
+	Outage   time.Duration
+
+	// Err è l'errore dell'API che ha rivelato la disconnessione, come in
+	// DeviceRemoved.
+	Err error
+}
+
+// reconnectPoll è l'intervallo con cui, durante un'interruzione, la RSP viene
+// cercata tra i dispositivi.
+const reconnectPoll = time.Second
+
+// AutoReconnect abilita il ripristino automatico dello stream dopo la
+// disconnessione della RSP, rilevata come per OnDeviceRemoved: la RSP con lo
+// stesso numero di serie viene cercata ogni secondo tra quelle collegate e,
+// appena disponibile, riaperta con l'ultima configurazione, frequenza e gain
+// reduction impostate. report, se non nil, viene invocata da una goroutine
+// dedicata all'inizio ed alla fine di ogni interruzione. Durante
+// l'interruzione i metodi del ricevitore restituiscono gli errori dell'API; il
+// ripristino si interrompe con Close.
+func AutoReconnect(report func(Reconnect)) Option {
+	return Option{
+		apply: func() {
+			rsp.Reconnect = true
+			rsp.Reconnected = report
+		},
+	}
+}
+
+// reconnect cerca la RSP disconnessa a causa dell'errore e, rilevata
+// nell'istante start, e riavvia lo stream appena disponibile, fino a Close.
+func (r *radio) reconnect(report func(Reconnect), start time.Time, e error) {
+	if report != nil {
+		report(Reconnect{Hardware: r.hw, Time: start, Err: e})
+	}
+
+	for {
+		time.Sleep(reconnectPoll)
+
+		if !r.active() {
+			return
+		}
+
+		idx, ok := r.find()
+		if !ok {
+			continue
+		}
+
+		r.mu.Lock()
+		if r.baseband == nil {
+			r.mu.Unlock()
+			return
+		}
+		e := r.reopen(idx)
+		r.mu.Unlock()
+
+		if e != nil {
+			r.logger().Warn("rsp reconnect", "serial", r.hw.Serial, "err", e)
+			continue
+		}
+
+		r.started()
+
+		now := time.Now()
+		r.logger().Info("rsp reconnected", "serial", r.hw.Serial, "outage", now.Sub(start))
+
+		if report != nil {
+			report(Reconnect{Hardware: r.hw, Time: now, Restored: true, Outage: now.Sub(start)})
+		}
+
+		return
+	}
+}
+
+// find restituisce l'indice, tra le RSP collegate, di quella del ricevitore se
+// è disponibile.
+func (r *radio) find() (int, bool) {
+	devs, e := api.devices()
+	if e != nil {
+		return 0, false
+	}
+
+	for k, d := range devs {
+		if d.Available && (r.hw.Serial == "" || d.Serial == r.hw.Serial) {
+			return k, true
+		}
+	}
+
+	return 0, false
+}
+
+// reopen ferma lo stream della RSP disconnessa e lo riavvia sulla RSP di indice
+// idx con la configurazione attuale. Va invocata possedendo mu.
+func (r *radio) reopen(idx int) error {
+	// Lo stream della RSP disconnessa va comunque fermato: l'errore è
+	// atteso.
+	r.uninit()
+
+	if e := api.selectDevice(idx); e != nil {
+		return e
+	}
+	r.selected = true
+
+	// Frequenza e gain reduction attuali diventano quelle iniziali.
+	r.smu.Lock()
+	r.feat.InitialRF = double(r.rf / 1e6)
+	r.feat.InitialGR = r.gr
+	r.smu.Unlock()
+
+	return r.init()
+}
@@ -0,0 +1,157 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+// Recorder è un Connector che registra il segnale in banda base su uno Storage
+// nel formato cs16: campioni I e Q interlacciati, interi a 16 bit con segno in
+// little endian. La scrittura avviene in una goroutine dedicata così che la
+// lentezza del supporto, ad esempio di un object store remoto, non blocchi la
+// ricezione: se la coda dei frame in attesa di scrittura è piena i nuovi frame
+// vengono scartati e conteggiati.
+// I Recorder creati con NewWAVRecorder registrano invece in formato WAV, quelli
+// creati con NewRawWriter nel formato indicato.
+type Recorder struct {
+	w      io.WriteCloser
+	encode func(I, Q []int16) []byte
+	frames chan []byte
+	done   chan struct{}
+
+	mu      sync.Mutex
+	err     error
+	dropped int
+	closed  bool
+}
+
+// recorderQueue è il numero massimo di frame in attesa di essere scritti.
+const recorderQueue = 256
+
+// NewRecorder crea su st la registrazione name e restituisce il Recorder che vi
+// scrive il segnale ricevuto. La registrazione deve essere conclusa con Close.
+func NewRecorder(st Storage, name string) (*Recorder, error) {
+	w, e := st.Create(name)
+	if e != nil {
+		return nil, e
+	}
+
+	return newRecorder(w), nil
+}
+
+// newRecorder restituisce un Recorder che scrive il segnale ricevuto su w.
+func newRecorder(w io.WriteCloser) *Recorder {
+	r := &Recorder{
+		w:      w,
+		encode: encodeCS16,
+		frames: make(chan []byte, recorderQueue),
+		done:   make(chan struct{}),
+	}
+
+	go r.write()
+
+	return r
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (r *Recorder) Propagate(I []int16, Q []int16) {
+	b := r.encode(I, Q)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.frames <- b:
+	default:
+		r.dropped++
+	}
+}
+
+// Dropped restituisce il numero di frame scartati perché la scrittura non ha
+// tenuto il passo della ricezione.
+func (r *Recorder) Dropped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.dropped
+}
+
+// Err restituisce il primo errore di scrittura incontrato, dopo il quale i
+// frame successivi vengono ignorati.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Close attende la scrittura dei frame in coda e conclude la registrazione,
+// restituendo il primo errore incontrato.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return r.err
+	}
+	r.closed = true
+	close(r.frames)
+	r.mu.Unlock()
+
+	<-r.done
+
+	e := r.w.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = e
+	}
+
+	return r.err
+}
+
+// write scrive sul supporto i frame in coda fino alla chiusura del Recorder.
+func (r *Recorder) write() {
+	defer close(r.done)
+
+	for b := range r.frames {
+		if r.Err() != nil {
+			continue
+		}
+
+		if _, e := r.w.Write(b); e != nil {
+			r.mu.Lock()
+			r.err = e
+			r.mu.Unlock()
+		}
+	}
+}
+
+// encodeCS16 restituisce i campioni I e Q nel formato cs16.
+func encodeCS16(I, Q []int16) []byte {
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	b := make([]byte, 4*n)
+	for k := 0; k < n; k++ {
+		binary.LittleEndian.PutUint16(b[4*k:], uint16(I[k]))
+		binary.LittleEndian.PutUint16(b[4*k+2:], uint16(Q[k]))
+	}
+
+	return b
+}
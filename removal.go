@@ -0,0 +1,178 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"time"
+)
+
+// DeviceRemoved descrive la disconnessione della RSP usata da un ricevitore.
+type DeviceRemoved struct {
+	// Hardware descrive la RSP disconnessa e Time l'istante in cui la
+	// disconnessione è stata rilevata.
+	Hardware Hardware
+	Time     time.Time
+
+	// Err è l'errore dell'API che ha rivelato la disconnessione, nil se è
+	// stata rivelata dall'assenza dei campioni e della RSP tra i dispositivi.
+	Err error
+}
+
+const (
+	// removalPoll è l'intervallo con cui viene verificato l'arrivo dei
+	// campioni e removalStall l'assenza di campioni dopo la quale la RSP viene
+	// cercata tra i dispositivi.
+	removalPoll  = 250 * time.Millisecond
+	removalStall = time.Second
+
+	// removalErrors è il numero di HwError consecutivi restituiti dall'API
+	// dopo il quale la RSP viene considerata disconnessa.
+	removalErrors = 3
+)
+
+// OnDeviceRemoved abilita il rilevamento della disconnessione della RSP,
+// invocando report quando:
+//   - l'API segnala la disconnessione alla callback dello stream
+//   - un'operazione del ricevitore riceve dall'API l'errore HwRemoved o 3
+//     HwError consecutivi
+//   - la callback non riceve campioni per più di un secondo e la RSP non
+//     compare più tra quelle collegate al sistema.
+//
+// report viene invocata una sola volta per stream, da una goroutine dedicata:
+// può quindi chiudere il ricevitore o tentarne il ripristino. Dopo la
+// disconnessione lo stream non produce altri campioni; i metodi del
+// ricevitore restituiscono gli errori dell'API fino a Close.
+func OnDeviceRemoved(report func(DeviceRemoved)) Option {
+	return Option{
+		apply: func() {
+			rsp.Removed = report
+		},
+	}
+}
+
+// startRemoval avvia, se richiesto con OnDeviceRemoved o AutoReconnect, la
+// goroutine che rileva la disconnessione della RSP in assenza di campioni.
+func (r *radio) startRemoval() {
+	r.last.Store(time.Now().UnixNano())
+	r.hwErrors = 0
+	r.gone.Store(false)
+
+	if r.feat.Removed == nil && !r.feat.Reconnect {
+		return
+	}
+
+	r.removal = make(chan struct{})
+	r.removalDone = make(chan struct{})
+
+	go r.watchRemoval(r.removal, r.removalDone, r.feat)
+}
+
+// stopRemoval termina la goroutine avviata da startRemoval.
+func (r *radio) stopRemoval() {
+	if r.removal == nil {
+		return
+	}
+
+	close(r.removal)
+	<-r.removalDone
+
+	r.removal, r.removalDone = nil, nil
+}
+
+// watchRemoval verifica ogni removalPoll l'arrivo dei campioni e, se assenti
+// da più di removalStall, la presenza della RSP, fino alla chiusura di stop.
+func (r *radio) watchRemoval(stop, done chan struct{}, f features) {
+	defer close(done)
+
+	t := time.NewTicker(removalPoll)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+
+		if time.Since(time.Unix(0, r.last.Load())) < removalStall || r.present() {
+			continue
+		}
+
+		r.removed(f, nil)
+
+		return
+	}
+}
+
+// present indica se la RSP del ricevitore compare tra quelle collegate al
+// sistema. In caso di errore dell'API la RSP viene considerata presente.
+func (r *radio) present() bool {
+	devs, e := api.devices()
+	if e != nil {
+		return true
+	}
+
+	for _, d := range devs {
+		if r.hw.Serial == "" || d.Serial == r.hw.Serial {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hwRemoved notifica la disconnessione della RSP segnalata dall'API alla
+// callback dello stream, con il flag hwRemoved. È invocata dalla callback,
+// quindi senza possedere mu.
+func (r *radio) hwRemoved() {
+	r.smu.Lock()
+	f := r.feat
+	r.smu.Unlock()
+
+	r.removed(f, DeviceRemovedError)
+}
+
+// failed esamina l'errore e restituito dall'API durante un'operazione del
+// ricevitore, rilevando la disconnessione della RSP, e lo restituisce
+// inalterato. Va invocata possedendo mu.
+func (r *radio) failed(e error) error {
+	switch {
+	case e == nil:
+		r.hwErrors = 0
+	case errors.Is(e, DeviceRemovedError):
+		r.removed(r.feat, e)
+	case errors.Is(e, HwError):
+		r.hwErrors++
+		if r.hwErrors >= removalErrors {
+			r.removed(r.feat, e)
+		}
+	}
+
+	return e
+}
+
+// removed notifica, una sola volta per stream, la disconnessione della RSP
+// rilevata a causa dell'errore e alla funzione impostata in f con
+// OnDeviceRemoved ed avvia, se richiesto con AutoReconnect, il ripristino.
+func (r *radio) removed(f features, e error) {
+	if !r.gone.CompareAndSwap(false, true) {
+		return
+	}
+
+	now := time.Now()
+	r.logger().Error("rsp removed", "serial", r.hw.Serial, "err", e)
+
+	if f.Removed != nil {
+		go f.Removed(DeviceRemoved{Hardware: r.hw, Time: now, Err: e})
+	}
+
+	if f.Reconnect {
+		go r.reconnect(f.Reconnected, now, e)
+	}
+}
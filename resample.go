@@ -0,0 +1,117 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "sync"
+
+// CDRate è la frequenza di campionamento, espressa in Hz, dell'audio CD, in
+// alternativa ad AudioRate per le schede audio che la richiedono.
+const CDRate = 44100
+
+// Resampler è un AudioConnector che converte la frequenza di campionamento
+// dell'audio ricevuto e lo propaga al connettore audio fornito. La conversione
+// avviene con un filtro polifase di rapporto razionale up/down: il rapporto è
+// esatto per tutte le combinazioni di frequenze il cui rapporto ridotto ai
+// minimi termini ha termini non maggiori di 1024, come 48000/44100 o le
+// frequenze intermedie ottenute dalle frequenze di campionamento della RSP
+// espresse in Hz interi.
+type Resampler struct {
+	mu sync.Mutex
+
+	rs       *resampler
+	in, pcm  []float64
+	audio    []float32
+	inRate   float64
+	outRate  float64
+	out      AudioConnector
+	up, down int
+}
+
+// NewResampler restituisce un Resampler che converte l'audio campionato a in Hz
+// in audio campionato a out Hz, ad esempio da AudioRate a CDRate, e lo propaga
+// al connettore next.
+func NewResampler(in, out float64, next AudioConnector) *Resampler {
+	rs := newResampler(in, out, 0)
+
+	return &Resampler{
+		rs:      rs,
+		inRate:  in,
+		outRate: in * float64(rs.up) / float64(rs.down),
+		out:     next,
+		up:      rs.up,
+		down:    rs.down,
+	}
+}
+
+// Ratio restituisce i fattori di sovracampionamento e decimazione usati.
+func (r *Resampler) Ratio() (up, down int) {
+	return r.up, r.down
+}
+
+// Rate restituisce la frequenza di campionamento effettiva, espressa in Hz,
+// dell'audio propagato. Coincide con quella richiesta se il rapporto tra le due
+// frequenze è rappresentabile esattamente.
+func (r *Resampler) Rate() float64 {
+	return r.outRate
+}
+
+// PropagateAudio implementa l'interfaccia AudioConnector.
+func (r *Resampler) PropagateAudio(samples []float32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.in = r.in[:0]
+	for _, x := range samples {
+		r.in = append(r.in, float64(x))
+	}
+
+	r.pcm = r.rs.process(r.in, r.pcm[:0])
+	if len(r.pcm) == 0 || r.out == nil {
+		return
+	}
+
+	r.audio = toAudio(r.pcm, r.audio[:0])
+	r.out.PropagateAudio(r.audio)
+}
+
+// IQResampler è un Connector che converte la frequenza di campionamento del
+// segnale in banda base ricevuto e lo propaga al connettore fornito, per
+// raggiungere frequenze non ottenibili con la decimazione hardware, ad esempio
+// da 2.4 MHz a 2 MHz. Se il rapporto tra le frequenze, ridotto ai minimi
+// termini, ha termini non maggiori di 1024 la conversione avviene con un filtro
+// polifase razionale, altrimenti, come per i rapporti irrazionali, con un
+// banco polifase che interpola tra i rami: in entrambi i casi la frequenza dei
+// campioni propagati coincide con quella richiesta.
+type IQResampler struct {
+	mu sync.Mutex
+
+	rc  *rateConverter
+	out Connector
+}
+
+// NewIQResampler restituisce un IQResampler che converte il segnale campionato
+// ad in Hz in uno campionato ad out Hz e lo propaga al connettore next.
+func NewIQResampler(in, out float64, next Connector) *IQResampler {
+	return &IQResampler{
+		rc:  newRateConverter(in, out),
+		out: next,
+	}
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (r *IQResampler) Propagate(I, Q []int16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, q := r.rc.apply(I, Q)
+	if len(i) == 0 || r.out == nil {
+		return
+	}
+
+	r.out.Propagate(i, q)
+}
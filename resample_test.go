@@ -0,0 +1,83 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/iclac/sdrplay"
+	"github.com/iclac/sdrplay/sdrplaytest"
+)
+
+// TestResampler verifica il rapporto, il numero di campioni e la frequenza
+// dell'audio convertito da AudioRate a CDRate.
+func TestResampler(t *testing.T) {
+	a := &audioBuffer{}
+	r := sdrplay.NewResampler(sdrplay.AudioRate, sdrplay.CDRate, a)
+
+	if up, down := r.Ratio(); up != 147 || down != 160 {
+		t.Errorf("Ratio() = %d/%d, want 147/160", up, down)
+	}
+
+	if r.Rate() != sdrplay.CDRate {
+		t.Errorf("Rate() = %g, want %d", r.Rate(), sdrplay.CDRate)
+	}
+
+	in := make([]float32, 4800)
+	for k := 0; k < 10; k++ {
+		for n := range in {
+			in[n] = float32(0.5 * math.Sin(2*math.Pi*1000*float64(k*len(in)+n)/sdrplay.AudioRate))
+		}
+		r.PropagateAudio(in)
+	}
+
+	want := sdrplay.CDRate
+	if n := len(a.samples); math.Abs(float64(n-want)) > 100 {
+		t.Fatalf("got %d samples, want about %d", n, want)
+	}
+
+	x := a.samples[len(a.samples)/4:]
+	if l := toneLevel(x, 1000, sdrplay.CDRate); math.Abs(l-0.5) > 0.05 {
+		t.Errorf("1 kHz level %.3f after resampling, want 0.5", l)
+	}
+}
+
+// TestIQResampler verifica che il segnale in banda base convertito da 2.4 MHz a
+// 2 MHz abbia il numero di campioni atteso e conservi la frequenza di una
+// portante.
+func TestIQResampler(t *testing.T) {
+	const (
+		in     = 2.4e6
+		out    = 2e6
+		offset = 100e3
+	)
+
+	b := sdrplaytest.NewBuffer(0)
+	g := sdrplay.NewSignalGen(in, sdrplay.NewIQResampler(in, out, b), sdrplay.Tone(offset, -6))
+	g.Generate(in / 10)
+
+	I, Q := b.Samples()
+	if n := len(I); math.Abs(float64(n)-out/10) > 1000 {
+		t.Fatalf("got %d samples, want about %g", n, out/10)
+	}
+
+	// La frequenza della portante si ricava dalla rotazione media tra
+	// campioni successivi, scartato il transitorio iniziale.
+	var rot complex128
+	for k := len(I) / 4; k < len(I); k++ {
+		x := complex(float64(I[k]), float64(Q[k]))
+		p := complex(float64(I[k-1]), float64(Q[k-1]))
+		rot += x * complex(real(p), -imag(p))
+	}
+
+	f := math.Atan2(imag(rot), real(rot)) * out / (2 * math.Pi)
+	if math.Abs(f-offset) > 100 {
+		t.Errorf("carrier at %.0f Hz after resampling, want %.0f Hz", f, offset)
+	}
+}
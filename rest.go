@@ -0,0 +1,386 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type (
+	// HTTPControl è un http.Handler che permette di controllare il ricevitore
+	// con semplici richieste HTTP, ad esempio da curl o da un sistema di
+	// domotica. Le risorse, con valori in JSON, sono:
+	//
+	//	GET     /            stato completo del ricevitore
+	//	GET/PUT /frequency   frequenza sintonizzata in Hz, ad esempio 100e6
+	//	GET/PUT /gain        gain reduction in dB, ad esempio 40
+	//	GET/PUT /agc         AGC, ad esempio {"mode":"AGC5Hz","dbfs":-30}
+	//	GET/PUT /bandwidth   larghezza di banda in kHz, ad esempio 1536
+	//	GET     /recording   registrazione in corso
+	//	PUT     /recording   avvia la registrazione {"name":"..."}
+	//	DELETE  /recording   conclude la registrazione
+	//
+	// Le registrazioni con estensione .wav sono in formato WAV, le altre in
+	// formato cs16; il nome deve essere un percorso relativo interno allo
+	// Storage.
+	// Per impedire che una pagina web visitata dall'utente modifichi la
+	// configurazione della RSP, le richieste PUT devono avere Content-Type
+	// application/json e, come per SpectrumServer, sono accettate solo le
+	// richieste senza intestazione Origin, quelle dello stesso server e quelle
+	// delle origini autorizzate con AllowOrigin.
+	// Per montarlo sotto un prefisso di un http.ServeMux esistente si usa
+	// http.StripPrefix:
+	//
+	//	ctl := sdrplay.NewHTTPControl(fs, sdrplay.NewDisk("rec"), out)
+	//	rx, e := sdrplay.RSP(ctl, sdrplay.OnRetune(ctl))
+	//	ctl.Control(rx)
+	//	mux.Handle("/radio/", http.StripPrefix("/radio", ctl))
+	//
+	// HTTPControl è anche il Connector dal quale vengono registrati i frame,
+	// propagati poi inalterati ad out se non nil, ed un Trigger con cui
+	// seguire le risintonie non eseguite attraverso di esso.
+	HTTPControl struct {
+		mu sync.Mutex
+
+		rx      Receiver
+		st      Storage
+		fs      float64
+		origins []string
+
+		state httpState
+
+		recorder *Recorder
+		out      Connector
+	}
+
+	// httpState è lo stato del ricevitore noto ad HTTPControl.
+	httpState struct {
+		Frequency float64        `json:"frequency"`
+		Gain      int            `json:"gain"`
+		AGC       httpAGC        `json:"agc"`
+		Bandwidth int            `json:"bandwidth"`
+		Recording *httpRecording `json:"recording"`
+	}
+
+	// httpAGC è la risorsa /agc di HTTPControl.
+	httpAGC struct {
+		Mode AGCmode `json:"mode"`
+		DBFS int     `json:"dbfs"`
+	}
+
+	// httpRecording è la risorsa /recording di HTTPControl.
+	httpRecording struct {
+		Name    string `json:"name"`
+		Dropped int    `json:"dropped"`
+	}
+)
+
+var (
+	// RecordingError indica che è stata richiesta una registrazione mentre ne
+	// è in corso un'altra.
+	RecordingError = errors.New("Recording Error")
+
+	// NoStorageError indica che è stata richiesta una registrazione senza aver
+	// fornito uno Storage.
+	NoStorageError = errors.New("No Storage Error")
+)
+
+// NewHTTPControl restituisce un HTTPControl per un segnale campionato con
+// frequenza fs, espressa in Hz, che archivia le registrazioni su st e propaga i
+// frame ad out se non nil. Se st è nil le registrazioni non sono disponibili.
+func NewHTTPControl(fs float64, st Storage, out Connector) *HTTPControl {
+	return &HTTPControl{fs: fs, st: st, out: out}
+}
+
+// Control imposta il ricevitore al quale vengono applicate le richieste.
+// Frequenza, gain reduction, AGC e larghezza di banda iniziali sono quelli
+// configurati sulla RSP; per gli altri ricevitori sono nulli fino alla prima
+// richiesta.
+func (h *HTTPControl) Control(r Receiver) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.rx = r
+
+	if p, ok := r.(*radio); ok && p != nil {
+		c := p.Config()
+		h.state.Frequency = c.InitialRF * 1e6
+		h.state.Gain = c.InitialGR
+		h.state.AGC = httpAGC{Mode: c.AGC, DBFS: c.DBFS}
+		h.state.Bandwidth = int(c.Bandwidth)
+	}
+}
+
+// AllowOrigin autorizza le richieste dalle pagine delle origini indicate, ad
+// esempio "https://example.com:8443", oltre a quelle dello stesso server.
+func (h *HTTPControl) AllowOrigin(origins ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.origins = append(h.origins, origins...)
+}
+
+// Fire implementa l'interfaccia Trigger, aggiornando la frequenza restituita
+// dalla risorsa /frequency.
+func (h *HTTPControl) Fire(frequency float64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.state.Frequency = frequency
+
+	return nil
+}
+
+// Close conclude l'eventuale registrazione in corso.
+func (h *HTTPControl) Close() error {
+	h.mu.Lock()
+	r := h.recorder
+	h.recorder, h.state.Recording = nil, nil
+	h.mu.Unlock()
+
+	if r != nil {
+		return r.Close()
+	}
+
+	return nil
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (h *HTTPControl) Propagate(I []int16, Q []int16) {
+	h.mu.Lock()
+	r := h.recorder
+	h.mu.Unlock()
+
+	if r != nil {
+		r.Propagate(I, Q)
+	}
+
+	if h.out != nil {
+		h.out.Propagate(I, Q)
+	}
+}
+
+// ServeHTTP implementa l'interfaccia http.Handler.
+func (h *HTTPControl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resource := strings.Trim(r.URL.Path, "/")
+
+	h.mu.Lock()
+	allowed := allowedOrigin(r, h.origins)
+	h.mu.Unlock()
+
+	if !allowed {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	var v interface{}
+	var e error
+
+	switch {
+	case r.Method == http.MethodGet:
+		v, e = h.get(resource)
+	case r.Method == http.MethodPut:
+		v, e = h.put(resource, r)
+	case r.Method == http.MethodDelete && resource == "recording":
+		e = h.Close()
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case e == errHTTPNotFound:
+		http.NotFound(w, r)
+	case e != nil:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(httpStatus(e))
+		json.NewEncoder(w).Encode(map[string]string{"error": e.Error()})
+	case v == nil:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+var (
+	// errHTTPNotFound indica che la risorsa richiesta non esiste.
+	errHTTPNotFound = errors.New("not found")
+
+	// errHTTPContentType indica che il corpo della richiesta non è JSON.
+	errHTTPContentType = errors.New("content type must be application/json")
+)
+
+// httpMaxBody è la dimensione massima del corpo di una richiesta.
+const httpMaxBody = 4096
+
+// get restituisce il valore della risorsa indicata.
+func (h *HTTPControl) get(resource string) (interface{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.state
+	if h.recorder != nil {
+		rec := *s.Recording
+		rec.Dropped = h.recorder.Dropped()
+		s.Recording = &rec
+	}
+
+	switch resource {
+	case "":
+		return s, nil
+	case "frequency":
+		return s.Frequency, nil
+	case "gain":
+		return s.Gain, nil
+	case "agc":
+		return s.AGC, nil
+	case "bandwidth":
+		return s.Bandwidth, nil
+	case "recording":
+		return s.Recording, nil
+	}
+
+	return nil, errHTTPNotFound
+}
+
+// put applica al ricevitore il valore della risorsa indicata contenuto nella
+// richiesta r e lo restituisce.
+func (h *HTTPControl) put(resource string, r *http.Request) (interface{}, error) {
+	h.mu.Lock()
+	rx := h.rx
+	h.mu.Unlock()
+
+	if rx == nil && resource != "recording" {
+		return nil, NoReceiverError
+	}
+
+	if t, _, e := mime.ParseMediaType(r.Header.Get("Content-Type")); e != nil || t != "application/json" {
+		return nil, errHTTPContentType
+	}
+
+	dec := json.NewDecoder(io.LimitReader(r.Body, httpMaxBody))
+
+	switch resource {
+	case "frequency":
+		var f float64
+		if e := dec.Decode(&f); e != nil {
+			return nil, e
+		}
+		if e := rx.Tune(f); e != nil {
+			return nil, e
+		}
+		h.Fire(f)
+		return f, nil
+	case "gain":
+		var g int
+		if e := dec.Decode(&g); e != nil {
+			return nil, e
+		}
+		if e := rx.Gain(g); e != nil {
+			return nil, e
+		}
+		h.update(func(s *httpState) { s.Gain = g })
+		return g, nil
+	case "agc":
+		var a httpAGC
+		if e := dec.Decode(&a); e != nil {
+			return nil, e
+		}
+		if e := rx.SetUp(AGC(a.Mode, a.DBFS)); e != nil {
+			return nil, e
+		}
+		h.update(func(s *httpState) { s.AGC = a })
+		return a, nil
+	case "bandwidth":
+		var b int
+		if e := dec.Decode(&b); e != nil {
+			return nil, e
+		}
+		if e := rx.SetUp(Bandwidth(B(b))); e != nil {
+			return nil, e
+		}
+		h.update(func(s *httpState) { s.Bandwidth = b })
+		return b, nil
+	case "recording":
+		var rec httpRecording
+		if e := dec.Decode(&rec); e != nil {
+			return nil, e
+		}
+		return h.record(rec)
+	}
+
+	return nil, errHTTPNotFound
+}
+
+// update modifica lo stato noto con f.
+func (h *HTTPControl) update(f func(*httpState)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f(&h.state)
+}
+
+// record avvia la registrazione indicata.
+func (h *HTTPControl) record(rec httpRecording) (interface{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.st == nil {
+		return nil, NoStorageError
+	}
+	if h.recorder != nil {
+		return nil, RecordingError
+	}
+	if !filepath.IsLocal(filepath.FromSlash(rec.Name)) {
+		return nil, InvalidNameError
+	}
+
+	var r *Recorder
+	var e error
+	if strings.HasSuffix(strings.ToLower(rec.Name), ".wav") {
+		r, e = NewWAVRecorder(h.st, rec.Name, h.fs, h.state.Frequency)
+	} else {
+		r, e = NewRecorder(h.st, rec.Name)
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	h.recorder = r
+	h.state.Recording = &httpRecording{Name: rec.Name}
+
+	return h.state.Recording, nil
+}
+
+// httpStatus restituisce lo stato HTTP corrispondente all'errore e.
+func httpStatus(e error) int {
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(e, &syntax), errors.As(e, &typ), e == io.EOF, e == io.ErrUnexpectedEOF, e == InvalidNameError:
+		return http.StatusBadRequest
+	case e == errHTTPContentType:
+		return http.StatusUnsupportedMediaType
+	case e == RecordingError:
+		return http.StatusConflict
+	case e == NoReceiverError, e == NoStorageError:
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,84 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iclac/sdrplay"
+)
+
+// TestHTTPControl verifica che HTTPControl rifiuti le richieste malformate,
+// quelle provenienti da altre origini ed i nomi di registrazione esterni allo
+// Storage.
+func TestHTTPControl(t *testing.T) {
+	root := t.TempDir()
+
+	ctl := sdrplay.NewHTTPControl(2e6, sdrplay.NewDisk(filepath.Join(root, "rec")), nil)
+	ctl.AllowOrigin("https://example.com")
+	defer ctl.Close()
+
+	const json = "application/json"
+
+	tests := []struct {
+		method string
+		path   string
+		ctype  string
+		origin string
+		body   string
+		status int
+	}{
+		{"GET", "/", "", "", "", http.StatusOK},
+		{"GET", "/unknown", "", "", "", http.StatusNotFound},
+		{"POST", "/frequency", json, "", "100e6", http.StatusMethodNotAllowed},
+		{"PUT", "/frequency", json, "", "100e6", http.StatusServiceUnavailable},
+		{"PUT", "/recording", "text/plain", "", `{"name":"a.raw"}`, http.StatusUnsupportedMediaType},
+		{"PUT", "/recording", "", "", `{"name":"a.raw"}`, http.StatusUnsupportedMediaType},
+		{"PUT", "/recording", json, "", `{"name":`, http.StatusBadRequest},
+		{"PUT", "/recording", json, "", `{"name":1}`, http.StatusBadRequest},
+		{"PUT", "/recording", json, "", ``, http.StatusBadRequest},
+		{"PUT", "/recording", json, "", `{"name":""}`, http.StatusBadRequest},
+		{"PUT", "/recording", json, "", `{"name":"../escape.raw"}`, http.StatusBadRequest},
+		{"PUT", "/recording", json, "", `{"name":"fm/../../escape.raw"}`, http.StatusBadRequest},
+		{"PUT", "/recording", json, "", `{"name":"/tmp/escape.raw"}`, http.StatusBadRequest},
+		{"PUT", "/recording", json, "https://evil.example", `{"name":"a.raw"}`, http.StatusForbidden},
+		{"PUT", "/recording", json + "; charset=utf-8", "", `{"name":"a.raw"}`, http.StatusOK},
+		{"PUT", "/recording", json, "", `{"name":"b.raw"}`, http.StatusConflict},
+		{"DELETE", "/recording", "", "https://evil.example", "", http.StatusForbidden},
+		{"DELETE", "/recording", "", "https://example.com", "", http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.ctype != "" {
+			r.Header.Set("Content-Type", test.ctype)
+		}
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+
+		w := httptest.NewRecorder()
+		ctl.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("%s %s %q: got status %d, want %d", test.method, test.path, test.body, w.Code, test.status)
+		}
+	}
+
+	if _, e := os.Stat(filepath.Join(root, "rec", "a.raw")); e != nil {
+		t.Error(e)
+	}
+	if _, e := os.Stat(filepath.Join(root, "escape.raw")); e == nil {
+		t.Error("recording created outside the storage directory")
+	}
+}
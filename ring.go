@@ -0,0 +1,277 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// minRing è la capacità minima, in campioni, di un Ring.
+const minRing = 1 << 14
+
+// Ring è un Connector che disaccoppia la sorgente dei frame, tipicamente la
+// callback della RSP, dall'elaborazione: Propagate copia il frame in un buffer
+// circolare senza lock e ritorna immediatamente, mentre una goroutine dedicata
+// lo propaga ad out. La sorgente non attende quindi mai il codice a valle; se
+// il buffer è pieno il frame viene scartato e conteggiato da Overruns.
+//
+// Ring è un buffer a singolo produttore e singolo consumatore: Propagate non
+// va invocato da più goroutine contemporaneamente. I frame propagati ad out
+// contengono i campioni disponibili al momento della lettura e possono quindi
+// avere lunghezza diversa da quelli ricevuti.
+//
+// Ring implementa anche PacketConnector: le informazioni dei frame ricevuti
+// con PropagatePacket vengono conservate insieme ai campioni e, se out è a
+// sua volta un PacketConnector, propagate con ogni frame, che in tal caso non
+// supera la fine del pacchetto da cui proviene. FirstSample riporta il numero
+// del primo campione del frame propagato.
+type Ring struct {
+	// i e q sono il buffer circolare, di capacità pari ad una potenza di 2;
+	// head e tail sono il numero di campioni scritti e letti.
+	i, q       []int16
+	mask       uint64
+	head, tail atomic.Uint64
+
+	// packets è la coda circolare delle informazioni dei frame ricevuti con
+	// PropagatePacket; phead e ptail sono il numero di elementi scritti e
+	// letti. cur è il pacchetto dell'ultimo frame propagato, se current,
+	// usato solo dalla goroutine che propaga i frame. packeted indica se
+	// l'ultimo frame scritto è stato ricevuto con PropagatePacket, ed è usato
+	// solo dalla sorgente.
+	packets      []ringPacket
+	pmask        uint64
+	phead, ptail atomic.Uint64
+	cur          ringPacket
+	current      bool
+	packeted     bool
+
+	overruns atomic.Uint64
+
+	// pool, se non nil, contiene i buffer preallocati, impostati con
+	// l'opzione Recycle, nei quali vengono copiati i frame propagati.
+	pool atomic.Pointer[framePool]
+
+	// wake segnala al consumatore la disponibilità di nuovi campioni.
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	out Connector
+}
+
+// ringPacket sono le informazioni di un frame conservato in un Ring: at è il
+// numero, tra quelli scritti nel buffer, del primo campione del frame. none
+// indica un frame ricevuto con Propagate dopo uno ricevuto con PropagatePacket,
+// i cui campioni non hanno quindi informazioni.
+type ringPacket struct {
+	at   uint64
+	info PacketInfo
+	none bool
+}
+
+// ringPackets è il numero minimo di campioni per pacchetto per il quale la
+// coda delle informazioni di un Ring non si riempie prima del buffer.
+const ringPackets = 64
+
+// NewRing restituisce un Ring in grado di contenere d di segnale campionato con
+// frequenza fs, espressa in Hz, che propaga i frame ad out. La capacità viene
+// arrotondata alla potenza di 2 successiva, con un minimo di 16384 campioni.
+func NewRing(fs float64, d time.Duration, out Connector) *Ring {
+	size := minRing
+	for float64(size) < math.Ceil(fs*d.Seconds()) {
+		size <<= 1
+	}
+
+	r := &Ring{
+		i:       make([]int16, size),
+		q:       make([]int16, size),
+		mask:    uint64(size - 1),
+		packets: make([]ringPacket, size/ringPackets),
+		pmask:   uint64(size/ringPackets - 1),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		out:     out,
+	}
+
+	go r.run()
+
+	return r
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (r *Ring) Propagate(I []int16, Q []int16) {
+	r.write(nil, I, Q)
+}
+
+// PropagatePacket implementa l'interfaccia PacketConnector.
+func (r *Ring) PropagatePacket(info PacketInfo, I []int16, Q []int16) {
+	r.write(&info, I, Q)
+}
+
+// write copia nel buffer il frame di campioni I e Q e, se non nil, le relative
+// informazioni info.
+func (r *Ring) write(info *PacketInfo, I, Q []int16) {
+	// Di un frame con componenti di lunghezza diversa vengono accodati solo i
+	// campioni completi.
+	if len(Q) < len(I) {
+		I = I[:len(Q)]
+	}
+	Q = Q[:len(I)]
+	n := uint64(len(I))
+	head, tail := r.head.Load(), r.tail.Load()
+	phead, ptail := r.phead.Load(), r.ptail.Load()
+
+	// Un frame senza informazioni che segue un pacchetto viene segnalato nella
+	// coda, altrimenti i suoi campioni verrebbero attribuiti al pacchetto.
+	mark := info != nil || r.packeted
+
+	if n > uint64(len(r.i))-(head-tail) || mark && phead-ptail == uint64(len(r.packets)) {
+		r.overruns.Add(1)
+		return
+	}
+
+	k := int(head & r.mask)
+	c := copy(r.i[k:], I)
+	copy(r.q[k:], Q[:c])
+	copy(r.i, I[c:])
+	copy(r.q, Q[c:])
+
+	// Le informazioni vengono rese disponibili prima dei campioni, così che
+	// il consumatore le trovi leggendo il frame.
+	switch {
+	case info != nil:
+		r.packets[phead&r.pmask] = ringPacket{at: head, info: *info}
+		r.phead.Store(phead + 1)
+	case mark:
+		r.packets[phead&r.pmask] = ringPacket{at: head, none: true}
+		r.phead.Store(phead + 1)
+	}
+	r.packeted = info != nil
+
+	r.head.Store(head + n)
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Overruns restituisce il numero di frame scartati perché il buffer era pieno.
+func (r *Ring) Overruns() uint64 {
+	return r.overruns.Load()
+}
+
+// Fill restituisce il riempimento del buffer, tra 0 ed 1.
+func (r *Ring) Fill() float64 {
+	return float64(r.head.Load()-r.tail.Load()) / float64(len(r.i))
+}
+
+// Close propaga i campioni ancora nel buffer ed attende la fine della
+// goroutine che li propaga. Va invocato dopo che la sorgente ha smesso di
+// propagare frame.
+func (r *Ring) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+
+	return nil
+}
+
+// run propaga ad out i campioni presenti nel buffer fino all'invocazione di
+// Close.
+func (r *Ring) run() {
+	defer close(r.done)
+
+	for {
+		head, tail := r.head.Load(), r.tail.Load()
+		if head == tail {
+			select {
+			case <-r.wake:
+				continue
+			case <-r.stop:
+				if r.head.Load() == tail {
+					return
+				}
+				continue
+			}
+		}
+
+		k := int(tail & r.mask)
+		n := minInt(int(head-tail), len(r.i)-k)
+
+		info, ok := r.packet(tail, &n)
+
+		pool := r.pool.Load()
+		if pool != nil {
+			n = minInt(n, len(pool.i[0]))
+		}
+
+		i, q := pool.frame(n)
+		copy(i, r.i[k:k+n])
+		copy(q, r.q[k:k+n])
+		r.tail.Store(tail + uint64(n))
+
+		if pc, isPacket := r.out.(PacketConnector); ok && isPacket {
+			pc.PropagatePacket(info, i, q)
+		} else {
+			r.out.Propagate(i, q)
+		}
+	}
+}
+
+// recycle fa sì che i frame vengano propagati in count buffer preallocati di
+// n campioni, usati a rotazione come con l'opzione Recycle: i frame propagati
+// non superano quindi n campioni.
+func (r *Ring) recycle(n, count int) {
+	r.pool.Store(newFramePool(n, count))
+}
+
+// packet restituisce le informazioni del frame che contiene il campione at,
+// con FirstSample riferito ad at, limitando n così che il frame propagato non
+// superi l'inizio del successivo. ok è false se il campione non è stato
+// ricevuto con PropagatePacket.
+func (r *Ring) packet(at uint64, n *int) (info PacketInfo, ok bool) {
+	phead := r.phead.Load()
+
+	for ptail := r.ptail.Load(); ptail != phead; ptail++ {
+		p := r.packets[ptail&r.pmask]
+		if p.at > at {
+			*n = minInt(*n, int(p.at-at))
+			break
+		}
+
+		r.cur, r.current = p, !p.none
+		r.ptail.Store(ptail + 1)
+	}
+
+	if !r.current {
+		return PacketInfo{}, false
+	}
+
+	info = r.cur.info
+	info.FirstSample += uint32(at - r.cur.at)
+
+	return info, true
+}
+
+// Buffering inserisce tra la callback della RSP ed il Connector fornito a RSP
+// un Ring in grado di contenere d di segnale alla frequenza di campionamento
+// iniziale, così che la callback non attenda mai l'elaborazione. Close del
+// ricevitore attende che i campioni nel buffer siano stati propagati. Un valore
+// di d non positivo disabilita il buffer.
+func Buffering(d time.Duration) Option {
+	return Option{
+		apply: func() {
+			rsp.Buffering = d
+		},
+	}
+}
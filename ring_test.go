@@ -0,0 +1,168 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iclac/sdrplay"
+	"github.com/iclac/sdrplay/sdrplaytest"
+)
+
+// packetBuffer è un PacketConnector che conserva le informazioni ed il numero
+// di campioni dei frame propagati.
+type packetBuffer struct {
+	infos []sdrplay.PacketInfo
+	sizes []int
+}
+
+// Propagate implementa l'interfaccia sdrplay.Connector.
+func (p *packetBuffer) Propagate(I []int16, Q []int16) {
+	panic("Propagate invoked on a PacketConnector")
+}
+
+// PropagatePacket implementa l'interfaccia sdrplay.PacketConnector.
+func (p *packetBuffer) PropagatePacket(info sdrplay.PacketInfo, I []int16, Q []int16) {
+	p.infos = append(p.infos, info)
+	p.sizes = append(p.sizes, len(I))
+}
+
+// TestRingPackets verifica che Ring propaghi con ogni frame le informazioni del
+// pacchetto da cui proviene, anche attraversando la fine del buffer.
+func TestRingPackets(t *testing.T) {
+	const (
+		packets = 40
+		spp     = 1008
+	)
+
+	out := &packetBuffer{}
+	r := sdrplay.NewRing(2e6, 10*time.Millisecond, out)
+
+	I, Q := make([]int16, spp), make([]int16, spp)
+	for k := 0; k < packets; k++ {
+		r.PropagatePacket(sdrplay.PacketInfo{SamplesPerPacket: spp, FirstSample: uint32(1000 + k*spp), LNAGainReduction: k}, I, Q)
+		if k%8 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	r.Close()
+
+	if r.Overruns() != 0 {
+		t.Fatalf("%d overruns", r.Overruns())
+	}
+
+	next := 0
+	for k, info := range out.infos {
+		packet := next / spp
+
+		if info.FirstSample != uint32(1000+next) {
+			t.Errorf("frame %d: FirstSample %d, want %d", k, info.FirstSample, 1000+next)
+		}
+
+		if info.LNAGainReduction != packet || info.SamplesPerPacket != spp {
+			t.Errorf("frame %d: info %+v, want packet %d", k, info, packet)
+		}
+
+		if (next+out.sizes[k]-1)/spp != packet {
+			t.Errorf("frame %d: %d samples from %d cross the end of packet %d", k, out.sizes[k], next, packet)
+		}
+
+		next += out.sizes[k]
+	}
+
+	if next != packets*spp {
+		t.Errorf("propagated %d samples, want %d", next, packets*spp)
+	}
+}
+
+// mixedBuffer è un PacketConnector che conserva, per ogni frame, le
+// informazioni del pacchetto o, se propagato con Propagate, nil.
+type mixedBuffer struct {
+	infos []*sdrplay.PacketInfo
+	sizes []int
+}
+
+// Propagate implementa l'interfaccia sdrplay.Connector.
+func (m *mixedBuffer) Propagate(I []int16, Q []int16) {
+	m.infos = append(m.infos, nil)
+	m.sizes = append(m.sizes, len(I))
+}
+
+// PropagatePacket implementa l'interfaccia sdrplay.PacketConnector.
+func (m *mixedBuffer) PropagatePacket(info sdrplay.PacketInfo, I []int16, Q []int16) {
+	m.infos = append(m.infos, &info)
+	m.sizes = append(m.sizes, len(I))
+}
+
+// TestRingMixedFrames verifica che i frame ricevuti con Propagate dopo quelli
+// ricevuti con PropagatePacket vengano propagati senza le informazioni
+// dell'ultimo pacchetto.
+func TestRingMixedFrames(t *testing.T) {
+	const spp = 1008
+
+	// packets indica, per ogni frame scritto, se va scritto con
+	// PropagatePacket.
+	packets := []bool{true, true, false, false, true, false}
+
+	out := &mixedBuffer{}
+	r := sdrplay.NewRing(2e6, 10*time.Millisecond, out)
+
+	I, Q := make([]int16, spp), make([]int16, spp)
+	for k, p := range packets {
+		if p {
+			r.PropagatePacket(sdrplay.PacketInfo{SamplesPerPacket: spp, FirstSample: uint32(k * spp)}, I, Q)
+		} else {
+			r.Propagate(I, Q)
+		}
+	}
+	r.Close()
+
+	if r.Overruns() != 0 {
+		t.Fatalf("%d overruns", r.Overruns())
+	}
+
+	next := 0
+	for k, info := range out.infos {
+		frame := next / spp
+
+		switch {
+		case packets[frame] && info == nil:
+			t.Errorf("frame %d: samples from %d of packet %d propagated without info", k, next, frame)
+		case !packets[frame] && info != nil:
+			t.Errorf("frame %d: samples from %d propagated with info %+v", k, next, *info)
+		case info != nil && info.FirstSample != uint32(next):
+			t.Errorf("frame %d: FirstSample %d, want %d", k, info.FirstSample, next)
+		}
+
+		if (next+out.sizes[k]-1)/spp != frame && (packets[frame] || packets[(next+out.sizes[k]-1)/spp]) {
+			t.Errorf("frame %d: %d samples from %d cross the end of frame %d", k, out.sizes[k], next, frame)
+		}
+
+		next += out.sizes[k]
+	}
+
+	if next != len(packets)*spp {
+		t.Errorf("propagated %d samples, want %d", next, len(packets)*spp)
+	}
+}
+
+// TestRingUnequalComponents verifica che Ring accodi dei frame con componenti
+// di lunghezza diversa i soli campioni completi.
+func TestRingUnequalComponents(t *testing.T) {
+	c := sdrplaytest.NewCounter()
+	r := sdrplay.NewRing(2e6, 10*time.Millisecond, c)
+
+	r.Propagate(make([]int16, 1000), make([]int16, 600))
+	r.Propagate(make([]int16, 400), make([]int16, 900))
+	r.Close()
+
+	if n := c.Samples(); n != 1000 {
+		t.Errorf("propagated %d samples, want 1000", n)
+	}
+}
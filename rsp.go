@@ -27,11 +27,21 @@ type (
 		Gain(reduction int) error
 	}
 
-	// Receiver è l'interfaccia che descrive un semplice ricevitore radio.
+	// Receiver è l'interfaccia che descrive un semplice ricevitore radio. Le
+	// funzionalità proprie della RSP sono descritte da interfacce facoltative,
+	// come RelativeTuner, SyncUpdater o DeviceControl, che il ricevitore
+	// restituito da RSP implementa.
 	Receiver interface {
 		Tuner
 		Amplifier
 		SetUp(opts ...Option) error
+
+		// State restituisce lo stato attuale del ricevitore.
+		State() State
+
+		// Close ferma il ricevitore. Al ritorno il Connector non riceverà
+		// altri frame.
+		Close() error
 	}
 
 	// Connector è l'interfaccia che descrive un connettore, ossia il mezzo
@@ -49,6 +59,26 @@ type (
 		Propagate(I []int16, Q []int16)
 	}
 
+	// AudioConnector è l'interfaccia che descrive un connettore audio, ossia il
+	// mezzo attraverso il quale un demodulatore propaga il segnale audio che ha
+	// prodotto.
+	AudioConnector interface {
+		// PropagateAudio permette al demodulatore di propagare un frame di
+		// campioni audio monofonici. I campioni sono normalizzati così che il
+		// fondo scala corrisponda all'intervallo [-1, 1].
+		PropagateAudio(samples []float32)
+	}
+
+	// StereoConnector è l'interfaccia che descrive un connettore audio
+	// stereofonico.
+	StereoConnector interface {
+		// PropagateStereo permette al demodulatore di propagare un frame di
+		// campioni audio stereofonici: L ed R sono rispettivamente il canale
+		// sinistro e destro, hanno la stessa lunghezza e sono normalizzati come
+		// in PropagateAudio.
+		PropagateStereo(L []float32, R []float32)
+	}
+
 	// Option rappresenta un'opzione di configurazione di RSP.
 	Option struct {
 		apply func()
@@ -69,39 +99,96 @@ var (
 // RSP permette di ottenere un ricevitore con le caratteristiche desiderate (opts)
 // fornendo la rappresentazione in banda base del segnale desiderato al Connector
 // fornito.
-// Ad ogni invocazione, se presente, il precedente receiver verrà disattivato ed
+// Ad ogni invocazione, se presente, il precedente receiver verrà chiuso con Close ed
 // ogni suo metodo fornirà l'errore DeactivatedReceiverError.
 // Il baseband connector deve essere non nil altrimenti viene restituito l'errore
 // UnpluggedConnectorError. Le opzioni opts sono facoltative, se non presenti
 // verrà usata una configurazione di default.
+// Se la versione della libreria SDRplay è diversa da quella attesa viene
+// restituito l'errore VersionMismatchError, salvo che la differenza sia ammessa
+// con AllowVersionMismatch o VersionMismatch. Se le opzioni non sono ammesse
+// dalla RSP viene restituito un OptionError, e se non sono ammesse dal suo
+// modello un UnsupportedFeature salvo che sia impostato WarnUnsupported, senza
+// modificare l'eventuale ricevitore precedente.
+// Se l'avvio dello stream fallisce viene restituito l'errore insieme al
+// ricevitore, già chiuso.
+// Il ricevitore può essere usato contemporaneamente da più goroutine, anche dal
+// Connector durante la propagazione: le operazioni vengono eseguite una alla
+// volta.
 func RSP(baseband Connector, opts ...Option) (Receiver, error) {
 	if baseband == nil {
 		return nil, UnpluggedConnectorError
 	}
 
-	if rx != nil {
-		e := rx.uninit()
-		if e != nil {
+	f := configured(opts...)
+
+	if _, ok := baseband.(DualConnector); f.Duo == DualTuner && !ok {
+		return nil, &OptionError{Option: "Duo", Reason: "DualTuner mode requires a DualConnector"}
+	}
+
+	if e := checkVersion(f); e != nil {
+		return nil, e
+	}
+
+	if e := validate(f); e != nil {
+		return nil, e
+	}
+
+	rxMu.Lock()
+	defer rxMu.Unlock()
+
+	// La RSP del ricevitore precedente, se ancora attivo, non risulta
+	// disponibile ma è quella che verrà usata.
+	var hw Hardware
+	if rx != nil && rx.active() {
+		hw = rx.hw
+	} else {
+		var e error
+		if hw, e = detect(f.Duo); e != nil {
 			return nil, e
 		}
+	}
 
-		// Si disattiva il precedente ricevitore.
-		rx.baseband = nil
+	if e := hw.validate(f); e != nil {
+		return nil, e
 	}
 
-	newRadio()
+	f, e := hw.support(f)
+	if e != nil {
+		return nil, e
+	}
 
-	rsp = features{}
+	// Si disattiva il precedente ricevitore.
+	if rx != nil {
+		if e := rx.Close(); e != nil {
+			return nil, e
+		}
+	}
 
-	configure(fm102MHz...)
-	configure(opts...)
+	newRadio()
 
-	rx.feat = rsp
+	rx.feat = f
+	rx.hw = hw
 	rx.baseband = baseband
 
-	ie := rx.init()
+	if f.Buffering > 0 {
+		rx.ring = NewRing(float64(f.FS)*1e6, f.Buffering, baseband)
+		rx.baseband = rx.ring
+	}
+
+	if e := rx.init(); e != nil {
+		// Il ricevitore viene chiuso, fermando lo stream se già avviato,
+		// così che la successiva invocazione di RSP non debba farlo.
+		if ce := rx.Close(); ce != nil {
+			rx.logger().Warn("rsp close after failed init", "err", ce)
+		}
+
+		return rx, e
+	}
+
+	rx.started()
 
-	return rx, ie
+	return rx, nil
 }
 
 // B enumera tutte le larghezze di banda ammesse.
@@ -288,14 +375,19 @@ func LOmode(loMode LOfrequency) Option {
 	}
 }
 
-// Decimation enumera il fattore di decimazione.
+// Decimation enumera il fattore di decimazione. Il valore di ogni costante è
+// il fattore stesso: nelle versioni precedenti Factor2..Factor64 valevano per
+// errore il doppio, ad esempio Factor2 valeva 4, e chi ne usava il valore
+// numerico, o lo convertiva in Decimation, deve ora usare il fattore reale.
+// Le configurazioni salvate nel formato JSON della versione 1 vengono
+// convertite alla lettura.
 type Decimation int
 
 const (
 	// Factor0 indica nessuna decimazione.
 	Factor0 Decimation = 0
 	// Factor2 indica un fattore di decimazione pari a 2.
-	Factor2 Decimation = 1 << (iota + 1)
+	Factor2 Decimation = 1 << iota
 	// Factor4 indica un fattore di decimazione pari a 4.
 	Factor4
 	// Factor8 indica un fattore di decimazione pari a 8.
@@ -366,7 +458,8 @@ func InitialGR(dB int) Option {
 }
 
 // InitialRF imposta il valore iniziale della frequenza sintonizzata. Il valore
-// frequency viene considerato espresso in MHz.
+// frequency viene considerato espresso in MHz, a differenza di Tune: Frequency
+// imposta lo stesso valore espresso in Hz.
 func InitialRF(frequency float64) Option {
 	return Option{
 		apply: func() {
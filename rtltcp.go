@@ -0,0 +1,299 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"sync"
+)
+
+type (
+	// RTLTCPServer è un Connector che rende disponibile il segnale in banda
+	// base attraverso il protocollo di rtl_tcp, così che i programmi che lo
+	// supportano, come SDR# e gqrx, possano usare la RSP come se fosse una
+	// chiavetta RTL-SDR remota. Il segnale, normalizzato al fondo scala
+	// dell'ADC con l'opzione Normalize abilitata da Serve, viene convertito in
+	// campioni a 8 bit senza segno ed i comandi ricevuti dai client vengono
+	// applicati al ricevitore fornito a Serve.
+	//
+	//   srv := sdrplay.NewRTLTCPServer()
+	//   rx, e := sdrplay.RSP(srv)
+	//   ...
+	//   e = srv.ListenAndServe(":1234", rx)
+	RTLTCPServer struct {
+		mu sync.Mutex
+
+		rx       Receiver
+		log      *slog.Logger
+		clients  map[*rtlClient]struct{}
+		listener net.Listener
+		closed   bool
+	}
+
+	// RTLTCPOption configura un RTLTCPServer.
+	RTLTCPOption func(*RTLTCPServer)
+
+	// rtlClient è un client connesso ad RTLTCPServer.
+	rtlClient struct {
+		conn   net.Conn
+		frames chan []byte
+	}
+)
+
+const (
+	// rtlTuner è il tipo di sintonizzatore dichiarato ai client: R820T, che
+	// ha un'ampia gamma di guadagni.
+	rtlTuner = 5
+
+	// rtlQueue è il numero massimo di frame in attesa di essere inviati ad un
+	// client, oltre il quale i frame vengono scartati.
+	rtlQueue = 64
+
+	// rtlMaxGain è il massimo guadagno, in dB, dichiarabile da un client.
+	rtlMaxGain = 49.6
+)
+
+// Comandi del protocollo rtl_tcp.
+const (
+	rtlSetFrequency  = 0x01
+	rtlSetSampleRate = 0x02
+	rtlSetGainMode   = 0x03
+	rtlSetGain       = 0x04
+	rtlSetPPM        = 0x05
+	rtlSetGainIndex  = 0x0d
+)
+
+// rtlGains sono i guadagni, in decimi di dB, del sintonizzatore R820T.
+var rtlGains = []int{
+	0, 9, 14, 27, 37, 77, 87, 125, 144, 157, 166, 197, 207, 229, 254, 280,
+	297, 328, 338, 364, 372, 386, 402, 421, 434, 439, 445, 480, 496,
+}
+
+// NewRTLTCPServer restituisce un RTLTCPServer senza client connessi,
+// configurato con le opzioni opts.
+func NewRTLTCPServer(opts ...RTLTCPOption) *RTLTCPServer {
+	s := &RTLTCPServer{log: slog.Default(), clients: make(map[*rtlClient]struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// RTLTCPLogger imposta il logger sul quale RTLTCPServer riporta gli errori dei
+// comandi dei client; se l è nil, o senza l'opzione, viene usato
+// slog.Default().
+func RTLTCPLogger(l *slog.Logger) RTLTCPOption {
+	return func(s *RTLTCPServer) {
+		if l != nil {
+			s.log = l
+		}
+	}
+}
+
+// ListenAndServe accetta connessioni TCP all'indirizzo addr ed invoca Serve.
+func (s *RTLTCPServer) ListenAndServe(addr string, rx Receiver) error {
+	l, e := net.Listen("tcp", addr)
+	if e != nil {
+		return e
+	}
+
+	return s.Serve(l, rx)
+}
+
+// Serve accetta le connessioni dei client su l ed applica i loro comandi al
+// ricevitore rx, fino all'invocazione di Close o ad un errore di l. Su rx viene
+// abilitata l'opzione Normalize, così che la conversione a 8 bit usi i bit più
+// significativi dell'ADC con ogni modello e frequenza di campionamento.
+func (s *RTLTCPServer) Serve(l net.Listener, rx Receiver) error {
+	if e := rx.SetUp(Normalize(true)); e != nil {
+		l.Close()
+		return e
+	}
+
+	s.mu.Lock()
+	s.rx, s.listener = rx, l
+	s.mu.Unlock()
+
+	for {
+		conn, e := l.Accept()
+		if e != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+
+			if closed {
+				return nil
+			}
+			return e
+		}
+
+		go s.serve(conn)
+	}
+}
+
+// Close chiude il listener e tutte le connessioni dei client.
+func (s *RTLTCPServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for c := range s.clients {
+		c.conn.Close()
+	}
+
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+
+	return nil
+}
+
+// serve gestisce la connessione di un client: invia l'intestazione, avvia
+// l'invio dei campioni e ne interpreta i comandi fino alla disconnessione.
+func (s *RTLTCPServer) serve(conn net.Conn) {
+	c := &rtlClient{conn: conn, frames: make(chan []byte, rtlQueue)}
+
+	header := make([]byte, 12)
+	copy(header, "RTL0")
+	binary.BigEndian.PutUint32(header[4:], rtlTuner)
+	binary.BigEndian.PutUint32(header[8:], uint32(len(rtlGains)))
+	if _, e := conn.Write(header); e != nil {
+		conn.Close()
+		return
+	}
+
+	// Una connessione accettata mentre Close era in corso non viene
+	// registrata, perché nessuno la chiuderebbe più.
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	go c.send()
+
+	cmd := make([]byte, 5)
+	for {
+		if _, e := io.ReadFull(conn, cmd); e != nil {
+			break
+		}
+
+		s.command(cmd[0], binary.BigEndian.Uint32(cmd[1:]))
+	}
+
+	s.mu.Lock()
+	delete(s.clients, c)
+	close(c.frames)
+	s.mu.Unlock()
+
+	conn.Close()
+}
+
+// send invia al client i frame in coda fino alla sua disconnessione.
+func (c *rtlClient) send() {
+	for b := range c.frames {
+		if _, e := c.conn.Write(b); e != nil {
+			c.conn.Close()
+		}
+	}
+}
+
+// command applica al ricevitore il comando cmd con parametro p. I comandi non
+// applicabili alla RSP vengono ignorati; gli errori, che il protocollo non
+// prevede di comunicare al client, vengono riportati con livello Warn sul logger
+// impostato con RTLTCPLogger.
+// La frequenza di campionamento richiesta viene ottenuta con OutputRate e la IF
+// nulla, che scelgono una larghezza di banda compatibile e, per le frequenze
+// sotto i 2 MHz comuni tra i client rtl_tcp, la decimazione.
+func (s *RTLTCPServer) command(cmd byte, p uint32) {
+	s.mu.Lock()
+	rx, log := s.rx, s.log
+	s.mu.Unlock()
+
+	if rx == nil {
+		return
+	}
+
+	var e error
+	switch cmd {
+	case rtlSetFrequency:
+		e = rx.Tune(float64(p))
+	case rtlSetSampleRate:
+		e = rx.SetUp(OutputRate(float64(p)), IF(IFzero))
+	case rtlSetGainMode:
+		if p == 0 {
+			e = rx.SetUp(AGC(AGC5Hz, -30))
+		} else {
+			e = rx.SetUp(AGC(Disable, 0))
+		}
+	case rtlSetGain:
+		e = rx.Gain(rtlReduction(float64(int32(p)) / 10))
+	case rtlSetPPM:
+		// La correzione viene regolata senza reinizializzare lo stream, se
+		// il ricevitore lo consente.
+		if d, ok := rx.(DeviceControl); ok {
+			e = d.SetPPM(float64(int32(p)))
+		} else {
+			e = rx.SetUp(LOppm(float64(int32(p))))
+		}
+	case rtlSetGainIndex:
+		if int(p) < len(rtlGains) {
+			e = rx.Gain(rtlReduction(float64(rtlGains[p]) / 10))
+		}
+	}
+
+	if e == nil {
+		return
+	}
+
+	log.Warn("rtl_tcp command failed", "cmd", cmd, "param", p, "err", e)
+}
+
+// rtlReduction converte il guadagno in dB richiesto da un client nel gain
+// reduction della RSP, distribuendo l'intervallo 0-49.6dB su quello 59-20dB.
+func rtlReduction(gain float64) int {
+	gain = math.Max(0, math.Min(rtlMaxGain, gain))
+
+	return int(59 - gain*39/rtlMaxGain + 0.5)
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (s *RTLTCPServer) Propagate(I []int16, Q []int16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.clients) == 0 {
+		return
+	}
+
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	b := make([]byte, 2*n)
+	for k := 0; k < n; k++ {
+		b[2*k] = byte(I[k]>>8) ^ 0x80
+		b[2*k+1] = byte(Q[k]>>8) ^ 0x80
+	}
+
+	for c := range s.clients {
+		select {
+		case c.frames <- b:
+		default:
+		}
+	}
+}
@@ -0,0 +1,98 @@
+//go:build nosdr || mock
+
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/iclac/sdrplay"
+)
+
+// TestRTLTCPServer verifica che RTLTCPServer invii l'intestazione di rtl_tcp e
+// converta a 8 bit i campioni normalizzati al fondo scala dell'ADC: senza la
+// normalizzazione il tono del ricevitore simulato, a 14 bit, resterebbe entro
+// pochi livelli dal centro.
+func TestRTLTCPServer(t *testing.T) {
+	srv := sdrplay.NewRTLTCPServer()
+
+	rx, e := sdrplay.RSP(srv)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer rx.Close()
+
+	l, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(l, rx) }()
+
+	conn, e := net.Dial("tcp", l.Addr().String())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	header := make([]byte, 12)
+	if _, e := io.ReadFull(conn, header); e != nil {
+		t.Fatal(e)
+	}
+	if string(header[:4]) != "RTL0" {
+		t.Fatalf("header: got %q, want RTL0", header[:4])
+	}
+
+	b := make([]byte, 8192)
+	if _, e := io.ReadFull(conn, b); e != nil {
+		t.Fatal(e)
+	}
+
+	peak := 0
+	for _, v := range b {
+		if d := int(v) - 0x80; d > peak {
+			peak = d
+		} else if -d > peak {
+			peak = -d
+		}
+	}
+	if peak < 10 {
+		t.Errorf("peak %d levels from the center, want at least 10", peak)
+	}
+
+	// La correzione dell'oscillatore applicata alla RSP deve poter essere
+	// riportata a 0 ppm.
+	for _, ppm := range []int32{10, 0} {
+		cmd := binary.BigEndian.AppendUint32([]byte{0x05}, uint32(ppm))
+		if _, e := conn.Write(cmd); e != nil {
+			t.Fatal(e)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for sdrplay.MockPPM() != float64(ppm) {
+			if time.Now().After(deadline) {
+				t.Fatalf("ppm: got %g, want %d", sdrplay.MockPPM(), ppm)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if e := srv.Close(); e != nil {
+		t.Error(e)
+	}
+	if e := <-served; e != nil {
+		t.Errorf("Serve: %v", e)
+	}
+}
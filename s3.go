@@ -0,0 +1,369 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// S3 è uno Storage che archivia le registrazioni in un bucket di un object
+	// store compatibile con Amazon S3 (ad esempio MinIO). Le registrazioni
+	// vengono caricate con l'upload multipart man mano che vengono scritte,
+	// così che non sia necessario mantenerle interamente in memoria o su disco.
+	// Le richieste vengono firmate con AWS Signature Version 4 ed indirizzate
+	// in path-style (endpoint/bucket/oggetto).
+	S3 struct {
+		// Endpoint è l'URL del servizio, ad esempio https://s3.amazonaws.com
+		// oppure http://minio.local:9000.
+		Endpoint string
+
+		// Region è la regione usata nella firma delle richieste.
+		Region string
+
+		// Bucket è il bucket nel quale vengono create le registrazioni.
+		Bucket string
+
+		// AccessKey e SecretKey sono le credenziali di accesso.
+		AccessKey, SecretKey string
+
+		// PartSize è la dimensione, in byte, delle parti dell'upload multipart.
+		// Se minore del minimo ammesso da S3 (5MiB) viene usato il minimo.
+		PartSize int
+
+		// Retries è il numero di tentativi aggiuntivi eseguiti per ogni
+		// richiesta fallita a causa della rete o di un errore del server.
+		Retries int
+
+		// Client è il client HTTP usato per le richieste, se nil viene usato
+		// http.DefaultClient.
+		Client *http.Client
+	}
+
+	// s3Object è la registrazione in corso di caricamento su S3.
+	s3Object struct {
+		s    *S3
+		key  string
+		buf  bytes.Buffer
+		id   string
+		tags []s3Part
+		err  error
+	}
+
+	// s3Part descrive una parte caricata di un upload multipart.
+	s3Part struct {
+		Number int    `xml:"PartNumber"`
+		ETag   string `xml:"ETag"`
+	}
+
+	// S3Error descrive un errore restituito dall'object store.
+	S3Error struct {
+		Status  int
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+)
+
+// s3MinPart è la dimensione minima, in byte, di una parte di un upload
+// multipart ad eccezione dell'ultima.
+const s3MinPart = 5 << 20
+
+// Error implementa l'interfaccia error.
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("S3 error %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// Create implementa l'interfaccia Storage. Il nome della registrazione è la
+// chiave dell'oggetto all'interno del bucket.
+func (s *S3) Create(name string) (io.WriteCloser, error) {
+	if s.Bucket == "" {
+		return nil, errors.New("S3 bucket not specified")
+	}
+
+	return &s3Object{s: s, key: strings.TrimPrefix(name, "/")}, nil
+}
+
+// Write implementa l'interfaccia io.Writer. Ogni volta che i dati accumulati
+// raggiungono la dimensione di una parte, questa viene caricata.
+func (o *s3Object) Write(p []byte) (int, error) {
+	if o.err != nil {
+		return 0, o.err
+	}
+
+	o.buf.Write(p)
+
+	for o.buf.Len() >= o.s.partSize() {
+		if o.err = o.upload(o.buf.Next(o.s.partSize())); o.err != nil {
+			o.abort()
+			return 0, o.err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Close implementa l'interfaccia io.Closer: carica i dati rimanenti e completa
+// l'upload. Se non è stata caricata alcuna parte l'oggetto viene creato con una
+// singola richiesta.
+func (o *s3Object) Close() error {
+	if o.err != nil {
+		return o.err
+	}
+
+	if e := o.complete(); e != nil {
+		o.err = e
+		return e
+	}
+
+	o.err = errors.New("S3 object already closed")
+
+	return nil
+}
+
+// complete carica i dati rimanenti e conclude l'upload.
+func (o *s3Object) complete() error {
+	if o.id == "" {
+		_, e := o.s.do("PUT", o.key, nil, o.buf.Bytes())
+		return e
+	}
+
+	if o.buf.Len() > 0 {
+		if e := o.upload(o.buf.Next(o.buf.Len())); e != nil {
+			o.abort()
+			return e
+		}
+	}
+
+	body, e := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: o.tags})
+	if e != nil {
+		return e
+	}
+
+	if _, e := o.s.do("POST", o.key, url.Values{"uploadId": {o.id}}, body); e != nil {
+		o.abort()
+		return e
+	}
+
+	return nil
+}
+
+// upload carica la parte p, avviando l'upload multipart se necessario.
+func (o *s3Object) upload(p []byte) error {
+	if o.id == "" {
+		res, e := o.s.do("POST", o.key, url.Values{"uploads": {""}}, nil)
+		if e != nil {
+			return e
+		}
+
+		var r struct {
+			UploadID string `xml:"UploadId"`
+		}
+		if e := xml.Unmarshal(res.body, &r); e != nil {
+			return e
+		}
+
+		o.id = r.UploadID
+	}
+
+	n := len(o.tags) + 1
+	q := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {o.id}}
+
+	res, e := o.s.do("PUT", o.key, q, p)
+	if e != nil {
+		return e
+	}
+
+	o.tags = append(o.tags, s3Part{Number: n, ETag: res.header.Get("ETag")})
+
+	return nil
+}
+
+// abort annulla l'upload multipart in corso così che le parti già caricate non
+// occupino spazio nel bucket. Eventuali errori vengono ignorati.
+func (o *s3Object) abort() {
+	if o.id != "" {
+		o.s.do("DELETE", o.key, url.Values{"uploadId": {o.id}}, nil)
+	}
+}
+
+// partSize restituisce la dimensione effettiva delle parti dell'upload.
+func (s *S3) partSize() int {
+	if s.PartSize < s3MinPart {
+		return s3MinPart
+	}
+
+	return s.PartSize
+}
+
+// s3Response contiene la parte utile di una risposta dell'object store.
+type s3Response struct {
+	header http.Header
+	body   []byte
+}
+
+// do esegue, firmandola, la richiesta method sull'oggetto key con i parametri
+// query ed il contenuto body. In caso di errore di rete o del server la
+// richiesta viene ripetuta fino a s.Retries volte con attesa esponenziale.
+func (s *S3) do(method, key string, query url.Values, body []byte) (*s3Response, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var err error
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep((500 * time.Millisecond) << uint(attempt-1))
+		}
+
+		req, e := s.request(method, key, query, body)
+		if e != nil {
+			return nil, e
+		}
+
+		res, e := client.Do(req)
+		if e != nil {
+			err = e
+			continue
+		}
+
+		data, e := io.ReadAll(res.Body)
+		res.Body.Close()
+		if e != nil {
+			err = e
+			continue
+		}
+
+		// CompleteMultipartUpload può fallire restituendo comunque lo stato 200
+		// con un documento Error nel corpo della risposta.
+		if res.StatusCode/100 == 2 && !bytes.Contains(data, []byte("<Error>")) {
+			return &s3Response{header: res.Header, body: data}, nil
+		}
+
+		se := &S3Error{Status: res.StatusCode}
+		xml.Unmarshal(data, se)
+		err = se
+
+		if res.StatusCode/100 == 4 {
+			break
+		}
+	}
+
+	return nil, err
+}
+
+// request costruisce la richiesta HTTP firmata con AWS Signature Version 4.
+func (s *S3) request(method, key string, query url.Values, body []byte) (*http.Request, error) {
+	base, e := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if e != nil {
+		return nil, e
+	}
+
+	path := "/" + s.Bucket + "/" + key
+	u := *base
+	u.Path = base.Path + path
+	u.RawPath = base.Path + s3Escape(path, false)
+	u.RawQuery = s3Query(query)
+
+	req, e := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if e != nil {
+		return nil, e
+	}
+
+	now := time.Now().UTC()
+	stamp := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	hash := sha256.Sum256(body)
+	payload := hex.EncodeToString(hash[:])
+
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+
+	canonical := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		"host:" + u.Host + "\n" + "x-amz-content-sha256:" + payload + "\n" + "x-amz-date:" + stamp + "\n",
+		"host;x-amz-content-sha256;x-amz-date",
+		payload,
+	}, "\n")
+
+	scope := day + "/" + s.Region + "/s3/aws4_request"
+	ch := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(ch[:])
+
+	k := s3HMAC([]byte("AWS4"+s.SecretKey), day)
+	k = s3HMAC(k, s.Region)
+	k = s3HMAC(k, "s3")
+	k = s3HMAC(k, "aws4_request")
+	sig := hex.EncodeToString(s3HMAC(k, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/"+scope+", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="+sig)
+
+	return req, nil
+}
+
+// s3HMAC restituisce l'HMAC-SHA256 di data con chiave key.
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+// s3Query restituisce la query string canonica, con i parametri ordinati per
+// nome e codificati come richiesto dalla firma.
+func s3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// s3Escape codifica s secondo le regole della firma AWS: sono lasciati in chiaro
+// solo i caratteri non riservati e, se slash è false, il carattere '/'.
+func s3Escape(s string, slash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !slash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
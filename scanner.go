@@ -0,0 +1,584 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// ScanStep è la misura effettuata da Scanner su una frequenza.
+	ScanStep struct {
+		// Frequency è la frequenza sintonizzata espressa in Hz e Time l'istante
+		// in cui la misura è stata completata.
+		Frequency float64
+		Time      time.Time
+
+		// Sweep è il numero progressivo della scansione, a partire da 0.
+		Sweep int
+
+		// Power è la potenza media del segnale in banda base in dBFS.
+		Power float64
+
+		// Spectrum contiene, se richiesto con Spectra, la potenza media di ogni
+		// bin in dBFS, ordinata come in SpectrumFrame. Altrimenti è nil.
+		Spectrum []float64
+	}
+
+	// ScanHit descrive l'attività rilevata dallo squelch di Scanner su una
+	// frequenza.
+	ScanHit struct {
+		// Frequency è la frequenza su cui è stata rilevata l'attività, espressa
+		// in Hz, e Start l'istante in cui è stata rilevata.
+		Frequency float64
+		Start     time.Time
+
+		// Active indica se l'attività è in corso: è true nell'evento emesso
+		// alla sosta e false in quello emesso alla ripresa della scansione.
+		Active bool
+
+		// Level è il livello massimo del segnale in dBFS e Duration la durata
+		// dell'attività, nota solo alla ripresa della scansione.
+		Level    float64
+		Duration time.Duration
+	}
+
+	// Scanner è un Connector che percorre ciclicamente un elenco di frequenze,
+	// sintonizzando per ognuna il ricevitore e misurando il segnale in banda
+	// base per il tempo di permanenza. Ogni misura viene passata alla funzione
+	// di report e, se richiesto, al canale restituito da Steps. La sintonia
+	// avviene in una goroutine dedicata, avviata da Start e fermata da Stop.
+	// Se presente, ogni frame viene propagato inalterato al connettore out.
+	//
+	// Con lo squelch abilitato, la scansione si ferma sulle frequenze la cui
+	// potenza supera la soglia e riprende quando il segnale viene meno: durante
+	// la sosta i frame vengono propagati anche al connettore impostato con
+	// Listen, ad esempio un demodulatore o un Recorder, ed all'inizio ed alla
+	// fine vengono emessi gli eventi ScanHit.
+	//
+	//	sc := sdrplay.NewScanner(fs, nil, nil, report)
+	//	rx, e := sdrplay.RSP(sc)
+	//	sc.Plug(rx)
+	//	sc.Range(144e6, 146e6, 12.5e3)
+	//	e = sc.Start()
+	Scanner struct {
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento espressa in Hz.
+		fs float64
+
+		tuner Tuner
+		freqs []float64
+
+		// settle è il tempo scartato dopo ogni sintonia, dwell quello per cui
+		// viene misurato il segnale.
+		settle, dwell time.Duration
+
+		// step è la misura in corso; skip e remain sono i campioni ancora da
+		// scartare e da misurare, acc e count accumulano la potenza.
+		step   *ScanStep
+		skip   int
+		remain int
+		acc    float64
+		count  int
+		done   chan ScanStep
+
+		// buf, win e psd servono al calcolo degli spettri, blocks conta i
+		// blocchi trasformati.
+		buf    []complex128
+		win    []float64
+		psd    []float64
+		blocks int
+
+		// squelch indica se lo squelch è abilitato, threshold e hysteresis
+		// sono la soglia di sosta in dBFS e lo scarto sotto di essa necessario
+		// alla ripresa, hang il tempo per cui il segnale deve restare sotto la
+		// soglia.
+		squelch               bool
+		threshold, hysteresis float64
+		hang                  time.Duration
+
+		// hit è l'attività in corso, level il livello misurato e quiet il
+		// tempo trascorso sotto la soglia; released riceve l'attività
+		// conclusa.
+		hit      *ScanHit
+		level    float64
+		quiet    time.Duration
+		released chan ScanHit
+
+		stop    chan struct{}
+		stopped chan struct{}
+		err     error
+
+		steps  chan ScanStep
+		report func(ScanStep)
+		out    Connector
+
+		hits   chan ScanHit
+		onHit  func(ScanHit)
+		listen Connector
+	}
+)
+
+// scanQueue è il numero massimo di misure in attesa sul canale di Steps e di
+// eventi in attesa sul canale di Hits.
+const scanQueue = 64
+
+// EmptyScanError indica che non è stata fornita alcuna frequenza da scandire.
+var EmptyScanError = errors.New("Empty Scan Error")
+
+// NewScanner restituisce uno Scanner per un segnale campionato con frequenza
+// fs, espressa in Hz, che sintonizza t. Ogni misura viene passata a report, se
+// non nil, mentre i frame vengono propagati ad out se non nil. Il
+// sintonizzatore può essere fornito anche in seguito con Plug. Di default ogni
+// frequenza viene misurata per 100ms dopo averne scartati 20ms, e non vengono
+// calcolati spettri.
+func NewScanner(fs float64, t Tuner, out Connector, report func(ScanStep)) *Scanner {
+	return &Scanner{
+		fs:         fs,
+		tuner:      t,
+		settle:     20 * time.Millisecond,
+		dwell:      100 * time.Millisecond,
+		done:       make(chan ScanStep, 1),
+		hysteresis: 3,
+		hang:       2 * time.Second,
+		released:   make(chan ScanHit, 1),
+		report:     report,
+		out:        out,
+	}
+}
+
+// Plug collega allo Scanner il sintonizzatore da comandare, tipicamente il
+// Receiver restituito da RSP.
+func (s *Scanner) Plug(t Tuner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tuner = t
+}
+
+// List imposta le frequenze da scandire, espresse in Hz, nell'ordine fornito.
+// Il nuovo elenco viene usato dalla scansione successiva.
+func (s *Scanner) List(freqs ...float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.freqs = append([]float64(nil), freqs...)
+}
+
+// Range imposta come frequenze da scandire quelle da start a stop, estremi
+// compresi, a passi di step. Tutti i valori sono espressi in Hz.
+func (s *Scanner) Range(start, stop, step float64) {
+	var freqs []float64
+	if step > 0 {
+		n := int(math.Floor((stop-start)/step + 1e-9))
+		for k := 0; k <= n; k++ {
+			freqs = append(freqs, start+float64(k)*step)
+		}
+	}
+
+	s.List(freqs...)
+}
+
+// Dwell imposta il tempo per cui ogni frequenza viene misurata, misurato sui
+// campioni ricevuti, e quello scartato dopo la sintonia per lasciar
+// assestare il sintonizzatore.
+func (s *Scanner) Dwell(dwell, settle time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dwell = dwell
+	s.settle = settle
+}
+
+// Spectra abilita il calcolo per ogni frequenza di uno spettro di bins punti,
+// arrotondati alla potenza di 2 successiva. Con bins nullo il calcolo viene
+// disabilitato.
+func (s *Scanner) Spectra(bins int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if bins <= 0 {
+		s.buf, s.win, s.psd = nil, nil, nil
+		return
+	}
+
+	n := pow2(bins)
+	s.buf = make([]complex128, 0, n)
+	s.win = hann(n)
+	s.psd = make([]float64, n)
+}
+
+// Squelch abilita lo squelch con soglia threshold, in dBFS: la scansione si
+// ferma sulle frequenze la cui potenza supera la soglia e riprende quando resta
+// per il tempo hang più di 3dB sotto di essa. Con threshold nullo lo squelch
+// viene disabilitato. Di default hang vale 2s.
+func (s *Scanner) Squelch(threshold float64, hang time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.squelch = threshold != 0
+	s.threshold = threshold
+	s.hang = hang
+}
+
+// Listen imposta il connettore al quale propagare i frame durante le soste
+// dello squelch, ad esempio un demodulatore per ascoltare l'attività o un
+// Recorder per registrarla.
+func (s *Scanner) Listen(c Connector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listen = c
+}
+
+// OnHit imposta la funzione invocata all'inizio ed alla fine di ogni sosta
+// dello squelch.
+func (s *Scanner) OnHit(f func(ScanHit)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onHit = f
+}
+
+// Hits restituisce il canale sul quale vengono inviati gli eventi dello
+// squelch. Se il canale è pieno i nuovi eventi vengono scartati.
+func (s *Scanner) Hits() <-chan ScanHit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.hits == nil {
+		s.hits = make(chan ScanHit, scanQueue)
+	}
+
+	return s.hits
+}
+
+// Steps restituisce il canale sul quale vengono inviate le misure. Se il
+// canale è pieno le nuove misure vengono scartate.
+func (s *Scanner) Steps() <-chan ScanStep {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.steps == nil {
+		s.steps = make(chan ScanStep, scanQueue)
+	}
+
+	return s.steps
+}
+
+// Start avvia la scansione. Se non è stata fornita alcuna frequenza viene
+// restituito l'errore EmptyScanError, se manca il sintonizzatore l'errore
+// NoReceiverError.
+func (s *Scanner) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case len(s.freqs) == 0:
+		return EmptyScanError
+	case s.tuner == nil:
+		return NoReceiverError
+	case s.stop != nil:
+		return nil
+	}
+
+	s.err = nil
+	s.stop = make(chan struct{})
+	s.stopped = make(chan struct{})
+	go s.run(s.stop, s.stopped)
+
+	return nil
+}
+
+// Stop ferma la scansione, lasciando il ricevitore sintonizzato sull'ultima
+// frequenza, ed attende la fine della goroutine di sintonia.
+func (s *Scanner) Stop() {
+	s.mu.Lock()
+	stop, stopped := s.stop, s.stopped
+	s.stop = nil
+	s.step = nil
+	s.hit = nil
+	s.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-stopped
+}
+
+// Running indica se la scansione è in corso.
+func (s *Scanner) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stop != nil
+}
+
+// Err restituisce l'errore di sintonia che ha interrotto l'ultima scansione.
+func (s *Scanner) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// run esegue le scansioni fino alla chiusura di stop.
+func (s *Scanner) run(stop, stopped chan struct{}) {
+	defer close(stopped)
+
+	for sweep := 0; ; sweep++ {
+		s.mu.Lock()
+		freqs, tuner := s.freqs, s.tuner
+		s.mu.Unlock()
+
+		for _, f := range freqs {
+			if e := tuner.Tune(f); e != nil {
+				s.fail(stop, e)
+				return
+			}
+
+			s.arm(f, sweep)
+
+			var st ScanStep
+			select {
+			case st = <-s.done:
+				s.deliver(st)
+			case <-stop:
+				return
+			}
+
+			if !s.hold(st, stop) {
+				return
+			}
+		}
+	}
+}
+
+// hold, se lo squelch è abilitato e la misura st supera la soglia, ferma la
+// scansione fino a quando il segnale viene meno. Restituisce false se nel
+// frattempo la scansione è stata fermata.
+func (s *Scanner) hold(st ScanStep, stop chan struct{}) bool {
+	s.mu.Lock()
+	if !s.squelch || st.Power <= s.threshold {
+		s.mu.Unlock()
+		return true
+	}
+
+	select {
+	case <-s.released:
+	default:
+	}
+
+	hit := ScanHit{Frequency: st.Frequency, Start: st.Time, Active: true, Level: st.Power}
+	s.hit = &hit
+	s.level = st.Power
+	s.quiet = 0
+	s.mu.Unlock()
+
+	s.emit(hit)
+
+	select {
+	case hit = <-s.released:
+		s.emit(hit)
+		return true
+	case <-stop:
+		hit.Active = false
+		hit.Duration = time.Since(hit.Start)
+		s.emit(hit)
+		return false
+	}
+}
+
+// emit passa l'evento h alla funzione di OnHit ed al canale di Hits.
+func (s *Scanner) emit(h ScanHit) {
+	s.mu.Lock()
+	onHit, hits := s.onHit, s.hits
+	s.mu.Unlock()
+
+	if onHit != nil {
+		onHit(h)
+	}
+
+	if hits != nil {
+		select {
+		case hits <- h:
+		default:
+		}
+	}
+}
+
+// fail memorizza l'errore e, se la scansione non è già stata fermata, la
+// segna come conclusa.
+func (s *Scanner) fail(stop chan struct{}, e error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = e
+	if s.stop == stop {
+		s.stop = nil
+	}
+}
+
+// arm prepara la misura sulla frequenza f appena sintonizzata.
+func (s *Scanner) arm(f float64, sweep int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Una misura completata mentre la scansione veniva fermata non è mai
+	// stata consegnata.
+	select {
+	case <-s.done:
+	default:
+	}
+
+	s.step = &ScanStep{Frequency: f, Sweep: sweep}
+	s.skip = int(s.settle.Seconds() * s.fs)
+	s.remain = maxInt(1, int(s.dwell.Seconds()*s.fs))
+	s.acc, s.count = 0, 0
+
+	s.buf = s.buf[:0]
+	for k := range s.psd {
+		s.psd[k] = 0
+	}
+	s.blocks = 0
+}
+
+// deliver passa la misura st alla funzione di report ed al canale di Steps.
+func (s *Scanner) deliver(st ScanStep) {
+	s.mu.Lock()
+	report, steps := s.report, s.steps
+	s.mu.Unlock()
+
+	if report != nil {
+		report(st)
+	}
+
+	if steps != nil {
+		select {
+		case steps <- st:
+		default:
+		}
+	}
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (s *Scanner) Propagate(I []int16, Q []int16) {
+	s.mu.Lock()
+	if s.step != nil {
+		s.measure(I, Q)
+	}
+
+	var listen Connector
+	if s.hit != nil {
+		s.track(I, Q)
+		listen = s.listen
+	}
+	s.mu.Unlock()
+
+	if listen != nil {
+		listen.Propagate(I, Q)
+	}
+
+	if s.out != nil {
+		s.out.Propagate(I, Q)
+	}
+}
+
+// measure accumula i campioni I e Q nella misura in corso e, se questa è
+// completa, la consegna alla goroutine di sintonia.
+func (s *Scanner) measure(I, Q []int16) {
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	for k := 0; k < n && s.remain > 0; k++ {
+		if s.skip > 0 {
+			s.skip--
+			continue
+		}
+
+		i, q := float64(I[k])/fullScale, float64(Q[k])/fullScale
+		s.acc += i*i + q*q
+		s.count++
+		s.remain--
+
+		if s.win != nil {
+			s.buf = append(s.buf, complex(i, q))
+			if len(s.buf) == len(s.win) {
+				s.transform()
+			}
+		}
+	}
+
+	if s.remain > 0 {
+		return
+	}
+
+	st := *s.step
+	st.Time = time.Now()
+	st.Power = dB(s.acc / float64(s.count))
+	if s.blocks > 0 {
+		m := len(s.psd)
+		st.Spectrum = make([]float64, m)
+		for k, p := range s.psd {
+			st.Spectrum[(k+m/2)%m] = dB(p / float64(s.blocks))
+		}
+	}
+
+	s.step = nil
+	s.done <- st
+}
+
+// track aggiorna il livello del segnale durante una sosta e, se il segnale è
+// rimasto sotto la soglia per il tempo hang, conclude l'attività.
+func (s *Scanner) track(I, Q []int16) {
+	n := len(I)
+	s.level += smoothing(20*time.Millisecond, n, s.fs) * (dB(power(I, Q)) - s.level)
+	s.hit.Level = math.Max(s.hit.Level, s.level)
+
+	if s.level >= s.threshold-s.hysteresis {
+		s.quiet = 0
+		return
+	}
+
+	s.quiet += time.Duration(float64(n) / s.fs * float64(time.Second))
+	if s.quiet < s.hang {
+		return
+	}
+
+	hit := *s.hit
+	hit.Active = false
+	hit.Duration = time.Since(hit.Start)
+
+	s.hit = nil
+	s.released <- hit
+}
+
+// transform calcola lo spettro dei campioni accumulati in buf e lo somma ai
+// precedenti.
+func (s *Scanner) transform() {
+	n := len(s.win)
+	for k := range s.buf {
+		s.buf[k] *= complex(s.win[k], 0)
+	}
+
+	fft(s.buf)
+
+	for k, c := range s.buf {
+		s.psd[k] += (real(c)*real(c) + imag(c)*imag(c)) / float64(n*n)
+	}
+	s.blocks++
+	s.buf = s.buf[:0]
+}
@@ -0,0 +1,476 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type (
+	// Session è l'attività svolta da Scheduler durante un Job, ad esempio una
+	// registrazione o l'ascolto attraverso un demodulatore.
+	Session interface {
+		// Begin avvia la sessione del job iniziata all'istante start e
+		// restituisce il Connector al quale propagare il segnale ricevuto.
+		Begin(job Job, start time.Time) (Connector, error)
+
+		// End conclude la sessione avviata dall'ultimo Begin.
+		End() error
+	}
+
+	// Job descrive un'attività pianificata di Scheduler.
+	Job struct {
+		// Name è il nome del job, usato ad esempio per nominare le
+		// registrazioni.
+		Name string
+
+		// At è l'istante del primo avvio, Duration la durata di ogni
+		// esecuzione ed Every l'intervallo tra due avvii successivi, nullo se
+		// il job va eseguito una sola volta.
+		At       time.Time
+		Duration time.Duration
+		Every    time.Duration
+
+		// Frequency è la frequenza, espressa in Hz, da sintonizzare all'avvio,
+		// nulla per non modificare la sintonia.
+		Frequency float64
+
+		// Squelch è la soglia in dBFS oltre la quale il segnale viene
+		// propagato alla sessione, nulla per propagarlo sempre.
+		Squelch float64
+
+		Session Session
+	}
+
+	// ScheduleEvent descrive l'avvio o la conclusione di un Job.
+	ScheduleEvent struct {
+		Job Job
+
+		// Started indica se il job è stato avviato o concluso e Time
+		// l'istante in cui è avvenuto.
+		Started bool
+		Time    time.Time
+
+		// Err è l'errore incontrato avviando o concludendo il job. Se il job
+		// viene avviato mentre un altro è in corso, l'esecuzione viene saltata
+		// ed Err vale BusySchedulerError.
+		Err error
+	}
+
+	// Scheduler è un Connector che esegue dei Job pianificati: all'avvio di
+	// ognuno sintonizza il ricevitore, avvia la Session e le propaga il segnale
+	// ricevuto, eventualmente solo quando supera la soglia dello squelch, fino
+	// alla conclusione. Il ricevitore esegue un solo job alla volta. Se
+	// presente, ogni frame viene propagato inalterato al connettore out.
+	//
+	//	st := sdrplay.NewDisk("/var/lib/sdr")
+	//	sched := sdrplay.NewScheduler(fs, nil, nil)
+	//	rx, e := sdrplay.RSP(sched)
+	//	sched.Plug(rx)
+	//	rec, e := sdrplay.NewRecordSession(st, fs, sdrplay.WAV)
+	//	sched.Add(sdrplay.Daily("40m", 6, 0, 30*time.Minute, 7.2e6, rec))
+	//	e = sched.Start()
+	Scheduler struct {
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento espressa in Hz.
+		fs float64
+
+		tuner Tuner
+		jobs  []*scheduled
+
+		// active è il job in corso, nil se nessuno.
+		active *running
+
+		// wake sveglia la goroutine di pianificazione dopo un'aggiunta.
+		wake chan struct{}
+
+		stop    chan struct{}
+		stopped chan struct{}
+
+		onEvent func(ScheduleEvent)
+		out     Connector
+	}
+
+	// scheduled è un Job con l'istante del prossimo avvio, nullo se non ve ne
+	// sono altri.
+	scheduled struct {
+		job  Job
+		next time.Time
+	}
+
+	// running è un Job in corso.
+	running struct {
+		job  Job
+		end  time.Time
+		conn Connector
+		sq   *Squelch
+	}
+
+	// RecordSession è una Session che registra il segnale ricevuto durante ogni
+	// esecuzione del Job in un file distinto, il cui nome è composto dal nome
+	// del job e dall'istante di avvio in UTC.
+	RecordSession struct {
+		st     Storage
+		fs     float64
+		format FileFormat
+		rec    *Recorder
+	}
+
+	// listenSession è la Session restituita da ListenSession.
+	listenSession struct {
+		c Connector
+	}
+)
+
+// BusySchedulerError indica che un Job non è stato avviato perché un altro era
+// in corso.
+var BusySchedulerError = errors.New("Busy Scheduler Error")
+
+// NewScheduler restituisce uno Scheduler per un segnale campionato con
+// frequenza fs, espressa in Hz, che sintonizza t e propaga i frame ad out se
+// non nil. Il sintonizzatore può essere fornito anche in seguito con Plug.
+func NewScheduler(fs float64, t Tuner, out Connector) *Scheduler {
+	return &Scheduler{
+		fs:    fs,
+		tuner: t,
+		wake:  make(chan struct{}, 1),
+		out:   out,
+	}
+}
+
+// Daily restituisce il Job name che ogni giorno, dalle hour:minute dell'ora
+// locale e per il tempo duration, sintonizza la frequenza frequency, espressa in
+// Hz, ed esegue la sessione s.
+func Daily(name string, hour, minute int, duration time.Duration, frequency float64, s Session) Job {
+	now := time.Now()
+	at := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.Local)
+	if at.Add(duration).Before(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+
+	return Job{
+		Name:      name,
+		At:        at,
+		Duration:  duration,
+		Every:     24 * time.Hour,
+		Frequency: frequency,
+		Session:   s,
+	}
+}
+
+// Plug collega allo Scheduler il sintonizzatore da comandare, tipicamente il
+// Receiver restituito da RSP.
+func (s *Scheduler) Plug(t Tuner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tuner = t
+}
+
+// OnEvent imposta la funzione invocata all'avvio ed alla conclusione di ogni
+// Job.
+func (s *Scheduler) OnEvent(f func(ScheduleEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onEvent = f
+}
+
+// Add pianifica il Job job. Le esecuzioni già concluse vengono ignorate,
+// mentre quella eventualmente in corso viene avviata subito per il tempo
+// residuo.
+func (s *Scheduler) Add(job Job) {
+	s.mu.Lock()
+	s.jobs = append(s.jobs, &scheduled{job: job, next: job.At})
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start avvia la pianificazione. Se manca il sintonizzatore viene restituito
+// l'errore NoReceiverError.
+func (s *Scheduler) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.tuner == nil:
+		return NoReceiverError
+	case s.stop != nil:
+		return nil
+	}
+
+	s.stop = make(chan struct{})
+	s.stopped = make(chan struct{})
+	go s.run(s.stop, s.stopped)
+
+	return nil
+}
+
+// Stop ferma la pianificazione, concludendo il Job in corso.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop, stopped := s.stop, s.stopped
+	s.stop = nil
+	s.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-stopped
+}
+
+// run esegue i job fino alla chiusura di stop.
+func (s *Scheduler) run(stop, stopped chan struct{}) {
+	defer close(stopped)
+
+	for {
+		wait, ok := s.due(time.Now())
+
+		var timer <-chan time.Time
+		if ok {
+			timer = time.After(wait)
+		}
+
+		select {
+		case <-timer:
+		case <-s.wake:
+		case <-stop:
+			s.finish(time.Now())
+			return
+		}
+	}
+}
+
+// due esegue gli avvii e le conclusioni previsti entro l'istante now e
+// restituisce il tempo che manca al successivo, se ve n'è uno.
+func (s *Scheduler) due(now time.Time) (time.Duration, bool) {
+	s.mu.Lock()
+	active := s.active
+	s.mu.Unlock()
+
+	if active != nil && !now.Before(active.end) {
+		s.finish(now)
+		active = nil
+	}
+
+	s.mu.Lock()
+	var starting []*scheduled
+	var next time.Time
+	if active != nil {
+		next = active.end
+	}
+
+	for _, j := range s.jobs {
+		// Le esecuzioni già concluse vengono saltate.
+		for !j.next.IsZero() && !j.next.Add(j.job.Duration).After(now) {
+			j.advance()
+		}
+
+		if j.next.IsZero() {
+			continue
+		}
+
+		if !j.next.After(now) {
+			starting = append(starting, j)
+			continue
+		}
+
+		if next.IsZero() || j.next.Before(next) {
+			next = j.next
+		}
+	}
+	s.mu.Unlock()
+
+	for _, j := range starting {
+		s.begin(j, now)
+	}
+
+	// Gli avvii hanno potuto anticipare la prossima conclusione.
+	s.mu.Lock()
+	if s.active != nil && (next.IsZero() || s.active.end.Before(next)) {
+		next = s.active.end
+	}
+	s.mu.Unlock()
+
+	if next.IsZero() {
+		return 0, false
+	}
+
+	return next.Sub(now), true
+}
+
+// advance calcola il prossimo avvio del job.
+func (j *scheduled) advance() {
+	if j.job.Every <= 0 {
+		j.next = time.Time{}
+		return
+	}
+
+	j.next = j.next.Add(j.job.Every)
+}
+
+// begin avvia all'istante now l'esecuzione in corso del job j.
+func (s *Scheduler) begin(j *scheduled, now time.Time) {
+	s.mu.Lock()
+	job, end := j.job, j.next.Add(j.job.Duration)
+	busy, tuner := s.active != nil, s.tuner
+	j.advance()
+	s.mu.Unlock()
+
+	if busy {
+		s.emit(ScheduleEvent{Job: job, Started: true, Time: now, Err: BusySchedulerError})
+		return
+	}
+
+	if job.Frequency != 0 {
+		if e := tuner.Tune(job.Frequency); e != nil {
+			s.emit(ScheduleEvent{Job: job, Started: true, Time: now, Err: e})
+			return
+		}
+	}
+
+	r := &running{job: job, end: end}
+	if job.Session != nil {
+		c, e := job.Session.Begin(job, now)
+		if e != nil {
+			s.emit(ScheduleEvent{Job: job, Started: true, Time: now, Err: e})
+			return
+		}
+		r.conn = c
+	}
+
+	if job.Squelch != 0 {
+		r.sq = NewSquelch(s.fs, nil)
+		r.sq.Threshold(job.Squelch, -20)
+	}
+
+	s.mu.Lock()
+	s.active = r
+	s.mu.Unlock()
+
+	s.emit(ScheduleEvent{Job: job, Started: true, Time: now})
+}
+
+// finish conclude all'istante now il job in corso, se presente.
+func (s *Scheduler) finish(now time.Time) {
+	s.mu.Lock()
+	r := s.active
+	s.active = nil
+	s.mu.Unlock()
+
+	if r == nil {
+		return
+	}
+
+	var e error
+	if r.job.Session != nil {
+		e = r.job.Session.End()
+	}
+
+	s.emit(ScheduleEvent{Job: r.job, Time: now, Err: e})
+}
+
+// emit passa l'evento ev alla funzione di OnEvent.
+func (s *Scheduler) emit(ev ScheduleEvent) {
+	s.mu.Lock()
+	f := s.onEvent
+	s.mu.Unlock()
+
+	if f != nil {
+		f(ev)
+	}
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (s *Scheduler) Propagate(I []int16, Q []int16) {
+	s.mu.Lock()
+	r := s.active
+	s.mu.Unlock()
+
+	if r != nil && r.conn != nil {
+		open := true
+		if r.sq != nil {
+			r.sq.Propagate(I, Q)
+			open = r.sq.Open()
+		}
+
+		if open {
+			r.conn.Propagate(I, Q)
+		}
+	}
+
+	if s.out != nil {
+		s.out.Propagate(I, Q)
+	}
+}
+
+// NewRecordSession restituisce una RecordSession che registra su st il segnale,
+// campionato con frequenza fs espressa in Hz, nel formato CS16 o WAV. Con
+// formati diversi viene restituito l'errore UnsupportedFileError.
+func NewRecordSession(st Storage, fs float64, format FileFormat) (*RecordSession, error) {
+	if format != CS16 && format != WAV {
+		return nil, UnsupportedFileError
+	}
+
+	return &RecordSession{st: st, fs: fs, format: format}, nil
+}
+
+// Begin implementa l'interfaccia Session.
+func (r *RecordSession) Begin(job Job, start time.Time) (Connector, error) {
+	name := job.Name + "-" + start.UTC().Format("20060102T150405Z")
+
+	var e error
+	if r.format == WAV {
+		r.rec, e = NewWAVRecorder(r.st, name+".wav", r.fs, job.Frequency)
+	} else {
+		r.rec, e = NewRecorder(r.st, name+".cs16")
+	}
+
+	if e != nil {
+		return nil, e
+	}
+
+	return r.rec, nil
+}
+
+// End implementa l'interfaccia Session concludendo la registrazione.
+func (r *RecordSession) End() error {
+	if r.rec == nil {
+		return nil
+	}
+
+	e := r.rec.Close()
+	r.rec = nil
+
+	return e
+}
+
+// ListenSession restituisce una Session che durante ogni esecuzione propaga il
+// segnale a c, ad esempio un demodulatore collegato ad un AudioSink.
+func ListenSession(c Connector) Session {
+	return listenSession{c: c}
+}
+
+// Begin implementa l'interfaccia Session.
+func (l listenSession) Begin(job Job, start time.Time) (Connector, error) {
+	return l.c, nil
+}
+
+// End implementa l'interfaccia Session.
+func (l listenSession) End() error {
+	return nil
+}
@@ -0,0 +1,276 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+// Package sdrplaytest fornisce dei Connector e delle funzioni di verifica per
+// provare le applicazioni costruite con sdrplay: Buffer cattura i frame
+// propagati, Counter ne conta i campioni e ne misura il ritmo, Replay ripropaga
+// una sequenza catturata. Insieme a SignalGen o al build tag nosdr permettono
+// di provare una catena di elaborazione senza la RSP.
+//
+//	b := sdrplaytest.NewBuffer(0)
+//	g := sdrplay.NewSignalGen(2e6, b, sdrplay.Tone(100e3, -20))
+//	g.Generate(1 << 16)
+//	sdrplaytest.Replay(demod, b.Frames(), 0)
+package sdrplaytest
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iclac/sdrplay"
+)
+
+type (
+	// Frame è un frame di campioni catturato da Buffer, con l'istante in cui è
+	// stato ricevuto.
+	Frame struct {
+		I, Q []int16
+		Time time.Time
+	}
+
+	// Buffer è un Connector che conserva una copia dei frame propagati.
+	Buffer struct {
+		mu sync.Mutex
+
+		// limit è il numero di campioni oltre il quale i frame vengono
+		// scartati, nullo se illimitato; samples è il numero di campioni
+		// conservati.
+		limit   int
+		samples int
+		frames  []Frame
+
+		// wake viene chiuso e sostituito ad ogni frame ricevuto.
+		wake chan struct{}
+	}
+
+	// Counter è un Connector che conta i frame ed i campioni propagati,
+	// misurandone il ritmo.
+	Counter struct {
+		mu sync.Mutex
+
+		frames, samples int
+		first, last     time.Time
+
+		// firstSize è il numero di campioni del primo frame.
+		firstSize int
+	}
+)
+
+// NewBuffer restituisce un Buffer che, una volta catturati almeno limit
+// campioni, scarta i frame successivi; se limit è nullo i frame vengono
+// conservati tutti.
+func NewBuffer(limit int) *Buffer {
+	return &Buffer{limit: limit, wake: make(chan struct{})}
+}
+
+// Propagate implementa l'interfaccia sdrplay.Connector.
+func (b *Buffer) Propagate(I []int16, Q []int16) {
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limit > 0 && b.samples >= b.limit {
+		return
+	}
+
+	b.frames = append(b.frames, Frame{
+		I:    append([]int16(nil), I[:n]...),
+		Q:    append([]int16(nil), Q[:n]...),
+		Time: time.Now(),
+	})
+	b.samples += n
+
+	close(b.wake)
+	b.wake = make(chan struct{})
+}
+
+// Frames restituisce i frame catturati.
+func (b *Buffer) Frames() []Frame {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Frame(nil), b.frames...)
+}
+
+// Samples restituisce i campioni catturati, concatenati.
+func (b *Buffer) Samples() ([]int16, []int16) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	I := make([]int16, 0, b.samples)
+	Q := make([]int16, 0, b.samples)
+	for _, f := range b.frames {
+		I = append(I, f.I...)
+		Q = append(Q, f.Q...)
+	}
+
+	return I, Q
+}
+
+// Len restituisce il numero di campioni catturati.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.samples
+}
+
+// Reset elimina i frame catturati.
+func (b *Buffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.frames = nil
+	b.samples = 0
+}
+
+// Wait attende che siano stati catturati almeno n campioni, restituendo false
+// se non accade entro timeout.
+func (b *Buffer) Wait(n int, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+
+	for {
+		b.mu.Lock()
+		samples, wake := b.samples, b.wake
+		b.mu.Unlock()
+
+		if samples >= n {
+			return true
+		}
+
+		select {
+		case <-wake:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// NewCounter restituisce un Counter azzerato.
+func NewCounter() *Counter {
+	return &Counter{}
+}
+
+// Propagate implementa l'interfaccia sdrplay.Connector.
+func (c *Counter) Propagate(I []int16, Q []int16) {
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frames == 0 {
+		c.first = now
+		c.firstSize = n
+	}
+
+	c.frames++
+	c.samples += n
+	c.last = now
+}
+
+// Frames restituisce il numero di frame ricevuti.
+func (c *Counter) Frames() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.frames
+}
+
+// Samples restituisce il numero di campioni ricevuti.
+func (c *Counter) Samples() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.samples
+}
+
+// Rate restituisce il ritmo medio, in campioni al secondo, con cui sono stati
+// ricevuti i campioni. Il primo frame segna l'inizio della misura e non viene
+// quindi conteggiato. Restituisce 0 se sono stati ricevuti meno di due frame.
+func (c *Counter) Rate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elapsed := c.last.Sub(c.first).Seconds()
+	if c.frames < 2 || elapsed <= 0 {
+		return 0
+	}
+
+	return float64(c.samples-c.firstSize) / elapsed
+}
+
+// Dropped restituisce la frazione dei campioni attesi, con frequenza di
+// campionamento fs espressa in Hz, che non sono stati ricevuti nell'intervallo
+// di misura di Rate. Un valore negativo indica campioni in eccesso.
+func (c *Counter) Dropped(fs float64) float64 {
+	if fs <= 0 {
+		return 0
+	}
+
+	return 1 - c.Rate()/fs
+}
+
+// Reset azzera il Counter.
+func (c *Counter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	*c = Counter{}
+}
+
+// AssertRate segnala a t un errore se il ritmo misurato da c si discosta da
+// fs, espressa in Hz, più della frazione tolerance.
+func AssertRate(t testing.TB, c *Counter, fs, tolerance float64) {
+	t.Helper()
+
+	if rate := c.Rate(); math.Abs(rate-fs) > tolerance*fs {
+		t.Errorf("sample rate %.0f Hz, want %.0f Hz ± %.1f%%", rate, fs, tolerance*100)
+	}
+}
+
+// AssertNoDrops segnala a t un errore se la frazione dei campioni persi, con
+// frequenza di campionamento fs espressa in Hz, supera tolerance.
+func AssertNoDrops(t testing.TB, c *Counter, fs, tolerance float64) {
+	t.Helper()
+
+	if d := c.Dropped(fs); d > tolerance {
+		t.Errorf("dropped %.1f%% of samples, want at most %.1f%%", d*100, tolerance*100)
+	}
+}
+
+// AssertSamples segnala a t un errore se c ha ricevuto meno di n campioni.
+func AssertSamples(t testing.TB, c *Counter, n int) {
+	t.Helper()
+
+	if got := c.Samples(); got < n {
+		t.Errorf("received %d samples, want at least %d", got, n)
+	}
+}
+
+// Replay propaga ad out i frame catturati. Se speed è nullo i frame vengono
+// propagati immediatamente, altrimenti rispettando gli intervalli con cui sono
+// stati ricevuti, accelerati del fattore speed.
+func Replay(out sdrplay.Connector, frames []Frame, speed float64) {
+	for k, f := range frames {
+		if speed > 0 && k > 0 {
+			time.Sleep(time.Duration(float64(f.Time.Sub(frames[k-1].Time)) / speed))
+		}
+
+		out.Propagate(f.I, f.Q)
+	}
+}
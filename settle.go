@@ -0,0 +1,124 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SettlingTuner è l'interfaccia di un sintonizzatore che attende
+// l'assestamento del PLL dopo la sintonia. Il Receiver restituito da RSP
+// implementa SettlingTuner.
+type SettlingTuner interface {
+	Tuner
+
+	// TuneAndWait sintonizza la frequenza, espressa in Hz, come Tune e
+	// scarta i campioni ricevuti nel tempo settle successivo, durante
+	// l'assestamento del PLL, ritornando quando il Connector riceve i primi
+	// campioni validi. Se i campioni non riprendono entro 10 volte settle
+	// più un secondo restituisce un SettleError.
+	TuneAndWait(frequency float64, settle time.Duration) error
+}
+
+// settling descrive l'assestamento in corso dopo TuneAndWait: skip è il numero
+// di campioni ancora da scartare, done viene chiuso al termine ed err, se non
+// nil, riporta il motivo per cui l'assestamento è stato interrotto.
+type settling struct {
+	skip int
+	done chan struct{}
+	err  error
+}
+
+// SettleError è l'errore restituito da TuneAndWait quando i campioni non
+// riprendono entro il tempo massimo o l'attesa viene interrotta da un'altra
+// TuneAndWait.
+var SettleError = errors.New("Settle Error")
+
+// settleTimeout restituisce il tempo massimo di attesa di TuneAndWait per un
+// assestamento di durata settle.
+func settleTimeout(settle time.Duration) time.Duration {
+	return 10*settle + time.Second
+}
+
+// TuneAndWait implementa l'interfaccia SettlingTuner.
+func (r *radio) TuneAndWait(frequency float64, settle time.Duration) error {
+	r.mu.Lock()
+
+	if e := r.tune(frequency); e != nil {
+		r.mu.Unlock()
+		return e
+	}
+
+	s := &settling{
+		skip: int(settle.Seconds() * r.feat.rate()),
+		done: make(chan struct{}),
+	}
+
+	r.smu.Lock()
+	r.settled(fmt.Errorf("%w: superseded by the tuning of %g Hz", SettleError, frequency))
+	r.settle = s
+	r.smu.Unlock()
+
+	if e := r.retuned(nil, frequency); e != nil {
+		return e
+	}
+
+	// mu non viene trattenuto durante l'attesa, perché il Connector potrebbe
+	// invocare i metodi del ricevitore.
+	timer := time.NewTimer(settleTimeout(settle))
+	defer timer.Stop()
+
+	select {
+	case <-s.done:
+		return s.err
+	case <-timer.C:
+	}
+
+	r.smu.Lock()
+	defer r.smu.Unlock()
+
+	if r.settle == s {
+		r.settled(fmt.Errorf("%w: no samples within %s", SettleError, settleTimeout(settle)))
+	}
+
+	<-s.done
+
+	return s.err
+}
+
+// settled termina l'assestamento in corso, se presente, riportando err a chi
+// lo attende. Va invocata possedendo r.smu.
+func (r *radio) settled(err error) {
+	if r.settle == nil {
+		return
+	}
+
+	r.settle.err = err
+	close(r.settle.done)
+	r.settle = nil
+}
+
+// discard restituisce quanti dei primi n campioni di un frame vanno scartati
+// perché ricevuti durante l'assestamento, terminandolo quando il frame contiene
+// i primi campioni validi. Va invocata possedendo r.smu.
+func (r *radio) discard(n int) int {
+	s := r.settle
+	if s == nil {
+		return 0
+	}
+
+	skip := minInt(s.skip, n)
+	s.skip -= skip
+	if skip < n {
+		r.settled(nil)
+	}
+
+	return skip
+}
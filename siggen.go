@@ -0,0 +1,347 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type (
+	// Signal è un segnale di prova sintetizzato da SignalGen. I segnali si
+	// ottengono con Tone, FMTone, FMAudio, Noise e Sweep.
+	Signal interface {
+		// add somma ai campioni buf, normalizzati al fondo scala, i campioni
+		// successivi del segnale campionato con frequenza fs.
+		add(buf []complex128, fs float64)
+	}
+
+	// SignalGen è un Receiver che, al posto della RSP, propaga al Connector
+	// fornito la somma di segnali di prova sintetizzati, così da poter provare
+	// demodulatori e stadi DSP in modo riproducibile. I segnali sono definiti
+	// rispetto alla frequenza centrale: Tune, Gain e SetUp non hanno effetto
+	// su di essi.
+	//
+	//	g := sdrplay.NewSignalGen(2e6, demod, sdrplay.FMTone(200e3, -20, 75e3, 1e3), sdrplay.Noise(-60))
+	//	g.Generate(2e6)
+	SignalGen struct {
+		virtual
+
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento in Hz e speed il fattore di
+		// accelerazione, nullo per la massima velocità possibile.
+		fs    float64
+		speed float64
+
+		signals []Signal
+		buf     []complex128
+		I, Q    []int16
+
+		stop    chan struct{}
+		stopped chan struct{}
+
+		baseband Connector
+	}
+
+	// tone è una portante non modulata.
+	tone struct {
+		offset, amplitude, phase float64
+	}
+
+	// fm è una portante modulata in frequenza dal segnale audio, normalizzato
+	// all'intervallo [-1, 1], restituito da audio per ogni istante t espresso
+	// in secondi.
+	fm struct {
+		offset, amplitude, deviation float64
+		audio                        func(t float64) float64
+
+		phase float64
+		n     int64
+	}
+
+	// noise è un rumore gaussiano complesso.
+	noise struct {
+		sigma float64
+		rnd   *rand.Rand
+	}
+
+	// sweep è una portante la cui frequenza varia linearmente da start a stop
+	// in period, ripetutamente.
+	sweep struct {
+		start, stop, amplitude float64
+		period                 time.Duration
+
+		phase float64
+		n     int64
+	}
+)
+
+// sigFrame è il numero di campioni propagati ad ogni frame da SignalGen.
+const sigFrame = 16384
+
+// NewSignalGen restituisce un SignalGen che propaga a baseband la somma dei
+// segnali signals campionati con frequenza fs, espressa in Hz. I campioni
+// vengono prodotti in modo sincrono con Generate oppure, in tempo reale o
+// accelerati, dopo l'invocazione di Start.
+func NewSignalGen(fs float64, baseband Connector, signals ...Signal) *SignalGen {
+	return &SignalGen{
+		fs:       fs,
+		speed:    1,
+		signals:  signals,
+		buf:      make([]complex128, sigFrame),
+		I:        make([]int16, sigFrame),
+		Q:        make([]int16, sigFrame),
+		baseband: baseband,
+	}
+}
+
+// Add aggiunge i segnali signals a quelli sintetizzati.
+func (g *SignalGen) Add(signals ...Signal) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.signals = append(g.signals, signals...)
+}
+
+// Speed imposta il fattore di accelerazione dello stream avviato con Start: 1
+// corrisponde al tempo reale, 0 alla massima velocità possibile.
+func (g *SignalGen) Speed(x float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.speed = math.Max(0, x)
+}
+
+// SampleRate restituisce la frequenza di campionamento del segnale in Hz.
+func (g *SignalGen) SampleRate() float64 {
+	return g.fs
+}
+
+// Generate propaga immediatamente i successivi n campioni.
+func (g *SignalGen) Generate(n int) {
+	for n > 0 {
+		n -= g.frame(minInt(n, sigFrame))
+	}
+}
+
+// Start avvia lo stream dei campioni fino all'invocazione di Stop. Se manca il
+// Connector viene restituito l'errore UnpluggedConnectorError.
+func (g *SignalGen) Start() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch {
+	case g.baseband == nil:
+		return UnpluggedConnectorError
+	case g.stop != nil:
+		return nil
+	}
+
+	g.stop = make(chan struct{})
+	g.stopped = make(chan struct{})
+	go g.run(g.stop, g.stopped)
+
+	return nil
+}
+
+// Stop interrompe lo stream ed attende la fine della goroutine che lo produce.
+func (g *SignalGen) Stop() {
+	g.mu.Lock()
+	stop, stopped := g.stop, g.stopped
+	g.stop = nil
+	g.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-stopped
+}
+
+// Close implementa l'interfaccia Receiver interrompendo lo stream come Stop.
+func (g *SignalGen) Close() error {
+	g.Stop()
+
+	return nil
+}
+
+// run propaga i campioni rispettando la velocità impostata fino alla chiusura
+// di stop.
+func (g *SignalGen) run(stop, stopped chan struct{}) {
+	defer close(stopped)
+
+	start, sent := time.Now(), 0.0
+	for {
+		sent += float64(g.frame(sigFrame))
+
+		g.mu.Lock()
+		speed := g.speed
+		g.mu.Unlock()
+
+		var wait time.Duration
+		if speed > 0 {
+			wait = time.Until(start.Add(time.Duration(sent / (g.fs * speed) * float64(time.Second))))
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// frame sintetizza e propaga un frame di n campioni, restituendone il numero.
+func (g *SignalGen) frame(n int) int {
+	g.mu.Lock()
+
+	buf := g.buf[:n]
+	for k := range buf {
+		buf[k] = 0
+	}
+
+	for _, s := range g.signals {
+		s.add(buf, g.fs)
+	}
+
+	I, Q := g.I[:n], g.Q[:n]
+	for k, x := range buf {
+		I[k] = clamp16(real(x) * fullScale)
+		Q[k] = clamp16(imag(x) * fullScale)
+	}
+
+	g.mu.Unlock()
+
+	if g.baseband != nil {
+		g.baseband.Propagate(I, Q)
+	}
+
+	return n
+}
+
+// amplitude restituisce l'ampiezza, normalizzata al fondo scala, di una
+// portante di livello level espresso in dBFS.
+func amplitude(level float64) float64 {
+	return math.Pow(10, level/20)
+}
+
+// Tone restituisce una portante non modulata posta offset Hz dalla frequenza
+// centrale, con livello level espresso in dBFS.
+func Tone(offset, level float64) Signal {
+	return &tone{offset: offset, amplitude: amplitude(level)}
+}
+
+// add implementa l'interfaccia Signal.
+func (s *tone) add(buf []complex128, fs float64) {
+	step := 2 * math.Pi * s.offset / fs
+	for k := range buf {
+		sin, cos := math.Sincos(s.phase)
+		buf[k] += complex(s.amplitude*cos, s.amplitude*sin)
+		s.phase = math.Mod(s.phase+step, 2*math.Pi)
+	}
+}
+
+// FMTone restituisce una portante posta offset Hz dalla frequenza centrale,
+// con livello level espresso in dBFS, modulata in frequenza con deviazione
+// deviation da un tono audio di frequenza tone, entrambe espresse in Hz.
+func FMTone(offset, level, deviation, tone float64) Signal {
+	return &fm{
+		offset:    offset,
+		amplitude: amplitude(level),
+		deviation: deviation,
+		audio: func(t float64) float64 {
+			return math.Sin(2 * math.Pi * tone * t)
+		},
+	}
+}
+
+// FMAudio restituisce una portante posta offset Hz dalla frequenza centrale,
+// con livello level espresso in dBFS, modulata in frequenza con deviazione
+// deviation, espressa in Hz, dai campioni audio campionati con frequenza rate,
+// ripetuti ciclicamente. I campioni sono normalizzati all'intervallo [-1, 1],
+// come quelli propagati dai demodulatori.
+func FMAudio(offset, level, deviation float64, audio []float32, rate float64) Signal {
+	samples := append([]float32(nil), audio...)
+
+	return &fm{
+		offset:    offset,
+		amplitude: amplitude(level),
+		deviation: deviation,
+		audio: func(t float64) float64 {
+			if len(samples) == 0 {
+				return 0
+			}
+
+			// I campioni audio vengono interpolati linearmente.
+			x := math.Mod(t*rate, float64(len(samples)))
+			k := int(x)
+			a, b := float64(samples[k]), float64(samples[(k+1)%len(samples)])
+
+			return a + (b-a)*(x-float64(k))
+		},
+	}
+}
+
+// add implementa l'interfaccia Signal.
+func (s *fm) add(buf []complex128, fs float64) {
+	for k := range buf {
+		sin, cos := math.Sincos(s.phase)
+		buf[k] += complex(s.amplitude*cos, s.amplitude*sin)
+
+		f := s.offset + s.deviation*s.audio(float64(s.n)/fs)
+		s.phase = math.Mod(s.phase+2*math.Pi*f/fs, 2*math.Pi)
+		s.n++
+	}
+}
+
+// Noise restituisce un rumore gaussiano complesso con potenza level espressa
+// in dBFS. Il rumore è generato con un seme fisso, così che i campioni prodotti
+// siano riproducibili.
+func Noise(level float64) Signal {
+	return &noise{
+		sigma: amplitude(level) / math.Sqrt2,
+		rnd:   rand.New(rand.NewSource(1)),
+	}
+}
+
+// add implementa l'interfaccia Signal.
+func (s *noise) add(buf []complex128, fs float64) {
+	for k := range buf {
+		buf[k] += complex(s.sigma*s.rnd.NormFloat64(), s.sigma*s.rnd.NormFloat64())
+	}
+}
+
+// Sweep restituisce una portante, con livello level espresso in dBFS, la cui
+// frequenza varia linearmente in period da start a stop, espresse in Hz
+// rispetto alla frequenza centrale, ricominciando poi da start.
+func Sweep(start, stop float64, period time.Duration, level float64) Signal {
+	return &sweep{
+		start:     start,
+		stop:      stop,
+		amplitude: amplitude(level),
+		period:    period,
+	}
+}
+
+// add implementa l'interfaccia Signal.
+func (s *sweep) add(buf []complex128, fs float64) {
+	span := math.Max(1, s.period.Seconds()*fs)
+
+	for k := range buf {
+		sin, cos := math.Sincos(s.phase)
+		buf[k] += complex(s.amplitude*cos, s.amplitude*sin)
+
+		f := s.start + (s.stop-s.start)*math.Mod(float64(s.n), span)/span
+		s.phase = math.Mod(s.phase+2*math.Pi*f/fs, 2*math.Pi)
+		s.n++
+	}
+}
@@ -0,0 +1,83 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type (
+	// sigmfMeta è il contenuto del file di metadati di una registrazione
+	// SigMF.
+	sigmfMeta struct {
+		Global      sigmfGlobal    `json:"global"`
+		Captures    []sigmfCapture `json:"captures"`
+		Annotations []struct{}     `json:"annotations"`
+	}
+
+	// sigmfGlobal è l'oggetto global dei metadati SigMF.
+	sigmfGlobal struct {
+		Datatype   string  `json:"core:datatype"`
+		SampleRate float64 `json:"core:sample_rate"`
+		Version    string  `json:"core:version"`
+		Hardware   string  `json:"core:hw"`
+		Recorder   string  `json:"core:recorder"`
+	}
+
+	// sigmfCapture è un segmento della registrazione nei metadati SigMF.
+	sigmfCapture struct {
+		SampleStart int     `json:"core:sample_start"`
+		Frequency   float64 `json:"core:frequency"`
+		Datetime    string  `json:"core:datetime"`
+	}
+)
+
+// NewSigMFRecorder crea su st la registrazione SigMF name, composta dal file di
+// metadati name.sigmf-meta e dal file dei campioni name.sigmf-data, e
+// restituisce il Recorder che vi scrive il segnale ricevuto nel formato ci16_le.
+// Il parametro fs è la frequenza di campionamento e frequency la frequenza
+// centrale del segnale, entrambe espresse in Hz. La registrazione deve essere
+// conclusa con Close.
+func NewSigMFRecorder(st Storage, name string, fs, frequency float64) (*Recorder, error) {
+	meta := sigmfMeta{
+		Global: sigmfGlobal{
+			Datatype:   "ci16_le",
+			SampleRate: fs,
+			Version:    "1.0.0",
+			Hardware:   "SDRplay RSP",
+			Recorder:   "github.com/iclac/sdrplay",
+		},
+		Captures: []sigmfCapture{{
+			Frequency: frequency,
+			Datetime:  time.Now().UTC().Format(time.RFC3339Nano),
+		}},
+		Annotations: []struct{}{},
+	}
+
+	b, e := json.MarshalIndent(meta, "", "  ")
+	if e != nil {
+		return nil, e
+	}
+
+	w, e := st.Create(name + ".sigmf-meta")
+	if e != nil {
+		return nil, e
+	}
+
+	if _, e := w.Write(b); e != nil {
+		w.Close()
+		return nil, e
+	}
+
+	if e := w.Close(); e != nil {
+		return nil, e
+	}
+
+	return NewRecorder(st, name+".sigmf-data")
+}
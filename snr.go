@@ -0,0 +1,200 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// SignalToNoise rappresenta una stima del rapporto segnale rumore prodotta
+	// da SNR. Tutti i valori sono espressi in dB, Signal e Noise relativi al
+	// fondo scala (dBFS).
+	SignalToNoise struct {
+		// Signal è la potenza del segnale nel canale, al netto del rumore.
+		Signal float64
+
+		// Noise è la potenza del rumore stimata nella larghezza del canale.
+		Noise float64
+
+		// SNR è il rapporto tra Signal e Noise.
+		SNR float64
+	}
+
+	// SNR è un Connector che stima con continuità il rapporto segnale rumore di
+	// un canale contenuto nel segnale in banda base. La potenza del rumore è
+	// stimata come mediana della densità spettrale all'esterno del canale,
+	// quella del segnale come la potenza nel canale meno quella del rumore.
+	// Se presente, ogni frame viene poi propagato inalterato al connettore out.
+	SNR struct {
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento del segnale espressa in Hz.
+		fs float64
+
+		// offset e width sono rispettivamente lo scostamento dal centro di banda
+		// e la larghezza del canale, espressi in Hz.
+		offset, width float64
+
+		// average è la costante di tempo con cui vengono mediati gli spettri.
+		average time.Duration
+
+		// buf accumula i campioni fino a raggiungere la dimensione della FFT.
+		buf []complex128
+		win []float64
+
+		// psd è la densità spettrale mediata, primed indica se è già stata
+		// inizializzata dal primo spettro.
+		psd    []float64
+		primed bool
+
+		// in indica i bin che appartengono al canale, outside raccoglie la
+		// densità spettrale degli altri: sono allocati una sola volta e
+		// riusati per ogni stima.
+		in      []bool
+		outside []float64
+
+		report func(SignalToNoise)
+		out    Connector
+	}
+)
+
+// snrBins è il numero di punti della FFT usata da SNR.
+const snrBins = 1024
+
+// NewSNR restituisce un SNR per un segnale campionato con frequenza fs, che
+// misura il canale di larghezza width centrato a offset Hz dalla frequenza
+// sintonizzata. Ogni nuova stima viene passata a report, mentre i frame vengono
+// propagati ad out se non nil. Di default gli spettri vengono mediati con una
+// costante di tempo di 500ms.
+func NewSNR(fs, offset, width float64, out Connector, report func(SignalToNoise)) *SNR {
+	return &SNR{
+		fs:      fs,
+		offset:  offset,
+		width:   width,
+		average: 500 * time.Millisecond,
+		buf:     make([]complex128, 0, snrBins),
+		win:     hann(snrBins),
+		psd:     make([]float64, snrBins),
+		in:      make([]bool, snrBins),
+		outside: make([]float64, 0, snrBins),
+		report:  report,
+		out:     out,
+	}
+}
+
+// Channel sposta la misura sul canale di larghezza width centrato a offset Hz
+// dalla frequenza sintonizzata.
+func (s *SNR) Channel(offset, width float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.offset = offset
+	s.width = width
+	s.primed = false
+}
+
+// Average imposta la costante di tempo con cui vengono mediati gli spettri:
+// valori maggiori rendono la stima più stabile ma più lenta.
+func (s *SNR) Average(tau time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.average = tau
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (s *SNR) Propagate(I []int16, Q []int16) {
+	s.mu.Lock()
+	var ests []SignalToNoise
+	for k := 0; k < len(I) && k < len(Q); k++ {
+		s.buf = append(s.buf, complex(float64(I[k])/fullScale, float64(Q[k])/fullScale))
+		if len(s.buf) == snrBins {
+			ests = append(ests, s.estimate())
+			s.buf = s.buf[:0]
+		}
+	}
+	s.mu.Unlock()
+
+	if s.report != nil {
+		for _, e := range ests {
+			s.report(e)
+		}
+	}
+
+	if s.out != nil {
+		s.out.Propagate(I, Q)
+	}
+}
+
+// estimate calcola lo spettro dei campioni accumulati in buf, lo media con i
+// precedenti e restituisce la stima aggiornata del rapporto segnale rumore.
+func (s *SNR) estimate() SignalToNoise {
+	for k := range s.buf {
+		s.buf[k] *= complex(s.win[k], 0)
+	}
+
+	fft(s.buf)
+
+	a := smoothing(s.average, snrBins, s.fs)
+	for k, c := range s.buf {
+		p := (real(c)*real(c) + imag(c)*imag(c)) / (snrBins * snrBins)
+		if !s.primed {
+			s.psd[k] = p
+		} else {
+			s.psd[k] += a * (p - s.psd[k])
+		}
+	}
+	s.primed = true
+
+	// Si separano le componenti spettrali nel canale da quelle esterne,
+	// escludendo il bin della continua che contiene lo spike DC della RSP.
+	lo := bin(s.offset-s.width/2, s.fs, snrBins)
+	hi := bin(s.offset+s.width/2, s.fs, snrBins)
+	for k := range s.in {
+		s.in[k] = false
+	}
+	channel := 0
+	for k := lo; ; k = (k + 1) % snrBins {
+		s.in[k] = true
+		channel++
+		if k == hi {
+			break
+		}
+	}
+
+	var signal float64
+	outside := s.outside[:0]
+	for k, p := range s.psd {
+		switch {
+		case s.in[k]:
+			signal += p
+		case k != 0:
+			outside = append(outside, p)
+		}
+	}
+
+	var noise float64
+	if len(outside) > 0 {
+		sort.Float64s(outside)
+		noise = outside[len(outside)/2] * float64(channel)
+	}
+
+	signal -= noise
+	if signal < floor {
+		signal = floor
+	}
+
+	return SignalToNoise{
+		Signal: dB(signal),
+		Noise:  dB(noise),
+		SNR:    dB(signal) - dB(noise),
+	}
+}
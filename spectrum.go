@@ -0,0 +1,230 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// SpectrumFrame è uno spettro prodotto da Spectrum.
+	SpectrumFrame struct {
+		// Frequency è la frequenza centrale e SampleRate la frequenza di
+		// campionamento, entrambe espresse in Hz.
+		Frequency  float64
+		SampleRate float64
+
+		// Power contiene la potenza di ogni bin espressa in dBFS, ordinata
+		// dalla frequenza Frequency-SampleRate/2 alla frequenza
+		// Frequency+SampleRate/2.
+		Power []float64
+
+		// Spurs contiene gli indici in Power dei bin occupati dalle spurie
+		// note della RSP, se richiesto con MaskSpurs.
+		Spurs []int
+	}
+
+	// Spectrum è un Connector che calcola lo spettro di potenza del segnale in
+	// banda base, mediato nel tempo, e lo passa con cadenza regolare alla
+	// funzione di report, ad esempio per disegnare un waterfall. Per limitare
+	// il carico, alle frequenze di campionamento elevate vengono trasformati
+	// solo alcuni blocchi di campioni per ogni spettro prodotto.
+	// Perché la frequenza centrale segua le risintonie, Spectrum va registrato
+	// come Trigger. Se presente, ogni frame viene poi propagato inalterato al
+	// connettore out.
+	Spectrum struct {
+		mu sync.Mutex
+
+		// fs e frequency sono la frequenza di campionamento e quella centrale
+		// espresse in Hz.
+		fs, frequency float64
+
+		// average è la costante di tempo con cui vengono mediati gli spettri.
+		average time.Duration
+
+		// hop è la distanza in campioni tra l'inizio di due blocchi
+		// trasformati, period quella tra due spettri prodotti; skip e elapsed
+		// contano i campioni trascorsi dall'ultimo blocco e dall'ultimo
+		// spettro.
+		hop, period   int
+		skip, elapsed int
+
+		buf    []complex128
+		win    []float64
+		psd    []float64
+		primed bool
+
+		// eq, se non nil, corregge gli spettri per la risposta del filtro.
+		eq *Equalizer
+
+		// hw è la RSP le cui spurie vengono trattate secondo spurs.
+		hw    Hardware
+		spurs SpurMask
+
+		report func(SpectrumFrame)
+		out    Connector
+	}
+)
+
+// spectrumBlocks è il numero massimo di blocchi trasformati per ogni spettro
+// prodotto.
+const spectrumBlocks = 8
+
+// NewSpectrum restituisce uno Spectrum per un segnale campionato con frequenza
+// fs, espressa in Hz, che calcola spettri di bins punti, arrotondati alla
+// potenza di 2 successiva. Ogni spettro viene passato a report, mentre i frame
+// vengono propagati ad out se non nil. Di default vengono prodotti 25 spettri al
+// secondo, mediati con una costante di tempo di 100ms.
+func NewSpectrum(fs float64, bins int, out Connector, report func(SpectrumFrame)) *Spectrum {
+	n := pow2(bins)
+
+	s := &Spectrum{
+		fs:      fs,
+		average: 100 * time.Millisecond,
+		buf:     make([]complex128, 0, n),
+		win:     hann(n),
+		psd:     make([]float64, n),
+		report:  report,
+		out:     out,
+	}
+	s.setRate(25)
+
+	return s
+}
+
+// Rate imposta il numero di spettri prodotti al secondo.
+func (s *Spectrum) Rate(fps float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setRate(fps)
+}
+
+// setRate calcola le distanze tra i blocchi trasformati e tra gli spettri
+// prodotti corrispondenti a fps spettri al secondo.
+func (s *Spectrum) setRate(fps float64) {
+	n := len(s.win)
+
+	s.period = maxInt(n, int(s.fs/fps))
+	s.hop = maxInt(n, s.period/spectrumBlocks)
+}
+
+// Average imposta la costante di tempo con cui vengono mediati gli spettri:
+// con valori nulli ogni spettro è calcolato dal solo ultimo blocco.
+func (s *Spectrum) Average(tau time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.average = tau
+}
+
+// Equalize imposta l'Equalizer con cui correggere gli spettri prodotti per la
+// risposta del filtro IF; nil disabilita la correzione.
+func (s *Spectrum) Equalize(eq *Equalizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.eq = eq
+}
+
+// Bins restituisce il numero di punti degli spettri prodotti.
+func (s *Spectrum) Bins() int {
+	return len(s.win)
+}
+
+// Fire implementa l'interfaccia Trigger: la media degli spettri riparte dalla
+// nuova frequenza centrale.
+func (s *Spectrum) Fire(frequency float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frequency = frequency
+	s.primed = false
+	s.buf = s.buf[:0]
+
+	return nil
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (s *Spectrum) Propagate(I []int16, Q []int16) {
+	s.mu.Lock()
+	n := len(s.win)
+	var frames []SpectrumFrame
+	for k := 0; k < len(I) && k < len(Q); k++ {
+		s.elapsed++
+
+		if s.skip > 0 {
+			s.skip--
+		} else {
+			s.buf = append(s.buf, complex(float64(I[k])/fullScale, float64(Q[k])/fullScale))
+			if len(s.buf) == n {
+				s.transform()
+				s.buf = s.buf[:0]
+				s.skip = s.hop - n
+			}
+		}
+
+		if s.elapsed >= s.period && s.primed {
+			frames = append(frames, s.frame())
+			s.elapsed = 0
+		}
+	}
+	s.mu.Unlock()
+
+	if s.report != nil {
+		for _, f := range frames {
+			s.report(f)
+		}
+	}
+
+	if s.out != nil {
+		s.out.Propagate(I, Q)
+	}
+}
+
+// transform calcola lo spettro dei campioni accumulati in buf e lo media con i
+// precedenti.
+func (s *Spectrum) transform() {
+	n := len(s.win)
+	for k := range s.buf {
+		s.buf[k] *= complex(s.win[k], 0)
+	}
+
+	fft(s.buf)
+
+	a := smoothing(s.average, s.hop, s.fs)
+	for k, c := range s.buf {
+		p := (real(c)*real(c) + imag(c)*imag(c)) / float64(n*n)
+		if !s.primed {
+			s.psd[k] = p
+		} else {
+			s.psd[k] += a * (p - s.psd[k])
+		}
+	}
+	s.primed = true
+}
+
+// frame restituisce lo spettro mediato in dBFS, riordinato così che le
+// frequenze negative precedano quelle positive.
+func (s *Spectrum) frame() SpectrumFrame {
+	n := len(s.psd)
+	power := make([]float64, n)
+	for k, p := range s.psd {
+		power[(k+n/2)%n] = dB(p)
+	}
+
+	if s.eq != nil {
+		s.eq.correct(power, s.fs/float64(n))
+	}
+
+	f := SpectrumFrame{Frequency: s.frequency, SampleRate: s.fs, Power: power}
+	s.maskSpurs(&f)
+
+	return f
+}
@@ -0,0 +1,140 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "math"
+
+type (
+	// Spur descrive una spuria interna della RSP: la frequenza, in Hz, la
+	// larghezza occupata, in Hz, e la sorgente che la genera.
+	Spur struct {
+		Frequency float64
+		Width     float64
+		Source    string
+	}
+
+	// SpurMask enumera il trattamento delle spurie negli spettri prodotti da
+	// Spectrum.
+	SpurMask int
+
+	// spurSource descrive una sorgente di spurie: le armoniche della
+	// frequenza spacing, larghe width Hz.
+	spurSource struct {
+		name    string
+		spacing float64
+		width   float64
+	}
+)
+
+const (
+	// SpurOff non tratta le spurie.
+	SpurOff SpurMask = iota
+	// SpurFlag riporta in SpectrumFrame.Spurs i bin occupati dalle spurie,
+	// lasciandone inalterata la potenza.
+	SpurFlag
+	// SpurNotch, oltre a riportarli, sostituisce la potenza dei bin occupati
+	// dalle spurie interpolando linearmente quella dei bin adiacenti.
+	SpurNotch
+)
+
+// spurTable contiene le sorgenti di spurie note di ogni modello: tutti
+// derivano i clock dal riferimento a 24 MHz, le cui armoniche compaiono come
+// portanti strette nello spettro ricevuto.
+var spurTable = map[Model][]spurSource{
+	RSP1:   {{name: "24 MHz reference", spacing: 24e6, width: 2e3}},
+	RSP1A:  {{name: "24 MHz reference", spacing: 24e6, width: 2e3}},
+	RSP2:   {{name: "24 MHz reference", spacing: 24e6, width: 2e3}},
+	RSPduo: {{name: "24 MHz reference", spacing: 24e6, width: 2e3}},
+	RSPdx:  {{name: "24 MHz reference", spacing: 24e6, width: 2e3}},
+}
+
+// Spurs restituisce le spurie interne note della RSP descritta da h che cadono
+// nella banda ricevuta sintonizzando la frequenza frequency con frequenza di
+// campionamento fs, entrambe espresse in Hz, ad esempio per il ricevitore rx:
+//
+//	s := rx.State()
+//	spurs := rx.(sdrplay.DeviceControl).Hardware().Spurs(s.Frequency, s.SampleRate)
+//
+// Con un modello sconosciuto restituisce nil.
+func (h Hardware) Spurs(frequency, fs float64) []Spur {
+	lo, hi := frequency-fs/2, frequency+fs/2
+
+	var spurs []Spur
+	for _, s := range spurTable[h.Model] {
+		for k := math.Ceil(lo / s.spacing); k*s.spacing <= hi; k++ {
+			if k <= 0 {
+				continue
+			}
+
+			spurs = append(spurs, Spur{Frequency: k * s.spacing, Width: s.width, Source: s.name})
+		}
+	}
+
+	return spurs
+}
+
+// MaskSpurs imposta il trattamento, secondo mode, delle spurie note della RSP
+// descritta da h negli spettri prodotti, calcolate ad ogni spettro per la
+// frequenza centrale attuale.
+func (s *Spectrum) MaskSpurs(h Hardware, mode SpurMask) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hw, s.spurs = h, mode
+}
+
+// maskSpurs tratta, secondo l'impostazione di MaskSpurs, le spurie dello
+// spettro f, riportando in f.Spurs i bin occupati.
+func (s *Spectrum) maskSpurs(f *SpectrumFrame) {
+	if s.spurs == SpurOff {
+		return
+	}
+
+	n := len(f.Power)
+	res := f.SampleRate / float64(n)
+	start := f.Frequency - f.SampleRate/2
+
+	for _, sp := range s.hw.Spurs(f.Frequency, f.SampleRate) {
+		half := math.Max(sp.Width/2, res)
+		lo := maxInt(0, int(math.Floor((sp.Frequency-half-start)/res)))
+		hi := minInt(n-1, int(math.Ceil((sp.Frequency+half-start)/res)))
+		if lo > hi {
+			continue
+		}
+
+		for k := lo; k <= hi; k++ {
+			f.Spurs = append(f.Spurs, k)
+		}
+
+		if s.spurs == SpurNotch {
+			notchBins(f.Power, lo, hi)
+		}
+	}
+}
+
+// notchBins sostituisce la potenza dei bin da lo a hi, inclusi, interpolando
+// linearmente quella dei bin adiacenti.
+func notchBins(power []float64, lo, hi int) {
+	left, right := lo-1, hi+1
+	switch {
+	case left < 0 && right >= len(power):
+		return
+	case left < 0:
+		left = right
+	case right >= len(power):
+		right = left
+	}
+
+	for k := lo; k <= hi; k++ {
+		var t float64
+		if right != left {
+			t = float64(k-left) / float64(right-left)
+		}
+		power[k] = power[left] + t*(power[right]-power[left])
+	}
+}
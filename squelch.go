@@ -0,0 +1,256 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"sync"
+	"time"
+)
+
+// SquelchMode enumera i criteri con cui lo Squelch decide se aprirsi. I criteri
+// possono essere combinati con l'operatore |: in tal caso lo squelch si apre
+// solo se tutti i criteri sono soddisfatti e si chiude appena uno non lo è più.
+type SquelchMode int
+
+const (
+	// PowerSquelch apre lo squelch quando la potenza del segnale in banda base
+	// supera la soglia.
+	PowerSquelch SquelchMode = 1 << iota
+	// NoiseSquelch apre lo squelch quando il rumore ad alta frequenza presente
+	// nell'audio demodulato scende sotto la soglia. È il criterio tipico dei
+	// ricevitori FM, nei quali la presenza di una portante silenzia il rumore.
+	NoiseSquelch
+)
+
+type (
+	// SquelchEvent descrive un'apertura o una chiusura dello Squelch.
+	SquelchEvent struct {
+		// Open indica se lo squelch si è aperto o chiuso.
+		Open bool
+
+		// Power è la potenza del segnale in banda base in dBFS.
+		Power float64
+
+		// Noise è il livello del rumore nell'audio demodulato in dB relativi
+		// al fondo scala.
+		Noise float64
+	}
+
+	// Squelch silenzia l'audio prodotto da un demodulatore quando non è
+	// presente un segnale utile. Squelch va inserito sia prima sia dopo il
+	// demodulatore: come Connector riceve il segnale in banda base, ne misura
+	// la potenza e lo propaga al demodulatore collegato con Plug; come
+	// AudioConnector riceve l'audio demodulato, ne misura il rumore e lo
+	// propaga al connettore out se aperto, altrimenti propaga silenzio.
+	//
+	//   sq := sdrplay.NewSquelch(fs, speaker)
+	//   sq.Plug(sdrplay.NewWBFM(fs, sq))
+	//   rx, e := sdrplay.RSP(sq)
+	Squelch struct {
+		mu sync.Mutex
+
+		// fs è la frequenza di campionamento del segnale in banda base
+		// espressa in Hz.
+		fs float64
+
+		mode SquelchMode
+
+		// power e noise sono le soglie dei due criteri, hysteresis è lo scarto
+		// in dB oltre la soglia necessario alla chiusura.
+		power, noise, hysteresis float64
+
+		// hang è il tempo per cui lo squelch resta aperto dopo che il segnale
+		// è venuto meno, quiet è il tempo trascorso da allora.
+		hang, quiet time.Duration
+
+		// open indica lo stato attuale, pLevel e nLevel sono i livelli misurati.
+		open           bool
+		pLevel, nLevel float64
+
+		// last è l'ultimo campione audio ricevuto, serve al filtro del rumore.
+		last float32
+
+		silence []float32
+
+		onChange func(SquelchEvent)
+		demod    Connector
+		out      AudioConnector
+	}
+)
+
+// NewSquelch restituisce uno Squelch per un segnale in banda base campionato
+// con frequenza fs, espressa in Hz, che propaga l'audio ad out. Di default lo
+// squelch usa il criterio PowerSquelch con soglia di -60dBFS, 3dB di isteresi e
+// 500ms di tempo di mantenimento; la soglia del NoiseSquelch è di -20dB.
+func NewSquelch(fs float64, out AudioConnector) *Squelch {
+	return &Squelch{
+		fs:         fs,
+		mode:       PowerSquelch,
+		power:      -60,
+		noise:      -20,
+		hysteresis: 3,
+		hang:       500 * time.Millisecond,
+		pLevel:     dB(0),
+		out:        out,
+	}
+}
+
+// Plug collega allo squelch il demodulatore al quale propagare il segnale in
+// banda base.
+func (s *Squelch) Plug(demod Connector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.demod = demod
+}
+
+// Mode imposta i criteri di apertura dello squelch.
+func (s *Squelch) Mode(m SquelchMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mode = m
+}
+
+// Threshold imposta le soglie di apertura: power, in dBFS, per il criterio
+// PowerSquelch e noise, in dB relativi al fondo scala audio, per il criterio
+// NoiseSquelch.
+func (s *Squelch) Threshold(power, noise float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.power = power
+	s.noise = noise
+}
+
+// Hysteresis imposta di quanti dB il livello deve superare la soglia, nel verso
+// opposto a quello di apertura, perché lo squelch si chiuda. Evita aperture e
+// chiusure continue con segnali vicini alla soglia.
+func (s *Squelch) Hysteresis(dB float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hysteresis = dB
+}
+
+// Hang imposta il tempo per cui lo squelch resta aperto dopo che il segnale è
+// venuto meno, così da non troncare le brevi pause del parlato.
+func (s *Squelch) Hang(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hang = d
+}
+
+// OnChange imposta la funzione invocata ad ogni apertura e chiusura dello
+// squelch, ad esempio per fermare o far ripartire uno scanner.
+func (s *Squelch) OnChange(f func(SquelchEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onChange = f
+}
+
+// Open indica se lo squelch è attualmente aperto.
+func (s *Squelch) Open() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.open
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (s *Squelch) Propagate(I []int16, Q []int16) {
+	s.mu.Lock()
+
+	n := len(I)
+	s.pLevel += smoothing(20*time.Millisecond, n, s.fs) * (dB(power(I, Q)) - s.pLevel)
+
+	ev, changed := s.evaluate(time.Duration(float64(n) / s.fs * float64(time.Second)))
+	f, demod := s.onChange, s.demod
+
+	s.mu.Unlock()
+
+	if changed && f != nil {
+		f(ev)
+	}
+
+	if demod != nil {
+		demod.Propagate(I, Q)
+	}
+}
+
+// PropagateAudio implementa l'interfaccia AudioConnector.
+func (s *Squelch) PropagateAudio(samples []float32) {
+	s.mu.Lock()
+
+	// Il rumore viene misurato sulla derivata discreta dell'audio, che ne
+	// esalta le componenti ad alta frequenza.
+	var acc float64
+	for _, x := range samples {
+		d := float64(x - s.last)
+		acc += d * d
+		s.last = x
+	}
+
+	if len(samples) > 0 {
+		level := dB(acc / float64(len(samples)))
+		s.nLevel += smoothing(20*time.Millisecond, len(samples), AudioRate) * (level - s.nLevel)
+	}
+
+	open := s.open
+	if !open {
+		if cap(s.silence) < len(samples) {
+			s.silence = make([]float32, len(samples))
+		}
+		samples = s.silence[:len(samples)]
+	}
+
+	s.mu.Unlock()
+
+	if s.out != nil {
+		s.out.PropagateAudio(samples)
+	}
+}
+
+// evaluate aggiorna lo stato dello squelch considerando che dall'ultima
+// valutazione è trascorso il tempo dt. Restituisce l'evento corrispondente e
+// se lo stato è cambiato.
+func (s *Squelch) evaluate(dt time.Duration) (SquelchEvent, bool) {
+	opening, closing := true, false
+
+	if s.mode&PowerSquelch != 0 {
+		opening = opening && s.pLevel > s.power
+		closing = closing || s.pLevel < s.power-s.hysteresis
+	}
+
+	if s.mode&NoiseSquelch != 0 {
+		opening = opening && s.nLevel < s.noise
+		closing = closing || s.nLevel > s.noise+s.hysteresis
+	}
+
+	if s.mode == 0 {
+		opening = true
+	}
+
+	changed := false
+	switch {
+	case !s.open && opening:
+		s.open, changed = true, true
+		s.quiet = 0
+	case s.open && closing:
+		s.quiet += dt
+		if s.quiet >= s.hang {
+			s.open, changed = false, true
+		}
+	case s.open:
+		s.quiet = 0
+	}
+
+	return SquelchEvent{Open: s.open, Power: s.pLevel, Noise: s.nLevel}, changed
+}
@@ -0,0 +1,109 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+// State descrive lo stato attuale di un Receiver.
+type State struct {
+	// Frequency è la frequenza sintonizzata e SampleRate la frequenza di
+	// campionamento, prima dell'eventuale decimazione, entrambe espresse in Hz.
+	Frequency  float64
+	SampleRate float64
+
+	Bandwidth B
+	IF        IFmode
+
+	// Decimation è il fattore di decimazione, 0 se la decimazione non è
+	// abilitata.
+	Decimation Decimation
+
+	// OutputRate è la frequenza, espressa in Hz, dei campioni propagati al
+	// Connector, dopo la decimazione ed il ricampionamento richiesto con
+	// OutputRate.
+	OutputRate float64
+
+	// GainReduction è il gain reduction impostato, espresso in dB, e
+	// SystemGainReduction quello complessivo del sistema riportato dall'API.
+	GainReduction       int
+	SystemGainReduction int
+
+	// LNAGainReduction è il gain reduction dovuto all'LNA, espresso in dB,
+	// riportato dall'API con l'ultima variazione del guadagno.
+	LNAGainReduction int
+
+	LNA bool
+	AGC AGCmode
+
+	// PPM è la correzione dell'oscillatore, espressa in ppm, impostata con
+	// l'opzione LOppm o con SetPPM.
+	PPM float64
+
+	// ADCBits è la risoluzione effettiva dell'ADC alla frequenza di
+	// campionamento attuale, 0 se il modello della RSP è sconosciuto.
+	ADCBits int
+}
+
+// state restituisce lo stato corrispondente alla configurazione c.
+func (c Config) state() State {
+	s := State{
+		Frequency:     c.InitialRF * 1e6,
+		SampleRate:    c.FS * 1e6,
+		Bandwidth:     c.Bandwidth,
+		IF:            c.IF,
+		GainReduction: c.InitialGR,
+		LNA:           c.LNA,
+		AGC:           c.AGC,
+		PPM:           c.LOppm,
+	}
+
+	s.OutputRate = s.SampleRate
+	if c.Decimate {
+		s.Decimation = c.Factor
+		if c.Factor > 0 {
+			s.OutputRate /= float64(c.Factor)
+		}
+	}
+
+	return s
+}
+
+// State implementa l'interfaccia Receiver.
+func (r *radio) State() State {
+	s := r.Config().state()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s.GainReduction = int(r.gr)
+	s.SystemGainReduction = int(r.grsys)
+	s.LNAGainReduction = int(r.lnagr.Load())
+	s.ADCBits = r.hw.ADCBits(s.SampleRate)
+	if r.feat.Output > 0 {
+		s.OutputRate = float64(r.feat.Output)
+	}
+
+	return s
+}
+
+// State implementa l'interfaccia Receiver riportando, se impostati, la
+// frequenza ed il gain reduction memorizzati con Tune e Gain.
+func (v *virtual) State() State {
+	s := DefaultConfig().state()
+
+	v.vmu.Lock()
+	defer v.vmu.Unlock()
+
+	if v.frequency != 0 {
+		s.Frequency = v.frequency
+	}
+	if v.reduction != 0 {
+		s.GainReduction = v.reduction
+	}
+	s.SystemGainReduction = s.GainReduction
+
+	return s
+}
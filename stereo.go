@@ -0,0 +1,143 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"math/cmplx"
+	"time"
+)
+
+// stereo decodifica il segnale multiplex (MPX) prodotto dal discriminatore di
+// WBFM: aggancia con un PLL il pilota a 19kHz, demodula la sottoportante L-R a
+// 38kHz e ricostruisce i canali sinistro e destro.
+type stereo struct {
+	// rate è la frequenza di campionamento del segnale MPX espressa in Hz.
+	rate float64
+
+	// phase e freq sono lo stato del PLL espressi in radianti e radianti per
+	// campione, pilot è l'uscita filtrata del rivelatore di fase.
+	phase, freq float64
+	pilot       complex128
+
+	// level e noise sono le stime filtrate delle componenti in fase e in
+	// quadratura del pilota, blend è la separazione applicata (0 mono, 1
+	// stereo), locked indica se il pilota è agganciato.
+	level, noise, blend float64
+	locked              bool
+
+	mono, side *resampler
+	dl, dr     *deemphasis
+
+	sum, diff   []float64
+	mpcm, spcm  []float64
+	left, right []float32
+	out         StereoConnector
+}
+
+const (
+	// stereoPilot è la frequenza del pilota stereo espressa in Hz.
+	stereoPilot = 19e3
+
+	// stereoLoop è la banda del PLL del pilota espressa in Hz.
+	stereoLoop = 20.0
+
+	// stereoLock è il livello minimo del pilota, relativo alla deviazione
+	// massima, oltre il quale il pilota è considerato presente. Il pilota è
+	// trasmesso normalmente al 9% della deviazione massima.
+	stereoLock = 0.03
+)
+
+// newStereo restituisce un decodificatore stereo per un segnale MPX campionato
+// a rate Hz, che applica la de-enfasi e e propaga l'audio ad out.
+func newStereo(rate float64, e Emphasis, out StereoConnector) *stereo {
+	s := &stereo{
+		rate: rate,
+		freq: 2 * math.Pi * stereoPilot / rate,
+		mono: newResampler(rate, AudioRate, wbfmAudio),
+		side: newResampler(rate, AudioRate, wbfmAudio),
+		out:  out,
+	}
+
+	s.deemphasis(e)
+
+	return s
+}
+
+// deemphasis imposta la de-enfasi applicata ai due canali.
+func (s *stereo) deemphasis(e Emphasis) {
+	s.dl = newDeemphasis(e, AudioRate)
+	s.dr = newDeemphasis(e, AudioRate)
+}
+
+// process decodifica i campioni MPX e propaga l'audio stereo ottenuto.
+func (s *stereo) process(mpx []float64) {
+	nominal := 2 * math.Pi * stereoPilot / s.rate
+	wn := 2 * math.Pi * stereoLoop / s.rate
+	alpha, beta := 2*0.707*wn, wn*wn
+
+	// Il rivelatore di fase è filtrato con una costante di tempo breve
+	// rispetto alla dinamica del PLL, mentre i livelli usati per decidere
+	// l'aggancio sono mediati più a lungo.
+	kp := smoothing(time.Millisecond, 1, s.rate)
+	kl := smoothing(100*time.Millisecond, 1, s.rate)
+
+	s.sum = s.sum[:0]
+	s.diff = s.diff[:0]
+	for _, d := range mpx {
+		// Con il PLL agganciato il pilota vale level*cos(phase), quindi la
+		// sottoportante, in fase con il doppio della fase del pilota, vale
+		// -sin(2*phase).
+		s.sum = append(s.sum, d)
+		s.diff = append(s.diff, -2*d*math.Sin(2*s.phase))
+
+		s.pilot += complex(kp, 0) * (complex(d, 0)*cmplx.Rect(1, -s.phase) - s.pilot)
+
+		e := math.Atan2(imag(s.pilot), real(s.pilot))
+		s.freq += beta * e
+		s.freq = math.Max(0.99*nominal, math.Min(1.01*nominal, s.freq))
+		s.phase = math.Mod(s.phase+s.freq+alpha*e, 2*math.Pi)
+
+		s.level += kl * (real(s.pilot) - s.level)
+		s.noise += kl * (math.Abs(imag(s.pilot)) - s.noise)
+	}
+
+	s.locked = s.level > stereoLock && s.noise < s.level/2
+
+	s.mpcm = s.mono.process(s.sum, s.mpcm[:0])
+	s.spcm = s.side.process(s.diff, s.spcm[:0])
+
+	n := len(s.mpcm)
+	if len(s.spcm) < n {
+		n = len(s.spcm)
+	}
+
+	// La separazione segue lo stato del pilota con una costante di tempo di
+	// mezzo secondo, così che il passaggio da stereo a mono sia graduale.
+	target := 0.0
+	if s.locked {
+		target = 1
+	}
+	kb := smoothing(500*time.Millisecond, 1, AudioRate)
+
+	s.left = s.left[:0]
+	s.right = s.right[:0]
+	for k := 0; k < n; k++ {
+		s.blend += kb * (target - s.blend)
+
+		m, d := s.mpcm[k], s.blend*s.spcm[k]
+		l, r := s.dl.process(m+d), s.dr.process(m-d)
+
+		s.left = append(s.left, float32(math.Max(-1, math.Min(1, l))))
+		s.right = append(s.right, float32(math.Max(-1, math.Min(1, r))))
+	}
+
+	if n > 0 {
+		s.out.PropagateStereo(s.left, s.right)
+	}
+}
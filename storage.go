@@ -0,0 +1,66 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type (
+	// Storage è l'interfaccia che descrive il supporto sul quale vengono
+	// archiviate le registrazioni, ad esempio il disco locale oppure un object
+	// store remoto.
+	Storage interface {
+		// Create crea la registrazione name e restituisce il mezzo attraverso il
+		// quale scriverne il contenuto. La registrazione è completa solo dopo
+		// che Close è stato invocato senza errori.
+		Create(name string) (io.WriteCloser, error)
+	}
+
+	// Disk è uno Storage che archivia le registrazioni come file all'interno di
+	// una directory del disco locale.
+	Disk struct {
+		dir string
+	}
+)
+
+// InvalidNameError indica che il nome di una registrazione non è un percorso
+// relativo interno alla directory dello Storage, ad esempio perché è assoluto o
+// contiene "..".
+var InvalidNameError = errors.New("Invalid Name Error")
+
+// NewDisk restituisce un Disk che archivia le registrazioni nella directory dir,
+// creandola al bisogno.
+func NewDisk(dir string) *Disk {
+	return &Disk{dir: dir}
+}
+
+// Create implementa l'interfaccia Storage. Il nome della registrazione deve
+// essere un percorso relativo interno alla directory di d, altrimenti viene
+// restituito InvalidNameError: il nome può provenire dalla rete, ad esempio
+// attraverso HTTPControl, e Create impedisce così la scrittura di file
+// arbitrari. Il nome può contenere delle sottodirectory, che vengono create se
+// non presenti. Il valore restituito è un *os.File, quindi
+// permette anche il posizionamento (Seek).
+func (d *Disk) Create(name string) (io.WriteCloser, error) {
+	name = filepath.FromSlash(name)
+	if !filepath.IsLocal(name) {
+		return nil, InvalidNameError
+	}
+
+	path := filepath.Join(d.dir, name)
+
+	if e := os.MkdirAll(filepath.Dir(path), 0755); e != nil {
+		return nil, e
+	}
+
+	return os.Create(path)
+}
@@ -0,0 +1,59 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iclac/sdrplay"
+)
+
+// TestDiskCreate verifica che Disk crei le registrazioni all'interno della sua
+// directory e rifiuti i nomi che ne uscirebbero.
+func TestDiskCreate(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		err  error
+	}{
+		{"rec.raw", "rec/rec.raw", nil},
+		{"fm/100M.wav", "rec/fm/100M.wav", nil},
+		{"fm/../rec.wav", "rec/rec.wav", nil},
+		{"", "", sdrplay.InvalidNameError},
+		{"..", "", sdrplay.InvalidNameError},
+		{"../escape.raw", "escape.raw", sdrplay.InvalidNameError},
+		{"fm/../../escape.raw", "escape.raw", sdrplay.InvalidNameError},
+		{"../../home/u/.bashrc", "", sdrplay.InvalidNameError},
+		{"/tmp/escape.raw", "", sdrplay.InvalidNameError},
+	}
+
+	root := t.TempDir()
+	d := sdrplay.NewDisk(filepath.Join(root, "rec"))
+
+	for _, test := range tests {
+		w, e := d.Create(test.name)
+		if !errors.Is(e, test.err) {
+			t.Errorf("%q: got error %v, want %v", test.name, e, test.err)
+		}
+		if e == nil {
+			w.Close()
+		}
+
+		if test.path == "" {
+			continue
+		}
+
+		_, e = os.Stat(filepath.Join(root, filepath.FromSlash(test.path)))
+		if exists := e == nil; exists != (test.err == nil) {
+			t.Errorf("%q: %s exists: %v", test.name, test.path, exists)
+		}
+	}
+}
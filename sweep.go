@@ -0,0 +1,278 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// SweepFrame è uno spettro a banda larga prodotto da SweepSpectrum.
+	SweepFrame struct {
+		// Start è la frequenza del primo bin e Step la distanza tra due bin
+		// consecutivi, entrambe espresse in Hz.
+		Start, Step float64
+
+		// Power contiene la potenza di ogni bin, in dBFS o, se Calibrated è
+		// true, in dBm.
+		Power      []float64
+		Calibrated bool
+
+		// Sweep è il numero progressivo della scansione e Time l'istante in cui
+		// è stata completata.
+		Sweep int
+		Time  time.Time
+	}
+
+	// SweepSpectrum è un Connector che misura lo spettro di un intervallo di
+	// frequenze più ampio della banda istantanea della RSP: l'intervallo viene
+	// percorso con uno Scanner in segmenti parzialmente sovrapposti, dei quali
+	// si usa solo la parte centrale, non attenuata dal filtro d'ingresso.
+	// Nelle zone di sovrapposizione i segmenti vengono raccordati con pesi
+	// lineari ed il picco della componente continua al centro di ogni
+	// segmento viene sostituito dalla media dei bin vicini. Ogni scansione
+	// completa produce un unico SweepFrame passato alla funzione di report.
+	SweepSpectrum struct {
+		mu sync.Mutex
+
+		sc *Scanner
+
+		// fs è la frequenza di campionamento, start e stop gli estremi
+		// dell'intervallo, tutti espressi in Hz.
+		fs, start, stop float64
+
+		// n è il numero di punti degli spettri dei segmenti, usable la
+		// frazione di banda usata e overlap la frazione di questa sovrapposta
+		// al segmento successivo.
+		n               int
+		usable, overlap float64
+
+		// centers sono le frequenze centrali dei segmenti.
+		centers []float64
+
+		// acc e weight accumulano la potenza pesata ed i pesi di ogni bin
+		// della scansione sweep, received conta i segmenti ricevuti.
+		acc, weight []float64
+		sweep       int
+		received    int
+
+		offset     float64
+		calibrated bool
+
+		// eq, se non nil, corregge gli spettri dei segmenti per la risposta
+		// del filtro.
+		eq *Equalizer
+
+		report func(SweepFrame)
+	}
+)
+
+// sweepDC è il numero di bin per lato, attorno al centro di ogni segmento,
+// sostituiti per eliminare il picco della componente continua.
+const sweepDC = 2
+
+// NewSweepSpectrum restituisce uno SweepSpectrum per un segnale campionato con
+// frequenza fs che misura l'intervallo da start a stop, con risoluzione di
+// circa res, tutti espressi in Hz, sintonizzando t. Ogni spettro viene passato
+// a report, mentre i frame vengono propagati ad out se non nil. Il
+// sintonizzatore può essere fornito anche in seguito con Plug. Di default
+// viene usato l'80% centrale di ogni segmento, con una sovrapposizione del 20%
+// tra segmenti consecutivi, e ogni segmento viene misurato per 100ms dopo
+// averne scartati 20ms.
+func NewSweepSpectrum(fs, start, stop, res float64, t Tuner, out Connector, report func(SweepFrame)) *SweepSpectrum {
+	s := &SweepSpectrum{
+		fs:      fs,
+		start:   start,
+		stop:    stop,
+		n:       pow2(int(math.Ceil(fs / res))),
+		usable:  0.8,
+		overlap: 0.2,
+		report:  report,
+	}
+
+	s.sc = NewScanner(fs, t, out, s.segment)
+	s.sc.Spectra(s.n)
+	s.plan()
+
+	return s
+}
+
+// Plug collega il sintonizzatore da comandare, tipicamente il Receiver
+// restituito da RSP.
+func (s *SweepSpectrum) Plug(t Tuner) {
+	s.sc.Plug(t)
+}
+
+// Dwell imposta il tempo per cui ogni segmento viene misurato e quello
+// scartato dopo la sintonia, come Scanner.Dwell.
+func (s *SweepSpectrum) Dwell(dwell, settle time.Duration) {
+	s.sc.Dwell(dwell, settle)
+}
+
+// Usable imposta la frazione della banda di ogni segmento effettivamente usata
+// e la frazione di questa sovrapposta al segmento successivo. Ha effetto dalla
+// scansione successiva all'avvio.
+func (s *SweepSpectrum) Usable(fraction, overlap float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usable = math.Max(0.1, math.Min(1, fraction))
+	s.overlap = math.Max(0, math.Min(0.5, overlap))
+	s.plan()
+}
+
+// Calibrate imposta l'offset in dB che, sommato al livello in dBFS, fornisce il
+// livello in dBm all'ingresso d'antenna, come Meter.Calibrate.
+func (s *SweepSpectrum) Calibrate(offset float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.offset = offset
+	s.calibrated = true
+}
+
+// Equalize imposta l'Equalizer con cui correggere gli spettri dei segmenti per
+// la risposta del filtro IF, così che i bordi dei segmenti non siano attenuati;
+// nil disabilita la correzione.
+func (s *SweepSpectrum) Equalize(eq *Equalizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.eq = eq
+}
+
+// Start avvia le scansioni.
+func (s *SweepSpectrum) Start() error {
+	s.mu.Lock()
+	for k := range s.acc {
+		s.acc[k], s.weight[k] = 0, 0
+	}
+	s.sweep, s.received = 0, 0
+	s.mu.Unlock()
+
+	return s.sc.Start()
+}
+
+// Stop ferma le scansioni.
+func (s *SweepSpectrum) Stop() {
+	s.sc.Stop()
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (s *SweepSpectrum) Propagate(I []int16, Q []int16) {
+	s.sc.Propagate(I, Q)
+}
+
+// plan calcola i segmenti che coprono l'intervallo e prepara gli accumulatori.
+func (s *SweepSpectrum) plan() {
+	hop := s.usable * s.fs * (1 - s.overlap)
+
+	s.centers = s.centers[:0]
+	for c := s.start + hop/2; c-hop/2 < s.stop || len(s.centers) == 0; c += hop {
+		s.centers = append(s.centers, c)
+	}
+	s.sc.List(s.centers...)
+
+	bins := int(math.Floor((s.stop-s.start)/s.step())) + 1
+	s.acc = make([]float64, bins)
+	s.weight = make([]float64, bins)
+	s.received = 0
+}
+
+// step restituisce la distanza in Hz tra due bin.
+func (s *SweepSpectrum) step() float64 {
+	return s.fs / float64(s.n)
+}
+
+// segment accumula lo spettro del segmento st e, se la scansione è completa,
+// produce lo SweepFrame corrispondente.
+func (s *SweepSpectrum) segment(st ScanStep) {
+	if st.Spectrum == nil {
+		return
+	}
+
+	s.mu.Lock()
+
+	if st.Sweep != s.sweep {
+		for k := range s.acc {
+			s.acc[k], s.weight[k] = 0, 0
+		}
+		s.sweep = st.Sweep
+		s.received = 0
+	}
+
+	spectrum := st.Spectrum
+	if s.eq != nil {
+		spectrum = append([]float64(nil), spectrum...)
+		s.eq.correct(spectrum, s.step())
+	}
+
+	n := len(spectrum)
+	power := make([]float64, n)
+	for k, p := range spectrum {
+		power[k] = math.Pow(10, p/10)
+	}
+
+	// Il picco della componente continua viene sostituito dalla media dei bin
+	// adiacenti.
+	if n > 2*sweepDC+2 {
+		dc := (power[n/2-sweepDC-1] + power[n/2+sweepDC+1]) / 2
+		for k := n/2 - sweepDC; k <= n/2+sweepDC; k++ {
+			power[k] = dc
+		}
+	}
+
+	// Il peso vale 1 nella parte del segmento non sovrapposta e decresce
+	// linearmente fino a 0 al bordo della parte usata, così che nelle zone
+	// di sovrapposizione i pesi di due segmenti consecutivi sommino a 1.
+	res := s.step()
+	half := s.usable * s.fs / 2
+	ramp := math.Max(2*half*s.overlap, res)
+	for k, p := range power {
+		d := math.Abs(float64(k-n/2) * res)
+		w := math.Min(1, (half-d)/ramp)
+		if w <= 0 {
+			continue
+		}
+
+		j := int(math.Floor((st.Frequency+float64(k-n/2)*res-s.start)/res + 0.5))
+		if j < 0 || j >= len(s.acc) {
+			continue
+		}
+
+		s.acc[j] += w * p
+		s.weight[j] += w
+	}
+
+	s.received++
+	if s.received < len(s.centers) {
+		s.mu.Unlock()
+		return
+	}
+
+	f := SweepFrame{
+		Start:      s.start,
+		Step:       res,
+		Power:      make([]float64, len(s.acc)),
+		Calibrated: s.calibrated,
+		Sweep:      st.Sweep,
+		Time:       st.Time,
+	}
+	for k := range s.acc {
+		f.Power[k] = dB(s.acc[k]/math.Max(s.weight[k], floor)) + s.offset
+	}
+	s.received = 0
+	report := s.report
+	s.mu.Unlock()
+
+	if report != nil {
+		report(f)
+	}
+}
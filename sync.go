@@ -0,0 +1,107 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "fmt"
+
+// SyncUpdater è l'interfaccia di un ricevitore che applica le variazioni di
+// frequenza e guadagno in un punto preciso del flusso dei campioni. Il
+// Receiver restituito da RSP implementa SyncUpdater.
+type SyncUpdater interface {
+	// ScheduleTune e ScheduleGain sintonizzano la frequenza, espressa in Hz,
+	// ed impostano il gain reduction, in dB, con gli aggiornamenti sincroni
+	// dell'API: la variazione ha effetto a partire dal campione sample,
+	// numerato come PacketInfo.FirstSample, così da essere allineata al
+	// flusso dei campioni. La frequenza deve restare nella banda di quella
+	// attuale, perché un cambio di banda reinizializza lo stream; State
+	// riporta i nuovi valori già al ritorno.
+	ScheduleTune(frequency float64, sample uint32) error
+	ScheduleGain(reduction int, sample uint32) error
+}
+
+// SyncPeriod imposta il periodo, espresso in campioni, degli aggiornamenti
+// sincroni dell'API: dopo ScheduleTune o ScheduleGain le variazioni richieste
+// successivamente con la stessa funzione vengono applicate ad intervalli di
+// samples campioni dal campione indicato. Con samples nullo, il default, il
+// periodo dell'API resta invariato. Se modificato con SetUp, lo stream non
+// viene reinizializzato.
+func SyncPeriod(samples uint32) Option {
+	return Option{
+		apply: func() {
+			rsp.SyncPeriod = samples
+		},
+	}
+}
+
+// ScheduleTune implementa l'interfaccia SyncUpdater.
+func (r *radio) ScheduleTune(frequency float64, sample uint32) error {
+	r.mu.Lock()
+
+	return r.retuned(r.scheduleTune(frequency, sample), frequency)
+}
+
+// scheduleTune programma la sintonia della frequenza, espressa in Hz, dal
+// campione sample. Va invocata possedendo r.mu.
+func (r *radio) scheduleTune(frequency float64, sample uint32) error {
+	if r.baseband == nil {
+		return DeactivatedReceiverError
+	}
+
+	if e := r.hw.checkInput(r.feat.Input, frequency); e != nil {
+		return e
+	}
+
+	rfMHz := r.hardware(frequency)
+	if band(float64(rfMHz)*1e6) != r.band {
+		return fmt.Errorf("%w: %g Hz requires reinitialising the stream", InvalidParamError, frequency)
+	}
+
+	if e := r.switchAntenna(frequency); e != nil {
+		return e
+	}
+
+	r.logger().Debug("rsp schedule tune", "rf", frequency, "sample", sample)
+
+	if e := r.failed(api.syncRf(rfMHz*1e6, sample)); e != nil {
+		return e
+	}
+
+	r.rf = frequency
+
+	return nil
+}
+
+// ScheduleGain implementa l'interfaccia SyncUpdater.
+func (r *radio) ScheduleGain(reduction int, sample uint32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.baseband == nil {
+		return DeactivatedReceiverError
+	}
+
+	r.logger().Debug("rsp schedule gain", "grdB", reduction, "sample", sample)
+
+	grsys, e := api.syncGr(integer(reduction), r.feat.LNA, sample)
+	if e := r.failed(e); e != nil {
+		return e
+	}
+
+	r.gr, r.grsys = integer(reduction), grsys
+
+	return nil
+}
+
+// syncPeriod imposta, se richiesto, il periodo degli aggiornamenti sincroni.
+func (r *radio) syncPeriod() error {
+	if r.feat.SyncPeriod == 0 {
+		return nil
+	}
+
+	return r.failed(api.setSyncPeriod(r.feat.SyncPeriod))
+}
@@ -0,0 +1,161 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"time"
+)
+
+type (
+	// Tee è un Connector che duplica i frame ricevuti verso più Connector, ad
+	// esempio un Recorder, un demodulatore ed uno Spectrum alimentati dallo
+	// stesso stream. Ogni ramo ha un proprio Ring ed una propria goroutine:
+	// riceve una copia dei campioni, che può quindi conservare o modificare, ed
+	// un ramo lento non rallenta gli altri né la sorgente ma perde i propri
+	// frame, conteggiati da Overruns.
+	//
+	//	t := sdrplay.NewTee(2e6, 100*time.Millisecond, recorder, fm, spectrum)
+	//	rx, e := sdrplay.RSP(t)
+	//
+	// Come per Ring, Propagate non va invocato da più goroutine
+	// contemporaneamente.
+	Tee struct {
+		rings []*Ring
+		rates []float64
+	}
+
+	// TeeBranch descrive un ramo di un Tee creato con NewTeeBranches, che
+	// propaga ad Out il segnale filtrato e decimato nella goroutine del ramo.
+	TeeBranch struct {
+		Out Connector
+
+		// Rate è la frequenza di campionamento desiderata, in Hz: il segnale
+		// viene decimato del massimo fattore intero che non scende sotto Rate.
+		// Zero lascia invariata la frequenza di campionamento.
+		Rate float64
+
+		// Cutoff è la frequenza di taglio, in Hz, del filtro passa basso
+		// applicato prima della decimazione. Zero equivale al 45% della
+		// frequenza di campionamento del ramo; senza decimazione il filtro
+		// viene applicato solo se Cutoff non è nullo.
+		Cutoff float64
+	}
+
+	// teeFilter è il Connector che filtra e decima i frame di un ramo prima di
+	// propagarli ad out. Viene invocato solo dalla goroutine del Ring del ramo.
+	teeFilter struct {
+		dec    *decimator
+		iq, bb []complex128
+		out    Connector
+	}
+)
+
+// NewTee restituisce un Tee che propaga i frame ad outs, con un buffer per ramo
+// in grado di contenere d di segnale campionato con frequenza fs, espressa in
+// Hz.
+func NewTee(fs float64, d time.Duration, outs ...Connector) *Tee {
+	branches := make([]TeeBranch, len(outs))
+	for k, out := range outs {
+		branches[k].Out = out
+	}
+
+	return NewTeeBranches(fs, d, branches...)
+}
+
+// NewTeeBranches restituisce un Tee con i rami branches, ognuno con un proprio
+// filtro e fattore di decimazione, ad esempio la frequenza piena verso un
+// Recorder e 48kHz verso un demodulatore:
+//
+//	t := sdrplay.NewTeeBranches(2e6, 100*time.Millisecond,
+//		sdrplay.TeeBranch{Out: recorder},
+//		sdrplay.TeeBranch{Out: nfm, Rate: 48e3, Cutoff: 8e3})
+//
+// Il buffer di ogni ramo può contenere d di segnale campionato con frequenza
+// fs, espressa in Hz.
+func NewTeeBranches(fs float64, d time.Duration, branches ...TeeBranch) *Tee {
+	t := &Tee{
+		rings: make([]*Ring, len(branches)),
+		rates: make([]float64, len(branches)),
+	}
+
+	for k, b := range branches {
+		factor := 1
+		if b.Rate > 0 {
+			factor = maxInt(int(fs/b.Rate), 1)
+		}
+		t.rates[k] = fs / float64(factor)
+
+		out := b.Out
+		switch {
+		case factor > 1:
+			cutoff := 0.45 / float64(factor)
+			if b.Cutoff > 0 {
+				cutoff = math.Min(b.Cutoff/fs, cutoff)
+			}
+			out = &teeFilter{dec: newDecimator(factor, cutoff), out: out}
+		case b.Cutoff > 0:
+			out = &teeFilter{dec: newFilter(math.Min(b.Cutoff/fs, 0.45), 63), out: out}
+		}
+
+		t.rings[k] = NewRing(fs, d, out)
+	}
+
+	return t
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (t *Tee) Propagate(I []int16, Q []int16) {
+	for _, r := range t.rings {
+		r.Propagate(I, Q)
+	}
+}
+
+// Rates restituisce, per ogni ramo nell'ordine passato al costruttore, la
+// frequenza di campionamento del segnale propagato, in Hz.
+func (t *Tee) Rates() []float64 {
+	return append([]float64(nil), t.rates...)
+}
+
+// Overruns restituisce, per ogni ramo nell'ordine passato al costruttore, il
+// numero di frame scartati perché il relativo buffer era pieno.
+func (t *Tee) Overruns() []uint64 {
+	o := make([]uint64, len(t.rings))
+	for k, r := range t.rings {
+		o[k] = r.Overruns()
+	}
+
+	return o
+}
+
+// Close propaga i campioni ancora nei buffer ed attende la fine delle
+// goroutine dei rami. Va invocato dopo che la sorgente ha smesso di propagare
+// frame, ad esempio dopo Close del ricevitore.
+func (t *Tee) Close() error {
+	for _, r := range t.rings {
+		r.Close()
+	}
+
+	return nil
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (f *teeFilter) Propagate(I []int16, Q []int16) {
+	f.iq = toComplex(I, Q, f.iq[:0])
+	f.bb = f.dec.process(f.iq, f.bb[:0])
+	if len(f.bb) == 0 {
+		return
+	}
+
+	i, q := make([]int16, len(f.bb)), make([]int16, len(f.bb))
+	for k, x := range f.bb {
+		i[k], q[k] = clamp16(real(x)*fullScale), clamp16(imag(x)*fullScale)
+	}
+
+	f.out.Propagate(i, q)
+}
@@ -0,0 +1,31 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+// TransferMode enumera le modalità di trasferimento USB dei campioni.
+type TransferMode int
+
+const (
+	// Isochronous è la modalità di default, a banda garantita ma senza
+	// ritrasmissione dei pacchetti persi.
+	Isochronous TransferMode = iota
+	// Bulk ritrasmette i pacchetti persi ed è più affidabile sugli host con
+	// controller USB poco prestanti, come il Raspberry Pi.
+	Bulk
+)
+
+// Transfer imposta la modalità di trasferimento USB dei campioni. L'API la
+// applica solo a stream fermo: se viene modificata con SetUp lo stream viene
+// riavviato.
+func Transfer(mode TransferMode) Option {
+	return Option{
+		apply: func() {
+			rsp.Transfer = mode
+		},
+	}
+}
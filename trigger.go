@@ -0,0 +1,120 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "time"
+
+type (
+	// Trigger è l'interfaccia che descrive un segnale di sincronismo verso
+	// apparati esterni (generatori di segnale, matrici di commutazione, ...)
+	// emesso ogni volta che la RSP viene sintonizzata su una nuova frequenza.
+	Trigger interface {
+		// Fire emette il segnale di sincronismo. Il parametro frequency è la
+		// nuova frequenza sintonizzata espressa in Hz.
+		Fire(frequency float64) error
+	}
+
+	// TriggerFunc permette di usare una semplice funzione come Trigger.
+	TriggerFunc func(frequency float64) error
+
+	// GPIOPulse è un Trigger che genera un impulso di durata width su un pin
+	// GPIO esportato tramite l'interfaccia sysfs di Linux.
+	GPIOPulse struct {
+		pin   int
+		width time.Duration
+	}
+)
+
+// Fire implementa l'interfaccia Trigger.
+func (f TriggerFunc) Fire(frequency float64) error {
+	return f(frequency)
+}
+
+// NewGPIOPulse esporta, se necessario, il pin GPIO indicato, lo configura come
+// uscita a livello basso e restituisce un GPIOPulse che su di esso genera
+// impulsi di durata width.
+func NewGPIOPulse(pin int, width time.Duration) (*GPIOPulse, error) {
+	if e := exportGPIO(pin); e != nil {
+		return nil, e
+	}
+
+	if e := writeGPIO(pin, false); e != nil {
+		return nil, e
+	}
+
+	return &GPIOPulse{pin: pin, width: width}, nil
+}
+
+// Fire implementa l'interfaccia Trigger. Il metodo ritorna al termine
+// dell'impulso.
+func (g *GPIOPulse) Fire(frequency float64) error {
+	if e := writeGPIO(g.pin, true); e != nil {
+		return e
+	}
+
+	time.Sleep(g.width)
+
+	return writeGPIO(g.pin, false)
+}
+
+// OnRetune permette di sincronizzare apparati esterni con il ricevitore: il
+// Trigger t viene attivato, a sintonia avvenuta, all'avvio della RSP e ad ogni
+// cambio di frequenza eseguito con Tune o SetUp, quindi anche all'inizio di ogni
+// segmento di una scansione o di un cambio di frequenza programmato. Un errore
+// del Trigger viene restituito dal metodo che ha causato il cambio di frequenza;
+// all'avvio e alla riapertura con AutoReconnect, quando lo stream è già
+// attivo, viene invece solo riportato nel log. Il Trigger viene attivato al
+// termine dell'operazione, così che la durata del segnale non blocchi gli
+// altri metodi del ricevitore, ed i segnali vengono emessi uno alla volta.
+func OnRetune(t Trigger) Option {
+	return Option{
+		apply: func() {
+			rsp.Trigger = t
+		},
+	}
+}
+
+// retuned rilascia r.mu, che va posseduto, e se e è nil attiva il Trigger del
+// ricevitore per la frequenza f espressa in Hz, restituendone l'errore.
+// Altrimenti restituisce e.
+func (r *radio) retuned(e error, f float64) error {
+	t := r.feat.Trigger
+	r.mu.Unlock()
+
+	if e != nil {
+		return e
+	}
+
+	return r.fire(t, f)
+}
+
+// started attiva il Trigger del ricevitore per la frequenza dello stream
+// appena avviato, riportando nel log l'eventuale errore. Va invocata senza
+// possedere r.mu.
+func (r *radio) started() {
+	r.mu.Lock()
+	t, f := r.feat.Trigger, r.rf
+	r.mu.Unlock()
+
+	if e := r.fire(t, f); e != nil {
+		r.logger().Warn("rsp trigger", "rf", f, "err", e)
+	}
+}
+
+// fire attiva, se non nil, il Trigger t per la frequenza f espressa in Hz. Va
+// invocata senza possedere r.mu; tmu serializza i segnali.
+func (r *radio) fire(t Trigger, f float64) error {
+	if t == nil {
+		return nil
+	}
+
+	r.tmu.Lock()
+	defer r.tmu.Unlock()
+
+	return t.Fire(f)
+}
@@ -0,0 +1,224 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+type (
+	// StepTuner è l'interfaccia di un sintonizzatore che si sposta a passi di
+	// canale. Il Receiver restituito da RSP implementa StepTuner.
+	StepTuner interface {
+		Tuner
+
+		// StepTune sintonizza il canale successivo, se up è true, o quello
+		// precedente, distanti dalla frequenza attuale il passo impostato con
+		// l'opzione TuningStep.
+		StepTune(up bool) error
+	}
+
+	// RelativeTuner è l'interfaccia di un sintonizzatore che si sposta
+	// rispetto alla frequenza attuale, per manopole e loop di AFC. Il
+	// Receiver restituito da RSP implementa RelativeTuner.
+	RelativeTuner interface {
+		Tuner
+
+		// TuneBy sposta la sintonia di delta Hz rispetto alla frequenza
+		// attuale: se la nuova frequenza resta nella stessa banda la RSP
+		// viene risintonizzata con lo spostamento relativo dell'API, più
+		// rapido, altrimenti come con Tune.
+		TuneBy(delta float64) error
+	}
+
+	// shifter trasla in frequenza il segnale in banda base moltiplicandolo per
+	// un oscillatore complesso.
+	shifter struct {
+		// rot è la rotazione per campione, osc il valore attuale
+		// dell'oscillatore.
+		rot, osc complex128
+	}
+)
+
+// TuningStep imposta il passo di canale, espresso in kHz, usato da StepTune.
+// Di default vale 100kHz.
+func TuningStep(kHz float64) Option {
+	return Option{
+		apply: func() {
+			rsp.Step = double(kHz)
+		},
+	}
+}
+
+// OffsetTuning abilita la sintonia con offset: la RSP viene sintonizzata kHz
+// sopra la frequenza richiesta ed il segnale in banda base viene traslato
+// digitalmente così che la frequenza richiesta torni al centro. In questo modo
+// il picco della componente continua cade kHz sotto il canale utile invece che
+// al suo centro. L'offset deve essere inferiore a metà della frequenza di
+// campionamento; con kHz nullo la sintonia con offset viene disabilitata.
+func OffsetTuning(kHz float64) Option {
+	return Option{
+		apply: func() {
+			rsp.TuneOffset = double(kHz)
+		},
+	}
+}
+
+// dcGuard è la distanza minima, in Hz, tra il picco della componente continua
+// ed il bordo del canale mantenuta da AvoidDC.
+const dcGuard = 10e3
+
+// AvoidDC, se channel è positiva, sceglie automaticamente l'offset di
+// OffsetTuning così che il picco della componente continua della IF nulla cada
+// fuori dal canale utile, largo channel Hz e centrato sulla frequenza
+// richiesta: l'offset è pari a 3/4 del canale e lascia almeno 10 kHz tra il
+// picco ed il bordo del canale. Il canale traslato deve restare entro la
+// larghezza di banda e la banda di Nyquist dei campioni propagati, altrimenti
+// RSP e SetUp restituiscono un OptionError. Con una IF non nulla il picco non
+// cade nel segnale e l'offset resta quello di OffsetTuning; AvoidDC(0) la
+// disabilita.
+func AvoidDC(channel float64) Option {
+	return Option{
+		apply: func() {
+			rsp.DCChannel = double(channel)
+		},
+	}
+}
+
+// avoidDC imposta in f l'offset di sintonia per il canale scelto con AvoidDC.
+func (f *features) avoidDC() {
+	if f.IF != IFzero {
+		return
+	}
+
+	ch := float64(f.DCChannel)
+	f.TuneOffset = double(math.Max(0.75*ch, ch/2+dcGuard) / 1e3)
+}
+
+// validateAvoidDC verifica che il canale scelto con AvoidDC, traslato
+// dall'offset di sintonia, resti entro la larghezza di banda e la banda di
+// Nyquist dei campioni prodotti dalla RSP.
+func validateAvoidDC(f features) error {
+	if f.DCChannel <= 0 || f.IF != IFzero {
+		return nil
+	}
+
+	edge := float64(f.TuneOffset)*1e3 + float64(f.DCChannel)/2
+	switch {
+	case edge > float64(f.BW)*1e3/2:
+		return &OptionError{Option: "AvoidDC", Conflict: "Bandwidth", Reason: fmt.Sprintf("channel edge %g kHz from the LO beyond bandwidth %d kHz", edge/1e3, int(f.BW))}
+	case edge > f.rate()/2:
+		return &OptionError{Option: "AvoidDC", Conflict: "FS", Reason: fmt.Sprintf("channel edge %g kHz from the LO beyond the Nyquist band of %g kHz", edge/1e3, f.rate()/1e3)}
+	}
+
+	return nil
+}
+
+// StepTune implementa l'interfaccia StepTuner.
+func (r *radio) StepTune(up bool) error {
+	r.mu.Lock()
+
+	step := float64(r.feat.Step) * 1e3
+	if step == 0 {
+		step = 100e3
+	}
+
+	if !up {
+		step = -step
+	}
+
+	frequency := r.rf + step
+
+	return r.retuned(r.tune(frequency), frequency)
+}
+
+// TuneBy implementa l'interfaccia RelativeTuner.
+func (r *radio) TuneBy(delta float64) error {
+	r.mu.Lock()
+
+	frequency := r.rf + delta
+
+	return r.retuned(r.tuneBy(frequency, delta), frequency)
+}
+
+// tuneBy sintonizza la frequenza, espressa in Hz, distante delta Hz da quella
+// attuale. Va invocata possedendo r.mu.
+func (r *radio) tuneBy(frequency, delta float64) error {
+	if r.baseband == nil {
+		return DeactivatedReceiverError
+	}
+
+	if band(float64(r.hardware(frequency))*1e6) != r.band {
+		return r.tune(frequency)
+	}
+
+	if e := r.hw.checkInput(r.feat.Input, frequency); e != nil {
+		return e
+	}
+
+	if e := r.switchAntenna(frequency); e != nil {
+		return e
+	}
+
+	if e := r.failed(api.shiftRf(double(delta))); e != nil {
+		return e
+	}
+
+	r.rf = frequency
+
+	return nil
+}
+
+// hardware restituisce la frequenza, in MHz, alla quale sintonizzare la RSP
+// per ricevere la frequenza rf espressa in Hz.
+func (r *radio) hardware(rf float64) double {
+	return double(rf/1e6 + float64(r.feat.TuneOffset)/1e3)
+}
+
+// setShift prepara la traslazione digitale corrispondente all'offset di
+// sintonia attuale.
+func (r *radio) setShift() {
+	var shift *shifter
+
+	if r.feat.TuneOffset != 0 {
+		fs := float64(r.feat.FS) * 1e6
+		if r.feat.Decimate && r.feat.Factor > 0 {
+			fs /= float64(r.feat.Factor)
+		}
+
+		// La RSP è sintonizzata sopra la frequenza richiesta, che compare
+		// quindi a -offset: va riportata a 0.
+		w := 2 * math.Pi * float64(r.feat.TuneOffset) * 1e3 / fs
+		shift = &shifter{rot: complex(math.Cos(w), math.Sin(w)), osc: 1}
+	}
+
+	r.smu.Lock()
+	r.shift = shift
+	r.smu.Unlock()
+}
+
+// apply trasla i campioni I e Q, sostituendoli con quelli traslati.
+func (s *shifter) apply(I, Q []int16) {
+	for k := 0; k < len(I) && k < len(Q); k++ {
+		x := complex(float64(I[k]), float64(Q[k])) * s.osc
+		I[k], Q[k] = clamp16(real(x)), clamp16(imag(x))
+		s.osc *= s.rot
+	}
+
+	// Si corregge l'ampiezza dell'oscillatore, che altrimenti deriverebbe per
+	// effetto degli arrotondamenti.
+	s.osc /= complex(cmplx.Abs(s.osc), 0)
+}
+
+// clamp16 restituisce il valore x arrotondato e limitato all'intervallo di un
+// int16.
+func clamp16(x float64) int16 {
+	return int16(math.Max(-fullScale, math.Min(fullScale-1, math.Round(x))))
+}
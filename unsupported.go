@@ -0,0 +1,148 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// FeatureCheck enumera i comportamenti possibili quando un'opzione non è
+	// ammessa dal modello della RSP.
+	FeatureCheck int
+
+	// UnsupportedFeature descrive un'opzione non ammessa dal modello della
+	// RSP. È restituito da RSP e SetUp prima di modificare la configurazione
+	// della RSP; errors.Is(e, UnsupportedFeatureError) è vero per ogni
+	// UnsupportedFeature.
+	UnsupportedFeature struct {
+		// Option è l'opzione e Model il modello che non la ammette.
+		Option string
+		Model  Model
+	}
+
+	// requirement descrive un'opzione ammessa solo da alcuni modelli: used
+	// indica se l'opzione è richiesta in f, supported se, con il valore
+	// richiesto in f, è ammessa dalla RSP h e disable la rimuove da f.
+	requirement struct {
+		option    string
+		used      func(f features) bool
+		supported func(h Hardware, f features) bool
+		disable   func(f *features)
+	}
+)
+
+const (
+	// FatalUnsupported fa fallire RSP e SetUp con un UnsupportedFeature.
+	FatalUnsupported FeatureCheck = iota
+	// WarnUnsupported riporta l'opzione nel log con livello Warn e prosegue
+	// senza applicarla.
+	WarnUnsupported
+)
+
+// UnsupportedFeatureError è l'errore di cui ogni UnsupportedFeature è un caso
+// particolare.
+var UnsupportedFeatureError = errors.New("Unsupported Feature Error")
+
+// requirements contiene le opzioni ammesse solo da alcuni modelli.
+var requirements = []requirement{
+	{
+		option:    "BiasT",
+		used:      func(f features) bool { return bool(f.BiasT) },
+		supported: func(h Hardware, f features) bool { return h.BiasT },
+		disable:   func(f *features) { f.BiasT = false },
+	},
+	{
+		option:    "BroadcastNotch",
+		used:      func(f features) bool { return bool(f.Broadcast) },
+		supported: func(h Hardware, f features) bool { return h.notch("FM") },
+		disable:   func(f *features) { f.Broadcast = false },
+	},
+	{
+		option:    "DABNotch",
+		used:      func(f features) bool { return bool(f.DABNotch) },
+		supported: func(h Hardware, f features) bool { return h.notch("DAB") },
+		disable:   func(f *features) { f.DABNotch = false },
+	},
+	{
+		option:    "AntennaInput",
+		used:      func(f features) bool { return f.Input != InputA },
+		supported: func(h Hardware, f features) bool { return int(f.Input) < len(h.Inputs) },
+		disable:   func(f *features) { f.Input = InputA },
+	},
+	{
+		option:    "HiZ",
+		used:      func(f features) bool { return bool(f.HiZ) },
+		supported: func(h Hardware, f features) bool { return h.HiZ },
+		disable:   func(f *features) { f.HiZ = false },
+	},
+	{
+		option:    "Duo",
+		used:      func(f features) bool { return f.Duo != SingleTuner },
+		supported: func(h Hardware, f features) bool { return h.Model == RSPduo },
+		disable:   func(f *features) { f.Duo = SingleTuner },
+	},
+}
+
+// Error implementa l'interfaccia error.
+func (e *UnsupportedFeature) Error() string {
+	return fmt.Sprintf("option %s not supported by %s", e.Option, e.Model)
+}
+
+// Unwrap restituisce UnsupportedFeatureError.
+func (e *UnsupportedFeature) Unwrap() error {
+	return UnsupportedFeatureError
+}
+
+// UnsupportedFeatures imposta il comportamento quando un'opzione non è ammessa
+// dal modello della RSP, ad esempio DABNotch su una RSP1: di default RSP e
+// SetUp restituiscono un UnsupportedFeature, con WarnUnsupported l'opzione
+// viene invece ignorata, senza invocare l'API.
+func UnsupportedFeatures(check FeatureCheck) Option {
+	return Option{
+		apply: func() {
+			rsp.Unsupported = check
+		},
+	}
+}
+
+// notch indica se la RSP descritta da h dispone del filtro notch name.
+func (h Hardware) notch(name string) bool {
+	for _, n := range h.Notches {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// support verifica che le opzioni di f siano ammesse dalla RSP descritta da h,
+// restituendo f privata, con WarnUnsupported, delle opzioni non ammesse. Con
+// un modello sconosciuto non viene eseguita alcuna verifica.
+func (h Hardware) support(f features) (features, error) {
+	if h.Model == UnknownModel {
+		return f, nil
+	}
+
+	for _, q := range requirements {
+		if !q.used(f) || q.supported(h, f) {
+			continue
+		}
+
+		if f.Unsupported != WarnUnsupported {
+			return f, &UnsupportedFeature{Option: q.option, Model: h.Model}
+		}
+
+		f.logger().Warn("rsp unsupported option", "option", q.option, "model", h.Model)
+		q.disable(&f)
+	}
+
+	return f, nil
+}
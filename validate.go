@@ -0,0 +1,123 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// OptionError descrive un'opzione con un valore non ammesso o incompatibile con
+// un'altra opzione. È restituito da RSP e SetUp prima di modificare la
+// configurazione della RSP; errors.Is(e, InvalidOptionError) è vero per ogni
+// OptionError.
+type OptionError struct {
+	// Option è l'opzione non valida e Conflict, se non vuota, quella con cui
+	// è incompatibile.
+	Option   string
+	Conflict string
+
+	// Reason spiega il motivo dell'errore.
+	Reason string
+}
+
+// InvalidOptionError è l'errore di cui ogni OptionError è un caso particolare.
+var InvalidOptionError = errors.New("Invalid Option Error")
+
+// Error implementa l'interfaccia error.
+func (e *OptionError) Error() string {
+	if e.Conflict == "" {
+		return fmt.Sprintf("invalid option %s: %s", e.Option, e.Reason)
+	}
+
+	return fmt.Sprintf("option %s conflicts with %s: %s", e.Option, e.Conflict, e.Reason)
+}
+
+// Unwrap restituisce InvalidOptionError.
+func (e *OptionError) Unwrap() error {
+	return InvalidOptionError
+}
+
+// lowIF descrive le combinazioni ammesse dall'API per una IF non nulla: la
+// frequenza di campionamento in MHz e le larghezze di banda massime.
+var lowIF = map[IFmode]struct {
+	fs double
+	bw B
+}{
+	IF450:  {2, BW600},
+	IF1620: {6, BW1536},
+	IF2048: {8, BW5000},
+}
+
+// validate verifica che la configurazione f sia ammessa dalla RSP,
+// restituendo un OptionError che descrive la prima incompatibilità trovata.
+func validate(f features) error {
+	switch {
+	case f.FS < 2 || f.FS > 10:
+		return &OptionError{Option: "FS", Reason: fmt.Sprintf("sample rate %g MHz outside 2 - 10 MHz", float64(f.FS))}
+
+	case f.InitialRF < 0.1 || f.InitialRF >= 2000:
+		return &OptionError{Option: "InitialRF", Reason: fmt.Sprintf("frequency %g MHz outside 0.1 - 2000 MHz", float64(f.InitialRF))}
+
+	case f.InitialGR != 0 && (f.InitialGR < MinGainReduction || f.InitialGR > MaxGainReduction):
+		return &OptionError{Option: "InitialGR", Reason: fmt.Sprintf("gain reduction %d dB outside %d - %d dB", int(f.InitialGR), MinGainReduction, MaxGainReduction)}
+
+	case f.DBFS > 0:
+		return &OptionError{Option: "AGC", Reason: fmt.Sprintf("set point %d dBFS above full scale", int(f.DBFS))}
+
+	case f.Transfer != Isochronous && f.Transfer != Bulk:
+		return &OptionError{Option: "Transfer", Reason: fmt.Sprintf("unknown transfer mode %d", int(f.Transfer))}
+
+	case bool(f.HiZ) && f.InitialRF >= 60:
+		return &OptionError{Option: "HiZ", Conflict: "InitialRF", Reason: fmt.Sprintf("the Hi-Z port receives below 60 MHz, not at %g MHz", float64(f.InitialRF))}
+
+	case math.Abs(float64(f.TuneOffset)) >= float64(f.FS)*1e3/2:
+		return &OptionError{Option: "OffsetTuning", Conflict: "FS", Reason: fmt.Sprintf("offset %g kHz beyond the Nyquist band of %g MHz", float64(f.TuneOffset), float64(f.FS))}
+	}
+
+	if e := validateDuo(f); e != nil {
+		return e
+	}
+
+	if e := validateOutputRate(f); e != nil {
+		return e
+	}
+
+	if e := validateAvoidDC(f); e != nil {
+		return e
+	}
+
+	if f.IF == IFzero {
+		if float64(f.BW) > float64(f.FS)*1e3 {
+			return &OptionError{Option: "Bandwidth", Conflict: "FS", Reason: fmt.Sprintf("bandwidth %d kHz wider than sample rate %g MHz", int(f.BW), float64(f.FS))}
+		}
+	} else {
+		mode, ok := lowIF[f.IF]
+		switch {
+		case !ok:
+			return &OptionError{Option: "IF", Reason: fmt.Sprintf("unsupported IF %d kHz", int(f.IF))}
+		case f.FS != mode.fs:
+			return &OptionError{Option: "IF", Conflict: "FS", Reason: fmt.Sprintf("IF %d kHz requires sample rate %g MHz", int(f.IF), float64(mode.fs))}
+		case f.BW > mode.bw:
+			return &OptionError{Option: "IF", Conflict: "Bandwidth", Reason: fmt.Sprintf("IF %d kHz allows bandwidth up to %d kHz", int(f.IF), int(mode.bw))}
+		case bool(f.Decimate):
+			return &OptionError{Option: "Decimate", Conflict: "IF", Reason: "decimation requires zero IF"}
+		}
+	}
+
+	if f.Decimate {
+		switch f.Factor {
+		case Factor2, Factor4, Factor8, Factor16, Factor32, Factor64:
+		default:
+			return &OptionError{Option: "Decimate", Reason: fmt.Sprintf("unsupported factor %d", int(f.Factor))}
+		}
+	}
+
+	return nil
+}
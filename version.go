@@ -0,0 +1,101 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"math"
+)
+
+type (
+	// VersionCheck enumera i comportamenti possibili quando la versione della
+	// libreria SDRplay installata è diversa da quella con cui è stato
+	// compilato il package.
+	VersionCheck int
+
+	// versionPolicy contiene i comportamenti per le differenze di versione
+	// minore, a parità di parte intera, e maggiore.
+	versionPolicy struct {
+		minor, major VersionCheck
+	}
+)
+
+const (
+	// FatalMismatch fa fallire RSP con l'errore VersionMismatchError.
+	FatalMismatch VersionCheck = iota
+	// WarnMismatch riporta la differenza nel log con livello Warn.
+	WarnMismatch
+	// IgnoreMismatch ignora la differenza.
+	IgnoreMismatch
+)
+
+// VersionMismatchError indica che la versione della libreria SDRplay è diversa
+// da quella attesa e che la differenza non è ammessa dalle opzioni.
+var VersionMismatchError = errors.New("Version Mismatch Error")
+
+// Version restituisce la versione della libreria SDRplay installata e quella
+// con cui è stato compilato il package.
+func Version() (library, expected float64, e error) {
+	library, e = api.apiVersion()
+
+	return library, api.expectedVersion(), e
+}
+
+// AllowVersionMismatch permette di usare una libreria SDRplay di versione
+// diversa da quella attesa, riportando la differenza nel log. Se minor è true
+// sono ammesse solo le differenze di versione minore, come tra 2.11 e 2.13,
+// mentre quelle di versione maggiore restano fatali. Di default ogni
+// differenza è fatale.
+func AllowVersionMismatch(minor bool) Option {
+	major := WarnMismatch
+	if minor {
+		major = FatalMismatch
+	}
+
+	return VersionMismatch(WarnMismatch, major)
+}
+
+// VersionMismatch imposta il comportamento per le differenze di versione
+// minore e maggiore tra la libreria SDRplay e quella attesa.
+func VersionMismatch(minor, major VersionCheck) Option {
+	return Option{
+		apply: func() {
+			rsp.Version = versionPolicy{minor: minor, major: major}
+		},
+	}
+}
+
+// checkVersion verifica la versione della libreria secondo la politica
+// impostata in f.
+func checkVersion(f features) error {
+	library, expected, e := Version()
+	if e != nil {
+		return e
+	}
+
+	// Le versioni sono float del C: la tolleranza assorbe l'errore di
+	// conversione.
+	if math.Abs(library-expected) < 1e-4 {
+		return nil
+	}
+
+	check := f.Version.minor
+	if math.Floor(library+1e-4) != math.Floor(expected+1e-4) {
+		check = f.Version.major
+	}
+
+	switch check {
+	case WarnMismatch:
+		f.logger().Warn("rsp version mismatch", "library", library, "expected", expected)
+	case IgnoreMismatch:
+	default:
+		return VersionMismatchError
+	}
+
+	return nil
+}
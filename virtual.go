@@ -0,0 +1,56 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import "sync"
+
+// virtual è la base dei Receiver che non usano la RSP, come FileReceiver:
+// memorizza la frequenza ed il gain reduction impostati senza che questi
+// abbiano effetto sul segnale prodotto, così che il codice scritto per la RSP
+// possa essere sviluppato e provato anche in assenza dell'hardware.
+type virtual struct {
+	vmu sync.Mutex
+
+	frequency float64
+	reduction int
+}
+
+// Tune implementa l'interfaccia Tuner memorizzando la frequenza richiesta.
+func (v *virtual) Tune(frequency float64) error {
+	v.vmu.Lock()
+	defer v.vmu.Unlock()
+
+	v.frequency = frequency
+
+	return nil
+}
+
+// Gain implementa l'interfaccia Amplifier memorizzando il gain reduction
+// richiesto.
+func (v *virtual) Gain(reduction int) error {
+	v.vmu.Lock()
+	defer v.vmu.Unlock()
+
+	v.reduction = reduction
+
+	return nil
+}
+
+// SetUp implementa l'interfaccia Receiver. Le opzioni riguardano la RSP e
+// vengono pertanto ignorate.
+func (v *virtual) SetUp(opts ...Option) error {
+	return nil
+}
+
+// tuned restituisce la frequenza impostata con Tune.
+func (v *virtual) tuned() float64 {
+	v.vmu.Lock()
+	defer v.vmu.Unlock()
+
+	return v.frequency
+}
@@ -0,0 +1,202 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+)
+
+// VRT è un Connector che trasmette il segnale in banda base via UDP con il
+// protocollo VITA-49 (VRT): i campioni vengono inviati in pacchetti IF Data
+// con identificativo di flusso e marca temporale UTC con risoluzione al
+// picosecondo, mentre frequenza centrale, larghezza di banda e frequenza di
+// campionamento vengono inviate in pacchetti IF Context all'avvio, ad ogni
+// risintonia ed una volta al secondo. Perché le risintonie vengano segnalate,
+// VRT va registrato come Trigger:
+//
+//	v, e := sdrplay.NewVRT("192.168.1.10:4991", 1, fs, bw)
+//	rx, e := sdrplay.RSP(v, sdrplay.OnRetune(v))
+type VRT struct {
+	mu sync.Mutex
+
+	conn net.Conn
+
+	// stream è l'identificativo del flusso, fs e bandwidth la frequenza di
+	// campionamento e la larghezza di banda, frequency la frequenza centrale,
+	// tutte in Hz.
+	stream                   uint32
+	fs, bandwidth, frequency float64
+
+	// start è l'istante del primo campione e samples il numero di campioni
+	// trasmessi, da cui si ricava la marca temporale di ogni pacchetto.
+	start   time.Time
+	samples uint64
+
+	// data e context sono i contatori, modulo 16, dei pacchetti trasmessi;
+	// changed indica che il contesto è cambiato e next è la posizione, in
+	// campioni, del prossimo invio periodico del contesto.
+	data, context uint32
+	changed       bool
+	next          uint64
+
+	err error
+	buf []byte
+}
+
+const (
+	// vrtSamples è il numero massimo di campioni di un pacchetto dati, scelto
+	// perché il datagramma non superi la MTU di Ethernet.
+	vrtSamples = 360
+
+	// vrtPrologue è la dimensione, in parole da 32 bit, dell'intestazione di
+	// un pacchetto: header, stream ID e marca temporale intera e frazionaria.
+	vrtPrologue = 5
+
+	// Tipi di pacchetto VRT.
+	vrtData    = 0x1
+	vrtContext = 0x4
+
+	// Bit del campo indicatore del contesto (CIF0).
+	vrtChange      = 1 << 31
+	vrtBandwidth   = 1 << 29
+	vrtRFFrequency = 1 << 27
+	vrtSampleRate  = 1 << 21
+)
+
+// NewVRT restituisce un VRT che invia all'indirizzo UDP addr il flusso stream,
+// campionato con frequenza fs e con larghezza di banda bandwidth, entrambe in
+// Hz.
+func NewVRT(addr string, stream uint32, fs, bandwidth float64) (*VRT, error) {
+	conn, e := net.Dial("udp", addr)
+	if e != nil {
+		return nil, e
+	}
+
+	return &VRT{
+		conn:      conn,
+		stream:    stream,
+		fs:        fs,
+		bandwidth: bandwidth,
+		changed:   true,
+	}, nil
+}
+
+// Fire implementa l'interfaccia Trigger: la nuova frequenza centrale viene
+// segnalata con un pacchetto di contesto prima dei campioni successivi.
+func (v *VRT) Fire(frequency float64) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.frequency = frequency
+	v.changed = true
+
+	return nil
+}
+
+// Err restituisce il primo errore di trasmissione incontrato.
+func (v *VRT) Err() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.err
+}
+
+// Close chiude il socket UDP.
+func (v *VRT) Close() error {
+	return v.conn.Close()
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (v *VRT) Propagate(I []int16, Q []int16) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.start.IsZero() {
+		v.start = time.Now()
+	}
+
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	for k := 0; k < n; k += vrtSamples {
+		if v.changed || v.samples >= v.next {
+			v.sendContext()
+		}
+
+		end := k + vrtSamples
+		if end > n {
+			end = n
+		}
+
+		b := v.prologue(vrtData, v.data, end-k)
+		for j := k; j < end; j++ {
+			b = append(b, byte(uint16(I[j])>>8), byte(I[j]), byte(uint16(Q[j])>>8), byte(Q[j]))
+		}
+
+		v.send(b)
+		v.data++
+		v.samples += uint64(end - k)
+	}
+}
+
+// sendContext trasmette il pacchetto di contesto.
+func (v *VRT) sendContext() {
+	cif := uint32(vrtBandwidth | vrtRFFrequency | vrtSampleRate)
+	if v.changed {
+		cif |= vrtChange
+	}
+
+	b := v.prologue(vrtContext, v.context, 7)
+	b = binary.BigEndian.AppendUint32(b, cif)
+	b = binary.BigEndian.AppendUint64(b, vrtFixed(v.bandwidth))
+	b = binary.BigEndian.AppendUint64(b, vrtFixed(v.frequency))
+	b = binary.BigEndian.AppendUint64(b, vrtFixed(v.fs))
+
+	v.send(b)
+	v.context++
+	v.changed = false
+	v.next = v.samples + uint64(v.fs)
+}
+
+// prologue restituisce l'intestazione di un pacchetto di tipo kind, con
+// contatore count e payload di words parole da 32 bit, marcato con l'istante
+// del prossimo campione.
+func (v *VRT) prologue(kind, count uint32, words int) []byte {
+	// La marca temporale è intera in secondi UTC (TSI=1) e frazionaria in
+	// picosecondi (TSF=2).
+	header := kind<<28 | 1<<22 | 2<<20 | (count%16)<<16 | uint32(vrtPrologue+words)
+
+	t := v.start.Add(time.Duration(float64(v.samples) / v.fs * float64(time.Second)))
+
+	b := v.buf[:0]
+	b = binary.BigEndian.AppendUint32(b, header)
+	b = binary.BigEndian.AppendUint32(b, v.stream)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))
+	b = binary.BigEndian.AppendUint64(b, uint64(t.Nanosecond())*1000)
+
+	return b
+}
+
+// send trasmette il pacchetto b, memorizzando il primo errore.
+func (v *VRT) send(b []byte) {
+	v.buf = b
+	if _, e := v.conn.Write(b); e != nil && v.err == nil {
+		v.err = e
+	}
+}
+
+// vrtFixed converte una frequenza in Hz nel formato a virgola fissa VRT a 64
+// bit con 20 bit di parte frazionaria.
+func vrtFixed(hz float64) uint64 {
+	return uint64(int64(hz * (1 << 20)))
+}
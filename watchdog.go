@@ -0,0 +1,120 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	// StallError descrive l'interruzione dei campioni rilevata dal watchdog
+	// impostato con l'opzione Watchdog; errors.Is(e, StreamStalledError) è
+	// vero per ogni StallError.
+	StallError struct {
+		// Last è l'istante dell'ultima invocazione della callback e Timeout
+		// il limite impostato.
+		Last    time.Time
+		Timeout time.Duration
+	}
+
+	// watchdog contiene i parametri impostati con l'opzione Watchdog.
+	watchdog struct {
+		timeout time.Duration
+		report  func(*StallError)
+	}
+)
+
+// StreamStalledError è l'errore di cui ogni StallError è un caso particolare.
+var StreamStalledError = errors.New("Stream Stalled Error")
+
+// Error implementa l'interfaccia error.
+func (e *StallError) Error() string {
+	return fmt.Sprintf("no samples since %s (timeout %v)", e.Last.Format(time.RFC3339Nano), e.Timeout)
+}
+
+// Unwrap restituisce StreamStalledError.
+func (e *StallError) Unwrap() error {
+	return StreamStalledError
+}
+
+// Watchdog invoca report se la callback dell'API non riceve campioni per più
+// di timeout, il caso tipico in cui l'API smette silenziosamente di consegnare
+// lo stream. report viene invocata una volta per interruzione, da una goroutine
+// dedicata, e può quindi riavviare o chiudere il ricevitore; se i campioni
+// riprendono, il watchdog torna ad attendere l'interruzione successiva. Un
+// timeout non positivo disabilita il watchdog.
+func Watchdog(timeout time.Duration, report func(*StallError)) Option {
+	return Option{
+		apply: func() {
+			rsp.Watchdog = watchdog{timeout: timeout, report: report}
+		},
+	}
+}
+
+// startWatchdog avvia, se impostato, il watchdog dello stream.
+func (r *radio) startWatchdog() {
+	if r.feat.Watchdog.timeout <= 0 || r.feat.Watchdog.report == nil {
+		return
+	}
+
+	r.watchdog = make(chan struct{})
+	r.watchdogDone = make(chan struct{})
+
+	go r.watch(r.watchdog, r.watchdogDone, r.feat.Watchdog)
+}
+
+// stopWatchdog termina il watchdog avviato da startWatchdog.
+func (r *radio) stopWatchdog() {
+	if r.watchdog == nil {
+		return
+	}
+
+	close(r.watchdog)
+	<-r.watchdogDone
+
+	r.watchdog, r.watchdogDone = nil, nil
+}
+
+// watch verifica, quattro volte per timeout, l'arrivo dei campioni fino alla
+// chiusura di stop.
+func (r *radio) watch(stop, done chan struct{}, w watchdog) {
+	defer close(done)
+
+	period := w.timeout / 4
+	if period < 10*time.Millisecond {
+		period = 10 * time.Millisecond
+	}
+
+	t := time.NewTicker(period)
+	defer t.Stop()
+
+	// stalled è l'istante dell'ultima callback dell'interruzione già
+	// notificata.
+	var stalled int64
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+
+		last := r.last.Load()
+		if last == stalled || time.Since(time.Unix(0, last)) <= w.timeout {
+			continue
+		}
+		stalled = last
+
+		e := &StallError{Last: time.Unix(0, last), Timeout: w.timeout}
+		r.logger().Warn("rsp stream stalled", "last", e.Last, "timeout", w.timeout)
+
+		go w.report(e)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// wavWriter scrive un file WAV a due canali (I e Q) a 16 bit, con il chunk auxi
+// che SDR#, HDSDR e SpectraVue usano per memorizzare la frequenza centrale.
+// Se il supporto permette il posizionamento, alla chiusura vengono aggiornate
+// le dimensioni e l'istante di fine registrazione nell'intestazione; oltre i
+// 4GB il file viene convertito in RF64 (EBU Tech 3306) sostituendo il chunk
+// JUNK riservato allo scopo con il chunk ds64.
+// Sui supporti che non permettono il posizionamento, come gli object store, le
+// dimensioni restano al valore 0xFFFFFFFF, convenzionalmente interpretato dai
+// lettori come "fino alla fine del file".
+type wavWriter struct {
+	w io.WriteCloser
+
+	fs        uint32
+	frequency uint32
+	start     time.Time
+
+	// size è il numero di byte di campioni scritti.
+	size uint64
+}
+
+const (
+	// wavHeader è la dimensione dell'intestazione scritta da wavWriter, che
+	// precede i campioni.
+	wavHeader = 156
+
+	// wavUnknown è la dimensione usata per i chunk di lunghezza non nota o
+	// superiore a 4GB.
+	wavUnknown = 0xFFFFFFFF
+)
+
+// NewWAVRecorder crea su st la registrazione name e restituisce il Recorder che
+// vi scrive il segnale ricevuto in formato WAV a due canali, compatibile con i
+// programmi che riproducono registrazioni IQ. Il parametro fs è la frequenza di
+// campionamento e frequency la frequenza centrale del segnale, entrambe espresse
+// in Hz. La registrazione deve essere conclusa con Close.
+func NewWAVRecorder(st Storage, name string, fs, frequency float64) (*Recorder, error) {
+	w, e := st.Create(name)
+	if e != nil {
+		return nil, e
+	}
+
+	ww := &wavWriter{
+		w:         w,
+		fs:        uint32(fs + 0.5),
+		frequency: uint32(frequency + 0.5),
+		start:     time.Now().UTC(),
+	}
+
+	_, seekable := w.(io.WriteSeeker)
+	if _, e := w.Write(ww.header(!seekable, ww.start)); e != nil {
+		w.Close()
+		return nil, e
+	}
+
+	return newRecorder(ww), nil
+}
+
+// Write implementa l'interfaccia io.Writer.
+func (w *wavWriter) Write(b []byte) (int, error) {
+	n, e := w.w.Write(b)
+	w.size += uint64(n)
+
+	return n, e
+}
+
+// Close implementa l'interfaccia io.Closer, aggiornando l'intestazione se il
+// supporto lo permette.
+func (w *wavWriter) Close() error {
+	if s, ok := w.w.(io.WriteSeeker); ok {
+		if _, e := s.Seek(0, io.SeekStart); e != nil {
+			w.w.Close()
+			return e
+		}
+
+		if _, e := s.Write(w.header(false, time.Now().UTC())); e != nil {
+			w.w.Close()
+			return e
+		}
+	}
+
+	return w.w.Close()
+}
+
+// header restituisce l'intestazione del file. Se unknown è vero le dimensioni
+// non sono note ed i relativi campi valgono wavUnknown; stop è l'istante di fine
+// registrazione.
+func (w *wavWriter) header(unknown bool, stop time.Time) []byte {
+	h := make([]byte, wavHeader)
+	le := binary.LittleEndian
+
+	riff := uint64(wavHeader-8) + w.size
+	rf64 := riff > wavUnknown
+
+	// Chunk RIFF (o RF64).
+	copy(h[0:], "RIFF")
+	switch {
+	case unknown || rf64:
+		le.PutUint32(h[4:], wavUnknown)
+	default:
+		le.PutUint32(h[4:], uint32(riff))
+	}
+	copy(h[8:], "WAVE")
+
+	// Chunk JUNK, sostituito da ds64 per i file RF64.
+	copy(h[12:], "JUNK")
+	le.PutUint32(h[16:], 28)
+	if rf64 {
+		copy(h[0:], "RF64")
+		copy(h[12:], "ds64")
+		le.PutUint64(h[20:], riff)
+		le.PutUint64(h[28:], w.size)
+		le.PutUint64(h[36:], w.size/4)
+	}
+
+	// Chunk fmt: PCM, 2 canali a 16 bit.
+	copy(h[48:], "fmt ")
+	le.PutUint32(h[52:], 16)
+	le.PutUint16(h[56:], 1)
+	le.PutUint16(h[58:], 2)
+	le.PutUint32(h[60:], w.fs)
+	le.PutUint32(h[64:], 4*w.fs)
+	le.PutUint16(h[68:], 4)
+	le.PutUint16(h[70:], 16)
+
+	// Chunk auxi: istanti di inizio e fine nel formato SYSTEMTIME, frequenza
+	// centrale, frequenza di campionamento e campi non usati.
+	copy(h[72:], "auxi")
+	le.PutUint32(h[76:], 68)
+	systemTime(h[80:], w.start)
+	systemTime(h[96:], stop)
+	le.PutUint32(h[112:], w.frequency)
+	le.PutUint32(h[116:], w.fs)
+
+	// Chunk data.
+	copy(h[148:], "data")
+	switch {
+	case unknown || rf64:
+		le.PutUint32(h[152:], wavUnknown)
+	default:
+		le.PutUint32(h[152:], uint32(w.size))
+	}
+
+	return h
+}
+
+// systemTime scrive in b l'istante t nel formato SYSTEMTIME di Windows.
+func systemTime(b []byte, t time.Time) {
+	le := binary.LittleEndian
+
+	le.PutUint16(b[0:], uint16(t.Year()))
+	le.PutUint16(b[2:], uint16(t.Month()))
+	le.PutUint16(b[4:], uint16(t.Weekday()))
+	le.PutUint16(b[6:], uint16(t.Day()))
+	le.PutUint16(b[8:], uint16(t.Hour()))
+	le.PutUint16(b[10:], uint16(t.Minute()))
+	le.PutUint16(b[12:], uint16(t.Second()))
+	le.PutUint16(b[14:], uint16(t.Nanosecond()/1e6))
+}
@@ -0,0 +1,172 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"math"
+	"math/cmplx"
+	"sync"
+)
+
+// AudioRate è la frequenza di campionamento, espressa in Hz, del segnale audio
+// prodotto dai demodulatori.
+const AudioRate = 48000
+
+type (
+	// WBFM è un Connector che demodula un segnale FM a banda larga (broadcast)
+	// centrato nella frequenza sintonizzata e propaga l'audio ottenuto, a
+	// AudioRate campioni al secondo, al connettore audio fornito.
+	// La catena di elaborazione è composta da:
+	//   * filtro di canale e decimazione ad una frequenza intermedia di circa
+	//     250kHz
+	//   * discriminatore a quadratura
+	//   * de-enfasi
+	//   * filtro audio e ricampionamento a AudioRate
+	// Se abilitata con Stereo, viene eseguita anche la decodifica stereofonica.
+	WBFM struct {
+		mu sync.Mutex
+
+		// rate è la frequenza intermedia, espressa in Hz, alla quale avviene la
+		// demodulazione.
+		rate float64
+
+		dec *decimator
+
+		// prev è l'ultimo campione in uscita dal decimatore, serve al
+		// discriminatore.
+		prev complex128
+
+		// gain normalizza l'uscita del discriminatore così che la deviazione
+		// massima corrisponda al fondo scala.
+		gain float64
+
+		de *deemphasis
+		rs *resampler
+
+		// st è il decodificatore stereo, nil se non abilitato.
+		st *stereo
+
+		// iq, bb, mpx e af sono i buffer di lavoro riusati ad ogni frame.
+		iq, bb       []complex128
+		mpx, af, pcm []float64
+		audio        []float32
+
+		out AudioConnector
+	}
+)
+
+const (
+	// wbfmRate è la frequenza intermedia, espressa in Hz, alla quale viene
+	// demodulato il segnale FM.
+	wbfmRate = 250e3
+
+	// wbfmDeviation è la deviazione massima, espressa in Hz, del segnale FM
+	// broadcast.
+	wbfmDeviation = 75e3
+
+	// wbfmAudio è la massima frequenza audio, espressa in Hz, del segnale
+	// monofonico FM broadcast.
+	wbfmAudio = 15e3
+)
+
+// NewWBFM restituisce un demodulatore WBFM per un segnale in banda base
+// campionato con frequenza fs, espressa in Hz, che propaga l'audio demodulato
+// al connettore out. Di default viene applicata la de-enfasi Emphasis50us,
+// quella in uso in Europa.
+func NewWBFM(fs float64, out AudioConnector) *WBFM {
+	factor := int(fs / wbfmRate)
+	if factor < 1 {
+		factor = 1
+	}
+
+	rate := fs / float64(factor)
+
+	return &WBFM{
+		rate: rate,
+		dec:  newDecimator(factor, math.Min(115e3/fs, 0.45/float64(factor))),
+		gain: rate / (2 * math.Pi * wbfmDeviation),
+		de:   newDeemphasis(Emphasis50us, rate),
+		rs:   newResampler(rate, AudioRate, wbfmAudio),
+		out:  out,
+	}
+}
+
+// Deemphasis imposta la de-enfasi applicata all'audio demodulato, che deve
+// corrispondere a quella in uso nella regione di ricezione.
+func (w *WBFM) Deemphasis(e Emphasis) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.de = newDeemphasis(e, w.rate)
+	if w.st != nil {
+		w.st.deemphasis(e)
+	}
+}
+
+// Stereo abilita la decodifica stereofonica: l'audio stereo viene propagato al
+// connettore out, in aggiunta all'audio monofonico propagato al connettore
+// fornito a NewWBFM (che può quindi essere nil). Quando il pilota a 19kHz non è
+// presente o il segnale è debole, la separazione dei canali viene ridotta
+// gradualmente fino a propagare lo stesso audio monofonico su entrambi i
+// canali. Un out nil disabilita la decodifica.
+func (w *WBFM) Stereo(out StereoConnector) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if out == nil {
+		w.st = nil
+		return
+	}
+
+	e := Emphasis50us
+	if w.de != nil {
+		e = w.de.emphasis
+	}
+
+	w.st = newStereo(w.rate, e, out)
+}
+
+// Pilot indica se la decodifica stereo è abilitata ed il pilota a 19kHz è
+// agganciato, ossia se il segnale ricevuto è effettivamente stereofonico.
+func (w *WBFM) Pilot() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.st != nil && w.st.locked
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (w *WBFM) Propagate(I []int16, Q []int16) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.iq = toComplex(I, Q, w.iq[:0])
+	w.bb = w.dec.process(w.iq, w.bb[:0])
+
+	w.mpx = w.mpx[:0]
+	w.af = w.af[:0]
+	for _, x := range w.bb {
+		d := cmplx.Phase(x*cmplx.Conj(w.prev)) * w.gain
+		w.prev = x
+
+		w.mpx = append(w.mpx, d)
+		w.af = append(w.af, w.de.process(d))
+	}
+
+	if w.st != nil {
+		w.st.process(w.mpx)
+	}
+
+	w.pcm = w.rs.process(w.af, w.pcm[:0])
+	if len(w.pcm) == 0 || w.out == nil {
+		return
+	}
+
+	w.audio = toAudio(w.pcm, w.audio[:0])
+	w.out.PropagateAudio(w.audio)
+}
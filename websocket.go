@@ -0,0 +1,503 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// SpectrumServer è un Connector che calcola lo spettro del segnale in banda
+	// base e lo trasmette via WebSocket ai browser connessi, ad esempio ad
+	// un'interfaccia web che disegna il waterfall. Implementa http.Handler, così
+	// da poter essere montato in un http.ServeMux esistente.
+	// Di default gli spettri sono inviati in messaggi binari, little endian,
+	// composti da frequenza centrale e frequenza di campionamento in Hz
+	// (float64) seguite dalla potenza di ogni bin in dBFS (float32); con il
+	// parametro format=json nell'URL sono invece inviati in messaggi di testo
+	// come {"frequency":...,"sampleRate":...,"power":[...]}.
+	// I client possono inviare messaggi di testo {"tune":Hz} e
+	// {"reduction":dB}, con il gain reduction come Amplifier.Gain, applicati
+	// al ricevitore fornito a Control; un eventuale errore viene restituito
+	// come {"error":"..."}.
+	// Poiché i comandi modificano la configurazione della RSP, vengono accettate
+	// solo le connessioni senza intestazione Origin, cioè non provenienti da un
+	// browser, o provenienti da una pagina dello stesso server; altre origini
+	// vanno autorizzate con AllowOrigin.
+	// Come Spectrum, SpectrumServer va registrato come Trigger perché la
+	// frequenza centrale segua le risintonie:
+	//
+	//	srv := sdrplay.NewSpectrumServer(fs, 1024)
+	//	rx, e := sdrplay.RSP(srv, sdrplay.OnRetune(srv))
+	//	...
+	//	e = srv.ListenAndServe(":8080", rx)
+	SpectrumServer struct {
+		*Spectrum
+
+		mu sync.Mutex
+
+		rx      Receiver
+		origins []string
+		clients map[*wsClient]struct{}
+		closed  bool
+	}
+
+	// wsClient è un browser connesso a SpectrumServer.
+	wsClient struct {
+		conn   net.Conn
+		json   bool
+		frames chan []byte
+		sent   chan struct{}
+	}
+
+	// wsCommand è un comando inviato da un client di SpectrumServer.
+	wsCommand struct {
+		Tune      *float64 `json:"tune"`
+		Reduction *int     `json:"reduction"`
+	}
+
+	// wsSpectrum è la codifica JSON di uno spettro.
+	wsSpectrum struct {
+		Frequency  float64   `json:"frequency"`
+		SampleRate float64   `json:"sampleRate"`
+		Power      []float32 `json:"power"`
+	}
+)
+
+const (
+	// wsGUID è la costante con cui viene calcolata la risposta all'handshake
+	// WebSocket (RFC 6455).
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// wsQueue è il numero massimo di messaggi in attesa di essere inviati ad
+	// un client, oltre il quale gli spettri vengono scartati.
+	wsQueue = 16
+
+	// wsMaxMessage è la dimensione massima di un messaggio accettato da un
+	// client.
+	wsMaxMessage = 4096
+
+	// wsCloseTimeout è il tempo concesso per inviare ad un client i frame
+	// rimasti in coda, compreso quello di chiusura, prima di chiuderne la
+	// connessione.
+	wsCloseTimeout = time.Second
+)
+
+// Opcode dei frame WebSocket.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+var (
+	// WebSocketError indica che un client non rispetta il protocollo
+	// WebSocket.
+	WebSocketError = errors.New("WebSocket Error")
+
+	// NoReceiverError indica che un comando non può essere applicato perché
+	// non è stato indicato il ricevitore da controllare.
+	NoReceiverError = errors.New("No Receiver Error")
+)
+
+// NewSpectrumServer restituisce uno SpectrumServer per un segnale campionato
+// con frequenza fs, espressa in Hz, che trasmette spettri di bins punti con le
+// impostazioni predefinite di Spectrum, modificabili con Rate ed Average.
+func NewSpectrumServer(fs float64, bins int) *SpectrumServer {
+	s := &SpectrumServer{clients: make(map[*wsClient]struct{})}
+	s.Spectrum = NewSpectrum(fs, bins, nil, s.broadcast)
+
+	return s
+}
+
+// Control imposta il ricevitore al quale vengono applicati i comandi dei
+// client. Senza ricevitore i comandi vengono rifiutati.
+func (s *SpectrumServer) Control(rx Receiver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rx = rx
+}
+
+// AllowOrigin autorizza le connessioni dalle pagine delle origini indicate, ad
+// esempio "https://example.com:8443", oltre a quelle dello stesso server.
+func (s *SpectrumServer) AllowOrigin(origins ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.origins = append(s.origins, origins...)
+}
+
+// ListenAndServe imposta il ricevitore rx con Control ed accetta connessioni
+// HTTP all'indirizzo addr, servendo i WebSocket su qualunque percorso.
+func (s *SpectrumServer) ListenAndServe(addr string, rx Receiver) error {
+	s.Control(rx)
+
+	return http.ListenAndServe(addr, s)
+}
+
+// Close chiude le connessioni di tutti i client e rifiuta le successive.
+func (s *SpectrumServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for c := range s.clients {
+		c.conn.Close()
+	}
+
+	return nil
+}
+
+// ServeHTTP implementa l'interfaccia http.Handler, eseguendo l'handshake
+// WebSocket e servendo il client fino alla sua disconnessione.
+func (s *SpectrumServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "WebSocket required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return
+	}
+
+	s.mu.Lock()
+	allowed := allowedOrigin(r, s.origins)
+	s.mu.Unlock()
+
+	if !allowed {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, rw, e := h.Hijack()
+	if e != nil {
+		return
+	}
+	defer conn.Close()
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if e := rw.Flush(); e != nil {
+		return
+	}
+
+	c := &wsClient{
+		conn:   conn,
+		json:   r.URL.Query().Get("format") == "json",
+		frames: make(chan []byte, wsQueue),
+		sent:   make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	go c.send()
+
+	s.receive(c, rw.Reader)
+
+	s.mu.Lock()
+	delete(s.clients, c)
+	close(c.frames)
+	s.mu.Unlock()
+
+	// La connessione viene chiusa solo dopo l'invio dei frame in coda, così
+	// che l'eventuale risposta al frame di chiusura raggiunga il client.
+	conn.SetWriteDeadline(time.Now().Add(wsCloseTimeout))
+	<-c.sent
+}
+
+// receive interpreta i messaggi ricevuti dal client fino alla sua
+// disconnessione o alla ricezione di un frame di chiusura.
+func (s *SpectrumServer) receive(c *wsClient, r *bufio.Reader) {
+	var msg []byte
+	var op byte
+
+	for {
+		fin, opcode, payload, e := wsRead(r)
+		if e != nil {
+			return
+		}
+
+		switch opcode {
+		case wsClose:
+			c.queue(wsFrame(wsClose, payload))
+			return
+		case wsPing:
+			c.queue(wsFrame(wsPong, payload))
+			continue
+		case wsPong:
+			continue
+		case wsContinuation:
+			if op == 0 {
+				return
+			}
+		default:
+			msg, op = msg[:0], opcode
+		}
+
+		msg = append(msg, payload...)
+		if len(msg) > wsMaxMessage {
+			return
+		}
+
+		if fin {
+			if op == wsText {
+				s.command(c, msg)
+			}
+			op = 0
+		}
+	}
+}
+
+// command applica al ricevitore il comando msg, restituendo al client
+// l'eventuale errore.
+func (s *SpectrumServer) command(c *wsClient, msg []byte) {
+	var cmd wsCommand
+	if e := json.Unmarshal(msg, &cmd); e != nil {
+		c.reply(e)
+		return
+	}
+
+	s.mu.Lock()
+	rx := s.rx
+	s.mu.Unlock()
+
+	if rx == nil {
+		c.reply(NoReceiverError)
+		return
+	}
+
+	if cmd.Tune != nil {
+		if e := rx.Tune(*cmd.Tune); e != nil {
+			c.reply(e)
+			return
+		}
+		s.Fire(*cmd.Tune)
+	}
+
+	if cmd.Reduction != nil {
+		if e := rx.Gain(*cmd.Reduction); e != nil {
+			c.reply(e)
+		}
+	}
+}
+
+// broadcast invia lo spettro f a tutti i client, codificandolo una sola volta
+// per ogni formato.
+func (s *SpectrumServer) broadcast(f SpectrumFrame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bin, text []byte
+	for c := range s.clients {
+		if c.json {
+			if text == nil {
+				text = wsFrame(wsText, encodeSpectrumJSON(f))
+			}
+			c.queue(text)
+		} else {
+			if bin == nil {
+				bin = wsFrame(wsBinary, encodeSpectrum(f))
+			}
+			c.queue(bin)
+		}
+	}
+}
+
+// queue accoda il frame b per l'invio al client, scartandolo se la coda è
+// piena.
+func (c *wsClient) queue(b []byte) {
+	select {
+	case c.frames <- b:
+	default:
+	}
+}
+
+// reply invia al client l'errore e.
+func (c *wsClient) reply(e error) {
+	b, _ := json.Marshal(map[string]string{"error": e.Error()})
+	c.queue(wsFrame(wsText, b))
+}
+
+// send invia al client i frame in coda fino alla sua disconnessione.
+func (c *wsClient) send() {
+	defer close(c.sent)
+
+	for b := range c.frames {
+		if _, e := c.conn.Write(b); e != nil {
+			c.conn.Close()
+		}
+	}
+}
+
+// encodeSpectrum restituisce la codifica binaria dello spettro f.
+func encodeSpectrum(f SpectrumFrame) []byte {
+	b := make([]byte, 16+4*len(f.Power))
+	binary.LittleEndian.PutUint64(b, math.Float64bits(f.Frequency))
+	binary.LittleEndian.PutUint64(b[8:], math.Float64bits(f.SampleRate))
+	for k, p := range f.Power {
+		binary.LittleEndian.PutUint32(b[16+4*k:], math.Float32bits(float32(p)))
+	}
+
+	return b
+}
+
+// encodeSpectrumJSON restituisce la codifica JSON dello spettro f.
+func encodeSpectrumJSON(f SpectrumFrame) []byte {
+	power := make([]float32, len(f.Power))
+	for k, p := range f.Power {
+		power[k] = float32(math.Round(p*10) / 10)
+	}
+
+	b, _ := json.Marshal(wsSpectrum{Frequency: f.Frequency, SampleRate: f.SampleRate, Power: power})
+
+	return b
+}
+
+// wsRead legge un frame inviato da un client e ne restituisce il flag FIN,
+// l'opcode ed il contenuto. I frame dei client devono essere mascherati.
+func wsRead(r io.Reader) (bool, byte, []byte, error) {
+	var h [8]byte
+	if _, e := io.ReadFull(r, h[:2]); e != nil {
+		return false, 0, nil, e
+	}
+
+	fin, opcode := h[0]&0x80 != 0, h[0]&0x0F
+	if h[1]&0x80 == 0 {
+		return false, 0, nil, WebSocketError
+	}
+
+	size := uint64(h[1] & 0x7F)
+	switch size {
+	case 126:
+		if _, e := io.ReadFull(r, h[:2]); e != nil {
+			return false, 0, nil, e
+		}
+		size = uint64(binary.BigEndian.Uint16(h[:2]))
+	case 127:
+		if _, e := io.ReadFull(r, h[:8]); e != nil {
+			return false, 0, nil, e
+		}
+		size = binary.BigEndian.Uint64(h[:8])
+	}
+
+	if size > wsMaxMessage {
+		return false, 0, nil, WebSocketError
+	}
+
+	var mask [4]byte
+	if _, e := io.ReadFull(r, mask[:]); e != nil {
+		return false, 0, nil, e
+	}
+
+	payload := make([]byte, size)
+	if _, e := io.ReadFull(r, payload); e != nil {
+		return false, 0, nil, e
+	}
+
+	for k := range payload {
+		payload[k] ^= mask[k%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// wsFrame restituisce il frame WebSocket, non mascherato e non frammentato,
+// con l'opcode ed il contenuto indicati.
+func wsFrame(opcode byte, payload []byte) []byte {
+	n := len(payload)
+
+	b := make([]byte, 0, n+10)
+	b = append(b, 0x80|opcode)
+	switch {
+	case n < 126:
+		b = append(b, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+
+	return append(b, payload...)
+}
+
+// allowedOrigin indica se la richiesta r può essere servita in base alla sua
+// intestazione Origin: sono ammesse le richieste senza Origin, quelle dello
+// stesso host e quelle delle origini indicate.
+func allowedOrigin(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, o := range origins {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+
+	u, e := url.Parse(origin)
+	if e != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && strings.EqualFold(u.Host, r.Host)
+}
+
+// headerContains indica se l'intestazione HTTP name contiene, tra i suoi valori
+// separati da virgole, il token indicato, senza distinzione tra maiuscole e
+// minuscole.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
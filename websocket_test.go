@@ -0,0 +1,246 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iclac/sdrplay"
+)
+
+// wsDial invia al server di test srv la richiesta di handshake WebSocket con le
+// intestazioni indicate e ne restituisce la connessione e la risposta.
+func wsDial(t *testing.T, srv *httptest.Server, header http.Header) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+
+	conn, e := net.Dial("tcp", srv.Listener.Addr().String())
+	if e != nil {
+		t.Fatal(e)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/", nil)
+	req.Header = header
+	if e := req.Write(conn); e != nil {
+		t.Fatal(e)
+	}
+
+	r := bufio.NewReader(conn)
+	res, e := http.ReadResponse(r, req)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return conn, r, res
+}
+
+// wsHeader restituisce le intestazioni di un handshake WebSocket valido con
+// l'origine indicata.
+func wsHeader(origin string) http.Header {
+	h := http.Header{
+		"Connection":            {"Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+		"Sec-Websocket-Version": {"13"},
+	}
+	if origin != "" {
+		h.Set("Origin", origin)
+	}
+
+	return h
+}
+
+// wsClientFrame restituisce il frame WebSocket mascherato con l'opcode ed il
+// contenuto indicati.
+func wsClientFrame(opcode byte, payload []byte) []byte {
+	mask := []byte{1, 2, 3, 4}
+
+	b := []byte{0x80 | opcode}
+	if n := len(payload); n < 126 {
+		b = append(b, 0x80|byte(n))
+	} else {
+		b = binary.BigEndian.AppendUint16(append(b, 0x80|126), uint16(n))
+	}
+	b = append(b, mask...)
+	for k, v := range payload {
+		b = append(b, v^mask[k%4])
+	}
+
+	return b
+}
+
+// wsServerFrame legge un frame non frammentato inviato dal server e ne
+// restituisce opcode e contenuto.
+func wsServerFrame(r io.Reader) (byte, []byte, error) {
+	h := make([]byte, 2)
+	if _, e := io.ReadFull(r, h); e != nil {
+		return 0, nil, e
+	}
+
+	n := int(h[1] & 0x7F)
+	if n == 126 {
+		if _, e := io.ReadFull(r, h); e != nil {
+			return 0, nil, e
+		}
+		n = int(binary.BigEndian.Uint16(h))
+	}
+
+	b := make([]byte, n)
+	_, e := io.ReadFull(r, b)
+
+	return h[0] & 0x0F, b, e
+}
+
+// TestSpectrumServerHandshake verifica che SpectrumServer rifiuti gli handshake
+// non validi e le origini non autorizzate.
+func TestSpectrumServerHandshake(t *testing.T) {
+	s := sdrplay.NewSpectrumServer(2e6, 64)
+	s.AllowOrigin("https://example.com")
+	defer s.Close()
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		header func(http.Header)
+		status int
+	}{
+		{"valid", func(h http.Header) {}, http.StatusSwitchingProtocols},
+		{"no upgrade", func(h http.Header) { h.Del("Upgrade") }, http.StatusBadRequest},
+		{"no connection", func(h http.Header) { h.Set("Connection", "keep-alive") }, http.StatusBadRequest},
+		{"no key", func(h http.Header) { h.Del("Sec-Websocket-Key") }, http.StatusBadRequest},
+		{"version 8", func(h http.Header) { h.Set("Sec-Websocket-Version", "8") }, http.StatusUpgradeRequired},
+		{"same origin", func(h http.Header) { h.Set("Origin", srv.URL) }, http.StatusSwitchingProtocols},
+		{"allowed origin", func(h http.Header) { h.Set("Origin", "https://EXAMPLE.com") }, http.StatusSwitchingProtocols},
+		{"cross origin", func(h http.Header) { h.Set("Origin", "https://evil.example") }, http.StatusForbidden},
+		{"other port", func(h http.Header) { h.Set("Origin", "http://127.0.0.1:1") }, http.StatusForbidden},
+		{"null origin", func(h http.Header) { h.Set("Origin", "null") }, http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		h := wsHeader("")
+		test.header(h)
+
+		conn, _, res := wsDial(t, srv, h)
+		conn.Close()
+
+		if res.StatusCode != test.status {
+			t.Errorf("%s: got status %d, want %d", test.name, res.StatusCode, test.status)
+		}
+	}
+}
+
+// TestSpectrumServerMessages verifica che SpectrumServer risponda con un errore
+// ai comandi malformati, chiuda la connessione ai frame non validi e completi
+// l'handshake di chiusura.
+func TestSpectrumServerMessages(t *testing.T) {
+	s := sdrplay.NewSpectrumServer(2e6, 64)
+	defer s.Close()
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		frames []byte
+		reply  string
+		opcode byte
+	}{
+		{"invalid JSON", wsClientFrame(0x1, []byte(`{"tune":`)), `"error"`, 0x1},
+		{"invalid type", wsClientFrame(0x1, []byte(`{"tune":"x"}`)), `"error"`, 0x1},
+		{"no receiver", wsClientFrame(0x1, []byte(`{"tune":100e6}`)), sdrplay.NoReceiverError.Error(), 0x1},
+		{"ping", wsClientFrame(0x9, []byte("abc")), "abc", 0xA},
+		{"close", wsClientFrame(0x8, []byte{0x03, 0xE8}), "\x03\xE8", 0x8},
+		{"unmasked", []byte{0x81, 0x02, '{', '}'}, "", 0},
+		{"too long", wsClientFrame(0x1, make([]byte, 5000)), "", 0},
+		{"orphan continuation", wsClientFrame(0x0, []byte("{}")), "", 0},
+	}
+
+	for _, test := range tests {
+		conn, r, res := wsDial(t, srv, wsHeader(""))
+		if res.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("%s: status %d", test.name, res.StatusCode)
+		}
+
+		if _, e := conn.Write(test.frames); e != nil {
+			t.Fatal(e)
+		}
+
+		opcode, b, e := wsServerFrame(r)
+		switch {
+		case test.opcode == 0 && e == nil:
+			t.Errorf("%s: got frame %#x %q, want the connection closed", test.name, opcode, b)
+		case test.opcode != 0 && e != nil:
+			t.Errorf("%s: %v", test.name, e)
+		case test.opcode != 0 && (opcode != test.opcode || !strings.Contains(string(b), test.reply)):
+			t.Errorf("%s: got frame %#x %q, want %#x containing %q", test.name, opcode, b, test.opcode, test.reply)
+		}
+
+		if test.opcode == 0x8 {
+			if _, _, e := wsServerFrame(r); e != io.EOF {
+				t.Errorf("%s: got %v after the close frame, want EOF", test.name, e)
+			}
+		}
+
+		conn.Close()
+	}
+}
+
+// wsReceiver è un Receiver che riporta su gains i gain reduction impostati.
+type wsReceiver struct {
+	sdrplay.Receiver
+	gains chan int
+}
+
+// Gain implementa l'interfaccia Amplifier.
+func (r *wsReceiver) Gain(reduction int) error {
+	r.gains <- reduction
+
+	return nil
+}
+
+// TestSpectrumServerReduction verifica che il comando {"reduction":dB} imposti
+// il gain reduction del ricevitore.
+func TestSpectrumServerReduction(t *testing.T) {
+	s := sdrplay.NewSpectrumServer(2e6, 64)
+	defer s.Close()
+
+	rx := &wsReceiver{gains: make(chan int, 1)}
+	s.Control(rx)
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	conn, _, res := wsDial(t, srv, wsHeader(""))
+	defer conn.Close()
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status %d", res.StatusCode)
+	}
+
+	if _, e := conn.Write(wsClientFrame(0x1, []byte(`{"reduction":30}`))); e != nil {
+		t.Fatal(e)
+	}
+
+	select {
+	case gr := <-rx.gains:
+		if gr != 30 {
+			t.Errorf("got gain reduction %d, want 30", gr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("gain reduction not set")
+	}
+}
@@ -0,0 +1,374 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync"
+)
+
+type (
+	// ZMQPublisher è un Connector che pubblica il segnale in banda base su un
+	// socket ZeroMQ di tipo PUB, al quale possono sottoscriversi socket SUB di
+	// qualunque linguaggio, ad esempio pyzmq o i blocchi ZMQ di GNU Radio. Ogni
+	// frame viene pubblicato come messaggio di due parti: il topic del flusso
+	// ed i campioni come float32 complessi in little endian normalizzati al
+	// fondo scala (gr_complex). Il protocollo ZMTP 3 è implementato
+	// direttamente, con il solo meccanismo di sicurezza NULL.
+	// Altri flussi, con topic diversi, possono essere pubblicati sullo stesso
+	// socket attraverso Stream.
+	ZMQPublisher struct {
+		*zmqSocket
+
+		topic []byte
+		buf   []byte
+	}
+
+	// zmqSocket è il socket PUB condiviso dai flussi di un ZMQPublisher.
+	zmqSocket struct {
+		mu sync.Mutex
+
+		listener net.Listener
+		peers    map[*zmqPeer]struct{}
+		closed   bool
+	}
+
+	// zmqPeer è un socket SUB connesso, con le sue sottoscrizioni.
+	zmqPeer struct {
+		conn net.Conn
+
+		mu   sync.Mutex
+		subs map[string]struct{}
+
+		frames chan []byte
+	}
+)
+
+const (
+	// zmqQueue è il numero massimo di messaggi in attesa di essere inviati ad
+	// un sottoscrittore, oltre il quale i messaggi vengono scartati.
+	zmqQueue = 64
+
+	// zmqMaxFrame è la dimensione massima di un frame accettato da un
+	// sottoscrittore.
+	zmqMaxFrame = 1 << 16
+
+	// Flag dei frame ZMTP.
+	zmqMore    = 0x01
+	zmqLong    = 0x02
+	zmqCommand = 0x04
+)
+
+// ZMTPError indica che un peer ZeroMQ non rispetta il protocollo ZMTP 3 o non è
+// un socket SUB.
+var ZMTPError = errors.New("ZMTP Error")
+
+// NewZMQPublisher crea un socket PUB in ascolto all'indirizzo TCP addr, ad
+// esempio ":5556" per tcp://*:5556, e restituisce il ZMQPublisher che vi
+// pubblica il segnale con il topic indicato.
+func NewZMQPublisher(addr string, topic string) (*ZMQPublisher, error) {
+	l, e := net.Listen("tcp", addr)
+	if e != nil {
+		return nil, e
+	}
+
+	s := &zmqSocket{listener: l, peers: make(map[*zmqPeer]struct{})}
+	go s.accept()
+
+	return &ZMQPublisher{zmqSocket: s, topic: []byte(topic)}, nil
+}
+
+// Stream restituisce un ZMQPublisher che pubblica sullo stesso socket con un
+// topic diverso.
+func (p *ZMQPublisher) Stream(topic string) *ZMQPublisher {
+	return &ZMQPublisher{zmqSocket: p.zmqSocket, topic: []byte(topic)}
+}
+
+// Addr restituisce l'indirizzo sul quale il socket è in ascolto.
+func (p *ZMQPublisher) Addr() net.Addr {
+	return p.listener.Addr()
+}
+
+// Close chiude il socket e le connessioni di tutti i sottoscrittori.
+func (s *zmqSocket) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for p := range s.peers {
+		p.conn.Close()
+	}
+
+	return s.listener.Close()
+}
+
+// Propagate implementa l'interfaccia Connector.
+func (p *ZMQPublisher) Propagate(I []int16, Q []int16) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.peers) == 0 {
+		return
+	}
+
+	n := len(I)
+	if len(Q) < n {
+		n = len(Q)
+	}
+
+	p.buf = p.buf[:0]
+	for k := 0; k < n; k++ {
+		p.buf = binary.LittleEndian.AppendUint32(p.buf, math.Float32bits(float32(I[k])/fullScale))
+		p.buf = binary.LittleEndian.AppendUint32(p.buf, math.Float32bits(float32(Q[k])/fullScale))
+	}
+
+	// Il messaggio viene codificato una sola volta e condiviso tra i
+	// sottoscrittori interessati.
+	var msg []byte
+	for peer := range p.peers {
+		if !peer.subscribed(p.topic) {
+			continue
+		}
+
+		if msg == nil {
+			msg = zmqFrame(nil, zmqMore, p.topic)
+			msg = zmqFrame(msg, 0, p.buf)
+		}
+
+		select {
+		case peer.frames <- msg:
+		default:
+		}
+	}
+}
+
+// accept accetta le connessioni dei sottoscrittori fino alla chiusura del
+// socket.
+func (s *zmqSocket) accept() {
+	for {
+		conn, e := s.listener.Accept()
+		if e != nil {
+			return
+		}
+
+		go s.serve(conn)
+	}
+}
+
+// serve gestisce la connessione di un sottoscrittore: esegue l'handshake
+// ZMTP, avvia l'invio dei messaggi e ne interpreta le sottoscrizioni fino alla
+// disconnessione.
+func (s *zmqSocket) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	if e := zmqHandshake(conn, r); e != nil {
+		return
+	}
+
+	p := &zmqPeer{
+		conn:   conn,
+		subs:   make(map[string]struct{}),
+		frames: make(chan []byte, zmqQueue),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.peers[p] = struct{}{}
+	s.mu.Unlock()
+
+	go p.send()
+
+	for {
+		flags, body, e := zmqRead(r)
+		if e != nil {
+			break
+		}
+
+		p.control(flags, body)
+	}
+
+	s.mu.Lock()
+	delete(s.peers, p)
+	close(p.frames)
+	s.mu.Unlock()
+}
+
+// send invia al sottoscrittore i messaggi in coda fino alla sua
+// disconnessione.
+func (p *zmqPeer) send() {
+	for b := range p.frames {
+		if _, e := p.conn.Write(b); e != nil {
+			p.conn.Close()
+		}
+	}
+}
+
+// control interpreta un frame ricevuto dal sottoscrittore. In ZMTP 3.0 le
+// sottoscrizioni sono messaggi il cui primo byte vale 1 (sottoscrizione) o 0
+// (cancellazione), in ZMTP 3.1 sono i comandi SUBSCRIBE e CANCEL.
+func (p *zmqPeer) control(flags byte, body []byte) {
+	var subscribe bool
+	var topic []byte
+
+	switch {
+	case flags&zmqCommand != 0:
+		if len(body) == 0 {
+			return
+		}
+
+		// La lunghezza del nome va convertita prima della somma, altrimenti
+		// per 255 l'aritmetica su byte tornerebbe a 0.
+		n := int(body[0])
+		if 1+n > len(body) {
+			return
+		}
+
+		switch string(body[1 : 1+n]) {
+		case "SUBSCRIBE":
+			subscribe = true
+		case "CANCEL":
+		default:
+			return
+		}
+		topic = body[1+n:]
+	case len(body) > 0 && body[0] <= 1:
+		subscribe, topic = body[0] == 1, body[1:]
+	default:
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if subscribe {
+		p.subs[string(topic)] = struct{}{}
+	} else {
+		delete(p.subs, string(topic))
+	}
+}
+
+// subscribed indica se il sottoscrittore è interessato al topic indicato, cioè
+// se una delle sue sottoscrizioni ne è un prefisso.
+func (p *zmqPeer) subscribed(topic []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for s := range p.subs {
+		if strings.HasPrefix(string(topic), s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// zmqHandshake scambia con il peer il saluto ZMTP 3 ed il comando READY,
+// verificando che si tratti di un socket SUB o XSUB.
+func zmqHandshake(w io.Writer, r io.Reader) error {
+	greeting := make([]byte, 64)
+	copy(greeting, []byte{0xFF, 0, 0, 0, 0, 0, 0, 0, 1, 0x7F, 3, 0})
+	copy(greeting[12:], "NULL")
+
+	ready := []byte("\x05READY\x0bSocket-Type\x00\x00\x00\x03PUB")
+
+	if _, e := w.Write(zmqFrame(greeting, zmqCommand, ready)); e != nil {
+		return e
+	}
+
+	peer := make([]byte, 64)
+	if _, e := io.ReadFull(r, peer); e != nil {
+		return e
+	}
+
+	if peer[0] != 0xFF || peer[9] != 0x7F || peer[10] < 3 || string(bytes.TrimRight(peer[12:32], "\x00")) != "NULL" {
+		return ZMTPError
+	}
+
+	flags, body, e := zmqRead(r)
+	if e != nil {
+		return e
+	}
+
+	if flags&zmqCommand == 0 || !bytes.HasPrefix(body, []byte("\x05READY")) {
+		return ZMTPError
+	}
+
+	// Le proprietà sono coppie nome-valore con lunghezza di 1 e 4 byte.
+	props := body[6:]
+	for len(props) > 0 {
+		n := int(props[0])
+		if 1+n+4 > len(props) {
+			return ZMTPError
+		}
+		name := string(props[1 : 1+n])
+		props = props[1+n:]
+
+		v := int(binary.BigEndian.Uint32(props))
+		if 4+v > len(props) {
+			return ZMTPError
+		}
+		value := string(props[4 : 4+v])
+		props = props[4+v:]
+
+		if strings.EqualFold(name, "Socket-Type") && value != "SUB" && value != "XSUB" {
+			return ZMTPError
+		}
+	}
+
+	return nil
+}
+
+// zmqRead legge un frame ZMTP e ne restituisce flag e contenuto.
+func zmqRead(r io.Reader) (byte, []byte, error) {
+	var h [9]byte
+	if _, e := io.ReadFull(r, h[:2]); e != nil {
+		return 0, nil, e
+	}
+
+	flags, size := h[0], uint64(h[1])
+	if flags&zmqLong != 0 {
+		if _, e := io.ReadFull(r, h[2:9]); e != nil {
+			return 0, nil, e
+		}
+		size = binary.BigEndian.Uint64(h[1:9])
+	}
+
+	if size > zmqMaxFrame {
+		return 0, nil, ZMTPError
+	}
+
+	body := make([]byte, size)
+	if _, e := io.ReadFull(r, body); e != nil {
+		return 0, nil, e
+	}
+
+	return flags, body, nil
+}
+
+// zmqFrame aggiunge a b il frame ZMTP con i flag ed il contenuto indicati.
+func zmqFrame(b []byte, flags byte, body []byte) []byte {
+	if len(body) > 255 {
+		b = append(b, flags|zmqLong)
+		b = binary.BigEndian.AppendUint64(b, uint64(len(body)))
+	} else {
+		b = append(b, flags, byte(len(body)))
+	}
+
+	return append(b, body...)
+}
@@ -0,0 +1,150 @@
+/*
+   sdrplay is a Go package that enables to use the RSP (by SDRplay) in a Go program.
+   Copyright (C) 2016 Claudio Carraro carraro.claudio@gmail.com
+
+   See the COPYING file to GPLv2 license details.
+*/
+
+package sdrplay_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/iclac/sdrplay"
+)
+
+// zmtpFrame codifica un frame ZMTP con i flag ed il contenuto indicati.
+func zmtpFrame(flags byte, body []byte) []byte {
+	if len(body) > 255 {
+		b := binary.BigEndian.AppendUint64([]byte{flags | 0x02}, uint64(len(body)))
+		return append(b, body...)
+	}
+
+	return append([]byte{flags, byte(len(body))}, body...)
+}
+
+// zmtpRead legge un frame ZMTP e ne restituisce flag e contenuto.
+func zmtpRead(r io.Reader) (byte, []byte, error) {
+	h := make([]byte, 9)
+	if _, e := io.ReadFull(r, h[:2]); e != nil {
+		return 0, nil, e
+	}
+
+	size := uint64(h[1])
+	if h[0]&0x02 != 0 {
+		if _, e := io.ReadFull(r, h[2:]); e != nil {
+			return 0, nil, e
+		}
+		size = binary.BigEndian.Uint64(h[1:])
+	}
+
+	body := make([]byte, size)
+	_, e := io.ReadFull(r, body)
+
+	return h[0], body, e
+}
+
+// zmtpSubscriber si connette al ZMQPublisher p come socket SUB ed esegue
+// l'handshake ZMTP 3.
+func zmtpSubscriber(t *testing.T, p *sdrplay.ZMQPublisher) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, e := net.Dial("tcp", p.Addr().String())
+	if e != nil {
+		t.Fatal(e)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	greeting := make([]byte, 64)
+	copy(greeting, []byte{0xFF, 0, 0, 0, 0, 0, 0, 0, 1, 0x7F, 3, 1})
+	copy(greeting[12:], "NULL")
+
+	msg := append(greeting, zmtpFrame(0x04, []byte("\x05READY\x0bSocket-Type\x00\x00\x00\x03SUB"))...)
+	if _, e := conn.Write(msg); e != nil {
+		t.Fatal(e)
+	}
+
+	r := bufio.NewReader(conn)
+	if _, e := io.ReadFull(r, greeting); e != nil {
+		t.Fatal(e)
+	}
+	if flags, body, e := zmtpRead(r); e != nil || flags&0x04 == 0 || !bytes.HasPrefix(body, []byte("\x05READY")) {
+		t.Fatalf("READY: flags %#x, body %q, error %v", flags, body, e)
+	}
+
+	return conn, r
+}
+
+// TestZMQPublisherControl verifica che i frame di controllo malformati di un
+// sottoscrittore vengano ignorati senza interrompere il servizio, e che una
+// sottoscrizione successiva riceva il flusso.
+func TestZMQPublisherControl(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+	}{
+		{"empty command", zmtpFrame(0x04, nil)},
+		{"empty message", zmtpFrame(0, nil)},
+		{"unknown message", zmtpFrame(0, []byte("\x02iq"))},
+		{"truncated name", zmtpFrame(0x04, []byte("\x09SUBSCR"))},
+		{"name length 255, truncated", zmtpFrame(0x04, append([]byte{255}, "SUBSCRIBE"...))},
+		{"name length 255", zmtpFrame(0x04, append([]byte{255}, bytes.Repeat([]byte("A"), 255)...))},
+		{"name length 255, topic", zmtpFrame(0x04, append([]byte{255}, bytes.Repeat([]byte("A"), 300)...))},
+		{"unknown command", zmtpFrame(0x04, []byte("\x05PING\x00iq"))},
+	}
+
+	p, e := sdrplay.NewZMQPublisher("127.0.0.1:0", "iq")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer p.Close()
+
+	I, Q := []int16{1, 2}, []int16{3, 4}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, r := zmtpSubscriber(t, p)
+			defer conn.Close()
+
+			msg := append(test.frame, zmtpFrame(0x04, []byte("\x09SUBSCRIBEiq"))...)
+			if _, e := conn.Write(msg); e != nil {
+				t.Fatal(e)
+			}
+
+			// La sottoscrizione viene registrata in modo asincrono: si
+			// pubblica finché il sottoscrittore non riceve il messaggio.
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				for {
+					select {
+					case <-done:
+						return
+					case <-time.After(time.Millisecond):
+						p.Propagate(I, Q)
+					}
+				}
+			}()
+
+			flags, topic, e := zmtpRead(r)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if flags&0x01 == 0 || string(topic) != "iq" {
+				t.Fatalf("topic: flags %#x, body %q", flags, topic)
+			}
+
+			if _, samples, e := zmtpRead(r); e != nil {
+				t.Fatal(e)
+			} else if len(samples) != 8*len(I) {
+				t.Errorf("got %d bytes of samples, want %d", len(samples), 8*len(I))
+			}
+		})
+	}
+}